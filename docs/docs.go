@@ -140,7 +140,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create Bus",
+                "description": "Create Bus. The seat layout is taken from \"seats\", generated from \"rows\" x \"columns\",\nor generated from \"total_seats\" with four seats per row.",
                 "consumes": [
                     "application/json"
                 ],
@@ -275,192 +275,110 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/pages/{title}": {
+        "/api/email-templates": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves a page's content by title. Available titles: \"about_us\", \"privacy_policy\", \"help_support\".",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "The current template of every editable email in every locale, shipped ones have builtin set.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "pages"
-                ],
-                "summary": "Get a page",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Page Title",
-                        "name": "title",
-                        "in": "path",
-                        "required": true
-                    }
+                    "email-templates"
                 ],
+                "summary": "List email templates",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domain.Page"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.EmailTemplate"
+                            }
                         }
                     },
-                    "404": {
-                        "description": "Page not found",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errs.Err"
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/api/email-templates/schema": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates the content of a page by title. Only admins can update.Available titles: \"about_us\", \"privacy_policy\", \"help_support\".",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "The variables each editable email can use, by template name. Fields of list items, like .Tickets[].QR,\nare used inside {{range .Tickets}} as {{.QR}}.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "pages"
-                ],
-                "summary": "Update a page",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Page Title",
-                        "name": "title",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Page content",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.UpdatePageRequest"
-                        }
-                    }
+                    "email-templates"
                 ],
+                "summary": "Email template variables",
                 "responses": {
                     "200": {
-                        "description": "Page updated successfully",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "400": {
-                        "description": "Invalid request",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "500": {
-                        "description": "Failed to update page",
+                        "description": "OK",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
-                                "type": "string"
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/templates.Variable"
+                                }
                             }
                         }
                     }
                 }
             }
         },
-        "/api/routes": {
+        "/api/email-templates/{name}/{locale}": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieve a list of available routes based on filters",
-                "consumes": [
-                    "application/json"
-                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "route"
+                    "email-templates"
                 ],
-                "summary": "Get Routes List",
+                "summary": "Get email template",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Departure location",
-                        "name": "departure",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Destination location",
-                        "name": "destination",
-                        "in": "query",
+                        "description": "tickets, cancellation, reminder or route_change",
+                        "name": "name",
+                        "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Travel date (format: YYYY-MM-DD)",
-                        "name": "date",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Number of passengers",
-                        "name": "passengers",
-                        "in": "query",
+                        "description": "en, ru or kk",
+                        "name": "locale",
+                        "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Page number for pagination (default: 1)",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Page size for pagination (default: 30)",
-                        "name": "pageSize",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "List of routes",
+                        "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "array",
-                                "items": {
-                                    "$ref": "#/definitions/domain.Route"
-                                }
-                            }
+                            "$ref": "#/definitions/domain.EmailTemplate"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
@@ -473,13 +391,13 @@ const docTemplate = `{
                     }
                 }
             },
-            "post": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new bus route",
+                "description": "The subject is plain text, the body is the HTML inside the email layout. Both are Go templates that can\nonly use the variables of the schema; the template is rendered against sample data before it is saved.",
                 "consumes": [
                     "application/json"
                 ],
@@ -487,25 +405,39 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "route"
+                    "email-templates"
                 ],
-                "summary": "Create Route",
+                "summary": "Save email template",
                 "parameters": [
                     {
-                        "description": "Route creation request",
+                        "type": "string",
+                        "description": "tickets, cancellation, reminder or route_change",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "en, ru or kk",
+                        "name": "locale",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Template sources",
                         "name": "requestBody",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.CreateRouteRequest"
+                            "$ref": "#/definitions/model.EmailTemplateRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Success",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domain.Route"
+                            "$ref": "#/definitions/domain.EmailTemplate"
                         }
                     },
                     "400": {
@@ -514,6 +446,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/errs.Err"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -521,44 +459,40 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/routes/{routeId}": {
-            "get": {
+            },
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieve a route by its ID",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Emails use the shipped template again. The saved versions are kept and can be restored.",
                 "tags": [
-                    "route"
+                    "email-templates"
                 ],
-                "summary": "Get Route",
+                "summary": "Revert email template",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Route ID",
-                        "name": "routeId",
+                        "description": "tickets, cancellation, reminder or route_change",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "en, ru or kk",
+                        "name": "locale",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Success",
-                        "schema": {
-                            "$ref": "#/definitions/aulway_internal_handler_route_model.RouteResponse"
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Template was not edited",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
@@ -570,47 +504,62 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/api/email-templates/{name}/{locale}/preview": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update an existing route by its ID",
+                "description": "Renders the sources in the body, or the current template when the body is empty, against a sample\norder. With format=html the rendered email is returned as a page.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/html"
                 ],
                 "tags": [
-                    "route"
+                    "email-templates"
                 ],
-                "summary": "Update Route",
+                "summary": "Preview email template",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Route ID",
-                        "name": "routeId",
+                        "description": "tickets, cancellation, reminder or route_change",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Update Route Request Body",
+                        "type": "string",
+                        "description": "en, ru or kk",
+                        "name": "locale",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or html",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Template sources",
                         "name": "requestBody",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.UpdateRouteRequest"
+                            "$ref": "#/definitions/model.EmailTemplateRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Route updated successfully",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/domain.EmailPreview"
                         }
                     },
                     "400": {
@@ -619,6 +568,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/errs.Err"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -626,42 +581,50 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/email-templates/{name}/{locale}/versions": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Delete a route by its ID",
-                "consumes": [
-                    "application/json"
-                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "route"
+                    "email-templates"
                 ],
-                "summary": "Delete Route",
+                "summary": "Email template history",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Route ID",
-                        "name": "routeId",
+                        "description": "tickets, cancellation, reminder or route_change",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "en, ru or kk",
+                        "name": "locale",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Success",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.EmailTemplateVersion"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
@@ -675,66 +638,65 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/tickets": {
-            "get": {
+        "/api/email-templates/{name}/{locale}/versions/{version}/restore": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieve tickets sorted by user, start date, route, price, status, or payment status with pagination.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Saves the version again as the newest one.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "email-templates"
                 ],
-                "summary": "Get sorted and paginated tickets",
+                "summary": "Restore email template version",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Sort by field (user, start_date, route, price, status, payment_status)",
-                        "name": "sort_by",
-                        "in": "query",
+                        "description": "tickets, cancellation, reminder or route_change",
+                        "name": "name",
+                        "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "default": "asc",
-                        "description": "Sorting order (asc or desc)",
-                        "name": "order",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number (default: 1)",
-                        "name": "page",
-                        "in": "query"
+                        "description": "en, ru or kk",
+                        "name": "locale",
+                        "in": "path",
+                        "required": true
                     },
                     {
                         "type": "integer",
-                        "default": 30,
-                        "description": "Number of tickets per page (default: 30)",
-                        "name": "page_size",
-                        "in": "query"
+                        "description": "Version to restore",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "List of tickets",
+                        "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/domain.Ticket"
-                            }
+                            "$ref": "#/definitions/domain.EmailTemplate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
@@ -742,117 +704,70 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/tickets/users/cancelled": {
+        "/api/manifest-key": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
+                "description": "Returns the base64 Ed25519 public key drivers' devices verify manifests with. The key can only check\nsignatures, it cannot sign manifests or ticket codes.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
-                ],
-                "summary": "Get cancelled tickets",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number (default: 1)",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 30,
-                        "description": "Number of tickets per page (default: 30)",
-                        "name": "page_size",
-                        "in": "query"
-                    }
+                    "boarding"
                 ],
+                "summary": "Manifest signing key",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/domain.Ticket"
-                            }
-                        }
-                    },
-                    "403": {
-                        "description": "Forbidden",
-                        "schema": {
-                            "$ref": "#/definitions/errs.Err"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/errs.Err"
+                            "$ref": "#/definitions/model.ManifestKeyResponse"
                         }
                     }
                 }
             }
         },
-        "/api/tickets/users/{userId}": {
+        "/api/orders/{orderNumber}/ledger": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Fetches a user's past or upcoming tickets based on the type parameter.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists the ledger entries of the payment of an order with the running balance.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "ledger"
                 ],
-                "summary": "Get user tickets",
+                "summary": "Order ledger",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID",
-                        "name": "userId",
+                        "description": "Order number",
+                        "name": "orderNumber",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "enum": [
-                            "upcoming",
-                            "past"
-                        ],
-                        "type": "string",
-                        "description": "Type of tickets",
-                        "name": "type",
-                        "in": "query",
-                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/domain.Ticket"
-                            }
+                            "$ref": "#/definitions/domain.LedgerBalance"
                         }
                     },
-                    "400": {
-                        "description": "Invalid type",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
                     },
                     "500": {
-                        "description": "Failed to fetch tickets",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
@@ -860,27 +775,39 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/tickets/users/{userId}/cancelled": {
+        "/api/outbox": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
+                "description": "Lists the transactional emails, newest first. Use status=dead for the ones that ran out of retries.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "outbox"
                 ],
-                "summary": "Get cancelled tickets",
+                "summary": "List outbox emails",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID",
-                        "name": "userId",
-                        "in": "path",
-                        "required": true
+                        "description": "pending, sent or dead",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number (default: 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default: 30)",
+                        "name": "pageSize",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -889,12 +816,12 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/domain.Ticket"
+                                "$ref": "#/definitions/domain.OutboxMessage"
                             }
                         }
                     },
-                    "403": {
-                        "description": "Forbidden",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
@@ -908,35 +835,26 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/tickets/users/{userId}/{ticketId}": {
-            "get": {
+        "/api/outbox/{messageId}/resend": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Puts the email back in the queue with a fresh set of retries, the worker sends it on its next run.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "outbox"
                 ],
-                "summary": "Get user ticket's detail",
+                "summary": "Resend outbox email",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID",
-                        "name": "userId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Ticket ID",
-                        "name": "ticketId",
+                        "description": "Message ID",
+                        "name": "messageId",
                         "in": "path",
                         "required": true
                     }
@@ -945,11 +863,11 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domain.Ticket"
+                            "$ref": "#/definitions/domain.OutboxMessage"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
@@ -963,14 +881,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/tickets/users/{userId}/{ticketId}/cancel": {
-            "put": {
+        "/api/pages/{title}": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Cancels a ticket by ID if it belongs to the user",
+                "description": "Retrieves a page's content by title. Available titles: \"about_us\", \"privacy_policy\", \"help_support\".",
                 "consumes": [
                     "application/json"
                 ],
@@ -978,68 +896,43 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "pages"
                 ],
-                "summary": "Cancel ticket",
+                "summary": "Get a page",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID",
-                        "name": "userId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Ticket ID",
-                        "name": "ticketId",
+                        "description": "Page Title",
+                        "name": "title",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "email for sending mail",
-                        "name": "email",
-                        "in": "query",
-                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Cancellation successful",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/errs.Err"
-                        }
-                    },
-                    "403": {
-                        "description": "Access denied",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/errs.Err"
+                            "$ref": "#/definitions/domain.Page"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Page not found",
                         "schema": {
-                            "$ref": "#/definitions/errs.Err"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            }
-        },
-        "/api/tickets/{routeId}": {
-            "post": {
+            },
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Allows a user to purchase one or more tickets for a specific route using card details.",
+                "description": "Updates the content of a page by title. Only admins can update.Available titles: \"about_us\", \"privacy_policy\", \"help_support\".",
                 "consumes": [
                     "application/json"
                 ],
@@ -1047,52 +940,48 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "pages"
                 ],
-                "summary": "Buy tickets",
+                "summary": "Update a page",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Route ID",
-                        "name": "routeId",
+                        "description": "Page Title",
+                        "name": "title",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Payment method ID - pm_card_visa",
-                        "name": "payment_id",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "description": "Buy Ticket Request Body",
-                        "name": "requestBody",
+                        "description": "Page content",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.BuyTicketRequest"
+                            "$ref": "#/definitions/model.UpdatePageRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully purchased tickets",
+                        "description": "Page updated successfully",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/domain.Ticket"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
                     "400": {
-                        "description": "Invalid request or request binding failed",
+                        "description": "Invalid request",
                         "schema": {
-                            "$ref": "#/definitions/errs.Err"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Failed to update page",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1103,55 +992,45 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/users": {
+        "/api/payments/{paymentId}/ledger": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves a paginated list of users.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists the charges, refunds, chargebacks and fees of a payment with the running balance after each entry.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "ledger"
                 ],
-                "summary": "Get list of users",
+                "summary": "Payment ledger",
                 "parameters": [
                     {
-                        "minimum": 1,
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "maximum": 100,
-                        "minimum": 1,
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Number of users per page",
-                        "name": "pageSize",
-                        "in": "query"
+                        "type": "string",
+                        "description": "Payment ID",
+                        "name": "paymentId",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "List of users",
+                        "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/domain.User"
-                            }
+                            "$ref": "#/definitions/domain.LedgerBalance"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
                         }
                     },
                     "500": {
-                        "description": "Failed to retrieve users",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
@@ -1159,111 +1038,91 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/users/{userId}": {
+        "/api/payments/{paymentId}/ledger/check": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Fetches user details based on the provided user ID",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Compares the charged and refunded totals of the ledger with the amounts the payment provider reports.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "ledger"
                 ],
-                "summary": "Get user details",
+                "summary": "Check payment ledger",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID",
-                        "name": "userId",
+                        "description": "Payment ID",
+                        "name": "paymentId",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "User details retrieved successfully",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domain.User"
+                            "$ref": "#/definitions/domain.LedgerCheck"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/api/refund-policies": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates user information based on the given user ID",
-                "consumes": [
-                    "application/json"
-                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
-                ],
-                "summary": "Update user details",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "User ID",
-                        "name": "userId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "User update request body",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.UpdateUserRequest"
-                        }
-                    }
+                    "refund-policies"
                 ],
+                "summary": "List refund policies",
                 "responses": {
                     "200": {
-                        "description": "User updated successfully",
-                        "schema": {
-                            "$ref": "#/definitions/model.UserResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Invalid request body",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/errs.Err"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.RefundPolicy"
+                            }
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
                     }
                 }
             },
-            "delete": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Deletes a user by their ID. Only admin or the user themselves can delete.",
+                "description": "Tiers give the refund percent from a number of hours before departure, e.g. 100% from 72 hours,\n50% from 24 hours. Cancelling later than the smallest tier is not allowed.\nA non-refundable policy allows cancelling until departure without a refund.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1271,45 +1130,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "refund-policies"
                 ],
-                "summary": "Delete a user",
+                "summary": "Create refund policy",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User ID",
-                        "name": "userId",
-                        "in": "path",
-                        "required": true
+                        "description": "Refund policy",
+                        "name": "requestBody",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.RefundPolicyRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "User successfully deleted",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/domain.RefundPolicy"
                         }
                     },
                     "400": {
-                        "description": "Invalid user ID",
-                        "schema": {
-                            "$ref": "#/definitions/errs.Err"
-                        }
-                    },
-                    "403": {
-                        "description": "Unauthorized",
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
                     },
-                    "404": {
-                        "description": "User not found",
+                    "409": {
+                        "description": "Route already has a policy",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
                     },
                     "500": {
-                        "description": "Failed to delete user",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
@@ -1317,50 +1172,38 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/users/{userId}/change-password": {
-            "put": {
+        "/api/refund-policies/{policyId}": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "consumes": [
-                    "application/json"
-                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "refund-policies"
                 ],
-                "summary": "Change password",
+                "summary": "Get refund policy",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID",
-                        "name": "userId",
+                        "description": "Refund policy ID",
+                        "name": "policyId",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "change password",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.ResetPasswordRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "password change was successful",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/domain.RefundPolicy"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
@@ -1372,16 +1215,13 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/users/{userId}/favorites": {
-            "get": {
+            },
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Returns a list of user's favorite routes with route and bus info",
                 "consumes": [
                     "application/json"
                 ],
@@ -1389,97 +1229,81 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "favorites"
+                    "refund-policies"
                 ],
-                "summary": "Get Favorite Routes",
+                "summary": "Update refund policy",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID",
-                        "name": "userId",
+                        "description": "Refund policy ID",
+                        "name": "policyId",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "Page number for pagination (default: 1)",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Page size for pagination (default: 30)",
-                        "name": "pageSize",
-                        "in": "query"
+                        "description": "Refund policy",
+                        "name": "requestBody",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.RefundPolicyRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "List of favorite routes",
+                        "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/domain.Route"
-                            }
+                            "$ref": "#/definitions/domain.RefundPolicy"
                         }
                     },
-                    "403": {
-                        "description": "Access Denied",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "Route already has a policy",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
                     }
                 }
             },
-            "post": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Adds a route to the user's favorites",
-                "consumes": [
-                    "application/json"
-                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "favorites"
+                    "refund-policies"
                 ],
-                "summary": "Add to Favorites",
+                "summary": "Delete refund policy",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID",
-                        "name": "userId",
+                        "description": "Refund policy ID",
+                        "name": "policyId",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Favorite Route Request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.AddFavoriteRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Added to favorites",
+                        "description": "refund policy deleted",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "string"
                         }
                     },
                     "400": {
@@ -1488,14 +1312,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/errs.Err"
                         }
                     },
-                    "403": {
-                        "description": "Access Denied",
-                        "schema": {
-                            "$ref": "#/definitions/errs.Err"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
@@ -1503,14 +1321,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/users/{userId}/favorites/{routeId}": {
-            "delete": {
+        "/api/routes": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Removes a route from the user's favorites",
+                "description": "Retrieve a list of available routes based on filters",
                 "consumes": [
                     "application/json"
                 ],
@@ -1518,32 +1336,61 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "favorites"
+                    "route"
                 ],
-                "summary": "Remove from Favorites",
+                "summary": "Get Routes List",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID",
-                        "name": "userId",
-                        "in": "path",
+                        "description": "Departure location",
+                        "name": "departure",
+                        "in": "query",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Route ID",
-                        "name": "routeId",
-                        "in": "path",
+                        "description": "Destination location",
+                        "name": "destination",
+                        "in": "query",
                         "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Removed from favorites",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Travel date (format: YYYY-MM-DD)",
+                        "name": "date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of passengers",
+                        "name": "passengers",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number for pagination (default: 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size for pagination (default: 30)",
+                        "name": "pageSize",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of routes",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/domain.Route"
+                                }
                             }
                         }
                     },
@@ -1553,12 +1400,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/errs.Err"
                         }
                     },
-                    "403": {
-                        "description": "Access Denied",
-                        "schema": {
-                            "$ref": "#/definitions/errs.Err"
-                        }
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1566,10 +1407,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/auth/forgot-password": {
+            },
             "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new bus route",
                 "consumes": [
                     "application/json"
                 ],
@@ -1577,32 +1422,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "route"
                 ],
-                "summary": "Forgot password",
+                "summary": "Create Route",
                 "parameters": [
                     {
-                        "description": "forgot password",
-                        "name": "request",
+                        "description": "Route creation request",
+                        "name": "requestBody",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.ForgotPasswordRequest"
+                            "$ref": "#/definitions/model.CreateRouteRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Success",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/domain.Route"
                         }
                     },
                     "400": {
-                        "description": "Bad Request - Invalid request body",
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
@@ -1616,8 +1458,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/forgot-password/verify": {
-            "post": {
+        "/api/routes/{routeId}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a route by its ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -1625,32 +1473,27 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "route"
                 ],
-                "summary": "verify forgot password",
+                "summary": "Get Route",
                 "parameters": [
                     {
-                        "description": "required",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.VerifyResetCodeRequest"
-                        }
+                        "type": "string",
+                        "description": "Route ID",
+                        "name": "routeId",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Success",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/aulway_internal_handler_route_model.RouteResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request - Invalid request body",
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
@@ -1662,11 +1505,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/auth/signin": {
-            "post": {
-                "description": "Authenticate a user and return an access token.",
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update an existing route by its ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -1674,41 +1520,83 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "route"
                 ],
-                "summary": "User Signin",
+                "summary": "Update Route",
                 "parameters": [
                     {
-                        "description": "Signin Request Body",
-                        "name": "request",
+                        "type": "string",
+                        "description": "Route ID",
+                        "name": "routeId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Update Route Request Body",
+                        "name": "requestBody",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.SigninRequest"
+                            "$ref": "#/definitions/model.UpdateRouteRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Route updated successfully",
                         "schema": {
-                            "$ref": "#/definitions/model.SigninResponse"
+                            "type": "string"
                         }
                     },
                     "400": {
-                        "description": "Bad Request - Invalid request body",
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
                     },
-                    "403": {
-                        "description": "Forbidden - Password reset required",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a route by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "route"
+                ],
+                "summary": "Delete Route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Route ID",
+                        "name": "routeId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Success",
+                        "schema": {
+                            "type": "string"
+                        }
                     },
-                    "404": {
-                        "description": "Not Found - User not found or incorrect credentials",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
@@ -1722,39 +1610,39 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/signup": {
-            "post": {
-                "description": "Register a new user and send verification code.",
-                "consumes": [
-                    "application/json"
+        "/api/routes/{routeId}/boarding-stats": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Counts boarded passengers, no-shows and tickets still to board, with the occupancy of the bus\nand the share of due passengers that showed up. Tickets not scanned by the end of the grace period\nafter departure are counted as no-shows.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "boarding"
                 ],
-                "summary": "User Signup",
+                "summary": "Boarding statistics",
                 "parameters": [
                     {
-                        "description": "Signup Request Body",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.SignupRequest"
-                        }
+                        "type": "string",
+                        "description": "Route ID",
+                        "name": "routeId",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "response",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/domain.BoardingStats"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request - Invalid request body",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
@@ -1768,9 +1656,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/signup/verify": {
+        "/api/routes/{routeId}/holds": {
             "post": {
-                "description": "Register a new user and verify code.",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Reserves the given seats, or the first free ones for \"quantity\", until the hold expires.\nPass the returned hold id as \"hold_id\" when buying the tickets.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1778,29 +1671,42 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "tickets"
                 ],
-                "summary": "User Signup Verification",
+                "summary": "Hold seats",
                 "parameters": [
                     {
-                        "description": "VerifyEmail Request Body",
-                        "name": "request",
+                        "type": "string",
+                        "description": "Route ID",
+                        "name": "routeId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Hold Seats Request Body",
+                        "name": "requestBody",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.VerifyEmailRequest"
+                            "$ref": "#/definitions/model.HoldSeatsRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "response",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.SignupResponse"
+                            "$ref": "#/definitions/domain.SeatHold"
                         }
                     },
                     "400": {
-                        "description": "Bad Request - Invalid request body",
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "Seat is taken or does not exist",
                         "schema": {
                             "$ref": "#/definitions/errs.Err"
                         }
@@ -1813,79 +1719,4140 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "aulway_internal_handler_route_model.RouteResponse": {
-            "type": "object",
-            "properties": {
-                "available_seats": {
-                    "type": "integer"
+        },
+        "/api/routes/{routeId}/holds/{holdId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Release seat hold",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Route ID",
+                        "name": "routeId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Hold ID",
+                        "name": "holdId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/routes/{routeId}/manifest": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the approved tickets of a route for boarding without connectivity: passenger, seat, order number\nand the SHA-256 of the ticket QR token. The JSON manifest carries its signature in \"signature\",\nwith format=csv the signature of the CSV body is in the X-Manifest-Signature header.\nSignatures are base64 Ed25519, checked with the key from /api/manifest-key. The JSON signature\ncovers the manifest encoded without the \"signature\" field.",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "boarding"
+                ],
+                "summary": "Boarding manifest",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Route ID",
+                        "name": "routeId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "json",
+                        "description": "json or csv",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Manifest"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/routes/{routeId}/manifest/sync": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Boards the passengers scanned while offline, oldest scan first. Every scan is reported as boarded,\nduplicate (the ticket was already boarded), rejected (no longer valid or scanned outside the boarding\nwindow) or unknown (not on this route).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "boarding"
+                ],
+                "summary": "Sync offline boarding scans",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Route ID",
+                        "name": "routeId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Scans",
+                        "name": "requestBody",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.SyncRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ManifestSync"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/routes/{routeId}/seats": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the bus seat layout of a route and marks the seats that are already taken.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Get route seat map",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Route ID",
+                        "name": "routeId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.SeatMap"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tickets": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve tickets sorted by user, start date, route, price, status, or payment status with pagination.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Get sorted and paginated tickets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sort by field (user, start_date, route, price, status, payment_status)",
+                        "name": "sort_by",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "asc",
+                        "description": "Sorting order (asc or desc)",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number (default: 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 30,
+                        "description": "Number of tickets per page (default: 30)",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of tickets",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Ticket"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tickets/payments/{paymentId}/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Checks the payment of a purchase that needed 3-D Secure. Succeeded payments issue the tickets,\nfailed ones cancel them and give the seats back. A payment still in progress is returned as is.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Confirm ticket payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Payment ID",
+                        "name": "paymentId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Where to send the tickets",
+                        "name": "requestBody",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/model.ConfirmPaymentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Purchase"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Payment not found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tickets/users/cancelled": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Get cancelled tickets",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number (default: 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 30,
+                        "description": "Number of tickets per page (default: 30)",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Ticket"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tickets/users/{userId}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Fetches a user's past or upcoming tickets based on the type parameter.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Get user tickets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "upcoming",
+                            "past"
+                        ],
+                        "type": "string",
+                        "description": "Type of tickets",
+                        "name": "type",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Ticket"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid type",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to fetch tickets",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tickets/users/{userId}/cancelled": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Get cancelled tickets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Ticket"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tickets/users/{userId}/{ticketId}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Get user ticket's detail",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ticket ID",
+                        "name": "ticketId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Ticket"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tickets/users/{userId}/{ticketId}/cancel": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Cancels a ticket by ID if it belongs to the user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Cancel ticket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ticket ID",
+                        "name": "ticketId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "email for sending mail",
+                        "name": "email",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retries with the same key replay the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Cancellation successful",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "The refund policy does not allow cancelling now",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tickets/users/{userId}/{ticketId}/cancel/quote": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Shows what cancelling the ticket would refund under the refund policy of its route, nothing is cancelled",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Cancellation quote",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ticket ID",
+                        "name": "ticketId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.RefundQuote"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "Ticket was already used or missed",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tickets/users/{userId}/{ticketId}/pdf": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the ticket as a PDF with the route, bus, times, pickup addresses, seat, price, order number and QR code.",
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Download e-ticket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ticket ID",
+                        "name": "ticketId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "E-ticket",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "Ticket is cancelled or not paid yet",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tickets/users/{userId}/{ticketId}/wallet/apple": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the ticket as a signed .pkpass boarding pass with the route, times, seat and QR code.\nThe pass is updated on the device when the route's time or the ticket status changes.",
+                "produces": [
+                    "application/vnd.apple.pkpass"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Download Apple Wallet pass",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ticket ID",
+                        "name": "ticketId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Pass bundle",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "Ticket is cancelled or not paid yet",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "501": {
+                        "description": "Apple Wallet is not configured",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tickets/users/{userId}/{ticketId}/wallet/google": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns an \"Add to Google Wallet\" link holding the signed transit pass of the ticket.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Google Wallet link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ticket ID",
+                        "name": "ticketId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.GoogleWalletLink"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "Ticket is cancelled or not paid yet",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "501": {
+                        "description": "Google Wallet is not configured",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tickets/{routeId}": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Allows a user to purchase one or more tickets for a specific route using card details.\nPass the wanted seat numbers in \"seats\", or only a \"quantity\" to get the first free seats.\nSeats reserved before with a hold are bought by passing its \"hold_id\".\nWhen the card needs 3-D Secure the tickets are kept awaiting and 202 is returned with the PaymentIntent\nclient secret. Finish the authentication with stripe.js, then call the payment confirm endpoint.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Buy tickets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Route ID",
+                        "name": "routeId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Payment method ID - pm_card_visa",
+                        "name": "payment_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Buy Ticket Request Body",
+                        "name": "requestBody",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.BuyTicketRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retries with the same key replay the first response instead of charging again",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully purchased tickets",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Ticket"
+                            }
+                        }
+                    },
+                    "202": {
+                        "description": "Payment needs customer authentication",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Purchase"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or request binding failed",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "Seat is taken or does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency key reused with a different request",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves a paginated list of users.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get list of users",
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of users per page",
+                        "name": "pageSize",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of users",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.User"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to retrieve users",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{userId}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Fetches user details based on the provided user ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user details",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User details retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/domain.User"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates user information based on the given user ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update user details",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "User update request body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes a user by their ID. Only admin or the user themselves can delete.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User successfully deleted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "403": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to delete user",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{userId}/change-password": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Change password",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "change password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ResetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "password change was successful",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{userId}/devices": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "List devices",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Device"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Saves the FCM registration token of the app, purchases, cancellations, route changes and departure\nreminders are then pushed to it as well as emailed. Registering a token again updates it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Register device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Device",
+                        "name": "requestBody",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/aulway_internal_handler_device_model.RegisterDeviceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Device"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{userId}/devices/{token}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Called by the app on sign out.",
+                "tags": [
+                    "users"
+                ],
+                "summary": "Unregister device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "FCM registration token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{userId}/email/code": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Emails a verification code to the email of the user. Google, Apple and Firebase accounts are linked\nto the user by email only once it is verified. Nothing is sent when the email is verified already.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Send email code",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "The user has no email",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "429": {
+                        "description": "A code was emailed recently",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{userId}/email/verify": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Checks the code emailed to the user and marks the email verified.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Verify email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.EmailCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many attempts, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{userId}/favorites": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns a list of user's favorite routes with route and bus info",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "favorites"
+                ],
+                "summary": "Get Favorite Routes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number for pagination (default: 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size for pagination (default: 30)",
+                        "name": "pageSize",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of favorite routes",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Route"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Access Denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Adds a route to the user's favorites",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "favorites"
+                ],
+                "summary": "Add to Favorites",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Favorite Route Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.AddFavoriteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Added to favorites",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "403": {
+                        "description": "Access Denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{userId}/favorites/{routeId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes a route from the user's favorites",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "favorites"
+                ],
+                "summary": "Remove from Favorites",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Route ID",
+                        "name": "routeId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Removed from favorites",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "403": {
+                        "description": "Access Denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{userId}/identities": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "The Google and Apple accounts linked to the user, the first linked first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "List linked accounts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.UserIdentity"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{userId}/identities/{provider}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Unlinks the account of the provider from the user. The only way left to sign in cannot be unlinked,\nset a password or verify a phone first.",
+                "tags": [
+                    "users"
+                ],
+                "summary": "Unlink account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "google",
+                            "apple"
+                        ],
+                        "type": "string",
+                        "description": "Provider",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "The only way left to sign in",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{userId}/orders/{orderId}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the order with all tickets bought together in it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "orderId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Order"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{userId}/orders/{orderId}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Cancels the tickets listed in \"ticket_ids\", or the whole order when the list is empty.\nThe paid tickets are refunded together in one refund, made once the cancellation is saved and retried until the payment provider makes it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Cancel order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "orderId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "email for sending mail",
+                        "name": "email",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Tickets to cancel",
+                        "name": "requestBody",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/model.CancelOrderRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retries with the same key replay the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Order"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "The refund policy does not allow cancelling now",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{userId}/orders/{orderId}/cancel/quote": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Shows what cancelling the tickets would refund under the refund policy of the route, nothing is cancelled.\nLeave \"ticket_ids\" out to quote the whole order.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Cancellation quote",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "orderId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated ticket IDs",
+                        "name": "ticket_ids",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.RefundQuote"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "A ticket was already used or missed",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{userId}/orders/{orderId}/receipt": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the receipt of an order as an HTML document with the paid and refunded amounts,\nin the language picked in the user's settings.",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Order receipt",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "orderId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Receipt",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{userId}/phone": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Texts a verification code to the new phone, it becomes the phone of the user once the code is\nverified. A phone verified by another user is refused.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Change phone",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New phone",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.PhoneChangeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "Phone belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many codes requested for the phone",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "501": {
+                        "description": "Text messages are not configured",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{userId}/phone/verify": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Checks the code texted to the new phone and makes it the verified phone of the user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Verify phone change",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New phone and code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.VerifyPhoneChangeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "Phone belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many attempts, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{userId}/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "The devices the user is signed in on, the most recently used first. Current marks the session of\nthe token asking.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "List sessions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Session"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{userId}/sessions/{sessionId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Ends a session of the user, the device it belongs to is signed out at once.",
+                "tags": [
+                    "users"
+                ],
+                "summary": "Revoke session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{userId}/settings": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user settings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Settings"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Language is the one emails and notifications are sent in: en, ru or kk.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update user settings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Settings",
+                        "name": "requestBody",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdateSettingsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Settings"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/validate": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Checks the signature of a scanned ticket code and marks the ticket as boarded.\nA ticket boards once, scanning it again is rejected. Pass \"route_id\" to reject tickets for other routes.\nBoarding opens a set time before departure, two hours by default, and closes when the route arrives.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "boarding"
+                ],
+                "summary": "Validate ticket",
+                "parameters": [
+                    {
+                        "description": "Scanned code",
+                        "name": "requestBody",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ValidateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Passenger boarded",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Ticket"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "Ticket already used, cancelled, for another route or outside the boarding window",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "422": {
+                        "description": "Code is forged or unreadable",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/forgot-password": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Forgot password",
+                "parameters": [
+                    {
+                        "description": "forgot password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ForgotPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/forgot-password/verify": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "verify forgot password",
+                "parameters": [
+                    {
+                        "description": "required",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.VerifyResetCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - Code invalid or expired, it is dropped after five wrong tries",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Too many attempts, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/logout": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Ends the session of the access token, its access and refresh tokens stop working.",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Logout",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/oidc/{provider}": {
+            "get": {
+                "description": "Redirects to Google or Apple to sign in, they send the user back to the callback. The request is\nvalid for ten minutes and only in the browser it was made in, which keeps it in a cookie.",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Sign in with a provider",
+                "parameters": [
+                    {
+                        "enum": [
+                            "google",
+                            "apple"
+                        ],
+                        "type": "string",
+                        "description": "Provider",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "404": {
+                        "description": "Not Found - Provider not available",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/oidc/{provider}/callback": {
+            "get": {
+                "description": "Where Google (query) and Apple (form post) send the user back to. Signs in the user the account of the\nprovider is linked to. An account not linked yet is linked to the user who verified its email with\nus, or an account is signed up with it and created is set.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Provider sign-in callback",
+                "parameters": [
+                    {
+                        "enum": [
+                            "google",
+                            "apple"
+                        ],
+                        "type": "string",
+                        "description": "Provider",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization code",
+                        "name": "code",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "State of the sign-in request",
+                        "name": "state",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Set when the user did not sign in",
+                        "name": "error",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.OIDCSigninResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - Sign-in expired, began in another browser, refused or the email is not verified",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - Provider not available or user deleted",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - The user has another account of the provider linked, or the account with the email did not verify it",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Where Google (query) and Apple (form post) send the user back to. Signs in the user the account of the\nprovider is linked to. An account not linked yet is linked to the user who verified its email with\nus, or an account is signed up with it and created is set.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Provider sign-in callback",
+                "parameters": [
+                    {
+                        "enum": [
+                            "google",
+                            "apple"
+                        ],
+                        "type": "string",
+                        "description": "Provider",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization code",
+                        "name": "code",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "State of the sign-in request",
+                        "name": "state",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Set when the user did not sign in",
+                        "name": "error",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.OIDCSigninResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - Sign-in expired, began in another browser, refused or the email is not verified",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - Provider not available or user deleted",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - The user has another account of the provider linked, or the account with the email did not verify it",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/phone": {
+            "post": {
+                "description": "Texts a verification code to a Kazakh phone number (+7 or 8 followed by ten digits). The code signs in\nthe user the phone is verified for, or signs up a new one. A phone gets one code a minute and a few an hour.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Phone sign-in code",
+                "parameters": [
+                    {
+                        "description": "Phone",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.PhoneCodeRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Language of the SMS when the body has none",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid phone number",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Too many codes requested for the phone",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented - Text messages are not configured",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/phone/verify": {
+            "post": {
+                "description": "Checks the code texted to the phone and returns an access token. A phone no one has verified yet\ngets a new account, created is set then. A code can be tried five times.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Phone sign-in",
+                "parameters": [
+                    {
+                        "description": "Phone and code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.PhoneSigninRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.PhoneSigninResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid phone number or code",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Too many attempts, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/refresh": {
+            "post": {
+                "description": "Trades a refresh token for a new access token and a new refresh token, the one sent stops working.\nSending a refresh token that was already traded in ends its session.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Refresh tokens",
+                "parameters": [
+                    {
+                        "description": "Refresh token",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - Refresh token invalid, expired or revoked",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/signin": {
+            "post": {
+                "description": "Authenticate a user and return an access token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "User Signin",
+                "parameters": [
+                    {
+                        "description": "Signin Request Body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.SigninRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SigninResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - Password reset required",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - User not found or incorrect credentials",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Too many attempts, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/signup": {
+            "post": {
+                "description": "Register a new user and send verification code.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "User Signup",
+                "parameters": [
+                    {
+                        "description": "Signup Request Body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.SignupRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Language of the verification email when the body has none",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "response",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/signup/verify": {
+            "post": {
+                "description": "Register a new user and verify code.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "User Signup Verification",
+                "parameters": [
+                    {
+                        "description": "VerifyEmail Request Body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.VerifyEmailRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "response",
+                        "schema": {
+                            "$ref": "#/definitions/model.SignupResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid request body, or code invalid or expired",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Too many attempts, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallet/v1/devices/{deviceId}/registrations/{passTypeId}": {
+            "get": {
+                "description": "Apple Wallet web service: the serial numbers of the passes of a device changed since the previous tag.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Updated passes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device library ID",
+                        "name": "deviceId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pass type ID",
+                        "name": "passTypeId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "lastUpdated of the previous response",
+                        "name": "passesUpdatedSince",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.UpdatedPasses"
+                        }
+                    },
+                    "204": {
+                        "description": "Nothing changed"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallet/v1/devices/{deviceId}/registrations/{passTypeId}/{serialNumber}": {
+            "post": {
+                "description": "Apple Wallet web service: called by the device when the pass is added, authenticated with \"ApplePass \u003cauthenticationToken\u003e\".",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Register pass device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device library ID",
+                        "name": "deviceId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pass type ID",
+                        "name": "passTypeId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ticket ID",
+                        "name": "serialNumber",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Push token",
+                        "name": "requestBody",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/aulway_internal_handler_wallet_model.RegisterDeviceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Already registered"
+                    },
+                    "201": {
+                        "description": "Registered"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Apple Wallet web service: called by the device when the pass is removed.",
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Unregister pass device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device library ID",
+                        "name": "deviceId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pass type ID",
+                        "name": "passTypeId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ticket ID",
+                        "name": "serialNumber",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Unregistered"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    }
+                }
+            }
+        },
+        "/wallet/v1/log": {
+            "post": {
+                "description": "Apple Wallet web service: error messages a device ran into with our passes.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Pass device log",
+                "parameters": [
+                    {
+                        "description": "Messages",
+                        "name": "requestBody",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.LogRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/wallet/v1/passes/{passTypeId}/{serialNumber}": {
+            "get": {
+                "description": "Apple Wallet web service: the current .pkpass of a ticket, 304 when unchanged since If-Modified-Since.",
+                "produces": [
+                    "application/vnd.apple.pkpass"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Latest pass",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Pass type ID",
+                        "name": "passTypeId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ticket ID",
+                        "name": "serialNumber",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Pass bundle",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/stripe": {
+            "post": {
+                "description": "Verifies the Stripe-Signature header and applies payment_intent.succeeded, payment_intent.payment_failed,\ncharge.refunded and charge.dispute.created events to payments and tickets. Other events are acknowledged and ignored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Stripe webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Stripe webhook signature",
+                        "name": "Stripe-Signature",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event accepted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Unreadable payload or invalid signature",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Event could not be processed, Stripe retries it",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    },
+                    "503": {
+                        "description": "No webhook secret is configured",
+                        "schema": {
+                            "$ref": "#/definitions/errs.Err"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "aulway_internal_handler_device_model.RegisterDeviceRequest": {
+            "type": "object",
+            "properties": {
+                "platform": {
+                    "type": "string",
+                    "example": "android"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "fcm-registration-token"
+                }
+            }
+        },
+        "aulway_internal_handler_route_model.RouteResponse": {
+            "type": "object",
+            "properties": {
+                "available_seats": {
+                    "type": "integer"
+                },
+                "bus_id": {
+                    "type": "string"
+                },
+                "bus_number": {
+                    "type": "string"
+                },
+                "bus_total_seats": {
+                    "type": "integer"
+                },
+                "departure": {
+                    "type": "string"
+                },
+                "departure_location": {
+                    "type": "string"
+                },
+                "destination": {
+                    "type": "string"
+                },
+                "destination_location": {
+                    "type": "string"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "start_date": {
+                    "type": "string"
+                }
+            }
+        },
+        "aulway_internal_handler_wallet_model.RegisterDeviceRequest": {
+            "type": "object",
+            "properties": {
+                "pushToken": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.BoardingStats": {
+            "type": "object",
+            "properties": {
+                "boarded": {
+                    "type": "integer"
+                },
+                "cancelled": {
+                    "type": "integer"
+                },
+                "capacity": {
+                    "type": "integer"
+                },
+                "no_show": {
+                    "type": "integer"
+                },
+                "occupancy": {
+                    "type": "number"
+                },
+                "pending": {
+                    "type": "integer"
+                },
+                "route_id": {
+                    "type": "string"
+                },
+                "show_rate": {
+                    "type": "number"
+                },
+                "sold": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Bus": {
+            "type": "object",
+            "properties": {
+                "columns": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "rows": {
+                    "type": "integer"
+                },
+                "seats": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Seat"
+                    }
+                },
+                "total_seats": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Device": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "platform": {
+                    "type": "string",
+                    "example": "android"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.EmailPreview": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.EmailTemplate": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "builtin": {
+                    "type": "boolean"
+                },
+                "locale": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "updated_by": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.EmailTemplateVersion": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "locale": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.GoogleWalletLink": {
+            "type": "object",
+            "properties": {
+                "save_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.LedgerBalance": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "integer"
+                },
+                "chargebacks": {
+                    "type": "integer"
+                },
+                "charged": {
+                    "type": "integer"
+                },
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.LedgerEntry"
+                    }
+                },
+                "fees": {
+                    "type": "integer"
+                },
+                "payment_id": {
+                    "type": "string"
+                },
+                "refunded": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.LedgerCheck": {
+            "type": "object",
+            "properties": {
+                "consistent": {
+                    "type": "boolean"
+                },
+                "ledger_charged": {
+                    "type": "integer"
+                },
+                "ledger_refunded": {
+                    "type": "integer"
+                },
+                "payment_id": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "provider_charged": {
+                    "type": "integer"
+                },
+                "provider_refunded": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.LedgerEntry": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "balance": {
+                    "description": "Balance is the running balance of the payment after this entry",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "payment_id": {
+                    "type": "string"
+                },
+                "reference": {
+                    "type": "string"
+                },
+                "ticket_id": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.Manifest": {
+            "type": "object",
+            "properties": {
+                "departure": {
+                    "type": "string"
+                },
+                "destination": {
+                    "type": "string"
+                },
+                "generated_at": {
+                    "type": "string"
+                },
+                "passengers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.ManifestPassenger"
+                    }
+                },
+                "route_id": {
+                    "type": "string"
+                },
+                "signature": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.ManifestPassenger": {
+            "type": "object",
+            "properties": {
+                "boarded_at": {
+                    "type": "string"
+                },
+                "order_number": {
+                    "type": "string"
+                },
+                "passenger_name": {
+                    "type": "string"
+                },
+                "seat": {
+                    "type": "string"
+                },
+                "ticket_id": {
+                    "type": "string"
+                },
+                "token_hash": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.ManifestSync": {
+            "type": "object",
+            "properties": {
+                "boarded": {
+                    "type": "integer"
+                },
+                "duplicates": {
+                    "type": "integer"
+                },
+                "rejected": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.ScanResult"
+                    }
+                },
+                "route_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.Order": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "payment_id": {
+                    "type": "string"
+                },
+                "route_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "tickets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Ticket"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.OutboxMessage": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
                 },
-                "bus_id": {
+                "body": {
                     "type": "string"
                 },
-                "bus_number": {
+                "created_at": {
                     "type": "string"
                 },
-                "bus_total_seats": {
-                    "type": "integer"
+                "id": {
+                    "type": "string"
                 },
-                "departure": {
+                "kind": {
                     "type": "string"
                 },
-                "departure_location": {
+                "last_error": {
                     "type": "string"
                 },
-                "destination": {
+                "next_attempt_at": {
                     "type": "string"
                 },
-                "destination_location": {
+                "order_id": {
                     "type": "string"
                 },
-                "end_date": {
+                "recipient": {
                     "type": "string"
                 },
-                "id": {
+                "sent_at": {
                     "type": "string"
                 },
-                "price": {
+                "status": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.Page": {
+            "type": "object",
+            "properties": {
+                "Content": {
+                    "type": "string"
+                },
+                "ID": {
                     "type": "integer"
                 },
-                "start_date": {
+                "Title": {
+                    "description": "\"about_us\", \"privacy_policy\", \"support\"",
+                    "type": "string"
+                },
+                "UpdatedAt": {
                     "type": "string"
                 }
             }
         },
-        "domain.Bus": {
+        "domain.Purchase": {
+            "type": "object",
+            "properties": {
+                "client_secret": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "order_number": {
+                    "type": "string"
+                },
+                "payment_id": {
+                    "type": "string"
+                },
+                "payment_status": {
+                    "type": "string"
+                },
+                "tickets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Ticket"
+                    }
+                }
+            }
+        },
+        "domain.RefundPolicy": {
             "type": "object",
             "properties": {
+                "created_at": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
-                "number": {
+                "name": {
                     "type": "string"
                 },
-                "total_seats": {
-                    "type": "integer"
+                "non_refundable": {
+                    "type": "boolean"
+                },
+                "route_id": {
+                    "type": "string"
+                },
+                "tiers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.RefundTier"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
                 }
             }
         },
-        "domain.Page": {
+        "domain.RefundQuote": {
             "type": "object",
             "properties": {
-                "Content": {
+                "cancellable": {
+                    "type": "boolean"
+                },
+                "hours_before_departure": {
+                    "type": "integer"
+                },
+                "order_id": {
                     "type": "string"
                 },
-                "ID": {
+                "paid": {
                     "type": "integer"
                 },
-                "Title": {
-                    "description": "\"about_us\", \"privacy_policy\", \"support\"",
+                "policy_id": {
                     "type": "string"
                 },
-                "UpdatedAt": {
+                "policy_name": {
+                    "type": "string"
+                },
+                "reason": {
                     "type": "string"
+                },
+                "refund": {
+                    "type": "integer"
+                },
+                "refund_percent": {
+                    "type": "integer"
+                },
+                "tickets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.TicketRefund"
+                    }
+                }
+            }
+        },
+        "domain.RefundTier": {
+            "type": "object",
+            "properties": {
+                "min_hours_before": {
+                    "type": "integer",
+                    "example": 72
+                },
+                "refund_percent": {
+                    "type": "integer",
+                    "example": 100
                 }
             }
         },
@@ -1933,15 +5900,190 @@ const docTemplate = `{
                 }
             }
         },
+        "domain.ScanResult": {
+            "type": "object",
+            "properties": {
+                "boarded_at": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "result": {
+                    "type": "string"
+                },
+                "scanned_at": {
+                    "type": "string"
+                },
+                "ticket_id": {
+                    "type": "string"
+                },
+                "token_hash": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.Seat": {
+            "type": "object",
+            "properties": {
+                "bus_id": {
+                    "type": "string"
+                },
+                "class": {
+                    "description": "\"standard\", \"comfort\", \"vip\"",
+                    "type": "string"
+                },
+                "column": {
+                    "type": "integer"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "row": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.SeatHold": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "route_id": {
+                    "type": "string"
+                },
+                "seats": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.SeatMap": {
+            "type": "object",
+            "properties": {
+                "available_seats": {
+                    "type": "integer"
+                },
+                "bus_id": {
+                    "type": "string"
+                },
+                "columns": {
+                    "type": "integer"
+                },
+                "route_id": {
+                    "type": "string"
+                },
+                "rows": {
+                    "type": "integer"
+                },
+                "seats": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.SeatState"
+                    }
+                }
+            }
+        },
+        "domain.SeatState": {
+            "type": "object",
+            "properties": {
+                "bus_id": {
+                    "type": "string"
+                },
+                "class": {
+                    "description": "\"standard\", \"comfort\", \"vip\"",
+                    "type": "string"
+                },
+                "column": {
+                    "type": "integer"
+                },
+                "held": {
+                    "type": "boolean"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "row": {
+                    "type": "integer"
+                },
+                "taken": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "domain.Session": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "current": {
+                    "description": "Current is set on the session of the token the list was asked with",
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string",
+                    "example": "95.56.10.1"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string",
+                    "example": "Aulway/2.3 (Android 14)"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.Settings": {
+            "type": "object",
+            "properties": {
+                "language": {
+                    "type": "string"
+                },
+                "two_factor_enabled": {
+                    "type": "boolean"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
         "domain.Ticket": {
             "type": "object",
             "properties": {
+                "boarded_at": {
+                    "type": "string"
+                },
+                "boarded_by": {
+                    "type": "string"
+                },
                 "created_at": {
                     "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
+                "order_id": {
+                    "type": "string"
+                },
                 "order_number": {
                     "type": "string"
                 },
@@ -1961,12 +6103,49 @@ const docTemplate = `{
                 "route_id": {
                     "type": "string"
                 },
+                "seat_number": {
+                    "type": "string"
+                },
                 "status": {
-                    "description": "\"approved\", \"cancelled\", \"awaiting\"",
+                    "description": "\"approved\", \"cancelled\", \"awaiting\", \"boarded\", \"no_show\"",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.TicketRefund": {
+            "type": "object",
+            "properties": {
+                "price": {
+                    "type": "integer"
+                },
+                "refund": {
+                    "type": "integer"
+                },
+                "seat_number": {
+                    "type": "string"
+                },
+                "ticket_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.UpdatedPasses": {
+            "type": "object",
+            "properties": {
+                "lastUpdated": {
                     "type": "string"
                 },
-                "user_id": {
-                    "type": "string"
+                "serialNumbers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
@@ -1979,6 +6158,9 @@ const docTemplate = `{
                 "email": {
                     "type": "string"
                 },
+                "email_verified": {
+                    "type": "boolean"
+                },
                 "first_name": {
                     "type": "string"
                 },
@@ -1991,6 +6173,9 @@ const docTemplate = `{
                 "phone": {
                     "type": "string"
                 },
+                "phone_verified": {
+                    "type": "boolean"
+                },
                 "require_password_reset": {
                     "type": "boolean"
                 },
@@ -2002,6 +6187,31 @@ const docTemplate = `{
                 }
             }
         },
+        "domain.UserIdentity": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "rider@example.com"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "google"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
         "errs.Err": {
             "type": "object",
             "properties": {
@@ -2027,9 +6237,41 @@ const docTemplate = `{
         "model.BuyTicketRequest": {
             "type": "object",
             "properties": {
+                "hold_id": {
+                    "type": "string"
+                },
                 "quantity": {
                     "type": "integer"
                 },
+                "seats": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "1A",
+                        "1B"
+                    ]
+                },
+                "user_email": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.CancelOrderRequest": {
+            "type": "object",
+            "properties": {
+                "ticket_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "model.ConfirmPaymentRequest": {
+            "type": "object",
+            "properties": {
                 "user_email": {
                     "type": "string"
                 }
@@ -2038,9 +6280,21 @@ const docTemplate = `{
         "model.CreateRequest": {
             "type": "object",
             "properties": {
+                "columns": {
+                    "type": "integer"
+                },
                 "number": {
                     "type": "string"
                 },
+                "rows": {
+                    "type": "integer"
+                },
+                "seats": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.SeatRequest"
+                    }
+                },
                 "total_seats": {
                     "type": "integer"
                 }
@@ -2086,6 +6340,28 @@ const docTemplate = `{
                 }
             }
         },
+        "model.EmailCodeRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "model.EmailTemplateRequest": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string",
+                    "example": "\u003cp\u003eOrder {{.OrderNumber}}\u003c/p\u003e{{range .Tickets}}\u003cimg src=\"cid:{{.QR}}\"\u003e{{end}}"
+                },
+                "subject": {
+                    "type": "string",
+                    "example": "Your AulWay tickets – order {{.OrderNumber}}"
+                }
+            }
+        },
         "model.ForgotPasswordRequest": {
             "type": "object",
             "required": [
@@ -2097,6 +6373,157 @@ const docTemplate = `{
                 }
             }
         },
+        "model.HoldSeatsRequest": {
+            "type": "object",
+            "properties": {
+                "quantity": {
+                    "type": "integer"
+                },
+                "seats": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "1A",
+                        "1B"
+                    ]
+                }
+            }
+        },
+        "model.LogRequest": {
+            "type": "object",
+            "properties": {
+                "logs": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "model.ManifestKeyResponse": {
+            "type": "object",
+            "properties": {
+                "algorithm": {
+                    "type": "string",
+                    "example": "Ed25519"
+                },
+                "public_key": {
+                    "type": "string",
+                    "example": "mC2m0rJ8qGZ0R3GkXWw5bQm8b1o9Y0n6lYc2a1Qd6hE="
+                }
+            }
+        },
+        "model.OIDCSigninResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "created": {
+                    "description": "Created is set when no account had the email and one was signed up",
+                    "type": "boolean"
+                },
+                "expires_in": {
+                    "type": "integer",
+                    "example": 900
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/model.UserResponse"
+                }
+            }
+        },
+        "model.PhoneChangeRequest": {
+            "type": "object",
+            "properties": {
+                "phone": {
+                    "type": "string",
+                    "example": "+77011234567"
+                }
+            }
+        },
+        "model.PhoneCodeRequest": {
+            "type": "object",
+            "properties": {
+                "language": {
+                    "description": "Language of the SMS, Accept-Language is used without it",
+                    "type": "string",
+                    "example": "kk"
+                },
+                "phone": {
+                    "type": "string",
+                    "example": "+77011234567"
+                }
+            }
+        },
+        "model.PhoneSigninRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                },
+                "phone": {
+                    "type": "string",
+                    "example": "+77011234567"
+                }
+            }
+        },
+        "model.PhoneSigninResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "created": {
+                    "description": "Created is set when the phone was new and an account was signed up",
+                    "type": "boolean"
+                },
+                "expires_in": {
+                    "type": "integer",
+                    "example": 900
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/model.UserResponse"
+                }
+            }
+        },
+        "model.RefreshRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.RefundPolicyRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "Standard"
+                },
+                "non_refundable": {
+                    "type": "boolean"
+                },
+                "route_id": {
+                    "type": "string"
+                },
+                "tiers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.RefundTier"
+                    }
+                }
+            }
+        },
         "model.ResetPasswordRequest": {
             "type": "object",
             "properties": {
@@ -2111,6 +6538,40 @@ const docTemplate = `{
                 }
             }
         },
+        "model.ScanRequest": {
+            "type": "object",
+            "properties": {
+                "scanned_at": {
+                    "type": "string",
+                    "example": "2025-12-12T12:45:00+05:00"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "token_hash": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.SeatRequest": {
+            "type": "object",
+            "properties": {
+                "class": {
+                    "type": "string",
+                    "example": "standard"
+                },
+                "column": {
+                    "type": "integer"
+                },
+                "number": {
+                    "type": "string",
+                    "example": "1A"
+                },
+                "row": {
+                    "type": "integer"
+                }
+            }
+        },
         "model.SigninRequest": {
             "type": "object",
             "properties": {
@@ -2128,6 +6589,13 @@ const docTemplate = `{
                 "access_token": {
                     "type": "string"
                 },
+                "expires_in": {
+                    "type": "integer",
+                    "example": 900
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
                 "user": {
                     "$ref": "#/definitions/model.UserResponse"
                 }
@@ -2139,6 +6607,11 @@ const docTemplate = `{
                 "email": {
                     "type": "string"
                 },
+                "language": {
+                    "description": "Language of the verification email, Accept-Language is used without it",
+                    "type": "string",
+                    "example": "ru"
+                },
                 "password": {
                     "type": "string"
                 }
@@ -2150,11 +6623,44 @@ const docTemplate = `{
                 "access_token": {
                     "type": "string"
                 },
+                "expires_in": {
+                    "type": "integer",
+                    "example": 900
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
                 "user": {
                     "$ref": "#/definitions/model.UserResponse"
                 }
             }
         },
+        "model.SyncRequest": {
+            "type": "object",
+            "properties": {
+                "scans": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ScanRequest"
+                    }
+                }
+            }
+        },
+        "model.TokenResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "type": "integer",
+                    "example": 900
+                },
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
         "model.UpdatePageRequest": {
             "type": "object",
             "properties": {
@@ -2203,6 +6709,15 @@ const docTemplate = `{
                 }
             }
         },
+        "model.UpdateSettingsRequest": {
+            "type": "object",
+            "properties": {
+                "language": {
+                    "type": "string",
+                    "example": "kk"
+                }
+            }
+        },
         "model.UpdateUserRequest": {
             "type": "object",
             "properties": {
@@ -2229,6 +6744,10 @@ const docTemplate = `{
                 "email": {
                     "type": "string"
                 },
+                "email_verified": {
+                    "description": "EmailVerified is set once the email is confirmed with an emailed code,\nonly then are Google, Apple and Firebase accounts linked by it",
+                    "type": "boolean"
+                },
                 "firstname": {
                     "type": "string"
                 },
@@ -2241,6 +6760,10 @@ const docTemplate = `{
                 "phone": {
                     "type": "string"
                 },
+                "phone_verified": {
+                    "description": "PhoneVerified is set once the phone is confirmed with an SMS code, only\nthen can it be used to sign in",
+                    "type": "boolean"
+                },
                 "role": {
                     "type": "string"
                 },
@@ -2249,6 +6772,18 @@ const docTemplate = `{
                 }
             }
         },
+        "model.ValidateRequest": {
+            "type": "object",
+            "properties": {
+                "route_id": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "AW1.dGlja2V0fHJvdXRlfDFB.c2lnbmF0dXJl"
+                }
+            }
+        },
         "model.VerifyEmailRequest": {
             "type": "object",
             "properties": {
@@ -2263,6 +6798,19 @@ const docTemplate = `{
                 }
             }
         },
+        "model.VerifyPhoneChangeRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                },
+                "phone": {
+                    "type": "string",
+                    "example": "+77011234567"
+                }
+            }
+        },
         "model.VerifyResetCodeRequest": {
             "type": "object",
             "required": [
@@ -2281,11 +6829,27 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "templates.Variable": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": ".Tickets[].Seat"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "string"
+                }
+            }
         }
     },
     "securityDefinitions": {
         "BearerAuth": {
-            "description": "Use \"Bearer {your-firebase-token}\"",
+            "description": "Use \"Bearer {access-token}\", a Firebase ID token works in place of the access token",
             "type": "apiKey",
             "name": "Authorization",
             "in": "header"
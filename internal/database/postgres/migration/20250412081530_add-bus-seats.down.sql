@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_tickets_route_seat;
+
+ALTER TABLE tickets
+DROP COLUMN IF EXISTS seat_number;
+
+DROP TABLE IF EXISTS bus_seats;
+
+ALTER TABLE buses
+DROP COLUMN IF EXISTS rows,
+DROP COLUMN IF EXISTS columns;
@@ -0,0 +1,20 @@
+ALTER TABLE buses
+    ADD COLUMN rows INT NOT NULL DEFAULT 0 CHECK (rows >= 0),
+    ADD COLUMN columns INT NOT NULL DEFAULT 0 CHECK (columns >= 0);
+
+CREATE TABLE bus_seats (
+                           bus_id VARCHAR(50) NOT NULL REFERENCES buses(id) ON DELETE CASCADE,
+                           number VARCHAR(10) NOT NULL,
+                           seat_row INT NOT NULL CHECK (seat_row > 0),
+                           seat_column INT NOT NULL CHECK (seat_column > 0),
+                           class VARCHAR(20) NOT NULL DEFAULT 'standard' CHECK (class IN ('standard', 'comfort', 'vip')),
+                           PRIMARY KEY (bus_id, number),
+                           UNIQUE (bus_id, seat_row, seat_column)
+);
+
+ALTER TABLE tickets
+    ADD COLUMN seat_number VARCHAR(10);
+
+-- A seat can only be held by one live ticket per route
+CREATE UNIQUE INDEX idx_tickets_route_seat ON tickets(route_id, seat_number)
+    WHERE seat_number IS NOT NULL AND status <> 'cancelled';
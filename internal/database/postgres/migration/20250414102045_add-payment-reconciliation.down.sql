@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS stripe_events;
+
+ALTER TABLE tickets
+DROP CONSTRAINT tickets_payment_status_check;
+
+ALTER TABLE tickets
+    ADD CONSTRAINT tickets_payment_status_check
+        CHECK (payment_status IN ('pending', 'paid', 'failed', 'refunded'));
+
+ALTER TABLE payments
+DROP CONSTRAINT payments_status_check;
+
+ALTER TABLE payments
+    ADD CONSTRAINT payments_status_check
+        CHECK (status IN ('pending', 'successful', 'failed', 'refunded'));
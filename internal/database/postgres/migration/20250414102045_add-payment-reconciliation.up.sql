@@ -0,0 +1,20 @@
+ALTER TABLE payments
+DROP CONSTRAINT payments_status_check;
+
+ALTER TABLE payments
+    ADD CONSTRAINT payments_status_check
+        CHECK (status IN ('pending', 'successful', 'failed', 'refunded', 'disputed'));
+
+ALTER TABLE tickets
+DROP CONSTRAINT tickets_payment_status_check;
+
+ALTER TABLE tickets
+    ADD CONSTRAINT tickets_payment_status_check
+        CHECK (payment_status IN ('pending', 'paid', 'failed', 'refunded', 'disputed'));
+
+-- Stripe delivers webhooks at least once, processed ids make redeliveries no-ops
+CREATE TABLE stripe_events (
+                               id VARCHAR(255) PRIMARY KEY,
+                               type VARCHAR(100) NOT NULL,
+                               processed_at TIMESTAMP DEFAULT NOW()
+);
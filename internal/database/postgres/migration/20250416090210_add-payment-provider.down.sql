@@ -0,0 +1,3 @@
+ALTER TABLE payments
+DROP COLUMN provider,
+DROP COLUMN currency;
@@ -0,0 +1,3 @@
+ALTER TABLE payments
+    ADD COLUMN provider VARCHAR(50) NOT NULL DEFAULT 'stripe',
+    ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'KZT';
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS payment_ledger_no_update ON payment_ledger;
+DROP FUNCTION IF EXISTS payment_ledger_append_only();
+DROP TABLE IF EXISTS payment_ledger;
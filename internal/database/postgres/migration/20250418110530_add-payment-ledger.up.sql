@@ -0,0 +1,38 @@
+CREATE TABLE payment_ledger (
+                                id VARCHAR(50) PRIMARY KEY,
+                                payment_id VARCHAR(50) NOT NULL REFERENCES payments(id) ON DELETE RESTRICT,
+                                ticket_id VARCHAR(50) REFERENCES tickets(id) ON DELETE RESTRICT,
+                                entry_type VARCHAR(20) NOT NULL CHECK (entry_type IN ('charge', 'refund', 'partial_refund', 'chargeback', 'fee')),
+                                amount INT NOT NULL,
+                                currency VARCHAR(3) NOT NULL,
+                                reference VARCHAR(255),
+                                created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_payment_ledger_payment ON payment_ledger(payment_id, created_at);
+
+-- the ledger is the audit trail of money movements, rows are never changed
+CREATE FUNCTION payment_ledger_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'payment_ledger is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER payment_ledger_no_update
+    BEFORE UPDATE OR DELETE ON payment_ledger
+    FOR EACH ROW EXECUTE FUNCTION payment_ledger_append_only();
+
+-- payments made before the ledger existed start with their charge and, for
+-- refunded payments, a refund of the cancelled tickets
+INSERT INTO payment_ledger (id, payment_id, entry_type, amount, currency, created_at)
+SELECT gen_random_uuid()::text, p.id, 'charge', p.amount, p.currency, p.created_at
+FROM payments p
+WHERE p.status IN ('successful', 'refunded', 'disputed');
+
+INSERT INTO payment_ledger (id, payment_id, ticket_id, entry_type, amount, currency, created_at)
+SELECT gen_random_uuid()::text, t.payment_id, t.id,
+       CASE WHEN t.price < p.amount THEN 'partial_refund' ELSE 'refund' END,
+       -t.price, p.currency, p.updated_at
+FROM tickets t
+         JOIN payments p ON p.id = t.payment_id
+WHERE t.payment_status = 'refunded';
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_tickets_order;
+
+ALTER TABLE tickets
+DROP COLUMN IF EXISTS order_id;
+
+DROP TABLE IF EXISTS orders;
+
+DROP SEQUENCE IF EXISTS order_number_seq;
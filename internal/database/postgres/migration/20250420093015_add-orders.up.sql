@@ -0,0 +1,64 @@
+-- order numbers come from a sequence so two purchases can never share one
+CREATE SEQUENCE order_number_seq;
+
+CREATE TABLE orders (
+                        id VARCHAR(50) PRIMARY KEY,
+                        number VARCHAR(100) UNIQUE NOT NULL,
+                        user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                        route_id VARCHAR(50) NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+                        payment_id VARCHAR(50) REFERENCES payments(id),
+                        status VARCHAR(30) NOT NULL CHECK (status IN ('awaiting', 'active', 'partially_cancelled', 'cancelled')),
+                        total INT NOT NULL CHECK (total >= 0),
+                        created_at TIMESTAMP DEFAULT NOW(),
+                        updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_orders_user ON orders(user_id);
+
+ALTER TABLE tickets
+    ADD COLUMN order_id VARCHAR(50) REFERENCES orders(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_tickets_order ON tickets(order_id);
+
+-- existing tickets bought together shared a payment, each purchase becomes an
+-- order numbered after its first ticket, which is the number its email showed
+WITH purchases AS (
+    SELECT COALESCE(payment_id, id) AS purchase,
+           SUM(price)::INT AS total,
+           BOOL_AND(status = 'cancelled') AS all_cancelled,
+           BOOL_OR(status = 'cancelled') AS any_cancelled,
+           BOOL_OR(status = 'awaiting') AS awaiting
+    FROM tickets
+    GROUP BY COALESCE(payment_id, id)
+), firsts AS (
+    SELECT DISTINCT ON (COALESCE(payment_id, id))
+           COALESCE(payment_id, id) AS purchase, user_id, route_id, payment_id, order_number, created_at
+    FROM tickets
+    ORDER BY COALESCE(payment_id, id), created_at, id
+), numbered AS (
+    SELECT f.*, ROW_NUMBER() OVER (PARTITION BY f.order_number ORDER BY f.created_at, f.purchase) AS dup
+    FROM firsts f
+)
+INSERT INTO orders (id, number, user_id, route_id, payment_id, status, total, created_at, updated_at)
+SELECT 'ord-' || n.purchase,
+       CASE
+           WHEN n.order_number IS NULL THEN 'AW-' || LPAD(nextval('order_number_seq')::TEXT, 8, '0')
+           WHEN n.dup = 1 THEN n.order_number
+           ELSE n.order_number || '-' || n.dup
+           END,
+       n.user_id, n.route_id, n.payment_id,
+       CASE
+           WHEN p.all_cancelled THEN 'cancelled'
+           WHEN p.awaiting THEN 'awaiting'
+           WHEN p.any_cancelled THEN 'partially_cancelled'
+           ELSE 'active'
+           END,
+       p.total, n.created_at, n.created_at
+FROM numbered n
+         JOIN purchases p ON p.purchase = n.purchase;
+
+UPDATE tickets t
+SET order_id = o.id,
+    order_number = o.number
+FROM orders o
+WHERE o.id = 'ord-' || COALESCE(t.payment_id, t.id);
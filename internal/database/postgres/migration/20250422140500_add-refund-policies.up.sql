@@ -0,0 +1,17 @@
+-- a policy without a route is the default one, a route has at most one override
+CREATE TABLE refund_policies (
+                                 id VARCHAR(50) PRIMARY KEY,
+                                 name VARCHAR(100) NOT NULL,
+                                 route_id VARCHAR(50) REFERENCES routes(id) ON DELETE CASCADE,
+                                 non_refundable BOOLEAN NOT NULL DEFAULT FALSE,
+                                 tiers JSONB NOT NULL DEFAULT '[]',
+                                 created_at TIMESTAMP DEFAULT NOW(),
+                                 updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_refund_policies_route ON refund_policies((COALESCE(route_id, '')));
+
+-- the default keeps the rule cancellation had until now: a full refund up to
+-- 24 hours before departure and no cancellation after that
+INSERT INTO refund_policies (id, name, tiers)
+VALUES ('default', 'Default', '[{"min_hours_before": 24, "refund_percent": 100}]');
@@ -0,0 +1,12 @@
+ALTER TABLE tickets
+    DROP COLUMN IF EXISTS boarded_by,
+    DROP COLUMN IF EXISTS boarded_at;
+
+UPDATE users SET role = 'user' WHERE role = 'conductor';
+
+ALTER TABLE users
+DROP CONSTRAINT users_role_check;
+
+ALTER TABLE users
+    ADD CONSTRAINT users_role_check
+        CHECK (role IN ('user', 'admin', 'manager'));
@@ -0,0 +1,10 @@
+ALTER TABLE users
+DROP CONSTRAINT users_role_check;
+
+ALTER TABLE users
+    ADD CONSTRAINT users_role_check
+        CHECK (role IN ('user', 'admin', 'manager', 'conductor'));
+
+ALTER TABLE tickets
+    ADD COLUMN boarded_at TIMESTAMP,
+    ADD COLUMN boarded_by VARCHAR(50) REFERENCES users(id) ON DELETE SET NULL;
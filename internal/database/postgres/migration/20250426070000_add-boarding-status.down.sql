@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_tickets_route_status;
+
+UPDATE tickets SET status = 'approved' WHERE status IN ('boarded', 'no_show');
+
+ALTER TABLE tickets
+DROP CONSTRAINT tickets_status_check;
+
+ALTER TABLE tickets
+    ADD CONSTRAINT tickets_status_check
+        CHECK (status IN ('approved', 'cancelled', 'awaiting'));
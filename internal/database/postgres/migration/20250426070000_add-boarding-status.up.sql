@@ -0,0 +1,10 @@
+ALTER TABLE tickets
+DROP CONSTRAINT tickets_status_check;
+
+ALTER TABLE tickets
+    ADD CONSTRAINT tickets_status_check
+        CHECK (status IN ('approved', 'cancelled', 'awaiting', 'boarded', 'no_show'));
+
+UPDATE tickets SET status = 'boarded' WHERE boarded_at IS NOT NULL AND status = 'approved';
+
+CREATE INDEX idx_tickets_route_status ON tickets(route_id, status);
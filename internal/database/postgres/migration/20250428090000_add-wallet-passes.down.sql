@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS wallet_registrations;
+
+ALTER TABLE tickets
+    DROP COLUMN IF EXISTS updated_at;
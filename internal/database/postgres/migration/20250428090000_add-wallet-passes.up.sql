@@ -0,0 +1,18 @@
+-- passes re-download tickets changed since the last sync, updated_at is
+-- bumped on every status change and when the route of the ticket changes
+ALTER TABLE tickets
+    ADD COLUMN updated_at TIMESTAMP DEFAULT NOW();
+
+UPDATE tickets SET updated_at = COALESCE(boarded_at, created_at);
+
+-- devices that added a ticket to Apple Wallet and get pushed its updates
+CREATE TABLE wallet_registrations (
+                                      device_library_id VARCHAR(100) NOT NULL,
+                                      pass_type_id VARCHAR(100) NOT NULL,
+                                      serial_number VARCHAR(50) NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+                                      push_token VARCHAR(200) NOT NULL,
+                                      created_at TIMESTAMP DEFAULT NOW(),
+                                      PRIMARY KEY (device_library_id, pass_type_id, serial_number)
+);
+
+CREATE INDEX idx_wallet_registrations_serial ON wallet_registrations(serial_number);
@@ -0,0 +1,20 @@
+-- emails are written in the transaction of the purchase or cancellation they
+-- report and sent by the outbox worker, dead ones wait for an admin resend
+CREATE TABLE outbox_messages (
+                                 id VARCHAR(50) PRIMARY KEY,
+                                 kind VARCHAR(30) NOT NULL,
+                                 recipient VARCHAR(255) NOT NULL,
+                                 subject VARCHAR(255) NOT NULL,
+                                 body TEXT NOT NULL,
+                                 order_id VARCHAR(50) REFERENCES orders(id) ON DELETE CASCADE,
+                                 status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'dead')),
+                                 attempts INT NOT NULL DEFAULT 0,
+                                 next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+                                 last_error TEXT NOT NULL DEFAULT '',
+                                 sent_at TIMESTAMP,
+                                 created_at TIMESTAMP DEFAULT NOW(),
+                                 updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_outbox_messages_due ON outbox_messages(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_outbox_messages_status ON outbox_messages(status, created_at);
@@ -0,0 +1,3 @@
+ALTER TABLE settings ALTER COLUMN language SET DEFAULT 'en';
+
+DROP INDEX IF EXISTS idx_settings_user;
@@ -0,0 +1,8 @@
+-- a user has one settings row, its language picks the locale of the emails
+DELETE FROM settings s
+    USING settings newer
+WHERE s.user_id = newer.user_id AND s.id < newer.id;
+
+CREATE UNIQUE INDEX idx_settings_user ON settings(user_id);
+
+ALTER TABLE settings ALTER COLUMN language SET DEFAULT 'ru';
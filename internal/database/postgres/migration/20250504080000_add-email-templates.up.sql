@@ -0,0 +1,23 @@
+-- emails edited by admins, they replace the shipped template of their name
+-- and locale; every save is kept in email_template_versions
+CREATE TABLE email_templates (
+                                 name VARCHAR(50) NOT NULL,
+                                 locale VARCHAR(10) NOT NULL CHECK (locale IN ('en', 'ru', 'kk')),
+                                 version INT NOT NULL,
+                                 subject TEXT NOT NULL,
+                                 body TEXT NOT NULL,
+                                 updated_by VARCHAR(50) NOT NULL DEFAULT '',
+                                 updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+                                 PRIMARY KEY (name, locale)
+);
+
+CREATE TABLE email_template_versions (
+                                         name VARCHAR(50) NOT NULL,
+                                         locale VARCHAR(10) NOT NULL,
+                                         version INT NOT NULL,
+                                         subject TEXT NOT NULL,
+                                         body TEXT NOT NULL,
+                                         created_by VARCHAR(50) NOT NULL DEFAULT '',
+                                         created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+                                         PRIMARY KEY (name, locale, version)
+);
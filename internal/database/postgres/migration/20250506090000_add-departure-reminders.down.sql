@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_routes_start_date;
+DROP TABLE IF EXISTS departure_reminders;
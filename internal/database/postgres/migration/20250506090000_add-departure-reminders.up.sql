@@ -0,0 +1,11 @@
+-- reminders sent for an order, one per lead time and departure time, so a
+-- trip that is moved is reminded of again at its new time
+CREATE TABLE departure_reminders (
+                                     order_id VARCHAR(50) NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+                                     lead_minutes INT NOT NULL CHECK (lead_minutes > 0),
+                                     start_date TIMESTAMP NOT NULL,
+                                     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+                                     PRIMARY KEY (order_id, lead_minutes, start_date)
+);
+
+CREATE INDEX idx_routes_start_date ON routes(start_date);
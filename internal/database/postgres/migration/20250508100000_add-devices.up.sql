@@ -0,0 +1,11 @@
+-- FCM registration tokens of the app installations of users, a token moves
+-- to whoever signed in on the device last
+CREATE TABLE devices (
+                         token VARCHAR(4096) PRIMARY KEY,
+                         user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                         platform VARCHAR(20) NOT NULL CHECK (platform IN ('android', 'ios', 'web')),
+                         created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+                         updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_devices_user ON devices(user_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_phone_verified;
+ALTER TABLE users DROP COLUMN IF EXISTS phone_verified;
+
+DROP INDEX IF EXISTS idx_users_email;
+CREATE UNIQUE INDEX idx_users_email ON users(email);
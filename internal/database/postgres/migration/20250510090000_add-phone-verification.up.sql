@@ -0,0 +1,8 @@
+-- riders can sign up with only a phone number, their email stays empty
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+DROP INDEX IF EXISTS idx_users_email;
+CREATE UNIQUE INDEX idx_users_email ON users(email) WHERE email <> '';
+
+-- a phone signs in once it is verified, so it can belong to one user only
+ALTER TABLE users ADD COLUMN phone_verified BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE UNIQUE INDEX idx_users_phone_verified ON users(phone) WHERE phone_verified AND deleted_at IS NULL;
@@ -0,0 +1,15 @@
+-- a session is a sign-in on a device, its refresh token is rotated on every
+-- refresh and only the hash of the current one is kept
+CREATE TABLE sessions (
+                          id VARCHAR(50) PRIMARY KEY,
+                          user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                          refresh_hash VARCHAR(64) NOT NULL,
+                          user_agent VARCHAR(512) NOT NULL DEFAULT '',
+                          ip VARCHAR(64) NOT NULL DEFAULT '',
+                          created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+                          last_used_at TIMESTAMP NOT NULL DEFAULT NOW(),
+                          expires_at TIMESTAMP NOT NULL,
+                          revoked_at TIMESTAMP NULL
+);
+
+CREATE INDEX idx_sessions_user ON sessions(user_id) WHERE revoked_at IS NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_users_firebase_uid;
+ALTER TABLE users DROP COLUMN IF EXISTS firebase_uid;
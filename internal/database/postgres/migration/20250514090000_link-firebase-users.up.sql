@@ -0,0 +1,4 @@
+-- users signing in with Firebase are linked by their Firebase uid, the
+-- column was dropped while Firebase sign-in was unused
+ALTER TABLE users ADD COLUMN IF NOT EXISTS firebase_uid VARCHAR(128);
+CREATE UNIQUE INDEX idx_users_firebase_uid ON users(firebase_uid) WHERE firebase_uid IS NOT NULL;
@@ -0,0 +1,14 @@
+-- an identity is an account at a sign-in provider linked to a user, a user
+-- has at most one per provider
+CREATE TABLE user_identities (
+                                 id VARCHAR(50) PRIMARY KEY,
+                                 user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                 provider VARCHAR(32) NOT NULL,
+                                 subject VARCHAR(255) NOT NULL,
+                                 email VARCHAR(255) NOT NULL DEFAULT '',
+                                 created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+                                 last_used_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_user_identities_subject ON user_identities(provider, subject);
+CREATE UNIQUE INDEX idx_user_identities_user_provider ON user_identities(user_id, provider);
@@ -0,0 +1,4 @@
+-- accounts from Firebase and sign-in providers are linked by email, only to
+-- users who proved they own it. Emails could be changed without a code until
+-- now, so existing ones count as unverified until a code confirms them.
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
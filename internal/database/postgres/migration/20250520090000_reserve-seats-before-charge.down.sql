@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS refunds;
+
+-- a purchase that never reached the provider did not take any money
+UPDATE payments SET status = 'failed' WHERE status = 'initiated';
+UPDATE payments SET transaction_id = id WHERE transaction_id IS NULL;
+
+ALTER TABLE payments
+    ALTER COLUMN transaction_id SET NOT NULL;
+
+ALTER TABLE payments
+DROP CONSTRAINT payments_status_check;
+
+ALTER TABLE payments
+    ADD CONSTRAINT payments_status_check
+        CHECK (status IN ('pending', 'successful', 'failed', 'refunded', 'disputed'));
@@ -0,0 +1,30 @@
+-- purchases reserve their seats before the card is charged, the payment is
+-- initiated until the provider answers and has no transaction before that
+ALTER TABLE payments
+DROP CONSTRAINT payments_status_check;
+
+ALTER TABLE payments
+    ADD CONSTRAINT payments_status_check
+        CHECK (status IN ('initiated', 'pending', 'successful', 'failed', 'refunded', 'disputed'));
+
+ALTER TABLE payments
+    ALTER COLUMN transaction_id DROP NOT NULL;
+
+-- refunds are stored with the cancellation and made with the provider after
+-- it is committed, the ones the provider failed to make are retried
+CREATE TABLE refunds (
+                         id VARCHAR(50) PRIMARY KEY,
+                         payment_id VARCHAR(50) NOT NULL REFERENCES payments(id) ON DELETE RESTRICT,
+                         amount INT NOT NULL CHECK (amount > 0),
+                         partial BOOLEAN NOT NULL,
+                         tickets JSONB NOT NULL,
+                         status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded')),
+                         attempts INT NOT NULL DEFAULT 0,
+                         next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+                         last_error TEXT NOT NULL DEFAULT '',
+                         created_at TIMESTAMP DEFAULT NOW(),
+                         updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_refunds_due ON refunds(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_refunds_payment ON refunds(payment_id);
@@ -0,0 +1,4 @@
+-- QR codes of tickets bought before signed tokens hold the whole ticket as
+-- JSON, user and payment included, and conductors cannot validate them.
+-- Existing tickets start at version 0 and are reissued by the server.
+ALTER TABLE tickets ADD COLUMN qr_version SMALLINT NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_payments_expiring;
+
+ALTER TABLE payments
+DROP COLUMN expires_at;
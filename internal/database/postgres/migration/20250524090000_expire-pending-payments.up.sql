@@ -0,0 +1,8 @@
+-- a payment waiting for card authentication expires, its seats are released
+-- once the deadline is over
+ALTER TABLE payments
+    ADD COLUMN expires_at TIMESTAMP;
+
+UPDATE payments SET expires_at = created_at + INTERVAL '30 minutes' WHERE status = 'pending';
+
+CREATE INDEX idx_payments_expiring ON payments(expires_at) WHERE status = 'pending';
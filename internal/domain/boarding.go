@@ -0,0 +1,16 @@
+package domain
+
+// BoardingStats sums up who travelled on a route. Occupancy is boarded
+// passengers per seat of the bus, ShowRate is boarded per ticket that was due
+// to board once the no-shows are known.
+type BoardingStats struct {
+	RouteID   string  `json:"route_id"`
+	Capacity  int     `json:"capacity"`
+	Sold      int     `json:"sold"`
+	Boarded   int     `json:"boarded"`
+	NoShow    int     `json:"no_show"`
+	Pending   int     `json:"pending"`
+	Cancelled int     `json:"cancelled"`
+	Occupancy float64 `json:"occupancy"`
+	ShowRate  float64 `json:"show_rate"`
+}
@@ -4,4 +4,7 @@ type Bus struct {
 	Id         string `json:"id"`
 	Number     string `json:"number"`
 	TotalSeats int    `json:"total_seats"`
+	Rows       int    `json:"rows"`
+	Columns    int    `json:"columns"`
+	Seats      []Seat `json:"seats,omitempty" gorm:"-"`
 }
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+const (
+	PlatformAndroid = "android"
+	PlatformIOS     = "ios"
+	PlatformWeb     = "web"
+)
+
+// Device is an app installation of a user that receives push notifications,
+// identified by its FCM registration token.
+type Device struct {
+	Token     string    `json:"token" gorm:"primaryKey"`
+	UserID    string    `json:"user_id"`
+	Platform  string    `json:"platform" example:"android"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Device) TableName() string {
+	return "devices"
+}
@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// EmailTemplate is an email edited by admins. It replaces the one shipped
+// with the service for its name and locale; Builtin marks the shipped ones.
+type EmailTemplate struct {
+	Name      string    `json:"name" gorm:"primaryKey"`
+	Locale    string    `json:"locale" gorm:"primaryKey"`
+	Version   int       `json:"version"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Builtin   bool      `json:"builtin" gorm:"-"`
+}
+
+// EmailTemplateVersion is a saved revision of an email template. Versions
+// are kept after the template is reverted to the shipped one.
+type EmailTemplateVersion struct {
+	Name      string    `json:"name" gorm:"primaryKey"`
+	Locale    string    `json:"locale" gorm:"primaryKey"`
+	Version   int       `json:"version" gorm:"primaryKey"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EmailPreview is an email rendered against sample data.
+type EmailPreview struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// UserIdentity is an account at a sign-in provider, such as Google or Apple,
+// linked to a user. Subject is the id of the account at the provider.
+type UserIdentity struct {
+	ID         string    `json:"id" gorm:"primaryKey"`
+	UserID     string    `json:"user_id"`
+	Provider   string    `json:"provider" example:"google"`
+	Subject    string    `json:"-"`
+	Email      string    `json:"email" example:"rider@example.com"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+const (
+	LedgerCharge        = "charge"
+	LedgerRefund        = "refund"
+	LedgerPartialRefund = "partial_refund"
+	LedgerChargeback    = "chargeback"
+	LedgerFee           = "fee"
+)
+
+// LedgerEntry is one money movement of a payment. Charges are positive,
+// refunds, chargebacks and fees are negative. Entries are never changed.
+type LedgerEntry struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	PaymentID string    `json:"payment_id"`
+	TicketID  *string   `json:"ticket_id,omitempty"`
+	Type      string    `json:"type" gorm:"column:entry_type"`
+	Amount    int       `json:"amount"`
+	Currency  string    `json:"currency"`
+	Reference string    `json:"reference,omitempty"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	// Balance is the running balance of the payment after this entry
+	Balance int `json:"balance" gorm:"-"`
+}
+
+func (LedgerEntry) TableName() string {
+	return "payment_ledger"
+}
+
+// LedgerBalance sums up the ledger of a payment or of the payment of an order.
+type LedgerBalance struct {
+	PaymentID   string        `json:"payment_id"`
+	Charged     int           `json:"charged"`
+	Refunded    int           `json:"refunded"`
+	Chargebacks int           `json:"chargebacks"`
+	Fees        int           `json:"fees"`
+	Balance     int           `json:"balance"`
+	Entries     []LedgerEntry `json:"entries"`
+}
+
+// LedgerCheck compares the ledger of a payment with what the payment provider
+// reports for it.
+type LedgerCheck struct {
+	PaymentID        string `json:"payment_id"`
+	Provider         string `json:"provider"`
+	LedgerCharged    int    `json:"ledger_charged"`
+	ProviderCharged  int    `json:"provider_charged"`
+	LedgerRefunded   int    `json:"ledger_refunded"`
+	ProviderRefunded int    `json:"provider_refunded"`
+	Consistent       bool   `json:"consistent"`
+}
@@ -0,0 +1,51 @@
+package domain
+
+import "time"
+
+// Manifest lists the passengers of a route for drivers without connectivity.
+// TokenHash is the SHA-256 of the ticket QR token, a scanned code is checked
+// by hashing it and looking the hash up. Signature is the base64 Ed25519
+// signature of the manifest without the signature itself.
+type Manifest struct {
+	RouteID     string              `json:"route_id"`
+	Departure   string              `json:"departure"`
+	Destination string              `json:"destination"`
+	StartDate   time.Time           `json:"start_date"`
+	GeneratedAt time.Time           `json:"generated_at"`
+	Passengers  []ManifestPassenger `json:"passengers"`
+	Signature   string              `json:"signature,omitempty"`
+}
+
+type ManifestPassenger struct {
+	TicketID      string     `json:"ticket_id"`
+	PassengerName string     `json:"passenger_name"`
+	Seat          string     `json:"seat"`
+	OrderNumber   string     `json:"order_number"`
+	TokenHash     string     `json:"token_hash"`
+	BoardedAt     *time.Time `json:"boarded_at,omitempty"`
+}
+
+const (
+	ScanBoarded   = "boarded"
+	ScanDuplicate = "duplicate"
+	ScanRejected  = "rejected"
+	ScanUnknown   = "unknown"
+)
+
+// ScanResult is how one offline boarding scan was reconciled.
+type ScanResult struct {
+	TokenHash string     `json:"token_hash"`
+	TicketID  string     `json:"ticket_id,omitempty"`
+	ScannedAt time.Time  `json:"scanned_at"`
+	Result    string     `json:"result"`
+	BoardedAt *time.Time `json:"boarded_at,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+}
+
+type ManifestSync struct {
+	RouteID    string       `json:"route_id"`
+	Boarded    int          `json:"boarded"`
+	Duplicates int          `json:"duplicates"`
+	Rejected   int          `json:"rejected"`
+	Results    []ScanResult `json:"results"`
+}
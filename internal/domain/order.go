@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+const (
+	OrderAwaiting           = "awaiting"
+	OrderActive             = "active"
+	OrderPartiallyCancelled = "partially_cancelled"
+	OrderCancelled          = "cancelled"
+)
+
+// Order groups the tickets bought together in one purchase.
+type Order struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	Number    string    `json:"number"`
+	UserID    string    `json:"user_id"`
+	RouteID   string    `json:"route_id"`
+	PaymentID string    `json:"payment_id" gorm:"default:null"`
+	Status    string    `json:"status"`
+	Total     int       `json:"total"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Tickets   []Ticket  `json:"tickets,omitempty" gorm:"-"`
+}
+
+func (Order) TableName() string {
+	return "orders"
+}
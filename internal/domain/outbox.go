@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxDead    = "dead"
+)
+
+const (
+	// OutboxTickets is the purchase email, its tickets are attached as QR
+	// codes and PDFs when it is sent.
+	OutboxTickets      = "tickets"
+	OutboxCancellation = "cancellation"
+	OutboxReminder     = "reminder"
+	OutboxRouteChange  = "route_change"
+)
+
+// OutboxMessage is an email stored with the change it reports and sent by
+// the outbox worker, so it survives SMTP outages and restarts.
+type OutboxMessage struct {
+	ID            string     `json:"id" gorm:"primaryKey"`
+	Kind          string     `json:"kind"`
+	Recipient     string     `json:"recipient"`
+	Subject       string     `json:"subject"`
+	Body          string     `json:"body"`
+	OrderID       string     `json:"order_id" gorm:"default:null"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox_messages"
+}
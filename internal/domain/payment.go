@@ -3,15 +3,36 @@ package domain
 import "time"
 
 type Payment struct {
-	ID            string    `json:"id" gorm:"primaryKey"`
-	UserID        string    `json:"user_id" gorm:"not null"`
-	Amount        int       `json:"amount" gorm:"not null"`
-	Status        string    `json:"status" gorm:"not null"` // pending, successful, failed, refunded
-	TransactionID string    `json:"transaction_id" gorm:"unique;not null"`
-	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt     time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	ID     string `json:"id" gorm:"primaryKey"`
+	UserID string `json:"user_id" gorm:"not null"`
+	Amount int    `json:"amount" gorm:"not null"`
+	Status string `json:"status" gorm:"not null"` // initiated, pending, successful, failed, refunded
+	// TransactionID is empty while the payment is initiated, before the
+	// provider was asked to charge the card
+	TransactionID string `json:"transaction_id" gorm:"unique;default:null"`
+	Provider      string `json:"provider" gorm:"not null"`
+	Currency      string `json:"currency" gorm:"not null"`
+	// ExpiresAt is when a pending payment is given up and its seats released
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
 func (Payment) TableName() string {
 	return "payments"
 }
+
+// Purchase is the result of buying tickets. While the customer still has to
+// authenticate the payment its tickets stay awaiting and ClientSecret is set.
+type Purchase struct {
+	OrderID       string   `json:"order_id"`
+	OrderNumber   string   `json:"order_number"`
+	PaymentID     string   `json:"payment_id"`
+	PaymentStatus string   `json:"payment_status"`
+	ClientSecret  string   `json:"client_secret,omitempty"`
+	Tickets       []Ticket `json:"tickets"`
+	Bus           *Bus     `json:"-"`
+	Route         *Route   `json:"-"`
+	// Issued reports that the tickets were issued by this very call
+	Issued bool `json:"-"`
+}
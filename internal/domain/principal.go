@@ -0,0 +1,9 @@
+package domain
+
+// Principal is who a request is made by, as vouched for by its bearer token.
+// SessionID is empty for tokens that are not issued by us.
+type Principal struct {
+	UserID    string
+	Role      string
+	SessionID string
+}
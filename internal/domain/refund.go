@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+const (
+	RefundPending   = "pending"
+	RefundSucceeded = "succeeded"
+)
+
+// Refund is the money owed back for cancelled tickets. It is stored with the
+// cancellation and made with the payment provider once that is committed, a
+// refund the provider failed to make is retried until it goes through.
+type Refund struct {
+	ID            string         `json:"id" gorm:"primaryKey"`
+	PaymentID     string         `json:"payment_id"`
+	Amount        int            `json:"amount"`
+	Partial       bool           `json:"partial"`
+	Tickets       []TicketRefund `json:"tickets" gorm:"serializer:json"`
+	Status        string         `json:"status"`
+	Attempts      int            `json:"attempts"`
+	NextAttemptAt time.Time      `json:"next_attempt_at"`
+	LastError     string         `json:"last_error,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+func (Refund) TableName() string {
+	return "refunds"
+}
@@ -0,0 +1,66 @@
+package domain
+
+import "time"
+
+// RefundTier refunds RefundPercent of the ticket price when the ticket is
+// cancelled at least MinHoursBefore hours before departure.
+type RefundTier struct {
+	MinHoursBefore int `json:"min_hours_before" example:"72"`
+	RefundPercent  int `json:"refund_percent" example:"100"`
+}
+
+// RefundPolicy decides how much of a cancelled ticket is refunded. The policy
+// without a route is the default, a policy with a route overrides it there.
+// Cancelling closer to departure than the smallest tier is not allowed.
+type RefundPolicy struct {
+	ID            string       `json:"id" gorm:"primaryKey"`
+	Name          string       `json:"name"`
+	RouteID       *string      `json:"route_id,omitempty"`
+	NonRefundable bool         `json:"non_refundable"`
+	Tiers         []RefundTier `json:"tiers" gorm:"serializer:json"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+func (RefundPolicy) TableName() string {
+	return "refund_policies"
+}
+
+// Tier returns the tier that applies hoursBefore hours before departure.
+func (p *RefundPolicy) Tier(hoursBefore float64) (RefundTier, bool) {
+	var (
+		best  RefundTier
+		found bool
+	)
+	for _, tier := range p.Tiers {
+		if hoursBefore < float64(tier.MinHoursBefore) {
+			continue
+		}
+		if !found || tier.MinHoursBefore > best.MinHoursBefore {
+			best, found = tier, true
+		}
+	}
+
+	return best, found
+}
+
+// RefundQuote is what cancelling the tickets would refund.
+type RefundQuote struct {
+	OrderID              string         `json:"order_id"`
+	PolicyID             string         `json:"policy_id"`
+	PolicyName           string         `json:"policy_name"`
+	HoursBeforeDeparture int            `json:"hours_before_departure"`
+	Cancellable          bool           `json:"cancellable"`
+	Reason               string         `json:"reason,omitempty"`
+	RefundPercent        int            `json:"refund_percent"`
+	Paid                 int            `json:"paid"`
+	Refund               int            `json:"refund"`
+	Tickets              []TicketRefund `json:"tickets"`
+}
+
+type TicketRefund struct {
+	TicketID   string `json:"ticket_id"`
+	SeatNumber string `json:"seat_number"`
+	Price      int    `json:"price"`
+	Refund     int    `json:"refund"`
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefundPolicyTier(t *testing.T) {
+	// tiers in no particular order, the closest one below the hours applies
+	policy := &RefundPolicy{Tiers: []RefundTier{
+		{MinHoursBefore: 24, RefundPercent: 50},
+		{MinHoursBefore: 72, RefundPercent: 100},
+		{MinHoursBefore: 2, RefundPercent: 10},
+	}}
+
+	tests := []struct {
+		name        string
+		policy      *RefundPolicy
+		hoursBefore float64
+		want        RefundTier
+		found       bool
+	}{
+		{name: "well ahead", policy: policy, hoursBefore: 200, want: RefundTier{MinHoursBefore: 72, RefundPercent: 100}, found: true},
+		{name: "exactly on a tier", policy: policy, hoursBefore: 72, want: RefundTier{MinHoursBefore: 72, RefundPercent: 100}, found: true},
+		{name: "just under a tier", policy: policy, hoursBefore: 71.9, want: RefundTier{MinHoursBefore: 24, RefundPercent: 50}, found: true},
+		{name: "last tier", policy: policy, hoursBefore: 3, want: RefundTier{MinHoursBefore: 2, RefundPercent: 10}, found: true},
+		{name: "under every tier", policy: policy, hoursBefore: 1.5},
+		{name: "departed", policy: policy, hoursBefore: -1},
+		{name: "no tiers", policy: &RefundPolicy{}, hoursBefore: 100},
+		{name: "zero hour tier", policy: &RefundPolicy{Tiers: []RefundTier{{MinHoursBefore: 0, RefundPercent: 20}}}, hoursBefore: 0.5,
+			want: RefundTier{MinHoursBefore: 0, RefundPercent: 20}, found: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tier, found := tt.policy.Tier(tt.hoursBefore)
+			require.Equal(t, tt.found, found)
+			require.Equal(t, tt.want, tier)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// DepartureReminder records the reminder sent for an order ahead of its
+// trip. It is kept per departure time, a trip that is moved is reminded of
+// again.
+type DepartureReminder struct {
+	OrderID     string    `json:"order_id" gorm:"primaryKey"`
+	LeadMinutes int       `json:"lead_minutes" gorm:"primaryKey"`
+	StartDate   time.Time `json:"start_date" gorm:"primaryKey"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (DepartureReminder) TableName() string {
+	return "departure_reminders"
+}
+
+// TripRecipient is the buyer of an order with valid tickets on a route, who
+// is emailed about the trip.
+type TripRecipient struct {
+	OrderID     string
+	OrderNumber string
+	UserID      string
+	Email       string
+	RouteID     string
+	StartDate   time.Time
+	Seats       []string
+}
@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+type Seat struct {
+	BusId  string `json:"bus_id"`
+	Number string `json:"number"`
+	Row    int    `json:"row" gorm:"column:seat_row"`
+	Column int    `json:"column" gorm:"column:seat_column"`
+	Class  string `json:"class"` // "standard", "comfort", "vip"
+}
+
+func (Seat) TableName() string {
+	return "bus_seats"
+}
+
+type SeatState struct {
+	Seat
+	Taken bool `json:"taken"`
+	Held  bool `json:"held"`
+}
+
+type SeatMap struct {
+	RouteID        string      `json:"route_id"`
+	BusID          string      `json:"bus_id"`
+	Rows           int         `json:"rows"`
+	Columns        int         `json:"columns"`
+	AvailableSeats int         `json:"available_seats"`
+	Seats          []SeatState `json:"seats"`
+}
+
+type SeatHold struct {
+	ID        string    `json:"id"`
+	RouteID   string    `json:"route_id"`
+	UserID    string    `json:"user_id"`
+	Seats     []string  `json:"seats"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// Session is a sign-in of a user on a device. Access tokens name the session
+// they were issued for, so ending it signs the device out.
+type Session struct {
+	ID          string     `json:"id" gorm:"primaryKey"`
+	UserID      string     `json:"user_id"`
+	RefreshHash string     `json:"-"`
+	UserAgent   string     `json:"user_agent" example:"Aulway/2.3 (Android 14)"`
+	IP          string     `json:"ip" gorm:"column:ip" example:"95.56.10.1"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  time.Time  `json:"last_used_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RevokedAt   *time.Time `json:"-"`
+	// Current is set on the session of the token the list was asked with
+	Current bool `json:"current" gorm:"-"`
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
@@ -0,0 +1,14 @@
+package domain
+
+// Settings are the preferences of a user. Language is the locale emails and
+// notifications are sent in.
+type Settings struct {
+	ID               string `json:"-" gorm:"primaryKey"`
+	UserID           string `json:"user_id"`
+	Language         string `json:"language"`
+	TwoFactorEnabled bool   `json:"two_factor_enabled"`
+}
+
+func (Settings) TableName() string {
+	return "settings"
+}
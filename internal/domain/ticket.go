@@ -3,14 +3,20 @@ package domain
 import "time"
 
 type Ticket struct {
-	ID            string    `json:"id"`
-	UserID        string    `json:"user_id"`
-	RouteID       string    `json:"route_id"`
-	Price         int       `json:"price"`
-	Status        string    `json:"status"`         // "approved", "cancelled", "awaiting"
-	PaymentStatus string    `json:"payment_status"` // "pending", "paid", "failed", "refunded"
-	OrderNumber   string    `json:"order_number"`
-	PaymentID     string    `json:"payment_id" gorm:"column:payment_id"`
-	QRCode        string    `json:"qr_code"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID            string     `json:"id"`
+	UserID        string     `json:"user_id"`
+	RouteID       string     `json:"route_id"`
+	Price         int        `json:"price"`
+	SeatNumber    string     `json:"seat_number"`
+	Status        string     `json:"status"`         // "approved", "cancelled", "awaiting", "boarded", "no_show"
+	PaymentStatus string     `json:"payment_status"` // "pending", "paid", "failed", "refunded"
+	OrderID       string     `json:"order_id" gorm:"default:null"`
+	OrderNumber   string     `json:"order_number"`
+	PaymentID     string     `json:"payment_id" gorm:"column:payment_id"`
+	QRCode        string     `json:"qr_code"`
+	QRVersion     int        `json:"-"`
+	BoardedAt     *time.Time `json:"boarded_at,omitempty"`
+	BoardedBy     *string    `json:"boarded_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 }
@@ -9,7 +9,10 @@ import (
 type User struct {
 	ID                   string         `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
 	Email                string         `gorm:"type:varchar(255);unique;not null" json:"email"`
+	EmailVerified        bool           `gorm:"default:false" json:"email_verified"`
 	Phone                string         `gorm:"type:varchar(20);unique;not null" json:"phone"`
+	PhoneVerified        bool           `gorm:"default:false" json:"phone_verified"`
+	FirebaseUID          *string        `gorm:"column:firebase_uid" json:"-"`
 	Password             string         `gorm:"type:text;not null" json:"-"`
 	FirstName            string         `gorm:"type:varchar(100);not null" json:"first_name"`
 	LastName             string         `gorm:"type:varchar(100);not null" json:"last_name"`
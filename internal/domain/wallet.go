@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// WalletRegistration is a device that added a ticket to Apple Wallet.
+type WalletRegistration struct {
+	DeviceLibraryID string    `json:"device_library_id" gorm:"primaryKey"`
+	PassTypeID      string    `json:"pass_type_id" gorm:"primaryKey"`
+	SerialNumber    string    `json:"serial_number" gorm:"primaryKey"`
+	PushToken       string    `json:"push_token"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// UpdatedPasses answers a device asking which of its passes changed.
+type UpdatedPasses struct {
+	SerialNumbers []string `json:"serialNumbers"`
+	LastUpdated   string   `json:"lastUpdated"`
+}
+
+// GoogleWalletLink is an "Add to Google Wallet" link of a ticket.
+type GoogleWalletLink struct {
+	SaveURL string `json:"save_url"`
+}
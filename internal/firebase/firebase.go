@@ -2,15 +2,19 @@ package firebase
 
 import (
 	"context"
-	"firebase.google.com/go"
-	"firebase.google.com/go/auth"
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
 	"google.golang.org/api/option"
 	"log"
 )
 
+// NewApp connects to the Firebase project of the service account key file.
+func NewApp(ctx context.Context, credentialsFile string) (*firebase.App, error) {
+	return firebase.NewApp(ctx, nil, option.WithCredentialsFile(credentialsFile))
+}
+
 func InitializeFirebase() (*auth.Client, error) {
-	opt := option.WithCredentialsFile("firebase/aulway-9670a-firebase-adminsdk-fbsvc-f483a0bc6a.json")
-	app, err := firebase.NewApp(context.Background(), nil, opt)
+	app, err := NewApp(context.Background(), "firebase/aulway-9670a-firebase-adminsdk-fbsvc-f483a0bc6a.json")
 	if err != nil {
 		log.Fatalf("error initializing Firebase app: %v", err)
 		return nil, err
@@ -0,0 +1,181 @@
+package firebase
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// GoogleCertsURL serves the certificates Firebase signs ID tokens with.
+const GoogleCertsURL = "https://www.googleapis.com/robot/v1/metadata/x509/securetoken@system.gserviceaccount.com"
+
+const (
+	// defaultCertsTTL is how long certificates are kept when the response
+	// does not say.
+	defaultCertsTTL = time.Hour
+	// certsRefetchInterval is the least time between fetches forced by a key
+	// id the cached certificates do not have.
+	certsRefetchInterval = time.Minute
+)
+
+var maxAge = regexp.MustCompile(`max-age=(\d+)`)
+
+var ErrInvalidIDToken = errors.New("firebase id token is not valid")
+
+// IDToken holds the claims of a verified Firebase ID token.
+type IDToken struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	PhoneNumber   string `json:"phone_number"`
+	Name          string `json:"name"`
+	AuthTime      int64  `json:"auth_time"`
+	Firebase      struct {
+		SignInProvider string `json:"sign_in_provider"`
+	} `json:"firebase"`
+	jwt.RegisteredClaims
+}
+
+// UID is the Firebase user id the token was issued to.
+func (t *IDToken) UID() string {
+	return t.Subject
+}
+
+// IDTokenVerifier checks Firebase ID tokens of a project the way the Admin
+// SDK does, against Google's public certificates which are cached for as
+// long as Google allows.
+type IDTokenVerifier struct {
+	ProjectID string
+	CertsURL  string
+	Client    *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	expires time.Time
+	fetched time.Time
+}
+
+func NewIDTokenVerifier(projectID string) *IDTokenVerifier {
+	return &IDTokenVerifier{
+		ProjectID: projectID,
+		CertsURL:  GoogleCertsURL,
+		Client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Verify checks the signature, audience, issuer and lifetime of the token.
+func (v *IDTokenVerifier) Verify(ctx context.Context, token string) (*IDToken, error) {
+	claims := &IDToken{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}))
+
+	_, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("no key id")
+		}
+
+		return v.publicKey(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+
+	if !claims.VerifyAudience(v.ProjectID, true) {
+		return nil, fmt.Errorf("%w: audience is not %s", ErrInvalidIDToken, v.ProjectID)
+	}
+	if !claims.VerifyIssuer("https://securetoken.google.com/"+v.ProjectID, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer %s", ErrInvalidIDToken, claims.Issuer)
+	}
+	if claims.Subject == "" || len(claims.Subject) > 128 {
+		return nil, fmt.Errorf("%w: invalid subject", ErrInvalidIDToken)
+	}
+	if claims.AuthTime > time.Now().Unix() {
+		return nil, fmt.Errorf("%w: authenticated in the future", ErrInvalidIDToken)
+	}
+
+	return claims, nil
+}
+
+// publicKey returns the key the token was signed with, fetching the
+// certificates when the cached ones expired or do not have the key.
+func (v *IDTokenVerifier) publicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	if key, ok := v.keys[kid]; ok && now.Before(v.expires) {
+		return key, nil
+	}
+	if now.Before(v.expires) && now.Sub(v.fetched) < certsRefetchInterval {
+		return nil, fmt.Errorf("unknown key id %s", kid)
+	}
+
+	if err := v.fetchKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	key, ok := v.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %s", kid)
+	}
+
+	return key, nil
+}
+
+func (v *IDTokenVerifier) fetchKeys(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.CertsURL, nil)
+	if err != nil {
+		return fmt.Errorf("firebase certs request: %w", err)
+	}
+
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch firebase certs: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch firebase certs: status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("read firebase certs: %w", err)
+	}
+
+	var certs map[string]string
+	if err := json.Unmarshal(body, &certs); err != nil {
+		return fmt.Errorf("decode firebase certs: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(certs))
+	for kid, cert := range certs {
+		key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(cert))
+		if err != nil {
+			return fmt.Errorf("parse firebase cert %s: %w", kid, err)
+		}
+		keys[kid] = key
+	}
+
+	ttl := defaultCertsTTL
+	if match := maxAge.FindStringSubmatch(resp.Header.Get("Cache-Control")); match != nil {
+		if seconds, err := strconv.Atoi(match[1]); err == nil {
+			ttl = time.Duration(seconds) * time.Second
+		}
+	}
+
+	v.keys = keys
+	v.fetched = time.Now()
+	v.expires = v.fetched.Add(ttl)
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package firebase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+)
+
+const testProject = "aulway-test"
+
+// certServer serves a certificate of the key under kid the way Google does.
+func certServer(t *testing.T, key *rsa.PrivateKey, kid string, fetches *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "securetoken.system.gserviceaccount.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Header().Set("Cache-Control", "public, max-age=3600, must-revalidate")
+		json.NewEncoder(w).Encode(map[string]string{kid: string(cert)})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func mint(t *testing.T, key *rsa.PrivateKey, kid string, claims IDToken) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func validClaims() IDToken {
+	now := time.Now()
+	claims := IDToken{
+		Email:         "rider@example.com",
+		EmailVerified: true,
+		AuthTime:      now.Add(-time.Minute).Unix(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "https://securetoken.google.com/" + testProject,
+			Audience:  jwt.ClaimStrings{testProject},
+			Subject:   "firebase-uid-1",
+			IssuedAt:  jwt.NewNumericDate(now.Add(-time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+	}
+	claims.Firebase.SignInProvider = "google.com"
+
+	return claims
+}
+
+func TestIDTokenVerifier(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var fetches atomic.Int32
+	verifier := NewIDTokenVerifier(testProject)
+	verifier.CertsURL = certServer(t, key, "key-1", &fetches).URL
+	ctx := context.Background()
+
+	token, err := verifier.Verify(ctx, mint(t, key, "key-1", validClaims()))
+	require.NoError(t, err)
+	require.Equal(t, "firebase-uid-1", token.UID())
+	require.Equal(t, "rider@example.com", token.Email)
+	require.Equal(t, "google.com", token.Firebase.SignInProvider)
+
+	// the certificates are cached for as long as the response allows
+	_, err = verifier.Verify(ctx, mint(t, key, "key-1", validClaims()))
+	require.NoError(t, err)
+	require.EqualValues(t, 1, fetches.Load())
+
+	wrongAudience := validClaims()
+	wrongAudience.Audience = jwt.ClaimStrings{"another-project"}
+	wrongIssuer := validClaims()
+	wrongIssuer.Issuer = "https://securetoken.google.com/another-project"
+	expired := validClaims()
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	noSubject := validClaims()
+	noSubject.Subject = ""
+	futureAuth := validClaims()
+	futureAuth.AuthTime = time.Now().Add(time.Hour).Unix()
+
+	for name, token := range map[string]string{
+		"wrong audience": mint(t, key, "key-1", wrongAudience),
+		"wrong issuer":   mint(t, key, "key-1", wrongIssuer),
+		"expired":        mint(t, key, "key-1", expired),
+		"no subject":     mint(t, key, "key-1", noSubject),
+		"future auth":    mint(t, key, "key-1", futureAuth),
+		"other key":      mint(t, other, "key-1", validClaims()),
+		"unknown kid":    mint(t, key, "key-2", validClaims()),
+	} {
+		_, err := verifier.Verify(ctx, token)
+		require.ErrorIs(t, err, ErrInvalidIDToken, name)
+	}
+
+	// unknown key ids refetch at most once a minute
+	require.EqualValues(t, 1, fetches.Load())
+
+	hs256, err := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = verifier.Verify(ctx, hs256)
+	require.ErrorIs(t, err, ErrInvalidIDToken)
+}
@@ -2,11 +2,14 @@ package model
 
 import (
 	"aulway/internal/handler/user/model"
+	"time"
 )
 
 type SignupRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// Language of the verification email, Accept-Language is used without it
+	Language string `json:"language,omitempty" example:"ru"`
 }
 
 type VerifyEmailRequest struct {
@@ -27,13 +30,13 @@ type ResetPassword struct {
 }
 
 type SigninResponse struct {
-	AccessToken string             `json:"access_token"`
-	User        model.UserResponse `json:"user"`
+	TokenResponse
+	User model.UserResponse `json:"user"`
 }
 
 type SignupResponse struct {
-	AccessToken string             `json:"access_token"`
-	User        model.UserResponse `json:"user"`
+	TokenResponse
+	User model.UserResponse `json:"user"`
 }
 
 type ForgotPasswordRequest struct {
@@ -45,3 +48,63 @@ type VerifyResetCodeRequest struct {
 	Code        string `json:"code" validate:"required,len=6"`
 	NewPassword string `json:"new_password" validate:"required"`
 }
+
+type PhoneCodeRequest struct {
+	Phone string `json:"phone" example:"+77011234567"`
+	// Language of the SMS, Accept-Language is used without it
+	Language string `json:"language,omitempty" example:"kk"`
+}
+
+type PhoneSigninRequest struct {
+	Phone string `json:"phone" example:"+77011234567"`
+	Code  string `json:"code" example:"123456"`
+}
+
+type PhoneSigninResponse struct {
+	TokenResponse
+	User model.UserResponse `json:"user"`
+	// Created is set when the phone was new and an account was signed up
+	Created bool `json:"created"`
+}
+
+// TokenResponse are the tokens of a session. The access token is sent as
+// Bearer and expires in ExpiresIn seconds, the refresh token is traded for
+// new tokens at /auth/refresh and works once.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in" example:"900"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// OIDCCallbackRequest is what the provider sends the user back with, in the
+// query or, from Apple, as a form. User is the name Apple sends along the
+// first time a user signs in.
+type OIDCCallbackRequest struct {
+	Code             string `query:"code" form:"code"`
+	State            string `query:"state" form:"state"`
+	Error            string `query:"error" form:"error"`
+	ErrorDescription string `query:"error_description" form:"error_description"`
+	User             string `query:"user" form:"user"`
+}
+
+// OIDCStart is a sign-in with a provider about to begin. StateHash is kept
+// in a cookie of the browser it began in, only that browser can complete it.
+// CrossSitePost is set for providers that post the user back, the cookie has
+// to be sent along with a cross-site POST then.
+type OIDCStart struct {
+	URL           string
+	StateHash     string
+	CrossSitePost bool
+	Expires       time.Time
+}
+
+type OIDCSigninResponse struct {
+	TokenResponse
+	User model.UserResponse `json:"user"`
+	// Created is set when no account had the email and one was signed up
+	Created bool `json:"created"`
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/auth/model"
+	rerrs "aulway/internal/repository/errs"
+	"aulway/internal/utils/errs"
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"log/slog"
+	"net/http"
+)
+
+// oidcStateCookie binds a sign-in with a provider to the browser it began in.
+const oidcStateCookie = "oidc_state"
+
+type OIDC interface {
+	Begin(ctx context.Context, provider string) (*model.OIDCStart, error)
+	Complete(ctx context.Context, provider, stateHash string, req model.OIDCCallbackRequest) (*domain.User, bool, error)
+}
+
+// OIDCSigninHandler
+// @Summary Sign in with a provider
+// @Description Redirects to Google or Apple to sign in, they send the user back to the callback. The request is
+// @Description valid for ten minutes and only in the browser it was made in, which keeps it in a cookie.
+// @Tags auth
+// @Param provider path string true "Provider" Enums(google, apple)
+// @Success 302
+// @Failure 404 {object} errs.Err "Not Found - Provider not available"
+// @Failure 500 {object} errs.Err "Internal Server Error"
+// @Router /auth/oidc/{provider} [get]
+func OIDCSigninHandler(oidc OIDC) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start, err := oidc.Begin(c.Request().Context(), c.Param("provider"))
+		if errors.Is(err, errs.ErrUnknownProvider) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "failed to signin", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			slog.Error("oidc signin: error at beginning signin,", "error", err.Error())
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to signin", ErrDesc: err.Error()})
+		}
+
+		cookie := &http.Cookie{
+			Name:     oidcStateCookie,
+			Value:    start.StateHash,
+			Path:     "/auth/oidc/" + c.Param("provider"),
+			Expires:  start.Expires,
+			HttpOnly: true,
+			Secure:   c.Scheme() == "https",
+			SameSite: http.SameSiteLaxMode,
+		}
+		// Apple posts the user back from its own site, a Lax cookie would be
+		// left out of that request
+		if start.CrossSitePost {
+			cookie.Secure = true
+			cookie.SameSite = http.SameSiteNoneMode
+		}
+		c.SetCookie(cookie)
+
+		return c.Redirect(http.StatusFound, start.URL)
+	}
+}
+
+// OIDCCallbackHandler
+// @Summary Provider sign-in callback
+// @Description Where Google (query) and Apple (form post) send the user back to. Signs in the user the account of the
+// @Description provider is linked to. An account not linked yet is linked to the user who verified its email with
+// @Description us, or an account is signed up with it and created is set.
+// @Tags auth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param provider path string true "Provider" Enums(google, apple)
+// @Param code query string false "Authorization code"
+// @Param state query string false "State of the sign-in request"
+// @Param error query string false "Set when the user did not sign in"
+// @Success 200 {object} model.OIDCSigninResponse
+// @Failure 400 {object} errs.Err "Bad Request - Sign-in expired, began in another browser, refused or the email is not verified"
+// @Failure 404 {object} errs.Err "Not Found - Provider not available or user deleted"
+// @Failure 409 {object} errs.Err "Conflict - The user has another account of the provider linked, or the account with the email did not verify it"
+// @Failure 500 {object} errs.Err "Internal Server Error"
+// @Router /auth/oidc/{provider}/callback [get]
+// @Router /auth/oidc/{provider}/callback [post]
+func OIDCCallbackHandler(oidc OIDC, sessions Sessions) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req model.OIDCCallbackRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "failed to signin", ErrDesc: err.Error()})
+		}
+
+		if req.Error != "" {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "failed to signin", ErrDesc: req.Error + " " + req.ErrorDescription})
+		}
+
+		// the sign-in is done with either way, the state works once
+		stateHash := ""
+		if cookie, err := c.Cookie(oidcStateCookie); err == nil {
+			stateHash = cookie.Value
+		}
+		c.SetCookie(&http.Cookie{Name: oidcStateCookie, Path: "/auth/oidc/" + c.Param("provider"), MaxAge: -1, HttpOnly: true})
+
+		usr, created, err := oidc.Complete(c.Request().Context(), c.Param("provider"), stateHash, req)
+		if err != nil {
+			return c.JSON(oidcStatus(err), errs.Err{Err: "failed to signin", ErrDesc: err.Error()})
+		}
+
+		tokens, err := sessions.Start(c.Request().Context(), *usr, c.Request().UserAgent(), c.RealIP())
+		if err != nil {
+			slog.Error("oidc signin: error at starting session,", "error", err.Error())
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "create access token error", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, model.OIDCSigninResponse{
+			TokenResponse: *tokens,
+			User:          domainUserToResponse(*usr),
+			Created:       created,
+		})
+	}
+}
+
+// oidcStatus is the response status of a failed provider sign-in.
+func oidcStatus(err error) int {
+	switch {
+	case errors.Is(err, errs.ErrInvalidSigninState), errors.Is(err, errs.ErrSigninRefused), errors.Is(err, errs.ErrEmailNotVerified):
+		return http.StatusBadRequest
+	case errors.Is(err, errs.ErrUnknownProvider), errors.Is(err, rerrs.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, rerrs.ProviderAlreadyLinked), errors.Is(err, rerrs.EmailAlreadyExists), errors.Is(err, errs.ErrAccountExists):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"aulway/internal/handler/auth/model"
+	"aulway/internal/ratelimit"
+	rerrs "aulway/internal/repository/errs"
+	"aulway/internal/templates"
+	"aulway/internal/utils/errs"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"log/slog"
+	"net/http"
+)
+
+// SendPhoneCodeHandler
+// @Summary Phone sign-in code
+// @Description Texts a verification code to a Kazakh phone number (+7 or 8 followed by ten digits). The code signs in
+// @Description the user the phone is verified for, or signs up a new one. A phone gets one code a minute and a few an hour.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body model.PhoneCodeRequest true "Phone"
+// @Param Accept-Language header string false "Language of the SMS when the body has none"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} errs.Err "Bad Request - Invalid phone number"
+// @Failure 429 {object} errs.Err "Too Many Requests - Too many codes requested for the phone"
+// @Failure 500 {object} errs.Err "Internal Server Error"
+// @Failure 501 {object} errs.Err "Not Implemented - Text messages are not configured"
+// @Router /auth/phone [post]
+func SendPhoneCodeHandler(authService Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req model.PhoneCodeRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "send code failed", ErrDesc: err.Error()})
+		}
+
+		locale := req.Language
+		if !templates.Supported(locale) {
+			locale = templates.Match(c.Request().Header.Get("Accept-Language"))
+		}
+
+		err := authService.SendSigninCode(c.Request().Context(), req.Phone, locale)
+		if err != nil {
+			return c.JSON(phoneCodeStatus(c, err), errs.Err{Err: "send code failed", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, echo.Map{"message": "Verification code sent to phone"})
+	}
+}
+
+// PhoneSigninHandler
+// @Summary Phone sign-in
+// @Description Checks the code texted to the phone and returns an access token. A phone no one has verified yet
+// @Description gets a new account, created is set then. A code can be tried five times.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body model.PhoneSigninRequest true "Phone and code"
+// @Success 200 {object} model.PhoneSigninResponse
+// @Failure 400 {object} errs.Err "Bad Request - Invalid phone number or code"
+// @Failure 429 {object} errs.Err "Too Many Requests - Too many attempts, see Retry-After"
+// @Failure 500 {object} errs.Err "Internal Server Error"
+// @Router /auth/phone/verify [post]
+func PhoneSigninHandler(authService Service, sessions Sessions) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req model.PhoneSigninRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "failed to signin", ErrDesc: err.Error()})
+		}
+
+		usr, created, err := authService.SigninWithPhone(c.Request().Context(), req)
+		if err != nil {
+			return c.JSON(phoneCodeStatus(c, err), errs.Err{Err: "failed to signin", ErrDesc: err.Error()})
+		}
+
+		tokens, err := sessions.Start(c.Request().Context(), *usr, c.Request().UserAgent(), c.RealIP())
+		if err != nil {
+			slog.Error("phone signin: error at starting session,", "error", err.Error())
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "create access token error", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, model.PhoneSigninResponse{
+			TokenResponse: *tokens,
+			User:          domainUserToResponse(*usr),
+			Created:       created,
+		})
+	}
+}
+
+// phoneCodeStatus is the response status of a failed phone code request or
+// check.
+func phoneCodeStatus(c echo.Context, err error) int {
+	switch {
+	case ratelimit.RetryAfter(c.Response().Header(), err):
+		return http.StatusTooManyRequests
+	case errors.Is(err, errs.ErrIncorrectPhoneFormat), errors.Is(err, errs.ErrInvalidCode):
+		return http.StatusBadRequest
+	case errors.Is(err, errs.ErrTooManyCodeRequests):
+		return http.StatusTooManyRequests
+	case errors.Is(err, rerrs.PhoneAlreadyExists):
+		return http.StatusConflict
+	case errors.Is(err, errs.ErrSMSNotConfigured):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"aulway/internal/handler/auth/model"
+	rerrs "aulway/internal/repository/errs"
+	"aulway/internal/utils/errs"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+// RefreshHandler
+// @Summary Refresh tokens
+// @Description Trades a refresh token for a new access token and a new refresh token, the one sent stops working.
+// @Description Sending a refresh token that was already traded in ends its session.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body model.RefreshRequest true "Refresh token"
+// @Success 200 {object} model.TokenResponse
+// @Failure 400 {object} errs.Err "Bad Request - Invalid request body"
+// @Failure 401 {object} errs.Err "Unauthorized - Refresh token invalid, expired or revoked"
+// @Failure 500 {object} errs.Err "Internal Server Error"
+// @Router /auth/refresh [post]
+func RefreshHandler(sessions Sessions) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req model.RefreshRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "refresh failed", ErrDesc: err.Error()})
+		}
+		if req.RefreshToken == "" {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "refresh failed", ErrDesc: "refresh token required"})
+		}
+
+		tokens, err := sessions.Refresh(c.Request().Context(), req.RefreshToken, c.Request().UserAgent(), c.RealIP())
+		if errors.Is(err, errs.ErrInvalidRefreshToken) {
+			return c.JSON(http.StatusUnauthorized, errs.Err{Err: "refresh failed", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "refresh failed", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, tokens)
+	}
+}
+
+// LogoutHandler
+// @Summary Logout
+// @Description Ends the session of the access token, its access and refresh tokens stop working.
+// @Tags auth
+// @Security BearerAuth
+// @Success 204
+// @Failure 401 {object} errs.Err "Unauthorized"
+// @Failure 500 {object} errs.Err "Internal Server Error"
+// @Router /auth/logout [post]
+func LogoutHandler(sessions Sessions) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID, _ := c.Get("user_id").(string)
+		sessionID, _ := c.Get("session_id").(string)
+		if sessionID == "" {
+			// Firebase ID tokens have no session of ours to end
+			return c.NoContent(http.StatusNoContent)
+		}
+
+		err := sessions.Revoke(c.Request().Context(), userID, sessionID)
+		if err != nil && !errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "logout failed", ErrDesc: err.Error()})
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
@@ -3,7 +3,7 @@ package auth
 import (
 	"aulway/internal/handler/auth/model"
 	"aulway/internal/handler/user"
-	"aulway/internal/utils/config"
+	"aulway/internal/ratelimit"
 	"aulway/internal/utils/errs"
 	"github.com/labstack/echo/v4"
 	"log/slog"
@@ -21,9 +21,10 @@ import (
 // @Failure 400 {object} errs.Err "Bad Request - Invalid request body"
 // @Failure 403 {object} errs.Err "Forbidden - Password reset required"
 // @Failure 404 {object} errs.Err "Not Found - User not found or incorrect credentials"
+// @Failure 429 {object} errs.Err "Too Many Requests - Too many attempts, see Retry-After"
 // @Failure 500 {object} errs.Err "Internal Server Error"
 // @Router /auth/signin [post]
-func SigninHandler(authService Service, userService user.Service, cfg config.Config) echo.HandlerFunc {
+func SigninHandler(sessions Sessions, userService user.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		var req model.SigninRequest
 
@@ -37,6 +38,9 @@ func SigninHandler(authService Service, userService user.Service, cfg config.Con
 		}
 
 		usr, err := userService.ValidateUser(c.Request().Context(), req)
+		if ratelimit.RetryAfter(c.Response().Header(), err) {
+			return c.JSON(http.StatusTooManyRequests, errs.Err{Err: "failed to signin", ErrDesc: err.Error()})
+		}
 		if err != nil {
 			return c.JSON(http.StatusNotFound, errs.Err{Err: "failed to signin", ErrDesc: err.Error()})
 		}
@@ -45,15 +49,15 @@ func SigninHandler(authService Service, userService user.Service, cfg config.Con
 			return c.JSON(http.StatusForbidden, errs.Err{Err: "access denied", ErrDesc: "reset password required"})
 		}
 
-		token, err := authService.CreateAccessToken(c.Request().Context(), *usr, cfg.JWTTokenSecret, cfg.AccessTokenExpire)
+		tokens, err := sessions.Start(c.Request().Context(), *usr, c.Request().UserAgent(), c.RealIP())
 		if err != nil {
-			slog.Error("signin: error at creating access token,", "error", err.Error())
+			slog.Error("signin: error at starting session,", "error", err.Error())
 			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "create access token error", ErrDesc: err.Error()})
 		}
 
 		resp := model.SigninResponse{
-			AccessToken: token,
-			User:        domainUserToResponse(*usr),
+			TokenResponse: *tokens,
+			User:          domainUserToResponse(*usr),
 		}
 		return c.JSON(http.StatusOK, resp)
 	}
@@ -5,12 +5,14 @@ import (
 	"aulway/internal/handler/auth/model"
 	"aulway/internal/handler/user"
 	usermodel "aulway/internal/handler/user/model"
+	"aulway/internal/ratelimit"
 	"aulway/internal/service"
+	"aulway/internal/templates"
 	"aulway/internal/utils/config"
 	uerrs "aulway/internal/utils/errs"
 	"context"
 	"errors"
-	"firebase.google.com/go/auth"
+	"firebase.google.com/go/v4/auth"
 	"fmt"
 	"github.com/labstack/echo/v4"
 	"github.com/redis/go-redis/v9"
@@ -27,14 +29,25 @@ const (
 	UserRole  = "user"
 )
 
+// verificationCodeTTL is how long the code of a signup can be used.
+const verificationCodeTTL = 10 * time.Minute
+
 type userService interface {
 	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
 }
 
 type Service interface {
-	CreateAccessToken(ctx context.Context, user domain.User, jwtSecret string, expiry int) (string, error)
 	SendResetCode(ctx context.Context, email string) error
 	VerifyResetCode(ctx context.Context, req model.VerifyResetCodeRequest) error
+	VerifySignupCode(ctx context.Context, email, code string) error
+	SendSigninCode(ctx context.Context, phone, locale string) error
+	SigninWithPhone(ctx context.Context, req model.PhoneSigninRequest) (*domain.User, bool, error)
+}
+
+type Sessions interface {
+	Start(ctx context.Context, user domain.User, userAgent, ip string) (*model.TokenResponse, error)
+	Refresh(ctx context.Context, refreshToken, userAgent, ip string) (*model.TokenResponse, error)
+	Revoke(ctx context.Context, userID, sessionID string) error
 }
 
 // ForgotPasswordHandler
@@ -71,6 +84,8 @@ func ForgotPasswordHandler(svc Service) echo.HandlerFunc {
 // @Param request body model.VerifyResetCodeRequest true "required"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} errs.Err "Bad Request - Invalid request body"
+// @Failure 401 {object} errs.Err "Unauthorized - Code invalid or expired, it is dropped after five wrong tries"
+// @Failure 429 {object} errs.Err "Too Many Requests - Too many attempts, see Retry-After"
 // @Failure 500 {object} errs.Err "Internal Server Error"
 // @Router /auth/forgot-password/verify [post]
 func VerifyForgotPasswordHandler(svc Service) echo.HandlerFunc {
@@ -81,6 +96,9 @@ func VerifyForgotPasswordHandler(svc Service) echo.HandlerFunc {
 		}
 
 		err := svc.VerifyResetCode(c.Request().Context(), req)
+		if ratelimit.RetryAfter(c.Response().Header(), err) {
+			return c.JSON(http.StatusTooManyRequests, uerrs.Err{Err: err.Error()})
+		}
 		if err != nil {
 			return c.JSON(http.StatusUnauthorized, uerrs.Err{Err: err.Error()})
 		}
@@ -96,6 +114,7 @@ func VerifyForgotPasswordHandler(svc Service) echo.HandlerFunc {
 // @Accept json
 // @Produce json
 // @Param request body model.SignupRequest true "Signup Request Body"
+// @Param Accept-Language header string false "Language of the verification email when the body has none"
 // @Success 200 {string} string "response"
 // @Failure 400 {object} errs.Err "Bad Request - Invalid request body"
 // @Failure 500 {object} errs.Err "Internal Server Error"
@@ -128,12 +147,17 @@ func SignupHandler(redisClient *redis.Client, cfg config.Config, userS userServi
 
 		verificationCode := fmt.Sprintf("%06d", rand.Intn(1000000))
 
-		err = redisClient.Set(c.Request().Context(), "email_verification:"+req.Email, verificationCode, 10*time.Minute).Err()
+		err = service.StoreCode(c.Request().Context(), redisClient, "email_verification:"+req.Email, verificationCode, verificationCodeTTL)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, uerrs.Err{Err: "signup failed", ErrDesc: "failed to store verification code"})
 		}
 
-		err = service.SendEmail(req.Email, "Email Verification Code", fmt.Sprintf("Your verification code is: %s", verificationCode), cfg.SMTP)
+		locale := req.Language
+		if !templates.Supported(locale) {
+			locale = templates.Match(c.Request().Header.Get("Accept-Language"))
+		}
+
+		err = service.SendCodeEmail(req.Email, locale, templates.VerificationEmail, verificationCode, verificationCodeTTL, cfg.SMTP)
 		if err != nil {
 			log.Print(err.Error())
 			return c.JSON(http.StatusInternalServerError, uerrs.Err{Err: "signup failed", ErrDesc: "failed to send verification email"})
@@ -151,10 +175,11 @@ func SignupHandler(redisClient *redis.Client, cfg config.Config, userS userServi
 // @Produce json
 // @Param request body model.VerifyEmailRequest true "VerifyEmail Request Body"
 // @Success 200 {object} model.SignupResponse "response"
-// @Failure 400 {object} errs.Err "Bad Request - Invalid request body"
+// @Failure 400 {object} errs.Err "Bad Request - Invalid request body, or code invalid or expired"
+// @Failure 429 {object} errs.Err "Too Many Requests - Too many attempts, see Retry-After"
 // @Failure 500 {object} errs.Err "Internal Server Error"
 // @Router /auth/signup/verify [post]
-func VerifyEmailHandler(redisClient *redis.Client, userService user.Service, authService Service, cfg config.Config) echo.HandlerFunc {
+func VerifyEmailHandler(authService Service, userService user.Service, sessions Sessions) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		var req model.VerifyEmailRequest
 
@@ -162,17 +187,16 @@ func VerifyEmailHandler(redisClient *redis.Client, userService user.Service, aut
 			return c.JSON(http.StatusBadRequest, uerrs.Err{Err: "verification failed", ErrDesc: "invalid request"})
 		}
 
-		storedCode, err := redisClient.Get(c.Request().Context(), "email_verification:"+req.Email).Result()
-		if err == redis.Nil {
-			return c.JSON(http.StatusBadRequest, uerrs.Err{Err: "verification failed", ErrDesc: "verification code expired or not found"})
+		err := authService.VerifySignupCode(c.Request().Context(), req.Email, req.Code)
+		if ratelimit.RetryAfter(c.Response().Header(), err) {
+			return c.JSON(http.StatusTooManyRequests, uerrs.Err{Err: "verification failed", ErrDesc: err.Error()})
+		}
+		if errors.Is(err, uerrs.ErrInvalidCode) {
+			return c.JSON(http.StatusBadRequest, uerrs.Err{Err: "verification failed", ErrDesc: err.Error()})
 		} else if err != nil {
 			return c.JSON(http.StatusInternalServerError, uerrs.Err{Err: "verification failed", ErrDesc: "server error"})
 		}
 
-		if req.Code != storedCode {
-			return c.JSON(http.StatusBadRequest, uerrs.Err{Err: "verification failed", ErrDesc: "invalid verification code"})
-		}
-
 		createUserModel := model.SignupRequest{
 			Email:    req.Email,
 			Password: req.Password,
@@ -183,20 +207,18 @@ func VerifyEmailHandler(redisClient *redis.Client, userService user.Service, aut
 			return c.JSON(http.StatusInternalServerError, uerrs.Err{Err: "verification failed", ErrDesc: "failed to update user status"})
 		}
 
-		token, err := authService.CreateAccessToken(c.Request().Context(), *usr, cfg.JWTTokenSecret, cfg.AccessTokenExpire)
+		tokens, err := sessions.Start(c.Request().Context(), *usr, c.Request().UserAgent(), c.RealIP())
 		if err != nil {
-			slog.Error("signup: error at creating access token,", "error", err.Error())
+			slog.Error("signup: error at starting session,", "error", err.Error())
 
 			return c.JSON(http.StatusInternalServerError, uerrs.Err{Err: "signup failed", ErrDesc: err.Error()})
 		}
 
 		resp := model.SignupResponse{
-			AccessToken: token,
-			User:        domainUserToResponse(*usr),
+			TokenResponse: *tokens,
+			User:          domainUserToResponse(*usr),
 		}
 
-		redisClient.Del(c.Request().Context(), "email_verification:"+req.Email)
-
 		return c.JSON(http.StatusOK, resp)
 	}
 }
@@ -245,14 +267,16 @@ func VerifyEmailHandler(redisClient *redis.Client, userService user.Service, aut
 
 func domainUserToResponse(user domain.User) usermodel.UserResponse {
 	return usermodel.UserResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		Phone:     user.Phone,
-		Role:      user.Role,
-		Firstname: user.FirstName,
-		Lastname:  user.LastName,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:            user.ID,
+		Email:         user.Email,
+		Phone:         user.Phone,
+		EmailVerified: user.EmailVerified,
+		PhoneVerified: user.PhoneVerified,
+		Role:          user.Role,
+		Firstname:     user.FirstName,
+		Lastname:      user.LastName,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
 	}
 }
 
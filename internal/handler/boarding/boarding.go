@@ -0,0 +1,199 @@
+package boarding
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/boarding/model"
+	rerrs "aulway/internal/repository/errs"
+	"aulway/internal/utils/errs"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type Service interface {
+	Validate(ctx context.Context, conductorID string, req model.ValidateRequest) (*domain.Ticket, error)
+	Manifest(ctx context.Context, routeID string) (*domain.Manifest, error)
+	ManifestCSV(ctx context.Context, routeID string) ([]byte, string, error)
+	ManifestKey() string
+	SyncScans(ctx context.Context, conductorID, routeID string, req model.SyncRequest) (*domain.ManifestSync, error)
+	RouteStats(ctx context.Context, routeID string) (*domain.BoardingStats, error)
+}
+
+// ManifestSignatureHeader carries the signature of a CSV manifest.
+const ManifestSignatureHeader = "X-Manifest-Signature"
+
+// ValidateTicketHandler checks a scanned ticket QR code at boarding.
+// @Summary      Validate ticket
+// @Description  Checks the signature of a scanned ticket code and marks the ticket as boarded.
+// @Description  A ticket boards once, scanning it again is rejected. Pass "route_id" to reject tickets for other routes.
+// @Description  Boarding opens a set time before departure, two hours by default, and closes when the route arrives.
+// @Tags         boarding
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        requestBody  body      model.ValidateRequest  true  "Scanned code"
+// @Success      200          {object}  domain.Ticket          "Passenger boarded"
+// @Failure      400          {object}  errs.Err
+// @Failure      409          {object}  errs.Err  "Ticket already used, cancelled, for another route or outside the boarding window"
+// @Failure      422          {object}  errs.Err  "Code is forged or unreadable"
+// @Failure      500          {object}  errs.Err
+// @Router       /api/validate [post]
+func ValidateTicketHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req model.ValidateRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "Binding request body failed", ErrDesc: err.Error()})
+		}
+		if err := req.Validate(); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "Bad request", ErrDesc: err.Error()})
+		}
+
+		conductorID := fmt.Sprintf("%v", c.Get("user_id"))
+
+		ticket, err := s.Validate(c.Request().Context(), conductorID, req)
+		switch {
+		case errors.Is(err, errs.ErrInvalidTicketToken):
+			return c.JSON(http.StatusUnprocessableEntity, errs.Err{Err: "invalid ticket", ErrDesc: err.Error()})
+		case errors.Is(err, errs.ErrTicketAlreadyBoarded),
+			errors.Is(err, errs.ErrTicketNotBoardable),
+			errors.Is(err, errs.ErrWrongRoute),
+			errors.Is(err, errs.ErrOutsideBoardingWindow):
+			return c.JSON(http.StatusConflict, errs.Err{Err: "boarding rejected", ErrDesc: err.Error()})
+		case err != nil:
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to validate ticket", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, ticket)
+	}
+}
+
+// GetManifestHandler exports the boarding manifest of a route.
+// @Summary      Boarding manifest
+// @Description  Lists the approved tickets of a route for boarding without connectivity: passenger, seat, order number
+// @Description  and the SHA-256 of the ticket QR token. The JSON manifest carries its signature in "signature",
+// @Description  with format=csv the signature of the CSV body is in the X-Manifest-Signature header.
+// @Description  Signatures are base64 Ed25519, checked with the key from /api/manifest-key. The JSON signature
+// @Description  covers the manifest encoded without the "signature" field.
+// @Tags         boarding
+// @Produce      json
+// @Produce      text/csv
+// @Security     BearerAuth
+// @Param        routeId  path      string  true   "Route ID"
+// @Param        format   query     string  false  "json or csv" default(json)
+// @Success      200      {object}  domain.Manifest
+// @Failure      404      {object}  errs.Err
+// @Failure      500      {object}  errs.Err
+// @Router       /api/routes/{routeId}/manifest [get]
+func GetManifestHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		routeID := c.Param("routeId")
+
+		if c.QueryParam("format") == "csv" {
+			data, signature, err := s.ManifestCSV(c.Request().Context(), routeID)
+			if errors.Is(err, rerrs.ErrRecordNotFound) {
+				return c.JSON(http.StatusNotFound, errs.Err{Err: "route not found", ErrDesc: err.Error()})
+			}
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to export manifest", ErrDesc: err.Error()})
+			}
+
+			c.Response().Header().Set(ManifestSignatureHeader, signature)
+			c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="manifest-%s.csv"`, routeID))
+			return c.Blob(http.StatusOK, "text/csv; charset=utf-8", data)
+		}
+
+		manifest, err := s.Manifest(c.Request().Context(), routeID)
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "route not found", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to export manifest", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, manifest)
+	}
+}
+
+// GetManifestKeyHandler returns the public key of manifest signatures.
+// @Summary      Manifest signing key
+// @Description  Returns the base64 Ed25519 public key drivers' devices verify manifests with. The key can only check
+// @Description  signatures, it cannot sign manifests or ticket codes.
+// @Tags         boarding
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  model.ManifestKeyResponse
+// @Router       /api/manifest-key [get]
+func GetManifestKeyHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, model.ManifestKeyResponse{Algorithm: "Ed25519", PublicKey: s.ManifestKey()})
+	}
+}
+
+// SyncManifestHandler uploads the boarding scans made offline.
+// @Summary      Sync offline boarding scans
+// @Description  Boards the passengers scanned while offline, oldest scan first. Every scan is reported as boarded,
+// @Description  duplicate (the ticket was already boarded), rejected (no longer valid or scanned outside the boarding
+// @Description  window) or unknown (not on this route).
+// @Tags         boarding
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        routeId      path      string             true  "Route ID"
+// @Param        requestBody  body      model.SyncRequest  true  "Scans"
+// @Success      200          {object}  domain.ManifestSync
+// @Failure      400          {object}  errs.Err
+// @Failure      404          {object}  errs.Err
+// @Failure      500          {object}  errs.Err
+// @Router       /api/routes/{routeId}/manifest/sync [post]
+func SyncManifestHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req model.SyncRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "Binding request body failed", ErrDesc: err.Error()})
+		}
+		if err := req.Validate(); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "Bad request", ErrDesc: err.Error()})
+		}
+
+		conductorID := fmt.Sprintf("%v", c.Get("user_id"))
+
+		sync, err := s.SyncScans(c.Request().Context(), conductorID, c.Param("routeId"), req)
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "route not found", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to sync scans", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, sync)
+	}
+}
+
+// GetBoardingStatsHandler returns the boarding statistics of a route.
+// @Summary      Boarding statistics
+// @Description  Counts boarded passengers, no-shows and tickets still to board, with the occupancy of the bus
+// @Description  and the share of due passengers that showed up. Tickets not scanned by the end of the grace period
+// @Description  after departure are counted as no-shows.
+// @Tags         boarding
+// @Produce      json
+// @Security     BearerAuth
+// @Param        routeId  path      string  true  "Route ID"
+// @Success      200      {object}  domain.BoardingStats
+// @Failure      404      {object}  errs.Err
+// @Failure      500      {object}  errs.Err
+// @Router       /api/routes/{routeId}/boarding-stats [get]
+func GetBoardingStatsHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		stats, err := s.RouteStats(c.Request().Context(), c.Param("routeId"))
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "route not found", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to get boarding stats", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, stats)
+	}
+}
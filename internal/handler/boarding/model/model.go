@@ -0,0 +1,49 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+type ValidateRequest struct {
+	Token   string `json:"token" example:"AW1.dGlja2V0fHJvdXRlfDFB.c2lnbmF0dXJl"`
+	RouteID string `json:"route_id"`
+}
+
+func (r ValidateRequest) Validate() error {
+	if r.Token == "" {
+		return errors.New("token is required")
+	}
+	return nil
+}
+
+type SyncRequest struct {
+	Scans []ScanRequest `json:"scans"`
+}
+
+// ScanRequest is a code scanned offline, sent as the scanned token or as the
+// token hash from the manifest.
+type ScanRequest struct {
+	Token     string    `json:"token"`
+	TokenHash string    `json:"token_hash"`
+	ScannedAt time.Time `json:"scanned_at" example:"2025-12-12T12:45:00+05:00"`
+}
+
+func (r SyncRequest) Validate() error {
+	if len(r.Scans) == 0 {
+		return errors.New("scans are required")
+	}
+	for _, scan := range r.Scans {
+		if scan.Token == "" && scan.TokenHash == "" {
+			return errors.New("every scan needs a token or a token_hash")
+		}
+	}
+	return nil
+}
+
+// ManifestKeyResponse is the key drivers' devices check manifest signatures
+// with.
+type ManifestKeyResponse struct {
+	Algorithm string `json:"algorithm" example:"Ed25519"`
+	PublicKey string `json:"public_key" example:"mC2m0rJ8qGZ0R3GkXWw5bQm8b1o9Y0n6lYc2a1Qd6hE="`
+}
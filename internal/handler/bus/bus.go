@@ -20,7 +20,8 @@ type Service interface {
 }
 
 // CreateBusHandler
-// @Description Create Bus
+// @Description Create Bus. The seat layout is taken from "seats", generated from "rows" x "columns",
+// @Description or generated from "total_seats" with four seats per row.
 // @Tags bus
 // @Accept json
 // @Produce json
@@ -2,16 +2,81 @@ package model
 
 import (
 	"errors"
+	"fmt"
 )
 
+var seatClasses = map[string]bool{
+	"standard": true,
+	"comfort":  true,
+	"vip":      true,
+}
+
 type CreateRequest struct {
-	Number     string `json:"number"`
-	TotalSeats int    `json:"total_seats"`
+	Number     string        `json:"number"`
+	TotalSeats int           `json:"total_seats"`
+	Rows       int           `json:"rows"`
+	Columns    int           `json:"columns"`
+	Seats      []SeatRequest `json:"seats"`
+}
+
+type SeatRequest struct {
+	Number string `json:"number" example:"1A"`
+	Row    int    `json:"row"`
+	Column int    `json:"column"`
+	Class  string `json:"class" example:"standard"`
 }
 
 func (createRequest CreateRequest) Validate() error {
-	if createRequest.Number == "" || createRequest.TotalSeats <= 0 {
+	if createRequest.Number == "" {
 		return errors.New("required fields are cannot be empty or is zero")
 	}
+
+	if len(createRequest.Seats) > 0 {
+		return createRequest.validateSeats()
+	}
+
+	if createRequest.Rows < 0 || createRequest.Columns < 0 {
+		return errors.New("rows and columns cannot be negative")
+	}
+
+	if createRequest.Rows > 0 && createRequest.Columns > 0 {
+		if createRequest.TotalSeats > createRequest.Rows*createRequest.Columns {
+			return errors.New("total seats do not fit into rows and columns")
+		}
+		return nil
+	}
+
+	if createRequest.TotalSeats <= 0 {
+		return errors.New("required fields are cannot be empty or is zero")
+	}
+	return nil
+}
+
+func (createRequest CreateRequest) validateSeats() error {
+	if createRequest.TotalSeats != 0 && createRequest.TotalSeats != len(createRequest.Seats) {
+		return errors.New("total seats does not match seat layout")
+	}
+
+	numbers := make(map[string]bool, len(createRequest.Seats))
+	positions := make(map[[2]int]bool, len(createRequest.Seats))
+
+	for _, seat := range createRequest.Seats {
+		if seat.Number == "" || seat.Row <= 0 || seat.Column <= 0 {
+			return errors.New("seat number, row and column are required")
+		}
+		if seat.Class != "" && !seatClasses[seat.Class] {
+			return fmt.Errorf("unknown seat class %q", seat.Class)
+		}
+		if numbers[seat.Number] {
+			return fmt.Errorf("duplicate seat number %q", seat.Number)
+		}
+		position := [2]int{seat.Row, seat.Column}
+		if positions[position] {
+			return fmt.Errorf("seat %q overlaps another seat", seat.Number)
+		}
+		numbers[seat.Number] = true
+		positions[position] = true
+	}
+
 	return nil
 }
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateRequestValidateSeats(t *testing.T) {
+	seat := func(number string, row, column int, class string) SeatRequest {
+		return SeatRequest{Number: number, Row: row, Column: column, Class: class}
+	}
+
+	tests := []struct {
+		name    string
+		req     CreateRequest
+		wantErr string
+	}{
+		{name: "layout", req: CreateRequest{Seats: []SeatRequest{seat("1A", 1, 1, "vip"), seat("1B", 1, 2, ""), seat("2A", 2, 1, "comfort")}}},
+		{name: "total matches", req: CreateRequest{TotalSeats: 2, Seats: []SeatRequest{seat("1A", 1, 1, ""), seat("1B", 1, 2, "")}}},
+		{name: "total does not match", req: CreateRequest{TotalSeats: 3, Seats: []SeatRequest{seat("1A", 1, 1, "")}}, wantErr: "total seats"},
+		{name: "no number", req: CreateRequest{Seats: []SeatRequest{seat("", 1, 1, "")}}, wantErr: "required"},
+		{name: "no row", req: CreateRequest{Seats: []SeatRequest{seat("1A", 0, 1, "")}}, wantErr: "required"},
+		{name: "negative column", req: CreateRequest{Seats: []SeatRequest{seat("1A", 1, -1, "")}}, wantErr: "required"},
+		{name: "unknown class", req: CreateRequest{Seats: []SeatRequest{seat("1A", 1, 1, "business")}}, wantErr: "unknown seat class"},
+		{name: "duplicate number", req: CreateRequest{Seats: []SeatRequest{seat("1A", 1, 1, ""), seat("1A", 1, 2, "")}}, wantErr: "duplicate seat number"},
+		{name: "same position", req: CreateRequest{Seats: []SeatRequest{seat("1A", 1, 1, ""), seat("1B", 1, 1, "")}}, wantErr: "overlaps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.validateSeats()
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCreateRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     CreateRequest
+		wantErr bool
+	}{
+		{name: "total only", req: CreateRequest{Number: "A 123 BC", TotalSeats: 40}},
+		{name: "rows and columns", req: CreateRequest{Number: "A 123 BC", Rows: 10, Columns: 4}},
+		{name: "total fits", req: CreateRequest{Number: "A 123 BC", TotalSeats: 38, Rows: 10, Columns: 4}},
+		{name: "total does not fit", req: CreateRequest{Number: "A 123 BC", TotalSeats: 41, Rows: 10, Columns: 4}, wantErr: true},
+		{name: "negative rows", req: CreateRequest{Number: "A 123 BC", TotalSeats: 4, Rows: -1}, wantErr: true},
+		{name: "no seats", req: CreateRequest{Number: "A 123 BC"}, wantErr: true},
+		{name: "no number", req: CreateRequest{TotalSeats: 40}, wantErr: true},
+		{name: "layout is checked", req: CreateRequest{Number: "A 123 BC", Seats: []SeatRequest{{Number: "1A"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package device
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/access"
+	"aulway/internal/handler/device/model"
+	rerrs "aulway/internal/repository/errs"
+	"aulway/internal/utils/errs"
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type Service interface {
+	RegisterDevice(ctx context.Context, userID string, req model.RegisterDeviceRequest) (*domain.Device, error)
+	ListDevices(ctx context.Context, userID string) ([]domain.Device, error)
+	UnregisterDevice(ctx context.Context, userID, token string) error
+}
+
+// RegisterDeviceHandler registers a device for push notifications
+// @Summary      Register device
+// @Description  Saves the FCM registration token of the app, purchases, cancellations, route changes and departure
+// @Description  reminders are then pushed to it as well as emailed. Registering a token again updates it.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        userId       path      string                       true  "User ID"
+// @Param        requestBody  body      model.RegisterDeviceRequest  true  "Device"
+// @Success      200          {object}  domain.Device
+// @Failure      400          {object}  errs.Err
+// @Failure      403          {object}  errs.Err  "Access denied"
+// @Failure      500          {object}  errs.Err
+// @Router       /api/users/{userId}/devices [post]
+func RegisterDeviceHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "register device failed", ErrDesc: "access denied"})
+		}
+
+		var req model.RegisterDeviceRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "Binding request body failed", ErrDesc: err.Error()})
+		}
+		if err := req.Validate(); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "Bad request", ErrDesc: err.Error()})
+		}
+
+		device, err := s.RegisterDevice(c.Request().Context(), c.Param("userId"), req)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "register device failed", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, device)
+	}
+}
+
+// ListDevicesHandler lists the devices of a user
+// @Summary      List devices
+// @Tags         users
+// @Produce      json
+// @Security     BearerAuth
+// @Param        userId  path      string  true  "User ID"
+// @Success      200     {array}   domain.Device
+// @Failure      403     {object}  errs.Err  "Access denied"
+// @Failure      500     {object}  errs.Err
+// @Router       /api/users/{userId}/devices [get]
+func ListDevicesHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "list devices failed", ErrDesc: "access denied"})
+		}
+
+		devices, err := s.ListDevices(c.Request().Context(), c.Param("userId"))
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "list devices failed", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, devices)
+	}
+}
+
+// UnregisterDeviceHandler stops pushes to a device
+// @Summary      Unregister device
+// @Description  Called by the app on sign out.
+// @Tags         users
+// @Security     BearerAuth
+// @Param        userId  path  string  true  "User ID"
+// @Param        token   path  string  true  "FCM registration token"
+// @Success      204
+// @Failure      403     {object}  errs.Err  "Access denied"
+// @Failure      404     {object}  errs.Err
+// @Failure      500     {object}  errs.Err
+// @Router       /api/users/{userId}/devices/{token} [delete]
+func UnregisterDeviceHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "unregister device failed", ErrDesc: "access denied"})
+		}
+
+		err := s.UnregisterDevice(c.Request().Context(), c.Param("userId"), c.Param("token"))
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "unregister device failed", ErrDesc: "device not found"})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "unregister device failed", ErrDesc: err.Error()})
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
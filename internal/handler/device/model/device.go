@@ -0,0 +1,30 @@
+package model
+
+import (
+	"aulway/internal/domain"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var platforms = []string{domain.PlatformAndroid, domain.PlatformIOS, domain.PlatformWeb}
+
+type RegisterDeviceRequest struct {
+	Token    string `json:"token" example:"fcm-registration-token"`
+	Platform string `json:"platform" example:"android"`
+}
+
+func (r RegisterDeviceRequest) Validate() error {
+	if r.Token == "" {
+		return errors.New("token is required")
+	}
+	if len(r.Token) > 4096 {
+		return errors.New("token is too long")
+	}
+	if !slices.Contains(platforms, r.Platform) {
+		return fmt.Errorf("platform must be one of %s", strings.Join(platforms, ", "))
+	}
+
+	return nil
+}
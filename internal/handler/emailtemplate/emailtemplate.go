@@ -0,0 +1,243 @@
+package emailtemplate
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/emailtemplate/model"
+	rerrs "aulway/internal/repository/errs"
+	"aulway/internal/templates"
+	"aulway/internal/utils/errs"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+)
+
+type Service interface {
+	Schema() map[string][]templates.Variable
+	ListTemplates(ctx context.Context) ([]domain.EmailTemplate, error)
+	GetTemplate(ctx context.Context, name, locale string) (*domain.EmailTemplate, error)
+	SaveTemplate(ctx context.Context, name, locale, adminID string, req model.EmailTemplateRequest) (*domain.EmailTemplate, error)
+	DeleteTemplate(ctx context.Context, name, locale string) error
+	ListVersions(ctx context.Context, name, locale string) ([]domain.EmailTemplateVersion, error)
+	RestoreVersion(ctx context.Context, name, locale string, version int, adminID string) (*domain.EmailTemplate, error)
+	Preview(ctx context.Context, name, locale string, req model.EmailTemplateRequest) (*domain.EmailPreview, error)
+}
+
+// GetSchemaHandler lists the variables of the editable emails
+// @Summary      Email template variables
+// @Description  The variables each editable email can use, by template name. Fields of list items, like .Tickets[].QR,
+// @Description  are used inside {{range .Tickets}} as {{.QR}}.
+// @Tags         email-templates
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string][]templates.Variable
+// @Router       /api/email-templates/schema [get]
+func GetSchemaHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, s.Schema())
+	}
+}
+
+// ListTemplatesHandler lists the email templates
+// @Summary      List email templates
+// @Description  The current template of every editable email in every locale, shipped ones have builtin set.
+// @Tags         email-templates
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   domain.EmailTemplate
+// @Failure      500  {object}  errs.Err
+// @Router       /api/email-templates [get]
+func ListTemplatesHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		list, err := s.ListTemplates(c.Request().Context())
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to list email templates", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, list)
+	}
+}
+
+// GetTemplateHandler returns an email template
+// @Summary      Get email template
+// @Tags         email-templates
+// @Produce      json
+// @Security     BearerAuth
+// @Param        name    path      string  true  "tickets, cancellation, reminder or route_change"
+// @Param        locale  path      string  true  "en, ru or kk"
+// @Success      200     {object}  domain.EmailTemplate
+// @Failure      404     {object}  errs.Err
+// @Failure      500     {object}  errs.Err
+// @Router       /api/email-templates/{name}/{locale} [get]
+func GetTemplateHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		template, err := s.GetTemplate(c.Request().Context(), c.Param("name"), c.Param("locale"))
+		if err != nil {
+			return templateError(c, "failed to get email template", err)
+		}
+
+		return c.JSON(http.StatusOK, template)
+	}
+}
+
+// SaveTemplateHandler saves a new version of an email template
+// @Summary      Save email template
+// @Description  The subject is plain text, the body is the HTML inside the email layout. Both are Go templates that can
+// @Description  only use the variables of the schema; the template is rendered against sample data before it is saved.
+// @Tags         email-templates
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        name         path      string                      true  "tickets, cancellation, reminder or route_change"
+// @Param        locale       path      string                      true  "en, ru or kk"
+// @Param        requestBody  body      model.EmailTemplateRequest  true  "Template sources"
+// @Success      200          {object}  domain.EmailTemplate
+// @Failure      400          {object}  errs.Err
+// @Failure      404          {object}  errs.Err
+// @Failure      500          {object}  errs.Err
+// @Router       /api/email-templates/{name}/{locale} [put]
+func SaveTemplateHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req model.EmailTemplateRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "Binding request body failed", ErrDesc: err.Error()})
+		}
+		if err := req.Validate(); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "Bad request", ErrDesc: err.Error()})
+		}
+
+		adminID := fmt.Sprintf("%v", c.Get("user_id"))
+		template, err := s.SaveTemplate(c.Request().Context(), c.Param("name"), c.Param("locale"), adminID, req)
+		if err != nil {
+			return templateError(c, "failed to save email template", err)
+		}
+
+		return c.JSON(http.StatusOK, template)
+	}
+}
+
+// DeleteTemplateHandler reverts an email to its shipped template
+// @Summary      Revert email template
+// @Description  Emails use the shipped template again. The saved versions are kept and can be restored.
+// @Tags         email-templates
+// @Security     BearerAuth
+// @Param        name    path  string  true  "tickets, cancellation, reminder or route_change"
+// @Param        locale  path  string  true  "en, ru or kk"
+// @Success      204
+// @Failure      404     {object}  errs.Err  "Template was not edited"
+// @Failure      500     {object}  errs.Err
+// @Router       /api/email-templates/{name}/{locale} [delete]
+func DeleteTemplateHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if err := s.DeleteTemplate(c.Request().Context(), c.Param("name"), c.Param("locale")); err != nil {
+			return templateError(c, "failed to revert email template", err)
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// ListVersionsHandler lists the saved versions of an email template
+// @Summary      Email template history
+// @Tags         email-templates
+// @Produce      json
+// @Security     BearerAuth
+// @Param        name    path      string  true  "tickets, cancellation, reminder or route_change"
+// @Param        locale  path      string  true  "en, ru or kk"
+// @Success      200     {array}   domain.EmailTemplateVersion
+// @Failure      404     {object}  errs.Err
+// @Failure      500     {object}  errs.Err
+// @Router       /api/email-templates/{name}/{locale}/versions [get]
+func ListVersionsHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		versions, err := s.ListVersions(c.Request().Context(), c.Param("name"), c.Param("locale"))
+		if err != nil {
+			return templateError(c, "failed to list email template versions", err)
+		}
+
+		return c.JSON(http.StatusOK, versions)
+	}
+}
+
+// RestoreVersionHandler makes an earlier version current again
+// @Summary      Restore email template version
+// @Description  Saves the version again as the newest one.
+// @Tags         email-templates
+// @Produce      json
+// @Security     BearerAuth
+// @Param        name     path      string  true  "tickets, cancellation, reminder or route_change"
+// @Param        locale   path      string  true  "en, ru or kk"
+// @Param        version  path      int     true  "Version to restore"
+// @Success      200      {object}  domain.EmailTemplate
+// @Failure      400      {object}  errs.Err
+// @Failure      404      {object}  errs.Err
+// @Failure      500      {object}  errs.Err
+// @Router       /api/email-templates/{name}/{locale}/versions/{version}/restore [post]
+func RestoreVersionHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		version, err := strconv.Atoi(c.Param("version"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "Bad request", ErrDesc: "version must be a number"})
+		}
+
+		adminID := fmt.Sprintf("%v", c.Get("user_id"))
+		template, err := s.RestoreVersion(c.Request().Context(), c.Param("name"), c.Param("locale"), version, adminID)
+		if err != nil {
+			return templateError(c, "failed to restore email template", err)
+		}
+
+		return c.JSON(http.StatusOK, template)
+	}
+}
+
+// PreviewHandler renders an email template against sample tickets
+// @Summary      Preview email template
+// @Description  Renders the sources in the body, or the current template when the body is empty, against a sample
+// @Description  order. With format=html the rendered email is returned as a page.
+// @Tags         email-templates
+// @Accept       json
+// @Produce      json,html
+// @Security     BearerAuth
+// @Param        name         path      string                      true   "tickets, cancellation, reminder or route_change"
+// @Param        locale       path      string                      true   "en, ru or kk"
+// @Param        format       query     string                      false  "json (default) or html"
+// @Param        requestBody  body      model.EmailTemplateRequest  false  "Template sources"
+// @Success      200          {object}  domain.EmailPreview
+// @Failure      400          {object}  errs.Err
+// @Failure      404          {object}  errs.Err
+// @Failure      500          {object}  errs.Err
+// @Router       /api/email-templates/{name}/{locale}/preview [post]
+func PreviewHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req model.EmailTemplateRequest
+		if c.Request().ContentLength != 0 {
+			if err := c.Bind(&req); err != nil {
+				return c.JSON(http.StatusBadRequest, errs.Err{Err: "Binding request body failed", ErrDesc: err.Error()})
+			}
+		}
+
+		preview, err := s.Preview(c.Request().Context(), c.Param("name"), c.Param("locale"), req)
+		if err != nil {
+			return templateError(c, "failed to preview email template", err)
+		}
+
+		if c.QueryParam("format") == "html" {
+			return c.HTML(http.StatusOK, preview.Body)
+		}
+
+		return c.JSON(http.StatusOK, preview)
+	}
+}
+
+func templateError(c echo.Context, message string, err error) error {
+	switch {
+	case errors.Is(err, errs.ErrInvalidEmailTemplate):
+		return c.JSON(http.StatusBadRequest, errs.Err{Err: message, ErrDesc: err.Error()})
+	case errors.Is(err, errs.ErrUnknownEmailTemplate), errors.Is(err, rerrs.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, errs.Err{Err: message, ErrDesc: err.Error()})
+	default:
+		return c.JSON(http.StatusInternalServerError, errs.Err{Err: message, ErrDesc: err.Error()})
+	}
+}
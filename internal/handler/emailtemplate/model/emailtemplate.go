@@ -0,0 +1,19 @@
+package model
+
+import "errors"
+
+// EmailTemplateRequest holds the sources of an email: the subject is plain
+// text, the body is the HTML put inside the email layout. Both use Go
+// template syntax with the variables of the template's schema.
+type EmailTemplateRequest struct {
+	Subject string `json:"subject" example:"Your AulWay tickets – order {{.OrderNumber}}"`
+	Body    string `json:"body" example:"<p>Order {{.OrderNumber}}</p>{{range .Tickets}}<img src=\"cid:{{.QR}}\">{{end}}"`
+}
+
+func (r EmailTemplateRequest) Validate() error {
+	if r.Subject == "" || r.Body == "" {
+		return errors.New("subject and body are required")
+	}
+
+	return nil
+}
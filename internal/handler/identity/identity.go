@@ -0,0 +1,78 @@
+package identity
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/access"
+	rerrs "aulway/internal/repository/errs"
+	"aulway/internal/utils/errs"
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type Service interface {
+	ListIdentities(ctx context.Context, userID string) ([]domain.UserIdentity, error)
+	Unlink(ctx context.Context, userID, provider string) error
+}
+
+// ListIdentitiesHandler lists the provider accounts the user signs in with
+// @Summary      List linked accounts
+// @Description  The Google and Apple accounts linked to the user, the first linked first.
+// @Tags         users
+// @Produce      json
+// @Security     BearerAuth
+// @Param        userId  path      string  true  "User ID"
+// @Success      200     {array}   domain.UserIdentity
+// @Failure      403     {object}  errs.Err  "Access denied"
+// @Failure      500     {object}  errs.Err
+// @Router       /api/users/{userId}/identities [get]
+func ListIdentitiesHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "list identities failed", ErrDesc: "access denied"})
+		}
+
+		identities, err := s.ListIdentities(c.Request().Context(), c.Param("userId"))
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "list identities failed", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, identities)
+	}
+}
+
+// UnlinkIdentityHandler stops a provider account from signing the user in
+// @Summary      Unlink account
+// @Description  Unlinks the account of the provider from the user. The only way left to sign in cannot be unlinked,
+// @Description  set a password or verify a phone first.
+// @Tags         users
+// @Security     BearerAuth
+// @Param        userId    path  string  true  "User ID"
+// @Param        provider  path  string  true  "Provider"  Enums(google, apple)
+// @Success      204
+// @Failure      403       {object}  errs.Err  "Access denied"
+// @Failure      404       {object}  errs.Err
+// @Failure      409       {object}  errs.Err  "The only way left to sign in"
+// @Failure      500       {object}  errs.Err
+// @Router       /api/users/{userId}/identities/{provider} [delete]
+func UnlinkIdentityHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "unlink identity failed", ErrDesc: "access denied"})
+		}
+
+		err := s.Unlink(c.Request().Context(), c.Param("userId"), c.Param("provider"))
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "unlink identity failed", ErrDesc: "no account of the provider is linked"})
+		}
+		if errors.Is(err, errs.ErrLastSigninMethod) {
+			return c.JSON(http.StatusConflict, errs.Err{Err: "unlink identity failed", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "unlink identity failed", ErrDesc: err.Error()})
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,92 @@
+package ledger
+
+import (
+	"aulway/internal/domain"
+	rerrs "aulway/internal/repository/errs"
+	"aulway/internal/utils/errs"
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type Service interface {
+	PaymentLedger(ctx context.Context, paymentID string) (*domain.LedgerBalance, error)
+	OrderLedger(ctx context.Context, orderNumber string) (*domain.LedgerBalance, error)
+	CheckPayment(ctx context.Context, paymentID string) (*domain.LedgerCheck, error)
+}
+
+// GetPaymentLedgerHandler returns the ledger of a payment.
+// @Summary      Payment ledger
+// @Description  Lists the charges, refunds, chargebacks and fees of a payment with the running balance after each entry.
+// @Tags         ledger
+// @Produce      json
+// @Security     BearerAuth
+// @Param        paymentId  path      string  true  "Payment ID"
+// @Success      200        {object}  domain.LedgerBalance
+// @Failure      404        {object}  errs.Err
+// @Failure      500        {object}  errs.Err
+// @Router       /api/payments/{paymentId}/ledger [get]
+func GetPaymentLedgerHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ledger, err := s.PaymentLedger(c.Request().Context(), c.Param("paymentId"))
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "payment not found", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to get ledger", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, ledger)
+	}
+}
+
+// GetOrderLedgerHandler returns the ledger of the payment of an order.
+// @Summary      Order ledger
+// @Description  Lists the ledger entries of the payment of an order with the running balance.
+// @Tags         ledger
+// @Produce      json
+// @Security     BearerAuth
+// @Param        orderNumber  path      string  true  "Order number"
+// @Success      200          {object}  domain.LedgerBalance
+// @Failure      404          {object}  errs.Err
+// @Failure      500          {object}  errs.Err
+// @Router       /api/orders/{orderNumber}/ledger [get]
+func GetOrderLedgerHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ledger, err := s.OrderLedger(c.Request().Context(), c.Param("orderNumber"))
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "order not found", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to get ledger", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, ledger)
+	}
+}
+
+// CheckPaymentLedgerHandler compares the ledger of a payment with the payment provider.
+// @Summary      Check payment ledger
+// @Description  Compares the charged and refunded totals of the ledger with the amounts the payment provider reports.
+// @Tags         ledger
+// @Produce      json
+// @Security     BearerAuth
+// @Param        paymentId  path      string  true  "Payment ID"
+// @Success      200        {object}  domain.LedgerCheck
+// @Failure      404        {object}  errs.Err
+// @Failure      500        {object}  errs.Err
+// @Router       /api/payments/{paymentId}/ledger/check [get]
+func CheckPaymentLedgerHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		check, err := s.CheckPayment(c.Request().Context(), c.Param("paymentId"))
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "payment not found", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to check ledger", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, check)
+	}
+}
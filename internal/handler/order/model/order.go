@@ -0,0 +1,5 @@
+package model
+
+type CancelOrderRequest struct {
+	TicketIDs []string `json:"ticket_ids"`
+}
@@ -0,0 +1,180 @@
+package order
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/access"
+	"aulway/internal/handler/order/model"
+	rerrs "aulway/internal/repository/errs"
+	"aulway/internal/utils/config"
+	"aulway/internal/utils/errs"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+)
+
+type Service interface {
+	GetOrder(ctx context.Context, userID, orderID string) (*domain.Order, error)
+	CancelOrder(ctx context.Context, userID, orderID string, ticketIDs []string, email string) (*domain.Order, error)
+	OrderReceipt(ctx context.Context, userID, orderID string) (*domain.Order, string, error)
+	QuoteCancellation(ctx context.Context, userID, orderID string, ticketIDs []string) (*domain.RefundQuote, error)
+}
+
+// GetOrderHandler returns an order of the user with its tickets.
+// @Summary      Get order
+// @Description  Returns the order with all tickets bought together in it.
+// @Tags         orders
+// @Produce      json
+// @Security     BearerAuth
+// @Param        userId   path      string  true  "User ID"
+// @Param        orderId  path      string  true  "Order ID"
+// @Success      200      {object}  domain.Order
+// @Failure      403      {object}  errs.Err  "Access denied"
+// @Failure      404      {object}  errs.Err
+// @Failure      500      {object}  errs.Err
+// @Router       /api/users/{userId}/orders/{orderId} [get]
+func GetOrderHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "access denied", ErrDesc: "You are not allowed to view this order"})
+		}
+
+		order, err := s.GetOrder(c.Request().Context(), c.Param("userId"), c.Param("orderId"))
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "order not found", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to get order", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, order)
+	}
+}
+
+// CancelOrderHandler cancels a whole order or some of its tickets.
+// @Summary      Cancel order
+// @Description  Cancels the tickets listed in "ticket_ids", or the whole order when the list is empty.
+// @Description  The paid tickets are refunded together in one refund, made once the cancellation is saved and retried until the payment provider makes it.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        userId           path    string                    true   "User ID"
+// @Param        orderId          path    string                    true   "Order ID"
+// @Param        email            query   string                    true   "email for sending mail"
+// @Param        requestBody      body    model.CancelOrderRequest  false  "Tickets to cancel"
+// @Param        Idempotency-Key  header  string                    false  "Retries with the same key replay the first response"
+// @Success      200  {object}  domain.Order
+// @Failure      400  {object}  errs.Err
+// @Failure      403  {object}  errs.Err  "Access denied"
+// @Failure      404  {object}  errs.Err
+// @Failure      409  {object}  errs.Err  "The refund policy does not allow cancelling now"
+// @Failure      500  {object}  errs.Err
+// @Router       /api/users/{userId}/orders/{orderId}/cancel [post]
+func CancelOrderHandler(_ config.Config, s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "cancel failed", ErrDesc: "access denied"})
+		}
+
+		var req model.CancelOrderRequest
+		if c.Request().ContentLength > 0 {
+			if err := c.Bind(&req); err != nil {
+				return c.JSON(http.StatusBadRequest, errs.Err{Err: "invalid request", ErrDesc: err.Error()})
+			}
+		}
+
+		userID := c.Param("userId")
+		email := c.QueryParam("email")
+
+		order, err := s.CancelOrder(c.Request().Context(), userID, c.Param("orderId"), req.TicketIDs, email)
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "order not found", ErrDesc: err.Error()})
+		}
+		if errors.Is(err, errs.ErrCancellationNotAllowed) {
+			return c.JSON(http.StatusConflict, errs.Err{Err: "cancel error", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "cancel error", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, order)
+	}
+}
+
+// QuoteCancelOrderHandler previews the refund of cancelling an order.
+// @Summary      Cancellation quote
+// @Description  Shows what cancelling the tickets would refund under the refund policy of the route, nothing is cancelled.
+// @Description  Leave "ticket_ids" out to quote the whole order.
+// @Tags         orders
+// @Produce      json
+// @Security     BearerAuth
+// @Param        userId      path      string  true   "User ID"
+// @Param        orderId     path      string  true   "Order ID"
+// @Param        ticket_ids  query     string  false  "Comma separated ticket IDs"
+// @Success      200         {object}  domain.RefundQuote
+// @Failure      403         {object}  errs.Err  "Access denied"
+// @Failure      404         {object}  errs.Err
+// @Failure      409         {object}  errs.Err  "A ticket was already used or missed"
+// @Failure      500         {object}  errs.Err
+// @Router       /api/users/{userId}/orders/{orderId}/cancel/quote [get]
+func QuoteCancelOrderHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "access denied", ErrDesc: "You are not allowed to view this order"})
+		}
+
+		var ticketIDs []string
+		if param := c.QueryParam("ticket_ids"); param != "" {
+			ticketIDs = strings.Split(param, ",")
+		}
+
+		quote, err := s.QuoteCancellation(c.Request().Context(), c.Param("userId"), c.Param("orderId"), ticketIDs)
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "order not found", ErrDesc: err.Error()})
+		}
+		if errors.Is(err, errs.ErrCancellationNotAllowed) {
+			return c.JSON(http.StatusConflict, errs.Err{Err: "failed to quote cancellation", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to quote cancellation", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, quote)
+	}
+}
+
+// GetOrderReceiptHandler downloads the receipt of an order.
+// @Summary      Order receipt
+// @Description  Returns the receipt of an order as an HTML document with the paid and refunded amounts,
+// @Description  in the language picked in the user's settings.
+// @Tags         orders
+// @Produce      html
+// @Security     BearerAuth
+// @Param        userId   path      string  true  "User ID"
+// @Param        orderId  path      string  true  "Order ID"
+// @Success      200      {string}  string  "Receipt"
+// @Failure      403      {object}  errs.Err  "Access denied"
+// @Failure      404      {object}  errs.Err
+// @Failure      500      {object}  errs.Err
+// @Router       /api/users/{userId}/orders/{orderId}/receipt [get]
+func GetOrderReceiptHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "access denied", ErrDesc: "You are not allowed to view this order"})
+		}
+
+		order, receipt, err := s.OrderReceipt(c.Request().Context(), c.Param("userId"), c.Param("orderId"))
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "order not found", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to build receipt", ErrDesc: err.Error()})
+		}
+
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="receipt-%s.html"`, order.Number))
+		return c.HTML(http.StatusOK, receipt)
+	}
+}
@@ -0,0 +1,75 @@
+package outbox
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/pagination"
+	rerrs "aulway/internal/repository/errs"
+	"aulway/internal/utils/errs"
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type Service interface {
+	List(ctx context.Context, status string, page, pageSize int) ([]domain.OutboxMessage, error)
+	Resend(ctx context.Context, id string) (*domain.OutboxMessage, error)
+}
+
+// ListMessagesHandler lists the emails of the outbox
+// @Summary      List outbox emails
+// @Description  Lists the transactional emails, newest first. Use status=dead for the ones that ran out of retries.
+// @Tags         outbox
+// @Produce      json
+// @Security     BearerAuth
+// @Param        status    query     string  false  "pending, sent or dead"
+// @Param        page      query     int     false  "Page number (default: 1)"
+// @Param        pageSize  query     int     false  "Page size (default: 30)"
+// @Success      200       {array}   domain.OutboxMessage
+// @Failure      400       {object}  errs.Err
+// @Failure      500       {object}  errs.Err
+// @Router       /api/outbox [get]
+func ListMessagesHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		status := c.QueryParam("status")
+		switch status {
+		case "", domain.OutboxPending, domain.OutboxSent, domain.OutboxDead:
+		default:
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "invalid status", ErrDesc: "status must be pending, sent or dead"})
+		}
+
+		page, pageSize := pagination.GetPageInfo(c)
+
+		messages, err := s.List(c.Request().Context(), status, page, pageSize)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to list emails", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, messages)
+	}
+}
+
+// ResendMessageHandler queues an email again
+// @Summary      Resend outbox email
+// @Description  Puts the email back in the queue with a fresh set of retries, the worker sends it on its next run.
+// @Tags         outbox
+// @Produce      json
+// @Security     BearerAuth
+// @Param        messageId  path      string  true  "Message ID"
+// @Success      200        {object}  domain.OutboxMessage
+// @Failure      404        {object}  errs.Err
+// @Failure      500        {object}  errs.Err
+// @Router       /api/outbox/{messageId}/resend [post]
+func ResendMessageHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		message, err := s.Resend(c.Request().Context(), c.Param("messageId"))
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "email not found", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to resend email", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, message)
+	}
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"aulway/internal/domain"
+	"errors"
+	"fmt"
+)
+
+type RefundPolicyRequest struct {
+	Name          string              `json:"name" example:"Standard"`
+	RouteID       string              `json:"route_id"`
+	NonRefundable bool                `json:"non_refundable"`
+	Tiers         []domain.RefundTier `json:"tiers"`
+}
+
+func (r RefundPolicyRequest) Validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if len(r.Tiers) == 0 && !r.NonRefundable {
+		return errors.New("a refundable policy needs at least one tier")
+	}
+
+	hours := make(map[int]bool, len(r.Tiers))
+	for _, tier := range r.Tiers {
+		if tier.MinHoursBefore < 0 {
+			return errors.New("min_hours_before cannot be negative")
+		}
+		if tier.RefundPercent < 0 || tier.RefundPercent > 100 {
+			return fmt.Errorf("refund_percent %d is not between 0 and 100", tier.RefundPercent)
+		}
+		if hours[tier.MinHoursBefore] {
+			return fmt.Errorf("duplicate tier for %d hours", tier.MinHoursBefore)
+		}
+		hours[tier.MinHoursBefore] = true
+	}
+
+	return nil
+}
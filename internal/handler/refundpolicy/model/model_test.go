@@ -0,0 +1,40 @@
+package model
+
+import (
+	"aulway/internal/domain"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefundPolicyRequestValidate(t *testing.T) {
+	tiers := []domain.RefundTier{{MinHoursBefore: 72, RefundPercent: 100}, {MinHoursBefore: 24, RefundPercent: 50}}
+
+	tests := []struct {
+		name    string
+		req     RefundPolicyRequest
+		wantErr string
+	}{
+		{name: "tiered", req: RefundPolicyRequest{Name: "Standard", Tiers: tiers}},
+		{name: "route override", req: RefundPolicyRequest{Name: "Holiday", RouteID: "route-1", Tiers: tiers[:1]}},
+		{name: "non-refundable without tiers", req: RefundPolicyRequest{Name: "Saver", NonRefundable: true}},
+		{name: "bounds", req: RefundPolicyRequest{Name: "Edge", Tiers: []domain.RefundTier{{MinHoursBefore: 0, RefundPercent: 0}, {MinHoursBefore: 1, RefundPercent: 100}}}},
+		{name: "no name", req: RefundPolicyRequest{Tiers: tiers}, wantErr: "name is required"},
+		{name: "refundable without tiers", req: RefundPolicyRequest{Name: "Standard"}, wantErr: "at least one tier"},
+		{name: "negative hours", req: RefundPolicyRequest{Name: "Standard", Tiers: []domain.RefundTier{{MinHoursBefore: -1, RefundPercent: 100}}}, wantErr: "cannot be negative"},
+		{name: "negative percent", req: RefundPolicyRequest{Name: "Standard", Tiers: []domain.RefundTier{{MinHoursBefore: 24, RefundPercent: -5}}}, wantErr: "between 0 and 100"},
+		{name: "over 100 percent", req: RefundPolicyRequest{Name: "Standard", Tiers: []domain.RefundTier{{MinHoursBefore: 24, RefundPercent: 101}}}, wantErr: "between 0 and 100"},
+		{name: "same hours twice", req: RefundPolicyRequest{Name: "Standard", Tiers: []domain.RefundTier{{MinHoursBefore: 24, RefundPercent: 50}, {MinHoursBefore: 24, RefundPercent: 80}}}, wantErr: "duplicate tier"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
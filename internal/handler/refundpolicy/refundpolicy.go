@@ -0,0 +1,161 @@
+package refundpolicy
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/refundpolicy/model"
+	rerrs "aulway/internal/repository/errs"
+	"aulway/internal/utils/errs"
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type Service interface {
+	CreatePolicy(ctx context.Context, req model.RefundPolicyRequest) (*domain.RefundPolicy, error)
+	GetPolicy(ctx context.Context, id string) (*domain.RefundPolicy, error)
+	ListPolicies(ctx context.Context) ([]domain.RefundPolicy, error)
+	UpdatePolicy(ctx context.Context, id string, req model.RefundPolicyRequest) (*domain.RefundPolicy, error)
+	DeletePolicy(ctx context.Context, id string) error
+}
+
+// ListPoliciesHandler lists the refund policies, the default one first.
+// @Summary      List refund policies
+// @Tags         refund-policies
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   domain.RefundPolicy
+// @Failure      500  {object}  errs.Err
+// @Router       /api/refund-policies [get]
+func ListPoliciesHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		policies, err := s.ListPolicies(c.Request().Context())
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to get refund policies", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, policies)
+	}
+}
+
+// GetPolicyHandler returns a refund policy.
+// @Summary      Get refund policy
+// @Tags         refund-policies
+// @Produce      json
+// @Security     BearerAuth
+// @Param        policyId  path      string  true  "Refund policy ID"
+// @Success      200       {object}  domain.RefundPolicy
+// @Failure      404       {object}  errs.Err
+// @Failure      500       {object}  errs.Err
+// @Router       /api/refund-policies/{policyId} [get]
+func GetPolicyHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		policy, err := s.GetPolicy(c.Request().Context(), c.Param("policyId"))
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "refund policy not found", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to get refund policy", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, policy)
+	}
+}
+
+// CreatePolicyHandler adds a refund policy that overrides the default on a route.
+// @Summary      Create refund policy
+// @Description  Tiers give the refund percent from a number of hours before departure, e.g. 100% from 72 hours,
+// @Description  50% from 24 hours. Cancelling later than the smallest tier is not allowed.
+// @Description  A non-refundable policy allows cancelling until departure without a refund.
+// @Tags         refund-policies
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        requestBody  body      model.RefundPolicyRequest  true  "Refund policy"
+// @Success      200          {object}  domain.RefundPolicy
+// @Failure      400          {object}  errs.Err
+// @Failure      409          {object}  errs.Err  "Route already has a policy"
+// @Failure      500          {object}  errs.Err
+// @Router       /api/refund-policies [post]
+func CreatePolicyHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req model.RefundPolicyRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "Binding request body failed", ErrDesc: err.Error()})
+		}
+		if err := req.Validate(); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "Bad request", ErrDesc: err.Error()})
+		}
+
+		policy, err := s.CreatePolicy(c.Request().Context(), req)
+		if errors.Is(err, errs.ErrRouteHasRefundPolicy) {
+			return c.JSON(http.StatusConflict, errs.Err{Err: "failed to create refund policy", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "failed to create refund policy", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, policy)
+	}
+}
+
+// UpdatePolicyHandler replaces a refund policy.
+// @Summary      Update refund policy
+// @Tags         refund-policies
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        policyId     path      string                     true  "Refund policy ID"
+// @Param        requestBody  body      model.RefundPolicyRequest  true  "Refund policy"
+// @Success      200          {object}  domain.RefundPolicy
+// @Failure      400          {object}  errs.Err
+// @Failure      404          {object}  errs.Err
+// @Failure      409          {object}  errs.Err  "Route already has a policy"
+// @Router       /api/refund-policies/{policyId} [put]
+func UpdatePolicyHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req model.RefundPolicyRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "Binding request body failed", ErrDesc: err.Error()})
+		}
+		if err := req.Validate(); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "Bad request", ErrDesc: err.Error()})
+		}
+
+		policy, err := s.UpdatePolicy(c.Request().Context(), c.Param("policyId"), req)
+		switch {
+		case errors.Is(err, rerrs.ErrRecordNotFound):
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "refund policy not found", ErrDesc: err.Error()})
+		case errors.Is(err, errs.ErrRouteHasRefundPolicy):
+			return c.JSON(http.StatusConflict, errs.Err{Err: "failed to update refund policy", ErrDesc: err.Error()})
+		case err != nil:
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "failed to update refund policy", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, policy)
+	}
+}
+
+// DeletePolicyHandler removes a route override, the route falls back to the default policy.
+// @Summary      Delete refund policy
+// @Tags         refund-policies
+// @Produce      json
+// @Security     BearerAuth
+// @Param        policyId  path      string  true  "Refund policy ID"
+// @Success      200       {string}  string  "refund policy deleted"
+// @Failure      400       {object}  errs.Err
+// @Failure      404       {object}  errs.Err
+// @Router       /api/refund-policies/{policyId} [delete]
+func DeletePolicyHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		err := s.DeletePolicy(c.Request().Context(), c.Param("policyId"))
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "refund policy not found", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "failed to delete refund policy", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, "refund policy deleted")
+	}
+}
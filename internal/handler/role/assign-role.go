@@ -3,7 +3,7 @@ package role
 //import (
 //	"aulway/internal/handler/auth"
 //	"aulway/internal/handler/user"
-//	fbAuth "firebase.google.com/go/auth"
+//	fbAuth "firebase.google.com/go/v4/auth"
 //	"github.com/labstack/echo/v4"
 //	"net/http"
 //)
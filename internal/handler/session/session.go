@@ -0,0 +1,75 @@
+package session
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/access"
+	rerrs "aulway/internal/repository/errs"
+	"aulway/internal/utils/errs"
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type Service interface {
+	ListSessions(ctx context.Context, userID, current string) ([]domain.Session, error)
+	Revoke(ctx context.Context, userID, sessionID string) error
+}
+
+// ListSessionsHandler lists where the user is signed in
+// @Summary      List sessions
+// @Description  The devices the user is signed in on, the most recently used first. Current marks the session of
+// @Description  the token asking.
+// @Tags         users
+// @Produce      json
+// @Security     BearerAuth
+// @Param        userId  path      string  true  "User ID"
+// @Success      200     {array}   domain.Session
+// @Failure      403     {object}  errs.Err  "Access denied"
+// @Failure      500     {object}  errs.Err
+// @Router       /api/users/{userId}/sessions [get]
+func ListSessionsHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "list sessions failed", ErrDesc: "access denied"})
+		}
+
+		current, _ := c.Get("session_id").(string)
+		sessions, err := s.ListSessions(c.Request().Context(), c.Param("userId"), current)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "list sessions failed", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, sessions)
+	}
+}
+
+// RevokeSessionHandler signs a device out
+// @Summary      Revoke session
+// @Description  Ends a session of the user, the device it belongs to is signed out at once.
+// @Tags         users
+// @Security     BearerAuth
+// @Param        userId     path  string  true  "User ID"
+// @Param        sessionId  path  string  true  "Session ID"
+// @Success      204
+// @Failure      403        {object}  errs.Err  "Access denied"
+// @Failure      404        {object}  errs.Err
+// @Failure      500        {object}  errs.Err
+// @Router       /api/users/{userId}/sessions/{sessionId} [delete]
+func RevokeSessionHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "revoke session failed", ErrDesc: "access denied"})
+		}
+
+		err := s.Revoke(c.Request().Context(), c.Param("userId"), c.Param("sessionId"))
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "revoke session failed", ErrDesc: "session not found"})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "revoke session failed", ErrDesc: err.Error()})
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"aulway/internal/templates"
+	"fmt"
+	"strings"
+)
+
+type UpdateSettingsRequest struct {
+	Language string `json:"language" example:"kk"`
+}
+
+func (r UpdateSettingsRequest) Validate() error {
+	if !templates.Supported(r.Language) {
+		return fmt.Errorf("language must be one of %s", strings.Join(templates.Locales, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package settings
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/access"
+	"aulway/internal/handler/settings/model"
+	"aulway/internal/utils/errs"
+	"context"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type Service interface {
+	GetSettings(ctx context.Context, userID string) (*domain.Settings, error)
+	UpdateSettings(ctx context.Context, userID string, req model.UpdateSettingsRequest) (*domain.Settings, error)
+}
+
+// GetSettingsHandler returns the settings of a user
+// @Summary      Get user settings
+// @Tags         users
+// @Produce      json
+// @Security     BearerAuth
+// @Param        userId  path      string  true  "User ID"
+// @Success      200     {object}  domain.Settings
+// @Failure      403     {object}  errs.Err  "Access denied"
+// @Failure      500     {object}  errs.Err
+// @Router       /api/users/{userId}/settings [get]
+func GetSettingsHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "get settings failed", ErrDesc: "access denied"})
+		}
+
+		settings, err := s.GetSettings(c.Request().Context(), c.Param("userId"))
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "get settings failed", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, settings)
+	}
+}
+
+// UpdateSettingsHandler changes the settings of a user
+// @Summary      Update user settings
+// @Description  Language is the one emails and notifications are sent in: en, ru or kk.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        userId       path      string                       true  "User ID"
+// @Param        requestBody  body      model.UpdateSettingsRequest  true  "Settings"
+// @Success      200          {object}  domain.Settings
+// @Failure      400          {object}  errs.Err
+// @Failure      403          {object}  errs.Err  "Access denied"
+// @Failure      500          {object}  errs.Err
+// @Router       /api/users/{userId}/settings [put]
+func UpdateSettingsHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "update settings failed", ErrDesc: "access denied"})
+		}
+
+		var req model.UpdateSettingsRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "Binding request body failed", ErrDesc: err.Error()})
+		}
+		if err := req.Validate(); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "Bad request", ErrDesc: err.Error()})
+		}
+
+		settings, err := s.UpdateSettings(c.Request().Context(), c.Param("userId"), req)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "update settings failed", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, settings)
+	}
+}
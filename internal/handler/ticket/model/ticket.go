@@ -1,6 +1,17 @@
 package model
 
 type BuyTicketRequest struct {
-	Quantity  int    `json:"quantity"`
+	Quantity  int      `json:"quantity"`
+	Seats     []string `json:"seats" example:"1A,1B"`
+	HoldID    string   `json:"hold_id"`
+	UserEmail string   `json:"user_email"`
+}
+
+type HoldSeatsRequest struct {
+	Quantity int      `json:"quantity"`
+	Seats    []string `json:"seats" example:"1A,1B"`
+}
+
+type ConfirmPaymentRequest struct {
 	UserEmail string `json:"user_email"`
 }
@@ -5,13 +5,14 @@ import (
 	"aulway/internal/handler/access"
 	"aulway/internal/handler/pagination"
 	"aulway/internal/handler/ticket/model"
+	rerrs "aulway/internal/repository/errs"
 	"aulway/internal/service"
 	"aulway/internal/utils/config"
 	"aulway/internal/utils/errs"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/labstack/echo/v4"
-	"log/slog"
 	"net/http"
 	"time"
 )
@@ -22,12 +23,18 @@ const (
 )
 
 type Service interface {
-	BuyTickets(ctx context.Context, userID, routeID string, paymentMethodID string, ticketAmount int, stripeKey string) ([]domain.Ticket, *domain.Bus, *domain.Route, error)
+	BuyTickets(ctx context.Context, userID, routeID string, paymentMethodID string, req model.BuyTicketRequest) (*domain.Purchase, error)
+	ConfirmPayment(ctx context.Context, userID, paymentID, email string) (*domain.Purchase, error)
+	GetSeatMap(ctx context.Context, routeID string) (*domain.SeatMap, error)
+	HoldSeats(ctx context.Context, userID, routeID string, req model.HoldSeatsRequest) (*domain.SeatHold, error)
+	ReleaseHold(ctx context.Context, userID, holdID string) error
 	GetUpcomingTickets(ctx context.Context, userID string, now time.Time) ([]domain.Ticket, error)
 	GetPastTickets(ctx context.Context, userID string, now time.Time) ([]domain.Ticket, error)
 	TicketDetails(ctx context.Context, ticketId string) (*domain.Ticket, error)
+	TicketPDF(ctx context.Context, userID, ticketID string) (*domain.Ticket, []byte, error)
 	GetTicketsSortBy(ctx context.Context, sortBy, ord string, page, pageSize int) ([]domain.Ticket, error)
-	CancelTicket(ctx context.Context, userID, ticketID, stripeKey string) (*domain.Ticket, string, error)
+	CancelTicket(ctx context.Context, userID, ticketID, email string) (*domain.Ticket, error)
+	QuoteTicketCancellation(ctx context.Context, userID, ticketID string) (*domain.RefundQuote, error)
 	GetCancelledTickets(ctx context.Context, userID string) ([]domain.Ticket, error)
 	GetAdminCancelledTickets(ctx context.Context, page, pageSize int) ([]domain.Ticket, error)
 }
@@ -35,18 +42,26 @@ type Service interface {
 // BuyTicketHandler processes ticket purchase requests for multiple tickets.
 // @Summary      Buy tickets
 // @Description  Allows a user to purchase one or more tickets for a specific route using card details.
+// @Description  Pass the wanted seat numbers in "seats", or only a "quantity" to get the first free seats.
+// @Description  Seats reserved before with a hold are bought by passing its "hold_id".
+// @Description  When the card needs 3-D Secure the tickets are kept awaiting and 202 is returned with the PaymentIntent
+// @Description  client secret. Finish the authentication with stripe.js, then call the payment confirm endpoint.
 // @Tags         tickets
 // @Accept       json
 // @Produce      json
 // @Param        routeId  path      string                     true  "Route ID"
 // @Param        payment_id query  string                      true  "Payment method ID - pm_card_visa"
 // @Param        requestBody body   model.BuyTicketRequest     true  "Buy Ticket Request Body"
+// @Param        Idempotency-Key header string                 false "Retries with the same key replay the first response instead of charging again"
 // @Security     BearerAuth
 // @Success      200      {array}   domain.Ticket             "Successfully purchased tickets"
+// @Success      202      {object}  domain.Purchase           "Payment needs customer authentication"
 // @Failure      400      {object}  errs.Err                  "Invalid request or request binding failed"
+// @Failure      409      {object}  errs.Err                  "Seat is taken or does not exist"
+// @Failure      422      {object}  errs.Err                  "Idempotency key reused with a different request"
 // @Failure      500      {object}  map[string]string         "Internal server error"
 // @Router       /api/tickets/{routeId} [post]
-func BuyTicketHandler(s Service, cfg config.Config) echo.HandlerFunc {
+func BuyTicketHandler(s Service, _ config.Config) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		var req model.BuyTicketRequest
 		if err := c.Bind(&req); err != nil {
@@ -57,87 +72,153 @@ func BuyTicketHandler(s Service, cfg config.Config) echo.HandlerFunc {
 		routeId := c.Param("routeId")
 		userID := c.Get("user_id").(string)
 
-		tickets, bus, route, err := s.BuyTickets(c.Request().Context(), userID, routeId, paymentId, req.Quantity, cfg.StripeKey)
+		purchase, err := s.BuyTickets(c.Request().Context(), userID, routeId, paymentId, req)
+		if isSeatError(err) {
+			return c.JSON(http.StatusConflict, errs.Err{Err: "seat unavailable", ErrDesc: err.Error()})
+		}
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "error", ErrDesc: err.Error()})
 		}
 
-		go func() {
-			emailBody := buildTicketEmailBody(tickets, bus, route)
-			err := service.SendEmailWithQR(req.UserEmail, "Your Bus Ticket(s)", tickets, cfg.SMTP, emailBody)
-			if err != nil {
-				slog.Error("failed to send ticket email", slog.String("user_id", userID), slog.String("error", err.Error()))
-			}
-		}()
+		if !purchase.Issued {
+			return c.JSON(http.StatusAccepted, purchase)
+		}
 
-		return c.JSON(http.StatusOK, tickets)
+		return c.JSON(http.StatusOK, purchase.Tickets)
 	}
 }
 
-func buildTicketEmailBody(tickets []domain.Ticket, bus *domain.Bus, route *domain.Route) string {
-	if len(tickets) == 0 {
-		return "<html><body><p>Билеты не найдены.</p></body></html>"
+// ConfirmPaymentHandler settles a purchase once its payment was authenticated.
+// @Summary      Confirm ticket payment
+// @Description  Checks the payment of a purchase that needed 3-D Secure. Succeeded payments issue the tickets,
+// @Description  failed ones cancel them and give the seats back. A payment still in progress is returned as is.
+// @Tags         tickets
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        paymentId    path      string                       true  "Payment ID"
+// @Param        requestBody  body      model.ConfirmPaymentRequest  false "Where to send the tickets"
+// @Success      200          {object}  domain.Purchase
+// @Failure      400          {object}  errs.Err
+// @Failure      404          {object}  errs.Err  "Payment not found"
+// @Failure      500          {object}  errs.Err
+// @Router       /api/tickets/payments/{paymentId}/confirm [post]
+func ConfirmPaymentHandler(s Service, _ config.Config) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req model.ConfirmPaymentRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "invalid request", ErrDesc: "request binding failed"})
+		}
+
+		paymentId := c.Param("paymentId")
+		userID := c.Get("user_id").(string)
+
+		purchase, err := s.ConfirmPayment(c.Request().Context(), userID, paymentId, req.UserEmail)
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "payment not found", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to confirm payment", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, purchase)
 	}
+}
 
-	orderNumber := tickets[0].OrderNumber
-	loc := time.FixedZone("Almaty", 5*60*60)
-	departureDate := route.StartDate.In(loc).Format("02 Jan 2006")
-	departureTime := route.StartDate.In(loc).Format("15:04")
-	arrivalDate := route.EndDate.In(loc).Format("02 Jan 2006")
-	arrivalTime := route.EndDate.In(loc).Format("15:04")
-
-	body := `<html><body style="font-family: Arial, sans-serif;">`
-	body += `<h2 style="color:#2d89ef;">Подтверждение покупки билетов – AulWay</h2><hr>`
-	body += fmt.Sprintf(`<p><strong>Номер заказа:</strong> %s</p>`, orderNumber)
-	body += fmt.Sprintf(`<p><strong>Маршрут:</strong> %s → %s<br>
-<strong>Автобус №:</strong> %s<br>
-<strong>Отправление:</strong> %s в %s (GMT+05 Алматы)<br>
-<strong>Прибытие:</strong> %s в %s (GMT+05 Алматы)<br>
-<strong>Адрес посадки:</strong> %s<br>
-<strong>Адрес высадки:</strong> %s</p>`,
-		route.Departure, route.Destination, bus.Number,
-		departureDate, departureTime,
-		arrivalDate, arrivalTime,
-		route.DepartureLocation, route.DestinationLocation,
-	)
-
-	body += `<table border="1" cellpadding="10" cellspacing="0" style="border-collapse: collapse; margin-top: 20px;">
-	<thead>
-		<tr>
-			<th>Место</th>
-			<th>Цена</th>
-			<th>QR-код</th>
-		</tr>
-	</thead>
-	<tbody>`
-
-	totalPrice := 0
-
-	for i, t := range tickets {
-		body += "<tr>"
-		body += fmt.Sprintf("<td>Билет #%d</td>", i+1)
-		body += fmt.Sprintf("<td>%d₸</td>", t.Price)
-
-		if t.QRCode != "" {
-			cid := fmt.Sprintf("qr%d.png", i+1)
-			body += fmt.Sprintf(`<td><img src="cid:%s" alt="QR-код" style="max-width:120px;"/></td>`, cid)
-		} else {
-			body += "<td>Нет</td>"
+// GetRouteSeatsHandler returns the seat map of a route.
+// @Summary      Get route seat map
+// @Description  Returns the bus seat layout of a route and marks the seats that are already taken.
+// @Tags         tickets
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        routeId  path      string  true  "Route ID"
+// @Success      200      {object}  domain.SeatMap
+// @Failure      500      {object}  errs.Err
+// @Router       /api/routes/{routeId}/seats [get]
+func GetRouteSeatsHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		routeId := c.Param("routeId")
+
+		seatMap, err := s.GetSeatMap(c.Request().Context(), routeId)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to get seats", ErrDesc: err.Error()})
 		}
 
-		body += "</tr>"
-		totalPrice += t.Price
+		return c.JSON(http.StatusOK, seatMap)
 	}
+}
 
-	body += "</tbody></table>"
+// HoldSeatsHandler reserves seats for the duration of the checkout.
+// @Summary      Hold seats
+// @Description  Reserves the given seats, or the first free ones for "quantity", until the hold expires.
+// @Description  Pass the returned hold id as "hold_id" when buying the tickets.
+// @Tags         tickets
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        routeId      path      string                  true  "Route ID"
+// @Param        requestBody  body      model.HoldSeatsRequest  true  "Hold Seats Request Body"
+// @Success      200          {object}  domain.SeatHold
+// @Failure      400          {object}  errs.Err
+// @Failure      409          {object}  errs.Err  "Seat is taken or does not exist"
+// @Failure      500          {object}  errs.Err
+// @Router       /api/routes/{routeId}/holds [post]
+func HoldSeatsHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req model.HoldSeatsRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "invalid request", ErrDesc: "request binding failed"})
+		}
 
-	body += fmt.Sprintf(`<p style="margin-top:20px;"><strong>Всего билетов:</strong> %d<br><strong>Общая сумма:</strong> %d₸</p>`,
-		len(tickets), totalPrice)
+		routeId := c.Param("routeId")
+		userID := c.Get("user_id").(string)
 
-	body += `<p style="margin-top:30px;">Спасибо за покупку!<br>Хорошей поездки с AulWay 😊</p>`
-	body += `</body></html>`
+		hold, err := s.HoldSeats(c.Request().Context(), userID, routeId, req)
+		if isSeatError(err) {
+			return c.JSON(http.StatusConflict, errs.Err{Err: "seat unavailable", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "hold seats failed", ErrDesc: err.Error()})
+		}
 
-	return body
+		return c.JSON(http.StatusOK, hold)
+	}
+}
+
+// ReleaseHoldHandler gives held seats back before the hold expires.
+// @Summary      Release seat hold
+// @Tags         tickets
+// @Produce      json
+// @Security     BearerAuth
+// @Param        routeId  path      string  true  "Route ID"
+// @Param        holdId   path      string  true  "Hold ID"
+// @Success      200      {object}  map[string]string
+// @Failure      404      {object}  errs.Err
+// @Failure      500      {object}  errs.Err
+// @Router       /api/routes/{routeId}/holds/{holdId} [delete]
+func ReleaseHoldHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		holdId := c.Param("holdId")
+		userID := c.Get("user_id").(string)
+
+		err := s.ReleaseHold(c.Request().Context(), userID, holdId)
+		if errors.Is(err, errs.ErrHoldNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "release hold failed", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "release hold failed", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{"message": "seats released"})
+	}
+}
+
+func isSeatError(err error) bool {
+	return errors.Is(err, errs.ErrSeatTaken) ||
+		errors.Is(err, errs.ErrUnknownSeat) ||
+		errors.Is(err, errs.ErrNoSeatsAvailable) ||
+		errors.Is(err, errs.ErrHoldNotFound)
 }
 
 // GetUserTicketsHandler returns a user's past or upcoming tickets.
@@ -213,6 +294,42 @@ func GetTicketDetailsHandler(service Service) echo.HandlerFunc {
 	}
 }
 
+// GetTicketPDFHandler downloads the printable e-ticket
+// @Summary      Download e-ticket
+// @Description  Returns the ticket as a PDF with the route, bus, times, pickup addresses, seat, price, order number and QR code.
+// @Tags         tickets
+// @Produce      application/pdf
+// @Security BearerAuth
+// @Param        userId    path      string  true  "User ID"
+// @Param        ticketId  path      string  true  "Ticket ID"
+// @Success      200       {file}    file    "E-ticket"
+// @Failure      403       {object}  errs.Err  "Access denied"
+// @Failure      404       {object}  errs.Err
+// @Failure      409       {object}  errs.Err  "Ticket is cancelled or not paid yet"
+// @Failure      500       {object}  errs.Err
+// @Router       /api/tickets/users/{userId}/{ticketId}/pdf [get]
+func GetTicketPDFHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "get ticket failed", ErrDesc: "access denied"})
+		}
+
+		ticket, pdf, err := s.TicketPDF(c.Request().Context(), c.Param("userId"), c.Param("ticketId"))
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "ticket not found", ErrDesc: err.Error()})
+		}
+		if errors.Is(err, errs.ErrTicketNotBoardable) {
+			return c.JSON(http.StatusConflict, errs.Err{Err: "ticket is not valid", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to render ticket", ErrDesc: err.Error()})
+		}
+
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, service.TicketPDFName(ticket)))
+		return c.Blob(http.StatusOK, "application/pdf", pdf)
+	}
+}
+
 // GetTicketsSortByHandler retrieves sorted and paginated tickets.
 // @Summary Get sorted and paginated tickets
 // @Description Retrieve tickets sorted by user, start date, route, price, status, or payment status with pagination.
@@ -255,12 +372,14 @@ func GetTicketsSortByHandler(service Service) echo.HandlerFunc {
 // @Param userId path string true "User ID"
 // @Param ticketId path string true "Ticket ID"
 // @Param email query string true "email for sending mail"
+// @Param Idempotency-Key header string false "Retries with the same key replay the first response"
 // @Success 200 {object} string "Cancellation successful"
 // @Failure 400 {object} errs.Err
 // @Failure 403 {object} errs.Err "Access denied"
+// @Failure 409 {object} errs.Err "The refund policy does not allow cancelling now"
 // @Failure 500 {object} errs.Err
 // @Router /api/tickets/users/{userId}/{ticketId}/cancel [put]
-func CancelTicketHandler(cfg config.Config, s Service) echo.HandlerFunc {
+func CancelTicketHandler(_ config.Config, s Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		if !access.Check(c, c.Get("user_id"), "userId") {
 			return c.JSON(http.StatusForbidden, errs.Err{Err: "cancel failed", ErrDesc: "access denied"})
@@ -271,22 +390,53 @@ func CancelTicketHandler(cfg config.Config, s Service) echo.HandlerFunc {
 
 		email := c.QueryParam("email")
 
-		_, msg, err := s.CancelTicket(c.Request().Context(), userID, ticketID, cfg.StripeKey)
+		_, err := s.CancelTicket(c.Request().Context(), userID, ticketID, email)
+		if errors.Is(err, errs.ErrCancellationNotAllowed) {
+			return c.JSON(http.StatusConflict, errs.Err{Err: "cancel error", ErrDesc: err.Error()})
+		}
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "cancel error", ErrDesc: err.Error()})
 		}
 
-		go func() {
-			err := service.SendEmail(email, "Билет отменён", msg, cfg.SMTP)
-			if err != nil {
-				slog.Error("failed to send cancellation email", slog.String("user_id", userID), slog.String("error", err.Error()))
-			}
-		}()
-
 		return c.JSON(http.StatusOK, map[string]string{"message": "Ticket successfully cancelled"})
 	}
 }
 
+// QuoteCancelTicketHandler previews the refund of cancelling a ticket
+// @Summary Cancellation quote
+// @Description Shows what cancelling the ticket would refund under the refund policy of its route, nothing is cancelled
+// @Tags tickets
+// @Produce json
+// @Security BearerAuth
+// @Param userId path string true "User ID"
+// @Param ticketId path string true "Ticket ID"
+// @Success 200 {object} domain.RefundQuote
+// @Failure 403 {object} errs.Err "Access denied"
+// @Failure 404 {object} errs.Err
+// @Failure 409 {object} errs.Err "Ticket was already used or missed"
+// @Failure 500 {object} errs.Err
+// @Router /api/tickets/users/{userId}/{ticketId}/cancel/quote [get]
+func QuoteCancelTicketHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "quote failed", ErrDesc: "access denied"})
+		}
+
+		quote, err := s.QuoteTicketCancellation(c.Request().Context(), c.Param("userId"), c.Param("ticketId"))
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "ticket not found", ErrDesc: err.Error()})
+		}
+		if errors.Is(err, errs.ErrCancellationNotAllowed) {
+			return c.JSON(http.StatusConflict, errs.Err{Err: "failed to quote cancellation", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to quote cancellation", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, quote)
+	}
+}
+
 // GetCancelledTicketsHandler user's cancelled tickets
 // @Summary Get cancelled tickets
 // @Tags tickets
@@ -0,0 +1,98 @@
+package user
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/access"
+	"aulway/internal/handler/user/model"
+	"aulway/internal/ratelimit"
+	rerrs "aulway/internal/repository/errs"
+	"aulway/internal/utils/errs"
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type EmailService interface {
+	SendEmailCode(ctx context.Context, userID string) error
+	VerifyEmail(ctx context.Context, userID, code string) (*domain.User, error)
+}
+
+// SendEmailCodeHandler starts verifying the email of the user
+// @Summary      Send email code
+// @Description  Emails a verification code to the email of the user. Google, Apple and Firebase accounts are linked
+// @Description  to the user by email only once it is verified. Nothing is sent when the email is verified already.
+// @Tags         users
+// @Produce      json
+// @Security     BearerAuth
+// @Param        userId  path      string  true  "User ID"
+// @Success      200     {object}  map[string]string
+// @Failure      400     {object}  errs.Err  "The user has no email"
+// @Failure      403     {object}  errs.Err  "Access denied"
+// @Failure      429     {object}  errs.Err  "A code was emailed recently"
+// @Failure      500     {object}  errs.Err
+// @Router       /api/users/{userId}/email/code [post]
+func SendEmailCodeHandler(service EmailService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "send email code failed", ErrDesc: "access denied"})
+		}
+
+		err := service.SendEmailCode(c.Request().Context(), c.Param("userId"))
+		if err != nil {
+			return c.JSON(emailCodeStatus(c, err), errs.Err{Err: "send email code failed", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, echo.Map{"message": "Verification code sent to email"})
+	}
+}
+
+// VerifyEmailHandler verifies the email of the user
+// @Summary      Verify email
+// @Description  Checks the code emailed to the user and marks the email verified.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        userId   path      string                  true  "User ID"
+// @Param        request  body      model.EmailCodeRequest  true  "Code"
+// @Success      200      {object}  model.UserResponse
+// @Failure      400      {object}  errs.Err
+// @Failure      403      {object}  errs.Err  "Access denied"
+// @Failure      429      {object}  errs.Err  "Too many attempts, see Retry-After"
+// @Failure      500      {object}  errs.Err
+// @Router       /api/users/{userId}/email/verify [post]
+func VerifyEmailHandler(service EmailService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "verify email failed", ErrDesc: "access denied"})
+		}
+
+		var req model.EmailCodeRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "error at binding request body", ErrDesc: err.Error()})
+		}
+
+		usr, err := service.VerifyEmail(c.Request().Context(), c.Param("userId"), req.Code)
+		if err != nil {
+			return c.JSON(emailCodeStatus(c, err), errs.Err{Err: "verify email failed", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, domainUserToResponse(*usr))
+	}
+}
+
+func emailCodeStatus(c echo.Context, err error) int {
+	switch {
+	case ratelimit.RetryAfter(c.Response().Header(), err):
+		return http.StatusTooManyRequests
+	case errors.Is(err, errs.ErrIncorrectEmailFormat), errors.Is(err, errs.ErrInvalidCode):
+		return http.StatusBadRequest
+	case errors.Is(err, errs.ErrTooManyEmailCodes):
+		return http.StatusTooManyRequests
+	case errors.Is(err, rerrs.ErrRecordNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
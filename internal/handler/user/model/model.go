@@ -14,14 +14,20 @@ type UpdateUserRequest struct {
 }
 
 type UserResponse struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	Role      string    `json:"role"`
-	Firstname string    `json:"firstname"`
-	Lastname  string    `json:"lastname"`
-	Phone     string    `json:"phone"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+	Phone     string `json:"phone"`
+	// EmailVerified is set once the email is confirmed with an emailed code,
+	// only then are Google, Apple and Firebase accounts linked by it
+	EmailVerified bool `json:"email_verified"`
+	// PhoneVerified is set once the phone is confirmed with an SMS code, only
+	// then can it be used to sign in
+	PhoneVerified bool      `json:"phone_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 func (UpdateUserRequest) ValidateEmail(email string) error {
@@ -43,3 +49,16 @@ type ResetPasswordRequest struct {
 	OldPassword string `json:"old_password"`
 	NewPassword string `json:"new_password"`
 }
+
+type PhoneChangeRequest struct {
+	Phone string `json:"phone" example:"+77011234567"`
+}
+
+type VerifyPhoneChangeRequest struct {
+	Phone string `json:"phone" example:"+77011234567"`
+	Code  string `json:"code" example:"123456"`
+}
+
+type EmailCodeRequest struct {
+	Code string `json:"code" example:"123456"`
+}
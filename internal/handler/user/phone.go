@@ -0,0 +1,112 @@
+package user
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/access"
+	"aulway/internal/handler/user/model"
+	"aulway/internal/ratelimit"
+	rerrs "aulway/internal/repository/errs"
+	"aulway/internal/utils/errs"
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type PhoneService interface {
+	SendPhoneChangeCode(ctx context.Context, userID string, req model.PhoneChangeRequest) error
+	VerifyPhoneChange(ctx context.Context, userID string, req model.VerifyPhoneChangeRequest) (*domain.User, error)
+}
+
+// ChangePhoneHandler starts a phone change
+// @Summary      Change phone
+// @Description  Texts a verification code to the new phone, it becomes the phone of the user once the code is
+// @Description  verified. A phone verified by another user is refused.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        userId   path      string                    true  "User ID"
+// @Param        request  body      model.PhoneChangeRequest  true  "New phone"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  errs.Err
+// @Failure      403      {object}  errs.Err  "Access denied"
+// @Failure      409      {object}  errs.Err  "Phone belongs to another user"
+// @Failure      429      {object}  errs.Err  "Too many codes requested for the phone"
+// @Failure      500      {object}  errs.Err
+// @Failure      501      {object}  errs.Err  "Text messages are not configured"
+// @Router       /api/users/{userId}/phone [post]
+func ChangePhoneHandler(service PhoneService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "change phone failed", ErrDesc: "access denied"})
+		}
+
+		var req model.PhoneChangeRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "error at binding request body", ErrDesc: err.Error()})
+		}
+
+		err := service.SendPhoneChangeCode(c.Request().Context(), c.Param("userId"), req)
+		if err != nil {
+			return c.JSON(phoneChangeStatus(c, err), errs.Err{Err: "change phone failed", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, echo.Map{"message": "Verification code sent to phone"})
+	}
+}
+
+// VerifyPhoneChangeHandler completes a phone change
+// @Summary      Verify phone change
+// @Description  Checks the code texted to the new phone and makes it the verified phone of the user.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        userId   path      string                          true  "User ID"
+// @Param        request  body      model.VerifyPhoneChangeRequest  true  "New phone and code"
+// @Success      200      {object}  model.UserResponse
+// @Failure      400      {object}  errs.Err
+// @Failure      403      {object}  errs.Err  "Access denied"
+// @Failure      409      {object}  errs.Err  "Phone belongs to another user"
+// @Failure      429      {object}  errs.Err  "Too many attempts, see Retry-After"
+// @Failure      500      {object}  errs.Err
+// @Router       /api/users/{userId}/phone/verify [post]
+func VerifyPhoneChangeHandler(service PhoneService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "verify phone failed", ErrDesc: "access denied"})
+		}
+
+		var req model.VerifyPhoneChangeRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "error at binding request body", ErrDesc: err.Error()})
+		}
+
+		usr, err := service.VerifyPhoneChange(c.Request().Context(), c.Param("userId"), req)
+		if err != nil {
+			return c.JSON(phoneChangeStatus(c, err), errs.Err{Err: "verify phone failed", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, domainUserToResponse(*usr))
+	}
+}
+
+func phoneChangeStatus(c echo.Context, err error) int {
+	switch {
+	case ratelimit.RetryAfter(c.Response().Header(), err):
+		return http.StatusTooManyRequests
+	case errors.Is(err, errs.ErrIncorrectPhoneFormat), errors.Is(err, errs.ErrInvalidCode):
+		return http.StatusBadRequest
+	case errors.Is(err, errs.ErrTooManyCodeRequests):
+		return http.StatusTooManyRequests
+	case errors.Is(err, rerrs.PhoneAlreadyExists):
+		return http.StatusConflict
+	case errors.Is(err, rerrs.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errs.ErrSMSNotConfigured):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
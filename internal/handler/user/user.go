@@ -6,6 +6,7 @@ import (
 	authModel "aulway/internal/handler/auth/model"
 	"aulway/internal/handler/pagination"
 	"aulway/internal/handler/user/model"
+	"aulway/internal/ratelimit"
 	rerrs "aulway/internal/repository/errs"
 	"aulway/internal/utils/errs"
 	"context"
@@ -56,6 +57,9 @@ func ChangePasswordHandler(service Service) echo.HandlerFunc {
 		}
 
 		err = service.ResetPassword(c.Request().Context(), req, false)
+		if ratelimit.RetryAfter(c.Response().Header(), err) {
+			return c.JSON(http.StatusTooManyRequests, errs.Err{Err: "reset password failed", ErrDesc: err.Error()})
+		}
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "reset password failed", ErrDesc: err.Error()})
 		}
@@ -191,13 +195,15 @@ func DeleteUserHandler(service Service) echo.HandlerFunc {
 
 func domainUserToResponse(user domain.User) model.UserResponse {
 	return model.UserResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		Phone:     user.Phone,
-		Role:      user.Role,
-		Firstname: user.FirstName,
-		Lastname:  user.LastName,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:            user.ID,
+		Email:         user.Email,
+		Phone:         user.Phone,
+		EmailVerified: user.EmailVerified,
+		PhoneVerified: user.PhoneVerified,
+		Role:          user.Role,
+		Firstname:     user.FirstName,
+		Lastname:      user.LastName,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
 	}
 }
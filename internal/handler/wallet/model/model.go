@@ -0,0 +1,20 @@
+package model
+
+import "errors"
+
+// RegisterDeviceRequest is sent by Apple Wallet when a pass is added.
+type RegisterDeviceRequest struct {
+	PushToken string `json:"pushToken"`
+}
+
+func (r RegisterDeviceRequest) Validate() error {
+	if r.PushToken == "" {
+		return errors.New("pushToken is required")
+	}
+	return nil
+}
+
+// LogRequest carries the errors a device ran into with our passes.
+type LogRequest struct {
+	Logs []string `json:"logs"`
+}
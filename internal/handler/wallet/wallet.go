@@ -0,0 +1,252 @@
+package wallet
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/access"
+	"aulway/internal/handler/wallet/model"
+	rerrs "aulway/internal/repository/errs"
+	"aulway/internal/utils/errs"
+	"aulway/internal/wallet"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type Service interface {
+	ApplePass(ctx context.Context, userID, ticketID string) (*domain.Ticket, []byte, error)
+	GoogleLink(ctx context.Context, userID, ticketID string) (*domain.GoogleWalletLink, error)
+	Authorize(passTypeID, serial, authorization string) error
+	RegisterDevice(ctx context.Context, deviceID, passTypeID, serial, pushToken string) (bool, error)
+	UnregisterDevice(ctx context.Context, deviceID, passTypeID, serial string) error
+	UpdatedPasses(ctx context.Context, deviceID, passTypeID, since string) (*domain.UpdatedPasses, error)
+	LatestPass(ctx context.Context, serial string) ([]byte, time.Time, error)
+}
+
+// GetApplePassHandler downloads the Apple Wallet pass of a ticket
+// @Summary      Download Apple Wallet pass
+// @Description  Returns the ticket as a signed .pkpass boarding pass with the route, times, seat and QR code.
+// @Description  The pass is updated on the device when the route's time or the ticket status changes.
+// @Tags         tickets
+// @Produce      application/vnd.apple.pkpass
+// @Security BearerAuth
+// @Param        userId    path      string  true  "User ID"
+// @Param        ticketId  path      string  true  "Ticket ID"
+// @Success      200       {file}    file    "Pass bundle"
+// @Failure      403       {object}  errs.Err  "Access denied"
+// @Failure      404       {object}  errs.Err
+// @Failure      409       {object}  errs.Err  "Ticket is cancelled or not paid yet"
+// @Failure      500       {object}  errs.Err
+// @Failure      501       {object}  errs.Err  "Apple Wallet is not configured"
+// @Router       /api/tickets/users/{userId}/{ticketId}/wallet/apple [get]
+func GetApplePassHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "get ticket failed", ErrDesc: "access denied"})
+		}
+
+		ticket, pass, err := s.ApplePass(c.Request().Context(), c.Param("userId"), c.Param("ticketId"))
+		if err != nil {
+			return passError(c, err)
+		}
+
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="ticket-%s-%s.pkpass"`, ticket.OrderNumber, ticket.SeatNumber))
+		return c.Blob(http.StatusOK, wallet.PKPassContentType, pass)
+	}
+}
+
+// GetGoogleLinkHandler returns the Google Wallet link of a ticket
+// @Summary      Google Wallet link
+// @Description  Returns an "Add to Google Wallet" link holding the signed transit pass of the ticket.
+// @Tags         tickets
+// @Produce      json
+// @Security BearerAuth
+// @Param        userId    path      string  true  "User ID"
+// @Param        ticketId  path      string  true  "Ticket ID"
+// @Success      200       {object}  domain.GoogleWalletLink
+// @Failure      403       {object}  errs.Err  "Access denied"
+// @Failure      404       {object}  errs.Err
+// @Failure      409       {object}  errs.Err  "Ticket is cancelled or not paid yet"
+// @Failure      500       {object}  errs.Err
+// @Failure      501       {object}  errs.Err  "Google Wallet is not configured"
+// @Router       /api/tickets/users/{userId}/{ticketId}/wallet/google [get]
+func GetGoogleLinkHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !access.Check(c, c.Get("user_id"), "userId") {
+			return c.JSON(http.StatusForbidden, errs.Err{Err: "get ticket failed", ErrDesc: "access denied"})
+		}
+
+		link, err := s.GoogleLink(c.Request().Context(), c.Param("userId"), c.Param("ticketId"))
+		if err != nil {
+			return passError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, link)
+	}
+}
+
+func passError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, rerrs.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, errs.Err{Err: "ticket not found", ErrDesc: err.Error()})
+	case errors.Is(err, errs.ErrTicketNotBoardable):
+		return c.JSON(http.StatusConflict, errs.Err{Err: "ticket is not valid", ErrDesc: err.Error()})
+	case errors.Is(err, errs.ErrWalletNotConfigured):
+		return c.JSON(http.StatusNotImplemented, errs.Err{Err: "wallet unavailable", ErrDesc: err.Error()})
+	default:
+		return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to build pass", ErrDesc: err.Error()})
+	}
+}
+
+// RegisterDeviceHandler subscribes a device to pass updates
+// @Summary      Register pass device
+// @Description  Apple Wallet web service: called by the device when the pass is added, authenticated with "ApplePass <authenticationToken>".
+// @Tags         wallet
+// @Accept       json
+// @Param        deviceId      path  string                       true  "Device library ID"
+// @Param        passTypeId    path  string                       true  "Pass type ID"
+// @Param        serialNumber  path  string                       true  "Ticket ID"
+// @Param        requestBody   body  model.RegisterDeviceRequest  true  "Push token"
+// @Success      201  "Registered"
+// @Success      200  "Already registered"
+// @Failure      400  {object}  errs.Err
+// @Failure      401  "Unauthorized"
+// @Router       /wallet/v1/devices/{deviceId}/registrations/{passTypeId}/{serialNumber} [post]
+func RegisterDeviceHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if err := s.Authorize(c.Param("passTypeId"), c.Param("serialNumber"), c.Request().Header.Get(echo.HeaderAuthorization)); err != nil {
+			return c.NoContent(http.StatusUnauthorized)
+		}
+
+		var req model.RegisterDeviceRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "Binding request body failed", ErrDesc: err.Error()})
+		}
+		if err := req.Validate(); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "Bad request", ErrDesc: err.Error()})
+		}
+
+		created, err := s.RegisterDevice(c.Request().Context(), c.Param("deviceId"), c.Param("passTypeId"), c.Param("serialNumber"), req.PushToken)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to register device", ErrDesc: err.Error()})
+		}
+		if created {
+			return c.NoContent(http.StatusCreated)
+		}
+
+		return c.NoContent(http.StatusOK)
+	}
+}
+
+// UnregisterDeviceHandler stops pass updates to a device
+// @Summary      Unregister pass device
+// @Description  Apple Wallet web service: called by the device when the pass is removed.
+// @Tags         wallet
+// @Param        deviceId      path  string  true  "Device library ID"
+// @Param        passTypeId    path  string  true  "Pass type ID"
+// @Param        serialNumber  path  string  true  "Ticket ID"
+// @Success      200  "Unregistered"
+// @Failure      401  "Unauthorized"
+// @Router       /wallet/v1/devices/{deviceId}/registrations/{passTypeId}/{serialNumber} [delete]
+func UnregisterDeviceHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if err := s.Authorize(c.Param("passTypeId"), c.Param("serialNumber"), c.Request().Header.Get(echo.HeaderAuthorization)); err != nil {
+			return c.NoContent(http.StatusUnauthorized)
+		}
+
+		err := s.UnregisterDevice(c.Request().Context(), c.Param("deviceId"), c.Param("passTypeId"), c.Param("serialNumber"))
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to unregister device", ErrDesc: err.Error()})
+		}
+
+		return c.NoContent(http.StatusOK)
+	}
+}
+
+// ListUpdatedPassesHandler lists the passes of a device that changed
+// @Summary      Updated passes
+// @Description  Apple Wallet web service: the serial numbers of the passes of a device changed since the previous tag.
+// @Tags         wallet
+// @Produce      json
+// @Param        deviceId            path   string  true   "Device library ID"
+// @Param        passTypeId          path   string  true   "Pass type ID"
+// @Param        passesUpdatedSince  query  string  false  "lastUpdated of the previous response"
+// @Success      200  {object}  domain.UpdatedPasses
+// @Success      204  "Nothing changed"
+// @Failure      400  {object}  errs.Err
+// @Router       /wallet/v1/devices/{deviceId}/registrations/{passTypeId} [get]
+func ListUpdatedPassesHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		updated, err := s.UpdatedPasses(c.Request().Context(), c.Param("deviceId"), c.Param("passTypeId"), c.QueryParam("passesUpdatedSince"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "failed to list passes", ErrDesc: err.Error()})
+		}
+		if updated == nil {
+			return c.NoContent(http.StatusNoContent)
+		}
+
+		return c.JSON(http.StatusOK, updated)
+	}
+}
+
+// GetLatestPassHandler returns the current version of a pass
+// @Summary      Latest pass
+// @Description  Apple Wallet web service: the current .pkpass of a ticket, 304 when unchanged since If-Modified-Since.
+// @Tags         wallet
+// @Produce      application/vnd.apple.pkpass
+// @Param        passTypeId    path  string  true  "Pass type ID"
+// @Param        serialNumber  path  string  true  "Ticket ID"
+// @Success      200  {file}  file  "Pass bundle"
+// @Success      304  "Not modified"
+// @Failure      401  "Unauthorized"
+// @Failure      404  {object}  errs.Err
+// @Router       /wallet/v1/passes/{passTypeId}/{serialNumber} [get]
+func GetLatestPassHandler(s Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if err := s.Authorize(c.Param("passTypeId"), c.Param("serialNumber"), c.Request().Header.Get(echo.HeaderAuthorization)); err != nil {
+			return c.NoContent(http.StatusUnauthorized)
+		}
+
+		pass, modified, err := s.LatestPass(c.Request().Context(), c.Param("serialNumber"))
+		if errors.Is(err, rerrs.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, errs.Err{Err: "pass not found", ErrDesc: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to build pass", ErrDesc: err.Error()})
+		}
+
+		modified = modified.UTC().Truncate(time.Second)
+		if since, err := http.ParseTime(c.Request().Header.Get("If-Modified-Since")); err == nil && !modified.After(since) {
+			return c.NoContent(http.StatusNotModified)
+		}
+
+		c.Response().Header().Set(echo.HeaderLastModified, modified.Format(http.TimeFormat))
+		return c.Blob(http.StatusOK, wallet.PKPassContentType, pass)
+	}
+}
+
+// LogHandler records errors reported by devices
+// @Summary      Pass device log
+// @Description  Apple Wallet web service: error messages a device ran into with our passes.
+// @Tags         wallet
+// @Accept       json
+// @Param        requestBody  body  model.LogRequest  true  "Messages"
+// @Success      200
+// @Router       /wallet/v1/log [post]
+func LogHandler() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req model.LogRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "Binding request body failed", ErrDesc: err.Error()})
+		}
+
+		for _, message := range req.Logs {
+			slog.Warn("apple wallet device log", slog.String("message", message))
+		}
+
+		return c.NoContent(http.StatusOK)
+	}
+}
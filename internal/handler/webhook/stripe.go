@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"aulway/internal/utils/errs"
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/webhook"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	StripeSignatureHeader = "Stripe-Signature"
+	maxPayloadBytes       = 65536
+)
+
+type Service interface {
+	HandleStripeEvent(ctx context.Context, event stripe.Event) error
+}
+
+// StripeWebhookHandler receives Stripe events and reconciles payments and tickets with them.
+// @Summary      Stripe webhook
+// @Description  Verifies the Stripe-Signature header and applies payment_intent.succeeded, payment_intent.payment_failed,
+// @Description  charge.refunded and charge.dispute.created events to payments and tickets. Other events are acknowledged and ignored.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        Stripe-Signature header string true "Stripe webhook signature"
+// @Success      200  {string}  string    "Event accepted"
+// @Failure      400  {object}  errs.Err  "Unreadable payload or invalid signature"
+// @Failure      500  {object}  errs.Err  "Event could not be processed, Stripe retries it"
+// @Failure      503  {object}  errs.Err  "No webhook secret is configured"
+// @Router       /webhooks/stripe [post]
+func StripeWebhookHandler(s Service, secret string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// an empty secret would let anyone sign events
+		if secret == "" {
+			return c.JSON(http.StatusServiceUnavailable, errs.Err{Err: "webhook not configured", ErrDesc: "no webhook secret is set"})
+		}
+
+		payload, err := io.ReadAll(io.LimitReader(c.Request().Body, maxPayloadBytes))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "failed to read payload", ErrDesc: err.Error()})
+		}
+
+		event, err := webhook.ConstructEventWithOptions(payload, c.Request().Header.Get(StripeSignatureHeader), secret, webhook.ConstructEventOptions{
+			IgnoreAPIVersionMismatch: true,
+		})
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, errs.Err{Err: "invalid signature", ErrDesc: err.Error()})
+		}
+
+		if err := s.HandleStripeEvent(c.Request().Context(), event); err != nil {
+			slog.Error("failed to handle stripe event", slog.String("event_id", event.ID), slog.String("type", string(event.Type)), slog.String("error", err.Error()))
+			return c.JSON(http.StatusInternalServerError, errs.Err{Err: "failed to handle event", ErrDesc: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, "OK")
+	}
+}
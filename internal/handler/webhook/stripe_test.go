@@ -0,0 +1,159 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/webhook"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testSecret = "whsec_test_secret"
+
+type fakeService struct {
+	events []stripe.Event
+	err    error
+}
+
+func (f *fakeService) HandleStripeEvent(ctx context.Context, event stripe.Event) error {
+	f.events = append(f.events, event)
+	return f.err
+}
+
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	payload, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return payload
+}
+
+func post(t *testing.T, s Service, payload []byte, signature string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/stripe", bytes.NewReader(payload))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(StripeSignatureHeader, signature)
+	rec := httptest.NewRecorder()
+
+	c := echo.New().NewContext(req, rec)
+	if err := StripeWebhookHandler(s, testSecret)(c); err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	return rec
+}
+
+func sign(payload []byte, secret string, at time.Time) string {
+	return webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{
+		Payload:   payload,
+		Secret:    secret,
+		Timestamp: at,
+	}).Header
+}
+
+func TestStripeWebhook_SignedFixtures(t *testing.T) {
+	cases := map[string]stripe.EventType{
+		"payment_intent_succeeded.json":      stripe.EventTypePaymentIntentSucceeded,
+		"payment_intent_payment_failed.json": stripe.EventTypePaymentIntentPaymentFailed,
+		"charge_refunded.json":               stripe.EventTypeChargeRefunded,
+		"charge_dispute_created.json":        stripe.EventTypeChargeDisputeCreated,
+	}
+
+	for name, eventType := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := &fakeService{}
+			payload := fixture(t, name)
+
+			rec := post(t, s, payload, sign(payload, testSecret, time.Now()))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", rec.Code, rec.Body.String())
+			}
+			if len(s.events) != 1 {
+				t.Fatalf("events handled = %d, want 1", len(s.events))
+			}
+			if s.events[0].Type != eventType {
+				t.Fatalf("event type = %s, want %s", s.events[0].Type, eventType)
+			}
+		})
+	}
+}
+
+func TestStripeWebhook_RejectsWrongSecret(t *testing.T) {
+	s := &fakeService{}
+	payload := fixture(t, "charge_refunded.json")
+
+	rec := post(t, s, payload, sign(payload, "whsec_other", time.Now()))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if len(s.events) != 0 {
+		t.Fatalf("unsigned event reached the service")
+	}
+}
+
+func TestStripeWebhook_RejectsTamperedPayload(t *testing.T) {
+	s := &fakeService{}
+	payload := fixture(t, "charge_refunded.json")
+	signature := sign(payload, testSecret, time.Now())
+
+	tampered := bytes.Replace(payload, []byte(`"refunded": true`), []byte(`"refunded": false`), 1)
+	rec := post(t, s, tampered, signature)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestStripeWebhook_RejectsStaleTimestamp(t *testing.T) {
+	s := &fakeService{}
+	payload := fixture(t, "payment_intent_succeeded.json")
+
+	rec := post(t, s, payload, sign(payload, testSecret, time.Now().Add(-time.Hour)))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestStripeWebhook_ServiceErrorAsksForRetry(t *testing.T) {
+	s := &fakeService{err: errors.New("db down")}
+	payload := fixture(t, "payment_intent_succeeded.json")
+
+	rec := post(t, s, payload, sign(payload, testSecret, time.Now()))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestStripeWebhook_RejectsWithoutSecret(t *testing.T) {
+	s := &fakeService{}
+	payload := fixture(t, "payment_intent_succeeded.json")
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/stripe", bytes.NewReader(payload))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(StripeSignatureHeader, sign(payload, "", time.Now()))
+	rec := httptest.NewRecorder()
+
+	if err := StripeWebhookHandler(s, "")(echo.New().NewContext(req, rec)); err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if len(s.events) != 0 {
+		t.Fatalf("event signed with an empty secret reached the service")
+	}
+}
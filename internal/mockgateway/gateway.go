@@ -0,0 +1,224 @@
+// Package mockgateway is a deterministic in-process card gateway modelled on
+// the Kaspi and Halyk acquiring APIs. It keeps payments in memory and decides
+// their outcome from the payment method alone, so purchases can be exercised
+// end to end without any external network.
+package mockgateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Payment methods with a fixed outcome, every other method is approved.
+const (
+	MethodDeclined = "pm_mock_declined"
+	Method3DS      = "pm_mock_3ds"
+	MethodPending  = "pm_mock_processing"
+)
+
+const (
+	StatusSucceeded      = "succeeded"
+	StatusRequiresAction = "requires_action"
+	StatusProcessing     = "processing"
+	StatusFailed         = "failed"
+	StatusCanceled       = "canceled"
+)
+
+var (
+	ErrNotFound     = errors.New("payment not found")
+	ErrInvalidState = errors.New("payment is not in a state that allows this")
+	ErrOverRefund   = errors.New("refund exceeds the captured amount")
+)
+
+type Payment struct {
+	ID            string `json:"id"`
+	Amount        int    `json:"amount"`
+	Refunded      int    `json:"refunded"`
+	Currency      string `json:"currency"`
+	Status        string `json:"status"`
+	PaymentMethod string `json:"payment_method"`
+	Reference     string `json:"reference"`
+	ClientSecret  string `json:"client_secret,omitempty"`
+}
+
+type CreatePaymentRequest struct {
+	Amount        int    `json:"amount"`
+	Currency      string `json:"currency"`
+	PaymentMethod string `json:"payment_method"`
+	Reference     string `json:"reference"`
+}
+
+type AuthenticateRequest struct {
+	Success bool `json:"success"`
+}
+
+type RefundRequest struct {
+	Amount int `json:"amount"`
+}
+
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type Gateway struct {
+	mu       sync.Mutex
+	seq      int
+	payments map[string]*Payment
+}
+
+func New() *Gateway {
+	return &Gateway{payments: make(map[string]*Payment)}
+}
+
+// Handler serves the gateway API:
+//
+//	POST /v1/payments                    charge a payment method
+//	GET  /v1/payments/{id}               read a payment
+//	POST /v1/payments/{id}/authenticate  finish 3-D Secure as the customer would
+//	POST /v1/payments/{id}/cancel        drop a payment that did not succeed
+//	POST /v1/payments/{id}/refunds       refund all or part of a payment
+func (g *Gateway) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/payments", g.create)
+	mux.HandleFunc("GET /v1/payments/{id}", g.get)
+	mux.HandleFunc("POST /v1/payments/{id}/authenticate", g.authenticate)
+	mux.HandleFunc("POST /v1/payments/{id}/cancel", g.cancel)
+	mux.HandleFunc("POST /v1/payments/{id}/refunds", g.refund)
+	return mux
+}
+
+// Payments returns a snapshot of every payment, oldest first.
+func (g *Gateway) Payments() []Payment {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	list := make([]Payment, 0, len(g.payments))
+	for _, p := range g.payments {
+		list = append(list, *p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+func (g *Gateway) create(w http.ResponseWriter, r *http.Request) {
+	var req CreatePaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Amount <= 0 || req.Currency == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "amount and currency are required")
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.seq++
+	p := &Payment{
+		ID:            fmt.Sprintf("mock_pay_%06d", g.seq),
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		PaymentMethod: req.PaymentMethod,
+		Reference:     req.Reference,
+	}
+
+	switch req.PaymentMethod {
+	case MethodDeclined:
+		p.Status = StatusFailed
+		g.payments[p.ID] = p
+		writeError(w, http.StatusPaymentRequired, "card_declined", "the card was declined")
+		return
+	case Method3DS:
+		p.Status = StatusRequiresAction
+		p.ClientSecret = p.ID + "_secret"
+	case MethodPending:
+		p.Status = StatusProcessing
+	default:
+		p.Status = StatusSucceeded
+	}
+
+	g.payments[p.ID] = p
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (g *Gateway) get(w http.ResponseWriter, r *http.Request) {
+	g.update(w, r.PathValue("id"), func(p *Payment) error { return nil })
+}
+
+func (g *Gateway) authenticate(w http.ResponseWriter, r *http.Request) {
+	var req AuthenticateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	g.update(w, r.PathValue("id"), func(p *Payment) error {
+		if p.Status != StatusRequiresAction {
+			return ErrInvalidState
+		}
+		p.Status = StatusFailed
+		if req.Success {
+			p.Status = StatusSucceeded
+		}
+		return nil
+	})
+}
+
+func (g *Gateway) cancel(w http.ResponseWriter, r *http.Request) {
+	g.update(w, r.PathValue("id"), func(p *Payment) error {
+		if p.Status == StatusSucceeded {
+			return ErrInvalidState
+		}
+		p.Status = StatusCanceled
+		return nil
+	})
+}
+
+func (g *Gateway) refund(w http.ResponseWriter, r *http.Request) {
+	var req RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Amount <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request", "amount must be positive")
+		return
+	}
+
+	g.update(w, r.PathValue("id"), func(p *Payment) error {
+		if p.Status != StatusSucceeded {
+			return ErrInvalidState
+		}
+		if p.Refunded+req.Amount > p.Amount {
+			return ErrOverRefund
+		}
+		p.Refunded += req.Amount
+		return nil
+	})
+}
+
+func (g *Gateway) update(w http.ResponseWriter, id string, apply func(p *Payment) error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p, ok := g.payments[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", ErrNotFound.Error())
+		return
+	}
+
+	if err := apply(p); err != nil {
+		writeError(w, http.StatusConflict, "invalid_state", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, p)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, Error{Code: code, Message: message})
+}
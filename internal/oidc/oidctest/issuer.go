@@ -0,0 +1,212 @@
+// Package oidctest runs a stub OpenID Connect issuer for tests. It signs
+// users in without asking, checks PKCE and the client on the code exchange
+// and issues ID tokens signed with a key made for the test.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const keyID = "stub-key"
+
+// User is who the issuer signs in.
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Issuer is a stub issuer, its URL is the issuer identifier.
+type Issuer struct {
+	URL          string
+	ClientID     string
+	ClientSecret string
+	Key          *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]grant
+}
+
+type grant struct {
+	user        User
+	nonce       string
+	challenge   string
+	redirectURI string
+}
+
+// NewIssuer starts an issuer that accepts the client, it stops with the
+// test.
+func NewIssuer(t testing.TB, clientID, clientSecret string) *Issuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := &Issuer{ClientID: clientID, ClientSecret: clientSecret, Key: key, codes: make(map[string]grant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", issuer.discovery)
+	mux.HandleFunc("/jwks", issuer.jwks)
+	mux.HandleFunc("/token", issuer.token)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	issuer.URL = server.URL
+
+	return issuer
+}
+
+// Authorize signs the user in at the authorization URL and returns the code
+// and state the issuer sends back to the redirect URL.
+func (i *Issuer) Authorize(t testing.TB, authURL string, user User) (code, state string) {
+	t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+
+	if query.Get("client_id") != i.ClientID || query.Get("response_type") != "code" {
+		t.Fatalf("unexpected authorization request %s", authURL)
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("authorization request without PKCE %s", authURL)
+	}
+
+	code = randomString()
+	i.mu.Lock()
+	i.codes[code] = grant{
+		user:        user,
+		nonce:       query.Get("nonce"),
+		challenge:   query.Get("code_challenge"),
+		redirectURI: query.Get("redirect_uri"),
+	}
+	i.mu.Unlock()
+
+	return code, query.Get("state")
+}
+
+// IDToken signs claims with the key of the issuer.
+func (i *Issuer) IDToken(t testing.TB, claims jwt.MapClaims) string {
+	t.Helper()
+
+	signed, err := i.sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+}
+
+func (i *Issuer) sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+
+	return token.SignedString(i.Key)
+}
+
+func (i *Issuer) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 i.URL,
+		"authorization_endpoint": i.URL + "/authorize",
+		"token_endpoint":         i.URL + "/token",
+		"jwks_uri":               i.URL + "/jwks",
+	})
+}
+
+func (i *Issuer) jwks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	json.NewEncoder(w).Encode(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(i.Key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(i.Key.E)).Bytes()),
+		}},
+	})
+}
+
+func (i *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+
+	if r.PostForm.Get("client_id") != i.ClientID || r.PostForm.Get("client_secret") != i.ClientSecret {
+		tokenError(w, "invalid_client")
+		return
+	}
+
+	// a code works once
+	i.mu.Lock()
+	grant, ok := i.codes[r.PostForm.Get("code")]
+	delete(i.codes, r.PostForm.Get("code"))
+	i.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("redirect_uri") != grant.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            i.URL,
+		"aud":            i.ClientID,
+		"sub":            grant.user.Subject,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          grant.nonce,
+		"email":          grant.user.Email,
+		"email_verified": grant.user.EmailVerified,
+		"name":           grant.user.Name,
+	}
+
+	idToken, err := i.sign(claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
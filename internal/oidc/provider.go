@@ -0,0 +1,338 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// defaultKeysTTL is how long signing keys are kept when the response
+	// does not say.
+	defaultKeysTTL = time.Hour
+	// keysRefetchInterval is the least time between fetches forced by a key
+	// id the cached keys do not have.
+	keysRefetchInterval = time.Minute
+)
+
+var maxAge = regexp.MustCompile(`max-age=(\d+)`)
+
+var (
+	ErrInvalidIDToken = errors.New("id token is not valid")
+	// ErrInvalidCode means the provider refused the authorization code, it
+	// was used already, expired or issued to someone else.
+	ErrInvalidCode = errors.New("authorization code was refused")
+)
+
+// SecretFunc returns the client secret sent with the authorization code.
+type SecretFunc func() (string, error)
+
+// StaticSecret is a client secret that does not change.
+func StaticSecret(secret string) SecretFunc {
+	return func() (string, error) { return secret, nil }
+}
+
+// IDToken holds the claims of a verified ID token.
+type IDToken struct {
+	Email         string `json:"email"`
+	EmailVerified Bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
+	Nonce         string `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// Bool is a boolean claim, Apple sends them as strings.
+type Bool bool
+
+func (b *Bool) UnmarshalJSON(data []byte) error {
+	value, err := strconv.ParseBool(strings.Trim(string(data), `"`))
+	if err != nil {
+		return fmt.Errorf("invalid boolean claim %s", data)
+	}
+
+	*b = Bool(value)
+	return nil
+}
+
+// Provider signs users in with the authorization code flow of an OpenID
+// Connect issuer, the code is bound to the request with PKCE and the ID token
+// with a nonce. The endpoints are discovered on first use, the signing keys
+// are cached for as long as the issuer allows.
+type Provider struct {
+	Name        string
+	Issuer      string
+	ClientID    string
+	Secret      SecretFunc
+	RedirectURL string
+	Scopes      []string
+	// AuthParams are added to the authorization URL.
+	AuthParams map[string]string
+	Client     *http.Client
+
+	mu          sync.Mutex
+	endpoints   *discovery
+	keys        map[string]*rsa.PublicKey
+	keysExpire  time.Time
+	keysFetched time.Time
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+func NewProvider(name, issuer, clientID string, secret SecretFunc, redirectURL string, scopes ...string) *Provider {
+	return &Provider{
+		Name:        name,
+		Issuer:      issuer,
+		ClientID:    clientID,
+		Secret:      secret,
+		RedirectURL: redirectURL,
+		Scopes:      append([]string{"openid"}, scopes...),
+		Client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// AuthCodeURL is where the user is sent to sign in. The verifier stays with
+// us, only its challenge is sent along.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	config, err := p.config(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	opts := []oauth2.AuthCodeOption{
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	}
+	for key, value := range p.AuthParams {
+		opts = append(opts, oauth2.SetAuthURLParam(key, value))
+	}
+
+	return config.AuthCodeURL(state, opts...), nil
+}
+
+// Exchange trades the authorization code for the ID token of the user and
+// verifies it was issued for the nonce.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*IDToken, error) {
+	config, err := p.config(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := p.Secret()
+	if err != nil {
+		return nil, fmt.Errorf("%s client secret: %w", p.Name, err)
+	}
+	config.ClientSecret = secret
+
+	token, err := config.Exchange(context.WithValue(ctx, oauth2.HTTPClient, p.Client), code, oauth2.VerifierOption(verifier))
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) && retrieveErr.Response.StatusCode < http.StatusInternalServerError {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCode, retrieveErr.ErrorCode)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s token exchange: %w", p.Name, err)
+	}
+
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, fmt.Errorf("%w: no id token in the response", ErrInvalidIDToken)
+	}
+
+	return p.Verify(ctx, rawIDToken, nonce)
+}
+
+// Verify checks the signature, audience, issuer, lifetime and nonce of the
+// ID token.
+func (p *Provider) Verify(ctx context.Context, rawIDToken, nonce string) (*IDToken, error) {
+	claims := &IDToken{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}))
+
+	_, err := parser.ParseWithClaims(rawIDToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("no key id")
+		}
+
+		return p.publicKey(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+
+	if !claims.VerifyAudience(p.ClientID, true) {
+		return nil, fmt.Errorf("%w: audience is not %s", ErrInvalidIDToken, p.ClientID)
+	}
+	if !claims.VerifyIssuer(p.Issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer %s", ErrInvalidIDToken, claims.Issuer)
+	}
+	if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w: no expiry", ErrInvalidIDToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidIDToken)
+	}
+
+	return claims, nil
+}
+
+func (p *Provider) config(ctx context.Context) (*oauth2.Config, error) {
+	endpoints, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &oauth2.Config{
+		ClientID:    p.ClientID,
+		RedirectURL: p.RedirectURL,
+		Scopes:      p.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   endpoints.AuthorizationEndpoint,
+			TokenURL:  endpoints.TokenEndpoint,
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}, nil
+}
+
+// discover fetches the endpoints of the issuer once.
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.endpoints != nil {
+		return p.endpoints, nil
+	}
+
+	var endpoints discovery
+	if _, err := p.get(ctx, strings.TrimSuffix(p.Issuer, "/")+"/.well-known/openid-configuration", &endpoints); err != nil {
+		return nil, fmt.Errorf("%s discovery: %w", p.Name, err)
+	}
+	if endpoints.Issuer != p.Issuer {
+		return nil, fmt.Errorf("%s discovery: issuer is %s", p.Name, endpoints.Issuer)
+	}
+
+	p.endpoints = &endpoints
+	return p.endpoints, nil
+}
+
+// publicKey returns the key the token was signed with, fetching the keys
+// when the cached ones expired or do not have the key.
+func (p *Provider) publicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	endpoints, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if key, ok := p.keys[kid]; ok && now.Before(p.keysExpire) {
+		return key, nil
+	}
+	if now.Before(p.keysExpire) && now.Sub(p.keysFetched) < keysRefetchInterval {
+		return nil, fmt.Errorf("unknown key id %s", kid)
+	}
+
+	if err := p.fetchKeys(ctx, endpoints.JWKSURI); err != nil {
+		return nil, err
+	}
+
+	key, ok := p.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %s", kid)
+	}
+
+	return key, nil
+}
+
+func (p *Provider) fetchKeys(ctx context.Context, jwksURI string) error {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	header, err := p.get(ctx, jwksURI, &set)
+	if err != nil {
+		return fmt.Errorf("%s keys: %w", p.Name, err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return fmt.Errorf("%s key %s: %w", p.Name, jwk.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return fmt.Errorf("%s key %s: %w", p.Name, jwk.Kid, err)
+		}
+
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	ttl := defaultKeysTTL
+	if match := maxAge.FindStringSubmatch(header.Get("Cache-Control")); match != nil {
+		if seconds, err := strconv.Atoi(match[1]); err == nil {
+			ttl = time.Duration(seconds) * time.Second
+		}
+	}
+
+	p.keys = keys
+	p.keysFetched = time.Now()
+	p.keysExpire = p.keysFetched.Add(ttl)
+
+	return nil
+}
+
+func (p *Provider) get(ctx context.Context, url string, v any) (http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+
+	return resp.Header, nil
+}
@@ -0,0 +1,126 @@
+package oidc_test
+
+import (
+	"aulway/internal/oidc"
+	"aulway/internal/oidc/oidctest"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestProviderSignsInWithPKCEAndNonce(t *testing.T) {
+	issuer := oidctest.NewIssuer(t, "aulway-web", "client-secret")
+	provider := oidc.NewProvider("stub", issuer.URL, "aulway-web", oidc.StaticSecret("client-secret"), "https://api.aulway.kz/auth/oidc/stub/callback", "email")
+	provider.AuthParams = map[string]string{"response_mode": "form_post"}
+	ctx := context.Background()
+
+	verifier := oauth2.GenerateVerifier()
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	require.NoError(t, err)
+
+	query, err := url.Parse(authURL)
+	require.NoError(t, err)
+	require.Equal(t, issuer.URL+"/authorize", query.Scheme+"://"+query.Host+query.Path)
+	require.Equal(t, "openid email", query.Query().Get("scope"))
+	require.Equal(t, "form_post", query.Query().Get("response_mode"))
+	require.NotContains(t, authURL, verifier)
+
+	user := oidctest.User{Subject: "sub-1", Email: "rider@example.com", EmailVerified: true, Name: "Aruzhan Seitkali"}
+	code, state := issuer.Authorize(t, authURL, user)
+	require.Equal(t, "state-1", state)
+
+	// the code is bound to the verifier
+	_, err = provider.Exchange(ctx, code, oauth2.GenerateVerifier(), "nonce-1")
+	require.ErrorIs(t, err, oidc.ErrInvalidCode)
+
+	code, _ = issuer.Authorize(t, authURL, user)
+	token, err := provider.Exchange(ctx, code, verifier, "nonce-1")
+	require.NoError(t, err)
+	require.Equal(t, "sub-1", token.Subject)
+	require.Equal(t, "rider@example.com", token.Email)
+	require.True(t, bool(token.EmailVerified))
+	require.Equal(t, "Aruzhan Seitkali", token.Name)
+
+	// and works once
+	_, err = provider.Exchange(ctx, code, verifier, "nonce-1")
+	require.ErrorIs(t, err, oidc.ErrInvalidCode)
+
+	// the ID token has to be issued for the nonce of the request
+	code, _ = issuer.Authorize(t, authURL, user)
+	_, err = provider.Exchange(ctx, code, verifier, "nonce-2")
+	require.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+}
+
+func TestProviderVerifiesIDTokens(t *testing.T) {
+	issuer := oidctest.NewIssuer(t, "aulway-web", "client-secret")
+	provider := oidc.NewProvider("stub", issuer.URL, "aulway-web", oidc.StaticSecret("client-secret"), "")
+	ctx := context.Background()
+
+	claims := func(changes jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"iss":   issuer.URL,
+			"aud":   "aulway-web",
+			"sub":   "sub-1",
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": "nonce-1",
+			"email": "rider@example.com",
+			// Apple sends booleans as strings
+			"email_verified": "true",
+		}
+		for key, value := range changes {
+			if value == nil {
+				delete(c, key)
+				continue
+			}
+			c[key] = value
+		}
+
+		return c
+	}
+
+	token, err := provider.Verify(ctx, issuer.IDToken(t, claims(nil)), "nonce-1")
+	require.NoError(t, err)
+	require.True(t, bool(token.EmailVerified))
+
+	for name, changes := range map[string]jwt.MapClaims{
+		"wrong audience": {"aud": "another-client"},
+		"wrong issuer":   {"iss": "https://issuer.example.com"},
+		"expired":        {"exp": time.Now().Add(-time.Minute).Unix()},
+		"no expiry":      {"exp": nil},
+		"no subject":     {"sub": nil},
+		"no nonce":       {"nonce": nil},
+	} {
+		_, err := provider.Verify(ctx, issuer.IDToken(t, claims(changes)), "nonce-1")
+		require.ErrorIs(t, err, oidc.ErrInvalidIDToken, name)
+	}
+
+	// a token signed with another key
+	other := oidctest.NewIssuer(t, "aulway-web", "client-secret")
+	_, err = provider.Verify(ctx, other.IDToken(t, claims(nil)), "nonce-1")
+	require.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+}
+
+func TestAppleSecretIsSignedByTheTeamKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	secret, err := oidc.AppleSecret("TEAM123456", "KEY1234567", "kz.aulway.web", key)()
+	require.NoError(t, err)
+
+	claims := &jwt.RegisteredClaims{}
+	parsed, err := jwt.ParseWithClaims(secret, claims, func(*jwt.Token) (interface{}, error) { return &key.PublicKey, nil })
+	require.NoError(t, err)
+	require.Equal(t, "KEY1234567", parsed.Header["kid"])
+	require.Equal(t, "TEAM123456", claims.Issuer)
+	require.Equal(t, "kz.aulway.web", claims.Subject)
+	require.True(t, claims.VerifyAudience(oidc.AppleIssuer, true))
+}
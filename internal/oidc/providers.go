@@ -0,0 +1,84 @@
+package oidc
+
+import (
+	"aulway/internal/utils/config"
+	"crypto/ecdsa"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Names of the providers users can sign in with.
+const (
+	Google = "google"
+	Apple  = "apple"
+)
+
+const (
+	GoogleIssuer = "https://accounts.google.com"
+	AppleIssuer  = "https://appleid.apple.com"
+)
+
+// appleSecretTTL is how long a client secret signed for Apple is valid, one
+// is signed for every exchange.
+const appleSecretTTL = 5 * time.Minute
+
+func NewGoogle(clientID, clientSecret, redirectURL string) *Provider {
+	return NewProvider(Google, GoogleIssuer, clientID, StaticSecret(clientSecret), redirectURL, "email", "profile")
+}
+
+// NewApple signs in with Apple. Apple posts the code back to the redirect
+// URL, along with the name of the user the first time they sign in.
+func NewApple(clientID, teamID, keyID string, key *ecdsa.PrivateKey, redirectURL string) *Provider {
+	provider := NewProvider(Apple, AppleIssuer, clientID, AppleSecret(teamID, keyID, clientID, key), redirectURL, "email", "name")
+	provider.AuthParams = map[string]string{"response_mode": "form_post"}
+
+	return provider
+}
+
+// AppleSecret signs client secrets with the Sign in with Apple key of the
+// team, Apple takes no static ones.
+func AppleSecret(teamID, keyID, clientID string, key *ecdsa.PrivateKey) SecretFunc {
+	return func() (string, error) {
+		now := time.Now()
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.RegisteredClaims{
+			Issuer:    teamID,
+			Subject:   clientID,
+			Audience:  jwt.ClaimStrings{AppleIssuer},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(appleSecretTTL)),
+		})
+		token.Header["kid"] = keyID
+
+		return token.SignedString(key)
+	}
+}
+
+// FromConfig returns the providers that have a client configured by name.
+func FromConfig(cfg config.OIDC) (map[string]*Provider, error) {
+	providers := make(map[string]*Provider)
+
+	if cfg.GoogleClientID != "" {
+		providers[Google] = NewGoogle(cfg.GoogleClientID, cfg.GoogleClientSecret, callbackURL(cfg.BaseURL, Google))
+	}
+
+	if cfg.AppleClientID != "" {
+		pem, err := os.ReadFile(cfg.AppleKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("apple sign in: read key: %w", err)
+		}
+		key, err := jwt.ParseECPrivateKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("apple sign in: parse key: %w", err)
+		}
+		providers[Apple] = NewApple(cfg.AppleClientID, cfg.AppleTeamID, cfg.AppleKeyID, key, callbackURL(cfg.BaseURL, Apple))
+	}
+
+	return providers, nil
+}
+
+func callbackURL(baseURL, provider string) string {
+	return baseURL + "/auth/oidc/" + provider + "/callback"
+}
@@ -0,0 +1,28 @@
+package push
+
+import (
+	"aulway/internal/firebase"
+	"aulway/internal/utils/config"
+	"context"
+	"fmt"
+)
+
+// FromConfig returns the FCM sender of the configured Firebase project, nil
+// when push notifications are not configured.
+func FromConfig(ctx context.Context, cfg config.Firebase) (Sender, error) {
+	if cfg.Credentials == "" {
+		return nil, nil
+	}
+
+	app, err := firebase.NewApp(ctx, cfg.Credentials)
+	if err != nil {
+		return nil, fmt.Errorf("firebase app: %w", err)
+	}
+
+	client, err := app.Messaging(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("firebase messaging: %w", err)
+	}
+
+	return NewFCM(client), nil
+}
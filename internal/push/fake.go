@@ -0,0 +1,47 @@
+package push
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Fake keeps the notifications it is given instead of sending them, for
+// tests and local runs without Firebase. Tokens listed in Stale are reported
+// as no longer registered.
+type Fake struct {
+	Stale []string
+
+	mu   sync.Mutex
+	sent []Sent
+}
+
+// Sent is a notification the fake was given.
+type Sent struct {
+	Tokens       []string
+	Notification Notification
+}
+
+func (f *Fake) Send(_ context.Context, tokens []string, notification Notification) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sent = append(f.sent, Sent{Tokens: slices.Clone(tokens), Notification: notification})
+
+	stale := make([]string, 0)
+	for _, token := range tokens {
+		if slices.Contains(f.Stale, token) {
+			stale = append(stale, token)
+		}
+	}
+
+	return stale, nil
+}
+
+// Sent returns the notifications given so far.
+func (f *Fake) Sent() []Sent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.sent)
+}
@@ -0,0 +1,74 @@
+// Package push delivers push notifications to the devices of users through
+// Firebase Cloud Messaging.
+package push
+
+import (
+	"context"
+	"firebase.google.com/go/v4/messaging"
+	"fmt"
+)
+
+// maxTokens is the most devices FCM takes in one multicast.
+const maxTokens = 500
+
+// Notification is shown by the device. Data is handed to the app, it names
+// what the notification is about.
+type Notification struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// Sender delivers a notification to device tokens. It returns the tokens
+// that are no longer registered, they should be forgotten.
+type Sender interface {
+	Send(ctx context.Context, tokens []string, notification Notification) (stale []string, err error)
+}
+
+// FCM sends through Firebase Cloud Messaging.
+type FCM struct {
+	client *messaging.Client
+}
+
+func NewFCM(client *messaging.Client) *FCM {
+	return &FCM{client: client}
+}
+
+func (f *FCM) Send(ctx context.Context, tokens []string, notification Notification) ([]string, error) {
+	stale := make([]string, 0)
+	failed := 0
+
+	for start := 0; start < len(tokens); start += maxTokens {
+		batch := tokens[start:min(start+maxTokens, len(tokens))]
+
+		// one HTTP v1 request per token, the legacy batch endpoint is gone
+		response, err := f.client.SendEachForMulticast(ctx, &messaging.MulticastMessage{
+			Tokens: batch,
+			Data:   notification.Data,
+			Notification: &messaging.Notification{
+				Title: notification.Title,
+				Body:  notification.Body,
+			},
+		})
+		if err != nil {
+			return stale, fmt.Errorf("fcm multicast: %w", err)
+		}
+
+		for i, result := range response.Responses {
+			if result.Success {
+				continue
+			}
+			if messaging.IsRegistrationTokenNotRegistered(result.Error) || messaging.IsInvalidArgument(result.Error) {
+				stale = append(stale, batch[i])
+				continue
+			}
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return stale, fmt.Errorf("fcm: %d of %d pushes failed", failed, len(tokens))
+	}
+
+	return stale, nil
+}
@@ -0,0 +1,78 @@
+// Package ratelimit guards the auth endpoints against brute force: sliding
+// window limits on requests and progressive lockout of accounts after failed
+// attempts, both kept in Redis so every instance sees the same counts.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindow counts the hits of a key over the last window and adds one
+// when there is room. It returns 1 and 0 when allowed, otherwise 0 and the
+// milliseconds until the oldest hit leaves the window.
+var slidingWindow = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+if redis.call('ZCARD', key) >= limit then
+	local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+	return {0, tonumber(oldest[2]) + window - now}
+end
+
+redis.call('ZADD', key, now, ARGV[4])
+redis.call('PEXPIRE', key, window)
+return {1, 0}
+`)
+
+// Limiter allows a number of hits per key over a sliding window.
+type Limiter struct {
+	redis *redis.Client
+}
+
+func NewLimiter(redis *redis.Client) *Limiter {
+	return &Limiter{redis: redis}
+}
+
+// Allow records a hit of the key unless it already had limit hits within
+// the window, then it returns false and how long until the next is allowed.
+func (l *Limiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	now := time.Now().UnixMilli()
+	member := strconv.FormatInt(now, 10) + "-" + strconv.FormatInt(rand.Int63(), 36)
+
+	result, err := slidingWindow.Run(ctx, l.redis, []string{"ratelimit:" + key}, now, window.Milliseconds(), limit, member).Int64Slice()
+	if err != nil {
+		return false, 0, fmt.Errorf("rate limit: %w", err)
+	}
+
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond, nil
+}
+
+// SetRetryAfter tells the client how long to wait, in whole seconds rounded
+// up so it does not come back too early.
+func SetRetryAfter(header http.Header, wait time.Duration) {
+	header.Set("Retry-After", strconv.Itoa(int(math.Ceil(max(wait, time.Second).Seconds()))))
+}
+
+// RetryAfter sets Retry-After when err is a lockout and reports whether it
+// was one, the response should then be 429.
+func RetryAfter(header http.Header, err error) bool {
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+
+	SetRetryAfter(header, locked.RetryAfter)
+	return true
+}
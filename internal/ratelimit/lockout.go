@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// failureMemory is how long failed attempts are remembered, a lockout grows
+// with the failures within it.
+const failureMemory = 24 * time.Hour
+
+var ErrLocked = errors.New("too many failed attempts, try again later")
+
+// LockedError is returned while an account is locked out.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s in %s", ErrLocked.Error(), e.RetryAfter.Round(time.Second))
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrLocked
+}
+
+// Lockout locks an account after every Threshold failed attempts, for Base
+// the first time and twice as long each time after, up to Max. A success
+// clears the failures. A nil Lockout never locks.
+type Lockout struct {
+	redis     *redis.Client
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+}
+
+func NewLockout(redis *redis.Client, threshold int, base, max time.Duration) *Lockout {
+	return &Lockout{
+		redis:     redis,
+		Threshold: threshold,
+		Base:      base,
+		Max:       max,
+	}
+}
+
+// Check returns a LockedError while the account is locked.
+func (l *Lockout) Check(ctx context.Context, account string) error {
+	if l == nil {
+		return nil
+	}
+
+	ttl, err := l.redis.PTTL(ctx, lockedKey(account)).Result()
+	if err != nil {
+		return fmt.Errorf("lockout check: %w", err)
+	}
+	if ttl > 0 {
+		return &LockedError{RetryAfter: ttl}
+	}
+
+	return nil
+}
+
+// Fail records a failed attempt on the account and returns a LockedError
+// when it locks the account.
+func (l *Lockout) Fail(ctx context.Context, account string) error {
+	if l == nil {
+		return nil
+	}
+
+	failures, err := l.redis.Incr(ctx, failuresKey(account)).Result()
+	if err != nil {
+		return fmt.Errorf("lockout failure: %w", err)
+	}
+	l.redis.Expire(ctx, failuresKey(account), failureMemory)
+
+	lock := l.duration(int(failures))
+	if lock == 0 {
+		return nil
+	}
+
+	if err := l.redis.Set(ctx, lockedKey(account), failures, lock).Err(); err != nil {
+		return fmt.Errorf("lockout failure: %w", err)
+	}
+
+	return &LockedError{RetryAfter: lock}
+}
+
+// Reset forgets the failed attempts of the account.
+func (l *Lockout) Reset(ctx context.Context, account string) {
+	if l == nil {
+		return
+	}
+
+	l.redis.Del(ctx, failuresKey(account), lockedKey(account))
+}
+
+// duration is how long the failures lock the account for, 0 when they do
+// not.
+func (l *Lockout) duration(failures int) time.Duration {
+	if l.Threshold <= 0 || failures == 0 || failures%l.Threshold != 0 {
+		return 0
+	}
+
+	lock := l.Base
+	for level := failures / l.Threshold; level > 1 && lock < l.Max; level-- {
+		lock *= 2
+	}
+
+	return min(lock, l.Max)
+}
+
+func failuresKey(account string) string {
+	return "lockout:failures:" + account
+}
+
+func lockedKey(account string) string {
+	return "lockout:locked:" + account
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func testRedis(t *testing.T) *redis.Client {
+	t.Helper()
+
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR is not set")
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	require.NoError(t, client.Ping(context.Background()).Err())
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestLockoutDoublesUpToMax(t *testing.T) {
+	lockout := NewLockout(nil, 3, time.Minute, 10*time.Minute)
+
+	for failures, want := range map[int]time.Duration{
+		1:  0,
+		2:  0,
+		3:  time.Minute,
+		4:  0,
+		6:  2 * time.Minute,
+		9:  4 * time.Minute,
+		12: 8 * time.Minute,
+		15: 10 * time.Minute,
+		90: 10 * time.Minute,
+	} {
+		require.Equal(t, want, lockout.duration(failures), failures)
+	}
+}
+
+func TestRetryAfterRoundsUp(t *testing.T) {
+	header := http.Header{}
+	require.False(t, RetryAfter(header, fmt.Errorf("other")))
+	require.Empty(t, header.Get("Retry-After"))
+
+	require.True(t, RetryAfter(header, fmt.Errorf("signin: %w", &LockedError{RetryAfter: 1500 * time.Millisecond})))
+	require.Equal(t, "2", header.Get("Retry-After"))
+
+	SetRetryAfter(header, 10*time.Millisecond)
+	require.Equal(t, "1", header.Get("Retry-After"))
+
+	var disabled *Lockout
+	require.NoError(t, disabled.Check(context.Background(), "email:a@example.com"))
+	require.NoError(t, disabled.Fail(context.Background(), "email:a@example.com"))
+}
+
+func TestLimiterSlidesWindow(t *testing.T) {
+	limiter := NewLimiter(testRedis(t))
+	ctx := context.Background()
+	key := fmt.Sprintf("test:%d", rand.Int())
+
+	for range 3 {
+		allowed, _, err := limiter.Allow(ctx, key, 3, 500*time.Millisecond)
+		require.NoError(t, err)
+		require.True(t, allowed)
+	}
+
+	allowed, wait, err := limiter.Allow(ctx, key, 3, 500*time.Millisecond)
+	require.NoError(t, err)
+	require.False(t, allowed)
+	require.Greater(t, wait, time.Duration(0))
+	require.LessOrEqual(t, wait, 500*time.Millisecond)
+
+	time.Sleep(wait + 50*time.Millisecond)
+	allowed, _, err = limiter.Allow(ctx, key, 3, 500*time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, allowed)
+}
+
+func TestLockoutLocksAndResets(t *testing.T) {
+	lockout := NewLockout(testRedis(t), 2, time.Minute, time.Hour)
+	ctx := context.Background()
+	account := fmt.Sprintf("email:lockout-%d@example.com", rand.Int())
+
+	require.NoError(t, lockout.Fail(ctx, account))
+	require.ErrorIs(t, lockout.Fail(ctx, account), ErrLocked)
+
+	var locked *LockedError
+	require.ErrorAs(t, lockout.Check(ctx, account), &locked)
+	require.InDelta(t, time.Minute.Seconds(), locked.RetryAfter.Seconds(), 2)
+
+	lockout.Reset(ctx, account)
+	require.NoError(t, lockout.Check(ctx, account))
+}
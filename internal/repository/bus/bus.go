@@ -19,11 +19,39 @@ func New(db *gorm.DB) Repository {
 }
 
 func (repo *Repository) Create(ctx context.Context, bus *domain.Bus) error {
-	if err := repo.db.WithContext(ctx).Create(&bus).Error; err != nil {
-		return err
+	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&bus).Error; err != nil {
+			return err
+		}
+
+		if len(bus.Seats) == 0 {
+			return nil
+		}
+
+		for i := range bus.Seats {
+			bus.Seats[i].BusId = bus.Id
+		}
+
+		if err := tx.Create(&bus.Seats).Error; err != nil {
+			return fmt.Errorf("create bus seats error: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (repo *Repository) GetSeats(ctx context.Context, busID string) ([]domain.Seat, error) {
+	seats := make([]domain.Seat, 0)
+
+	err := repo.db.WithContext(ctx).
+		Where("bus_id = ?", busID).
+		Order("seat_row ASC, seat_column ASC").
+		Find(&seats).Error
+	if err != nil {
+		return nil, fmt.Errorf("get bus seats error: %w", err)
 	}
 
-	return nil
+	return seats, nil
 }
 
 func (repo *Repository) Get(ctx context.Context, id string) (*domain.Bus, error) {
@@ -0,0 +1,71 @@
+package device
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/repository/errs"
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Repository {
+	return Repository{db: db}
+}
+
+// Save registers the device for its user, a token registered before is
+// moved to the user and platform it is saved with now.
+func (repo *Repository) Save(ctx context.Context, device *domain.Device) error {
+	err := repo.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "token"}},
+			DoUpdates: clause.AssignmentColumns([]string{"user_id", "platform", "updated_at"}),
+		}).
+		Create(device).Error
+	if err != nil {
+		return fmt.Errorf("save device error: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *Repository) ListByUser(ctx context.Context, userID string) ([]domain.Device, error) {
+	devices := make([]domain.Device, 0)
+
+	if err := repo.db.WithContext(ctx).Where("user_id = ?", userID).Order("updated_at DESC").Find(&devices).Error; err != nil {
+		return nil, fmt.Errorf("list devices error: %w", err)
+	}
+
+	return devices, nil
+}
+
+// Delete removes a device of the user, ErrRecordNotFound when the user has
+// no such device.
+func (repo *Repository) Delete(ctx context.Context, userID, token string) error {
+	result := repo.db.WithContext(ctx).Where("user_id = ? AND token = ?", userID, token).Delete(&domain.Device{})
+	if result.Error != nil {
+		return fmt.Errorf("delete device error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errs.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DeleteTokens forgets tokens FCM no longer knows, whoever they belong to.
+func (repo *Repository) DeleteTokens(ctx context.Context, tokens []string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	if err := repo.db.WithContext(ctx).Where("token IN ?", tokens).Delete(&domain.Device{}).Error; err != nil {
+		return fmt.Errorf("delete device tokens error: %w", err)
+	}
+
+	return nil
+}
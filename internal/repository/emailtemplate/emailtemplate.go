@@ -0,0 +1,129 @@
+package emailtemplate
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/repository/errs"
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Repository {
+	return Repository{db: db}
+}
+
+func (repo *Repository) List(ctx context.Context) ([]domain.EmailTemplate, error) {
+	templates := make([]domain.EmailTemplate, 0)
+
+	if err := repo.db.WithContext(ctx).Order("name, locale").Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("list email templates error: %w", err)
+	}
+
+	return templates, nil
+}
+
+func (repo *Repository) Get(ctx context.Context, name, locale string) (*domain.EmailTemplate, error) {
+	template := new(domain.EmailTemplate)
+
+	if err := repo.db.WithContext(ctx).First(template, "name = ? AND locale = ?", name, locale).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrRecordNotFound
+		}
+
+		return nil, fmt.Errorf("get email template error: %w", err)
+	}
+
+	return template, nil
+}
+
+// Save stores the template as its next version and makes it the current
+// one. Two saves racing for a version make one of them fail.
+func (repo *Repository) Save(ctx context.Context, template *domain.EmailTemplate) error {
+	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var last int
+		err := tx.Model(&domain.EmailTemplateVersion{}).
+			Where("name = ? AND locale = ?", template.Name, template.Locale).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&last).Error
+		if err != nil {
+			return fmt.Errorf("get email template version error: %w", err)
+		}
+
+		template.Version = last + 1
+		template.UpdatedAt = time.Now()
+
+		version := &domain.EmailTemplateVersion{
+			Name:      template.Name,
+			Locale:    template.Locale,
+			Version:   template.Version,
+			Subject:   template.Subject,
+			Body:      template.Body,
+			CreatedBy: template.UpdatedBy,
+			CreatedAt: template.UpdatedAt,
+		}
+		if err := tx.Create(version).Error; err != nil {
+			return fmt.Errorf("create email template version error: %w", err)
+		}
+
+		err = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}, {Name: "locale"}},
+			DoUpdates: clause.AssignmentColumns([]string{"version", "subject", "body", "updated_by", "updated_at"}),
+		}).Create(template).Error
+		if err != nil {
+			return fmt.Errorf("save email template error: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Delete removes the edited template, the shipped one is used again. Its
+// versions are kept.
+func (repo *Repository) Delete(ctx context.Context, name, locale string) error {
+	res := repo.db.WithContext(ctx).Delete(&domain.EmailTemplate{}, "name = ? AND locale = ?", name, locale)
+	if res.Error != nil {
+		return fmt.Errorf("delete email template error: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return errs.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Versions lists the saved versions of a template, newest first.
+func (repo *Repository) Versions(ctx context.Context, name, locale string) ([]domain.EmailTemplateVersion, error) {
+	versions := make([]domain.EmailTemplateVersion, 0)
+
+	err := repo.db.WithContext(ctx).
+		Where("name = ? AND locale = ?", name, locale).
+		Order("version DESC").
+		Find(&versions).Error
+	if err != nil {
+		return nil, fmt.Errorf("list email template versions error: %w", err)
+	}
+
+	return versions, nil
+}
+
+func (repo *Repository) Version(ctx context.Context, name, locale string, version int) (*domain.EmailTemplateVersion, error) {
+	v := new(domain.EmailTemplateVersion)
+
+	err := repo.db.WithContext(ctx).First(v, "name = ? AND locale = ? AND version = ?", name, locale, version).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrRecordNotFound
+		}
+
+		return nil, fmt.Errorf("get email template version error: %w", err)
+	}
+
+	return v, nil
+}
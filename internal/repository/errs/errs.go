@@ -3,8 +3,12 @@ package errs
 import "errors"
 
 var (
-	ErrRecordNotFound       = errors.New("record not found")
-	EmailAlreadyExists      = errors.New("email already exists")
-	ErrInvalidEmailPassword = "invalid email or password"
-	ErrTicketOutOfStock     = "tickets are out of stock"
+	ErrRecordNotFound         = errors.New("record not found")
+	EmailAlreadyExists        = errors.New("email already exists")
+	PhoneAlreadyExists        = errors.New("phone already belongs to another user")
+	FirebaseUserAlreadyLinked = errors.New("firebase user is already linked to another user")
+	IdentityAlreadyLinked     = errors.New("provider account is already linked to another user")
+	ProviderAlreadyLinked     = errors.New("user already has an account of this provider linked")
+	ErrInvalidEmailPassword   = "invalid email or password"
+	ErrTicketOutOfStock       = "tickets are out of stock"
 )
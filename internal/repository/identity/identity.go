@@ -0,0 +1,119 @@
+package identity
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/repository/errs"
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Repository {
+	return Repository{db: db}
+}
+
+func (repo *Repository) Create(ctx context.Context, identity *domain.UserIdentity) error {
+	if err := repo.db.WithContext(ctx).Create(identity).Error; err != nil {
+		if strings.Contains(err.Error(), "idx_user_identities_subject") {
+			return errs.IdentityAlreadyLinked
+		}
+		if strings.Contains(err.Error(), "idx_user_identities_user_provider") {
+			return errs.ProviderAlreadyLinked
+		}
+
+		return fmt.Errorf("create identity error: %w", err)
+	}
+
+	return nil
+}
+
+// CreateWithUser signs up the user with the identity linked.
+func (repo *Repository) CreateWithUser(ctx context.Context, user *domain.User, identity *domain.UserIdentity) error {
+	err := repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(identity).Error
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "idx_user_identities_subject") {
+			return errs.IdentityAlreadyLinked
+		}
+		if strings.Contains(err.Error(), "idx_users_email") {
+			return errs.EmailAlreadyExists
+		}
+
+		return fmt.Errorf("create user with identity error: %w", err)
+	}
+
+	return nil
+}
+
+// GetBySubject returns the identity of the account at the provider.
+func (repo *Repository) GetBySubject(ctx context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	var identity domain.UserIdentity
+
+	err := repo.db.WithContext(ctx).First(&identity, "provider = ? AND subject = ?", provider, subject).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrRecordNotFound
+		}
+
+		return nil, fmt.Errorf("get identity error: %w", err)
+	}
+
+	return &identity, nil
+}
+
+// Touch records a sign-in with the identity.
+func (repo *Repository) Touch(ctx context.Context, id string) error {
+	err := repo.db.WithContext(ctx).
+		Model(&domain.UserIdentity{}).
+		Where("id = ?", id).
+		Update("last_used_at", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("touch identity error: %w", err)
+	}
+
+	return nil
+}
+
+// ListByUser returns the identities linked to the user, the first linked
+// first.
+func (repo *Repository) ListByUser(ctx context.Context, userID string) ([]domain.UserIdentity, error) {
+	identities := make([]domain.UserIdentity, 0)
+
+	err := repo.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&identities).Error
+	if err != nil {
+		return nil, fmt.Errorf("list identities error: %w", err)
+	}
+
+	return identities, nil
+}
+
+// Delete unlinks the identity of the provider from the user,
+// ErrRecordNotFound when there is none.
+func (repo *Repository) Delete(ctx context.Context, userID, provider string) error {
+	res := repo.db.WithContext(ctx).
+		Where("user_id = ? AND provider = ?", userID, provider).
+		Delete(&domain.UserIdentity{})
+	if res.Error != nil {
+		return fmt.Errorf("delete identity error: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return errs.ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package ledger
+
+import (
+	"aulway/internal/domain"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Repository {
+	return Repository{db: db}
+}
+
+// Append adds an entry to the ledger, the table does not allow any other write.
+func (repo *Repository) Append(ctx context.Context, tx *gorm.DB, entry *domain.LedgerEntry) error {
+	if entry.ID == "" {
+		id, _ := uuid.NewV7()
+		entry.ID = id.String()
+	}
+
+	if err := tx.WithContext(ctx).Create(entry).Error; err != nil {
+		return fmt.Errorf("append ledger entry error: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *Repository) GetByPaymentID(ctx context.Context, paymentID string) ([]domain.LedgerEntry, error) {
+	entries := make([]domain.LedgerEntry, 0)
+
+	err := repo.db.WithContext(ctx).
+		Where("payment_id = ?", paymentID).
+		Order("created_at ASC, id ASC").
+		Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("get ledger entries error: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Sum returns the total of the entries of a payment with the given types.
+func (repo *Repository) Sum(ctx context.Context, tx *gorm.DB, paymentID string, types ...string) (int, error) {
+	var total int
+
+	err := tx.WithContext(ctx).
+		Model(&domain.LedgerEntry{}).
+		Where("payment_id = ? AND entry_type IN ?", paymentID, types).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&total).Error
+	if err != nil {
+		return 0, fmt.Errorf("sum ledger entries error: %w", err)
+	}
+
+	return total, nil
+}
@@ -0,0 +1,110 @@
+package notification
+
+import (
+	"aulway/internal/domain"
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Repository {
+	return Repository{db: db}
+}
+
+func (repo *Repository) BeginTransaction() *gorm.DB {
+	return repo.db.Begin()
+}
+
+// recipient is a row of the recipient queries, the seats come aggregated.
+type recipient struct {
+	OrderID     string
+	OrderNumber string
+	UserID      string
+	Email       string
+	RouteID     string
+	StartDate   time.Time
+	Seats       string
+}
+
+func (repo *Repository) recipients(query *gorm.DB) ([]domain.TripRecipient, error) {
+	var rows []recipient
+
+	err := query.
+		Table("orders o").
+		Select("o.id AS order_id, o.number AS order_number, o.user_id, u.email, r.id AS route_id, r.start_date, "+
+			"STRING_AGG(t.seat_number, ',' ORDER BY t.seat_number) AS seats").
+		Joins("JOIN routes r ON r.id = o.route_id").
+		Joins("JOIN users u ON u.id = o.user_id AND u.deleted_at IS NULL").
+		Joins("JOIN tickets t ON t.order_id = o.id AND t.status = ?", "approved").
+		Group("o.id, o.number, o.user_id, u.email, r.id, r.start_date").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make([]domain.TripRecipient, 0, len(rows))
+	for _, row := range rows {
+		recipients = append(recipients, domain.TripRecipient{
+			OrderID:     row.OrderID,
+			OrderNumber: row.OrderNumber,
+			UserID:      row.UserID,
+			Email:       row.Email,
+			RouteID:     row.RouteID,
+			StartDate:   row.StartDate,
+			Seats:       strings.Split(row.Seats, ","),
+		})
+	}
+
+	return recipients, nil
+}
+
+// RouteRecipients lists the orders of a route that still have approved
+// tickets, with their buyers.
+func (repo *Repository) RouteRecipients(ctx context.Context, routeID string) ([]domain.TripRecipient, error) {
+	recipients, err := repo.recipients(repo.db.WithContext(ctx).Where("o.route_id = ?", routeID).Order("o.created_at ASC"))
+	if err != nil {
+		return nil, fmt.Errorf("list route recipients error: %w", err)
+	}
+
+	return recipients, nil
+}
+
+// DueReminders lists up to limit orders with approved tickets on routes
+// departing after from and no later than to that have not been reminded
+// lead ahead of their current departure time. Orders bought less than lead
+// before the departure are left out, their purchase email was recent enough.
+func (repo *Repository) DueReminders(ctx context.Context, lead time.Duration, from, to time.Time, limit int) ([]domain.TripRecipient, error) {
+	minutes := int(lead.Minutes())
+
+	query := repo.db.WithContext(ctx).
+		Where("r.start_date > ? AND r.start_date <= ?", from, to).
+		Where("o.created_at < r.start_date - make_interval(mins => ?)", minutes).
+		Where("NOT EXISTS (SELECT 1 FROM departure_reminders d WHERE d.order_id = o.id AND d.lead_minutes = ? AND d.start_date = r.start_date)", minutes).
+		Order("r.start_date ASC").
+		Limit(limit)
+
+	recipients, err := repo.recipients(query)
+	if err != nil {
+		return nil, fmt.Errorf("list due reminders error: %w", err)
+	}
+
+	return recipients, nil
+}
+
+// RecordReminder stores the reminder in tx and reports whether it is new,
+// false means another run already sent it.
+func (repo *Repository) RecordReminder(ctx context.Context, tx *gorm.DB, reminder *domain.DepartureReminder) (bool, error) {
+	result := tx.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(reminder)
+	if result.Error != nil {
+		return false, fmt.Errorf("record reminder error: %w", result.Error)
+	}
+
+	return result.RowsAffected == 1, nil
+}
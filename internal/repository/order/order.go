@@ -0,0 +1,106 @@
+package order
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/repository/errs"
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Repository {
+	return Repository{db: db}
+}
+
+// NextNumber hands out the next order number, e.g. AW-00000042.
+func (repo *Repository) NextNumber(ctx context.Context, tx *gorm.DB) (string, error) {
+	var seq int64
+	if err := tx.WithContext(ctx).Raw("SELECT nextval('order_number_seq')").Scan(&seq).Error; err != nil {
+		return "", fmt.Errorf("next order number error: %w", err)
+	}
+
+	return fmt.Sprintf("AW-%08d", seq), nil
+}
+
+func (repo *Repository) Create(ctx context.Context, tx *gorm.DB, order *domain.Order) error {
+	if err := tx.WithContext(ctx).Create(order).Error; err != nil {
+		return fmt.Errorf("create order error: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *Repository) Get(ctx context.Context, id string) (*domain.Order, error) {
+	order := new(domain.Order)
+
+	if err := repo.db.WithContext(ctx).First(order, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrRecordNotFound
+		}
+
+		return nil, fmt.Errorf("get order error: %w", err)
+	}
+
+	return order, nil
+}
+
+func (repo *Repository) GetByNumber(ctx context.Context, number string) (*domain.Order, error) {
+	order := new(domain.Order)
+
+	if err := repo.db.WithContext(ctx).First(order, "number = ?", number).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrRecordNotFound
+		}
+
+		return nil, fmt.Errorf("get order error: %w", err)
+	}
+
+	return order, nil
+}
+
+// GetForUpdate reads the order and locks its row until tx ends.
+func (repo *Repository) GetForUpdate(ctx context.Context, tx *gorm.DB, id string) (*domain.Order, error) {
+	order := new(domain.Order)
+
+	if err := tx.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(order, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrRecordNotFound
+		}
+
+		return nil, fmt.Errorf("get order error: %w", err)
+	}
+
+	return order, nil
+}
+
+// RefreshStatus derives the order status from the state of its tickets.
+func (repo *Repository) RefreshStatus(ctx context.Context, tx *gorm.DB, id string) error {
+	err := tx.WithContext(ctx).Exec(`
+		UPDATE orders o
+		SET status = CASE
+				WHEN t.all_cancelled THEN 'cancelled'
+				WHEN t.awaiting THEN 'awaiting'
+				WHEN t.any_cancelled THEN 'partially_cancelled'
+				ELSE 'active'
+			END,
+			updated_at = NOW()
+		FROM (
+			SELECT BOOL_AND(status = 'cancelled') AS all_cancelled,
+				BOOL_OR(status = 'cancelled') AS any_cancelled,
+				BOOL_OR(status = 'awaiting') AS awaiting
+			FROM tickets
+			WHERE order_id = ?
+		) t
+		WHERE o.id = ?`, id, id).Error
+	if err != nil {
+		return fmt.Errorf("refresh order status error: %w", err)
+	}
+
+	return nil
+}
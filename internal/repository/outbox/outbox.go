@@ -0,0 +1,155 @@
+package outbox
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/repository/errs"
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Repository {
+	return Repository{db: db}
+}
+
+// Create stores a message in tx, it is only sent if tx commits.
+func (repo *Repository) Create(ctx context.Context, tx *gorm.DB, message *domain.OutboxMessage) error {
+	if err := tx.WithContext(ctx).Create(message).Error; err != nil {
+		return fmt.Errorf("create outbox message error: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *Repository) Get(ctx context.Context, id string) (*domain.OutboxMessage, error) {
+	message := new(domain.OutboxMessage)
+
+	if err := repo.db.WithContext(ctx).First(message, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrRecordNotFound
+		}
+
+		return nil, fmt.Errorf("get outbox message error: %w", err)
+	}
+
+	return message, nil
+}
+
+// List returns the messages in the given status, newest first, or all of
+// them when status is empty.
+func (repo *Repository) List(ctx context.Context, status string, page, pageSize int) ([]domain.OutboxMessage, error) {
+	messages := make([]domain.OutboxMessage, 0)
+
+	query := repo.db.WithContext(ctx).Order("created_at DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	err := query.Offset((page - 1) * pageSize).Limit(pageSize).Find(&messages).Error
+	if err != nil {
+		return nil, fmt.Errorf("list outbox messages error: %w", err)
+	}
+
+	return messages, nil
+}
+
+// Claim takes up to limit due messages and pushes their next attempt out by
+// lease, so other workers skip them while they are being sent. A worker that
+// dies mid-send leaves the message to be retried once the lease is over.
+func (repo *Repository) Claim(ctx context.Context, limit int, lease time.Duration) ([]domain.OutboxMessage, error) {
+	messages := make([]domain.OutboxMessage, 0)
+
+	err := repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", domain.OutboxPending, time.Now()).
+			Order("next_attempt_at ASC").
+			Limit(limit).
+			Find(&messages).Error
+		if err != nil || len(messages) == 0 {
+			return err
+		}
+
+		ids := make([]string, 0, len(messages))
+		for i := range messages {
+			ids = append(ids, messages[i].ID)
+			messages[i].Attempts++
+		}
+
+		return tx.Model(&domain.OutboxMessage{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"attempts":        gorm.Expr("attempts + 1"),
+				"next_attempt_at": time.Now().Add(lease),
+			}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox messages error: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (repo *Repository) MarkSent(ctx context.Context, id string, at time.Time) error {
+	err := repo.db.WithContext(ctx).
+		Model(&domain.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":     domain.OutboxSent,
+			"sent_at":    at,
+			"last_error": "",
+		}).Error
+	if err != nil {
+		return fmt.Errorf("mark outbox message sent error: %w", err)
+	}
+
+	return nil
+}
+
+// MarkFailed records a failed attempt, the message is retried at next or
+// dead-lettered when dead is set.
+func (repo *Repository) MarkFailed(ctx context.Context, id, lastError string, next time.Time, dead bool) error {
+	updates := map[string]interface{}{
+		"last_error":      lastError,
+		"next_attempt_at": next,
+	}
+	if dead {
+		updates["status"] = domain.OutboxDead
+	}
+
+	err := repo.db.WithContext(ctx).
+		Model(&domain.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+	if err != nil {
+		return fmt.Errorf("mark outbox message failed error: %w", err)
+	}
+
+	return nil
+}
+
+// Requeue puts a message back in the queue with a fresh set of attempts.
+func (repo *Repository) Requeue(ctx context.Context, id string) (*domain.OutboxMessage, error) {
+	result := repo.db.WithContext(ctx).
+		Model(&domain.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":          domain.OutboxPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("requeue outbox message error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, errs.ErrRecordNotFound
+	}
+
+	return repo.Get(ctx, id)
+}
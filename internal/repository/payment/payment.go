@@ -2,8 +2,13 @@ package payment
 
 import (
 	"aulway/internal/domain"
+	"aulway/internal/repository/errs"
 	"context"
+	"errors"
+	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 type Repository struct {
@@ -25,6 +30,9 @@ func (r *Repository) UpdateStatus(ctx context.Context, paymentID string, status
 func (r *Repository) GetByID(ctx context.Context, paymentID string) (*domain.Payment, error) {
 	var payment domain.Payment
 	if err := r.db.WithContext(ctx).Where("id = ?", paymentID).First(&payment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrRecordNotFound
+		}
 		return nil, err
 	}
 	return &payment, nil
@@ -41,3 +49,58 @@ func (r *Repository) Update(ctx context.Context, tx *gorm.DB, updates map[string
 	}
 	return nil
 }
+
+// GetForUpdate reads the payment and locks its row until tx ends.
+func (r *Repository) GetForUpdate(ctx context.Context, tx *gorm.DB, paymentID string) (*domain.Payment, error) {
+	var payment domain.Payment
+	if err := tx.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", paymentID).First(&payment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("get payment error: %w", err)
+	}
+	return &payment, nil
+}
+
+// GetByTransactionID reads the payment of a provider transaction and locks its
+// row until tx ends.
+func (r *Repository) GetByTransactionID(ctx context.Context, tx *gorm.DB, transactionID string) (*domain.Payment, error) {
+	var payment domain.Payment
+	if err := tx.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("transaction_id = ?", transactionID).First(&payment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("get payment by transaction error: %w", err)
+	}
+	return &payment, nil
+}
+
+// RecordEvent stores a processed provider event and reports false when it was
+// already recorded before.
+func (r *Repository) RecordEvent(ctx context.Context, tx *gorm.DB, eventID, eventType string) (bool, error) {
+	res := tx.WithContext(ctx).Exec(
+		"INSERT INTO stripe_events (id, type) VALUES (?, ?) ON CONFLICT (id) DO NOTHING",
+		eventID, eventType,
+	)
+	if res.Error != nil {
+		return false, fmt.Errorf("record event error: %w", res.Error)
+	}
+
+	return res.RowsAffected > 0, nil
+}
+
+// ListExpired returns the pending payments whose deadline passed before now
+// and the initiated ones created before initiatedBefore, which the purchase
+// left behind without an answer of the provider.
+func (r *Repository) ListExpired(ctx context.Context, now, initiatedBefore time.Time, limit int) ([]domain.Payment, error) {
+	var payments []domain.Payment
+	err := r.db.WithContext(ctx).
+		Where("(status = ? AND expires_at < ?) OR (status = ? AND created_at < ?)", "pending", now, "initiated", initiatedBefore).
+		Order("created_at").
+		Limit(limit).
+		Find(&payments).Error
+	if err != nil {
+		return nil, fmt.Errorf("list expired payments error: %w", err)
+	}
+	return payments, nil
+}
@@ -0,0 +1,119 @@
+package refund
+
+import (
+	"aulway/internal/domain"
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Repository {
+	return Repository{db: db}
+}
+
+func (repo *Repository) BeginTransaction() *gorm.DB {
+	return repo.db.Begin()
+}
+
+// Create stores a refund in tx, it is only made if tx commits.
+func (repo *Repository) Create(ctx context.Context, tx *gorm.DB, refund *domain.Refund) error {
+	if err := tx.WithContext(ctx).Create(refund).Error; err != nil {
+		return fmt.Errorf("create refund error: %w", err)
+	}
+
+	return nil
+}
+
+// Claim takes up to limit due refunds and pushes their next attempt out by
+// lease, so other workers skip them while the provider is asked. A worker
+// that dies mid-refund leaves it to be retried once the lease is over.
+func (repo *Repository) Claim(ctx context.Context, limit int, lease time.Duration) ([]domain.Refund, error) {
+	refunds := make([]domain.Refund, 0)
+
+	err := repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", domain.RefundPending, time.Now()).
+			Order("next_attempt_at ASC").
+			Limit(limit).
+			Find(&refunds).Error
+		if err != nil || len(refunds) == 0 {
+			return err
+		}
+
+		ids := make([]string, 0, len(refunds))
+		for i := range refunds {
+			ids = append(ids, refunds[i].ID)
+			refunds[i].Attempts++
+		}
+
+		return tx.Model(&domain.Refund{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"attempts":        gorm.Expr("attempts + 1"),
+				"next_attempt_at": time.Now().Add(lease),
+			}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claim refunds error: %w", err)
+	}
+
+	return refunds, nil
+}
+
+// MarkSucceeded settles a pending refund in tx and reports false when it was
+// settled before.
+func (repo *Repository) MarkSucceeded(ctx context.Context, tx *gorm.DB, id string) (bool, error) {
+	res := tx.WithContext(ctx).
+		Model(&domain.Refund{}).
+		Where("id = ? AND status = ?", id, domain.RefundPending).
+		Updates(map[string]interface{}{
+			"status":     domain.RefundSucceeded,
+			"last_error": "",
+			"updated_at": time.Now(),
+		})
+	if res.Error != nil {
+		return false, fmt.Errorf("mark refund succeeded error: %w", res.Error)
+	}
+
+	return res.RowsAffected > 0, nil
+}
+
+// MarkFailed records a failed attempt, the refund is retried at next.
+func (repo *Repository) MarkFailed(ctx context.Context, id, lastError string, next time.Time) error {
+	err := repo.db.WithContext(ctx).
+		Model(&domain.Refund{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"last_error":      lastError,
+			"next_attempt_at": next,
+			"updated_at":      time.Now(),
+		}).Error
+	if err != nil {
+		return fmt.Errorf("mark refund failed error: %w", err)
+	}
+
+	return nil
+}
+
+// PendingAmount is the total of the refunds of a payment that are not made
+// or not recorded in the ledger yet.
+func (repo *Repository) PendingAmount(ctx context.Context, tx *gorm.DB, paymentID string) (int, error) {
+	var total int
+
+	err := tx.WithContext(ctx).
+		Model(&domain.Refund{}).
+		Where("payment_id = ? AND status = ?", paymentID, domain.RefundPending).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&total).Error
+	if err != nil {
+		return 0, fmt.Errorf("sum pending refunds error: %w", err)
+	}
+
+	return total, nil
+}
@@ -0,0 +1,106 @@
+package refundpolicy
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/repository/errs"
+	uerror "aulway/internal/utils/errs"
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"strings"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Repository {
+	return Repository{db: db}
+}
+
+func (repo *Repository) Create(ctx context.Context, policy *domain.RefundPolicy) error {
+	if err := repo.db.WithContext(ctx).Create(policy).Error; err != nil {
+		if strings.Contains(err.Error(), "idx_refund_policies_route") {
+			return uerror.ErrRouteHasRefundPolicy
+		}
+		return fmt.Errorf("create refund policy error: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *Repository) Get(ctx context.Context, id string) (*domain.RefundPolicy, error) {
+	policy := new(domain.RefundPolicy)
+
+	if err := repo.db.WithContext(ctx).First(policy, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrRecordNotFound
+		}
+
+		return nil, fmt.Errorf("get refund policy error: %w", err)
+	}
+
+	return policy, nil
+}
+
+func (repo *Repository) List(ctx context.Context) ([]domain.RefundPolicy, error) {
+	policies := make([]domain.RefundPolicy, 0)
+
+	err := repo.db.WithContext(ctx).
+		Order("route_id NULLS FIRST, name ASC").
+		Find(&policies).Error
+	if err != nil {
+		return nil, fmt.Errorf("list refund policies error: %w", err)
+	}
+
+	return policies, nil
+}
+
+// ForRoute returns the override of the route, or the default policy when the
+// route has none.
+func (repo *Repository) ForRoute(ctx context.Context, routeID string) (*domain.RefundPolicy, error) {
+	policy := new(domain.RefundPolicy)
+
+	err := repo.db.WithContext(ctx).
+		Where("route_id = ? OR route_id IS NULL", routeID).
+		Order("route_id NULLS LAST").
+		First(policy).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrRecordNotFound
+		}
+
+		return nil, fmt.Errorf("get route refund policy error: %w", err)
+	}
+
+	return policy, nil
+}
+
+func (repo *Repository) Update(ctx context.Context, policy *domain.RefundPolicy) error {
+	err := repo.db.WithContext(ctx).
+		Model(&domain.RefundPolicy{}).
+		Where("id = ?", policy.ID).
+		Select("name", "route_id", "non_refundable", "tiers", "updated_at").
+		Updates(policy).Error
+	if err != nil {
+		if strings.Contains(err.Error(), "idx_refund_policies_route") {
+			return uerror.ErrRouteHasRefundPolicy
+		}
+		return fmt.Errorf("update refund policy error: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *Repository) Delete(ctx context.Context, id string) error {
+	result := repo.db.WithContext(ctx).Where("id = ?", id).Delete(&domain.RefundPolicy{})
+	if result.Error != nil {
+		return fmt.Errorf("delete refund policy error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errs.ErrRecordNotFound
+	}
+
+	return nil
+}
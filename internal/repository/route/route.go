@@ -5,9 +5,11 @@ import (
 	"aulway/internal/repository/errs"
 	uerror "aulway/internal/utils/errs"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"time"
 )
 
@@ -36,15 +38,42 @@ func (repo *Repository) Update(ctx context.Context, updates map[string]interface
 	return nil
 }
 
-func (repo *Repository) UpdateSeat(ctx context.Context, tx *gorm.DB, updates map[string]interface{}, id string) error {
-	err := tx.WithContext(ctx).Model(&domain.Route{}).Where("id = ?", id).Updates(updates).Error
-	if err != nil {
-		return fmt.Errorf("failed to update route: %w", err)
+// DecrementSeats takes seats off the route only if enough of them are left,
+// so concurrent purchases can never push available_seats below zero.
+func (repo *Repository) DecrementSeats(ctx context.Context, tx *gorm.DB, id string, count int) error {
+	res := tx.WithContext(ctx).
+		Model(&domain.Route{}).
+		Where("id = ? AND available_seats >= ?", id, count).
+		UpdateColumns(map[string]interface{}{
+			"available_seats": gorm.Expr("available_seats - ?", count),
+			"updated_at":      gorm.Expr("NOW()"),
+		})
+	if res.Error != nil {
+		return fmt.Errorf("failed to decrement available seats: %w", res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return uerror.ErrNoSeatsAvailable
 	}
 
 	return nil
 }
 
+// GetForUpdate reads the route and locks its row until tx ends.
+func (repo *Repository) GetForUpdate(ctx context.Context, tx *gorm.DB, id string) (*domain.Route, error) {
+	route := new(domain.Route)
+
+	if err := tx.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&route, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrRecordNotFound
+		}
+
+		return nil, fmt.Errorf("get route error: %w", err)
+	}
+
+	return route, nil
+}
+
 func (repo *Repository) Get(ctx context.Context, id string) (*domain.Route, error) {
 	route := new(domain.Route)
 
@@ -69,34 +98,61 @@ func (repo *Repository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
-func (repo *Repository) GetRoutesList(ctx context.Context, userID, departure, destination string, date time.Time, passengers, page, pageSize int) ([]domain.Route, int, error) {
+// SearchRouteIDs lists the routes between the cities on the day of date,
+// whatever their free seats.
+func (repo *Repository) SearchRouteIDs(ctx context.Context, departure, destination string, date time.Time) ([]string, error) {
+	startOfDay, endOfDay := searchDay(date)
+
+	var ids []string
+	if err := repo.db.WithContext(ctx).Model(&domain.Route{}).
+		Where("departure = ? AND destination = ? AND start_date >= ? AND start_date < ?", departure, destination, startOfDay, endOfDay).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// GetRoutesList finds the routes with room for the passengers. held counts the
+// seats of a route reserved during checkout, they are not free to book and
+// are left out of available_seats.
+func (repo *Repository) GetRoutesList(ctx context.Context, userID, departure, destination string, date time.Time, held map[string]int, passengers, page, pageSize int) ([]domain.Route, int, error) {
 	routes := make([]domain.Route, 0)
 	var total int
 
 	offset := (page - 1) * pageSize
 
+	if held == nil {
+		held = map[string]int{}
+	}
+	heldJSON, err := json.Marshal(held)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	query := `
 		SELECT r.id, r.departure, r.destination,
 		       r.departure_location, r.destination_location,
 		       r.start_date, r.end_date,
-		       r.available_seats, r.bus_id, r.price, r.created_at, r.updated_at,
+		       r.available_seats - COALESCE(h.seats::int, 0) AS available_seats,
+		       r.bus_id, r.price, r.created_at, r.updated_at,
 		       CASE WHEN f.user_id IS NULL THEN false ELSE true END AS is_favorite,
 		       COUNT(*) OVER() AS total_count
 		FROM routes r
 		LEFT JOIN favorite_routes f ON r.id = f.route_id AND f.user_id = ?
+		LEFT JOIN jsonb_each_text(?::jsonb) AS h(route_id, seats) ON h.route_id = r.id::text
 		WHERE r.departure = ? 
 		  AND r.destination = ? 
 		  AND r.start_date >= ? AND r.start_date < ?
-		  AND r.available_seats >= ?
+		  AND r.available_seats - COALESCE(h.seats::int, 0) >= ?
 		ORDER BY r.start_date ASC
 		LIMIT ? OFFSET ?
 	`
 
-	startOfDay := date.Truncate(24 * time.Hour)
-	endOfDay := startOfDay.Add(24 * time.Hour)
+	startOfDay, endOfDay := searchDay(date)
 
 	rows, err := repo.db.WithContext(ctx).Raw(query,
-		userID, departure, destination, startOfDay, endOfDay, passengers, pageSize, offset).Rows()
+		userID, string(heldJSON), departure, destination, startOfDay, endOfDay, passengers, pageSize, offset).Rows()
 	if err != nil {
 		return nil, 0, err
 	}
@@ -120,6 +176,11 @@ func (repo *Repository) GetRoutesList(ctx context.Context, userID, departure, de
 	return routes, total, nil
 }
 
+func searchDay(date time.Time) (time.Time, time.Time) {
+	startOfDay := date.Truncate(24 * time.Hour)
+	return startOfDay, startOfDay.Add(24 * time.Hour)
+}
+
 func (repo *Repository) GetAllRoutesList(ctx context.Context, page, pageSize int) ([]domain.Route, error) {
 
 	var routes []domain.Route
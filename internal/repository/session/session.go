@@ -0,0 +1,119 @@
+package session
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/repository/errs"
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Repository {
+	return Repository{db: db}
+}
+
+func (repo *Repository) Create(ctx context.Context, session *domain.Session) error {
+	if err := repo.db.WithContext(ctx).Create(session).Error; err != nil {
+		return fmt.Errorf("create session error: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns a session that is neither revoked nor expired.
+func (repo *Repository) Get(ctx context.Context, id string) (*domain.Session, error) {
+	var session domain.Session
+
+	err := repo.db.WithContext(ctx).
+		First(&session, "id = ? AND revoked_at IS NULL AND expires_at > ?", id, time.Now()).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrRecordNotFound
+		}
+
+		return nil, fmt.Errorf("get session error: %w", err)
+	}
+
+	return &session, nil
+}
+
+// Rotate replaces the refresh token of the session, provided it still has
+// the one being refreshed. False means another refresh got there first.
+func (repo *Repository) Rotate(ctx context.Context, session *domain.Session, previousHash string) (bool, error) {
+	res := repo.db.WithContext(ctx).
+		Model(&domain.Session{}).
+		Where("id = ? AND refresh_hash = ? AND revoked_at IS NULL", session.ID, previousHash).
+		Updates(map[string]interface{}{
+			"refresh_hash": session.RefreshHash,
+			"user_agent":   session.UserAgent,
+			"ip":           session.IP,
+			"last_used_at": session.LastUsedAt,
+			"expires_at":   session.ExpiresAt,
+		})
+	if res.Error != nil {
+		return false, fmt.Errorf("rotate session error: %w", res.Error)
+	}
+
+	return res.RowsAffected == 1, nil
+}
+
+// ListByUser returns the sessions of the user that can still be refreshed,
+// the most recently used first.
+func (repo *Repository) ListByUser(ctx context.Context, userID string) ([]domain.Session, error) {
+	sessions := make([]domain.Session, 0)
+
+	err := repo.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, fmt.Errorf("list sessions error: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// Revoke ends a session of the user, ErrRecordNotFound when the user has no
+// such session or it already ended.
+func (repo *Repository) Revoke(ctx context.Context, userID, id string) error {
+	res := repo.db.WithContext(ctx).
+		Model(&domain.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return fmt.Errorf("revoke session error: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return errs.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// RevokeAll ends every session of the user and returns their ids.
+func (repo *Repository) RevokeAll(ctx context.Context, userID string) ([]string, error) {
+	var revoked []domain.Session
+
+	err := repo.db.WithContext(ctx).
+		Model(&revoked).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return nil, fmt.Errorf("revoke sessions error: %w", err)
+	}
+
+	ids := make([]string, 0, len(revoked))
+	for _, session := range revoked {
+		ids = append(ids, session.ID)
+	}
+
+	return ids, nil
+}
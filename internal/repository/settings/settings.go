@@ -0,0 +1,49 @@
+package settings
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/repository/errs"
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Repository {
+	return Repository{db: db}
+}
+
+func (repo *Repository) Get(ctx context.Context, userID string) (*domain.Settings, error) {
+	settings := new(domain.Settings)
+
+	if err := repo.db.WithContext(ctx).First(settings, "user_id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrRecordNotFound
+		}
+
+		return nil, fmt.Errorf("get settings error: %w", err)
+	}
+
+	return settings, nil
+}
+
+// SetLanguage stores the language of a user, creating their settings when
+// they have none yet.
+func (repo *Repository) SetLanguage(ctx context.Context, settings *domain.Settings) error {
+	err := repo.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"language"}),
+		}).
+		Create(settings).Error
+	if err != nil {
+		return fmt.Errorf("set language error: %w", err)
+	}
+
+	return nil
+}
@@ -3,10 +3,12 @@ package ticket
 import (
 	"aulway/internal/domain"
 	"aulway/internal/repository/errs"
+	uerror "aulway/internal/utils/errs"
 	"context"
 	"errors"
 	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"strings"
 	"time"
 )
@@ -25,6 +27,9 @@ func (repo *Repository) BeginTransaction() *gorm.DB {
 
 func (repo *Repository) Create(ctx context.Context, tx *gorm.DB, ticket *domain.Ticket) error {
 	if err := tx.WithContext(ctx).Create(&ticket).Error; err != nil {
+		if strings.Contains(err.Error(), "idx_tickets_route_seat") {
+			return uerror.ErrSeatTaken
+		}
 		return err
 	}
 
@@ -45,6 +50,21 @@ func (repo *Repository) Get(ctx context.Context, id string) (*domain.Ticket, err
 	return ticket, nil
 }
 
+// GetForUpdate reads the ticket and locks its row until tx ends.
+func (repo *Repository) GetForUpdate(ctx context.Context, tx *gorm.DB, id string) (*domain.Ticket, error) {
+	ticket := new(domain.Ticket)
+
+	if err := tx.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&ticket, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrRecordNotFound
+		}
+
+		return nil, fmt.Errorf("get ticket error: %w", err)
+	}
+
+	return ticket, nil
+}
+
 func (repo *Repository) Update(ctx context.Context, tx *gorm.DB, updates map[string]interface{}, id string) error {
 	err := tx.WithContext(ctx).Model(&domain.Ticket{}).Where("id = ?", id).Updates(updates).Error
 	if err != nil {
@@ -54,6 +74,172 @@ func (repo *Repository) Update(ctx context.Context, tx *gorm.DB, updates map[str
 	return nil
 }
 
+func (repo *Repository) GetByPaymentID(ctx context.Context, tx *gorm.DB, paymentID string) ([]domain.Ticket, error) {
+	tickets := make([]domain.Ticket, 0)
+
+	err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("payment_id = ?", paymentID).
+		Order("created_at ASC").
+		Find(&tickets).Error
+	if err != nil {
+		return nil, fmt.Errorf("get tickets by payment error: %w", err)
+	}
+
+	return tickets, nil
+}
+
+func (repo *Repository) ListByOrderID(ctx context.Context, orderID string) ([]domain.Ticket, error) {
+	return repo.GetByOrderID(ctx, repo.db, orderID)
+}
+
+// GetByOrderID reads the tickets of an order and locks their rows until tx ends.
+func (repo *Repository) GetByOrderID(ctx context.Context, tx *gorm.DB, orderID string) ([]domain.Ticket, error) {
+	tickets := make([]domain.Ticket, 0)
+
+	err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ?", orderID).
+		Order("seat_number ASC, created_at ASC").
+		Find(&tickets).Error
+	if err != nil {
+		return nil, fmt.Errorf("get tickets by order error: %w", err)
+	}
+
+	return tickets, nil
+}
+
+// MarkBoarded records the boarding of an approved ticket, or of one already
+// marked as a no-show when the scan comes late from an offline device. It
+// reports false when the ticket was already boarded or is not valid for
+// travel, so a code scanned twice at once boards only one passenger.
+func (repo *Repository) MarkBoarded(ctx context.Context, id, boardedBy string, at time.Time) (bool, error) {
+	result := repo.db.WithContext(ctx).
+		Model(&domain.Ticket{}).
+		Where("id = ? AND status IN ? AND boarded_at IS NULL", id, []string{"approved", "no_show"}).
+		Updates(map[string]interface{}{
+			"status":     "boarded",
+			"boarded_at": at,
+			"boarded_by": boardedBy,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("mark ticket boarded error: %w", result.Error)
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// MarkNoShows marks the approved tickets of routes that departed before the
+// given time and were never scanned as no-shows.
+func (repo *Repository) MarkNoShows(ctx context.Context, departedBefore time.Time) (int64, error) {
+	result := repo.db.WithContext(ctx).
+		Model(&domain.Ticket{}).
+		Where("status = ? AND boarded_at IS NULL", "approved").
+		Where("route_id IN (?)", repo.db.Model(&domain.Route{}).Select("id").Where("start_date < ?", departedBefore)).
+		Update("status", "no_show")
+	if result.Error != nil {
+		return 0, fmt.Errorf("mark no-shows error: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+// ListStaleQRCodes returns tickets after afterID whose QR code is older than
+// version, in the order of their IDs.
+func (repo *Repository) ListStaleQRCodes(ctx context.Context, version int, afterID string, limit int) ([]domain.Ticket, error) {
+	tickets := make([]domain.Ticket, 0)
+
+	err := repo.db.WithContext(ctx).
+		Where("qr_version < ? AND id > ?", version, afterID).
+		Order("id").
+		Limit(limit).
+		Find(&tickets).Error
+	if err != nil {
+		return nil, fmt.Errorf("list stale qr codes error: %w", err)
+	}
+
+	return tickets, nil
+}
+
+// UpdateQRCode replaces the QR code of a ticket unless a code of the version
+// is already stored.
+func (repo *Repository) UpdateQRCode(ctx context.Context, id, qrCode string, version int) error {
+	err := repo.db.WithContext(ctx).
+		Model(&domain.Ticket{}).
+		Where("id = ? AND qr_version < ?", id, version).
+		Updates(map[string]interface{}{
+			"qr_code":    qrCode,
+			"qr_version": version,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("update qr code error: %w", err)
+	}
+
+	return nil
+}
+
+// TouchByRoute marks the valid tickets of a route as changed, for a route
+// update their wallet passes have to show, and returns them.
+func (repo *Repository) TouchByRoute(ctx context.Context, routeID string) ([]domain.Ticket, error) {
+	tickets := make([]domain.Ticket, 0)
+
+	err := repo.db.WithContext(ctx).
+		Model(&tickets).
+		Clauses(clause.Returning{}).
+		Where("route_id = ? AND status IN ?", routeID, []string{"approved", "boarded"}).
+		Update("updated_at", time.Now()).Error
+	if err != nil {
+		return nil, fmt.Errorf("touch route tickets error: %w", err)
+	}
+
+	return tickets, nil
+}
+
+// CountByStatus counts the tickets of a route per status.
+func (repo *Repository) CountByStatus(ctx context.Context, routeID string) (map[string]int, error) {
+	var rows []struct {
+		Status string
+		Count  int
+	}
+
+	err := repo.db.WithContext(ctx).
+		Model(&domain.Ticket{}).
+		Select("status, COUNT(*) AS count").
+		Where("route_id = ?", routeID).
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("count tickets by status error: %w", err)
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+
+	return counts, nil
+}
+
+// GetManifest lists the valid tickets of a route with their passengers,
+// the token hash is left for the caller to fill in.
+func (repo *Repository) GetManifest(ctx context.Context, routeID string) ([]domain.ManifestPassenger, error) {
+	passengers := make([]domain.ManifestPassenger, 0)
+
+	err := repo.db.WithContext(ctx).
+		Table("tickets").
+		Select("tickets.id AS ticket_id, TRIM(users.first_name || ' ' || users.last_name) AS passenger_name, "+
+			"tickets.seat_number AS seat, tickets.order_number, tickets.boarded_at").
+		Joins("JOIN users ON users.id = tickets.user_id").
+		Where("tickets.route_id = ? AND tickets.status IN ?", routeID, []string{"approved", "boarded", "no_show"}).
+		Order("tickets.seat_number ASC").
+		Scan(&passengers).Error
+	if err != nil {
+		return nil, fmt.Errorf("get manifest error: %w", err)
+	}
+
+	return passengers, nil
+}
+
 func (repo *Repository) Cancel(ctx context.Context, ticket *domain.Ticket) error {
 	err := repo.db.WithContext(ctx).
 		Model(&domain.Ticket{}).
@@ -77,6 +263,20 @@ func (repo *Repository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (repo *Repository) GetTakenSeats(ctx context.Context, routeID string) ([]string, error) {
+	seats := make([]string, 0)
+
+	err := repo.db.WithContext(ctx).
+		Model(&domain.Ticket{}).
+		Where("route_id = ? AND status <> ? AND seat_number IS NOT NULL", routeID, "cancelled").
+		Pluck("seat_number", &seats).Error
+	if err != nil {
+		return nil, fmt.Errorf("get taken seats error: %w", err)
+	}
+
+	return seats, nil
+}
+
 func (repo *Repository) GetUpcomingTickets(ctx context.Context, userID string, now time.Time) ([]domain.Ticket, error) {
 	tickets := make([]domain.Ticket, 0)
 	err := repo.db.WithContext(ctx).
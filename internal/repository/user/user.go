@@ -23,6 +23,12 @@ func NewRepository(db *gorm.DB) Repository {
 
 func (repo *Repository) Create(ctx context.Context, user *domain.User) error {
 	if err := repo.db.WithContext(ctx).Create(&user).Error; err != nil {
+		if strings.Contains(err.Error(), "idx_users_phone_verified") {
+			return errs.PhoneAlreadyExists
+		}
+		if strings.Contains(err.Error(), "idx_users_firebase_uid") {
+			return errs.FirebaseUserAlreadyLinked
+		}
 		if strings.Contains(err.Error(), "duplicate") {
 			slog.Debug(err.Error())
 			return errs.EmailAlreadyExists
@@ -110,6 +116,63 @@ func (repo *Repository) GetByEmail(ctx context.Context, email string) (*domain.U
 	return &user, nil
 }
 
+// GetByVerifiedPhone returns the user the phone was verified for.
+func (repo *Repository) GetByVerifiedPhone(ctx context.Context, phone string) (*domain.User, error) {
+	var user domain.User
+
+	if err := repo.db.WithContext(ctx).First(&user, "phone = ? AND phone_verified AND deleted_at IS NULL", phone).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrRecordNotFound
+		}
+
+		return nil, fmt.Errorf("get user by phone error: %w", err)
+	}
+
+	return &user, nil
+}
+
+// SetVerifiedPhone gives the user the phone they confirmed with a code.
+func (repo *Repository) SetVerifiedPhone(ctx context.Context, id, phone string) error {
+	res := repo.db.WithContext(ctx).
+		Model(&domain.User{}).
+		Where("id = ? AND deleted_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"phone":          phone,
+			"phone_verified": true,
+			"updated_at":     time.Now(),
+		})
+
+	if res.Error != nil {
+		if strings.Contains(res.Error.Error(), "idx_users_phone_verified") {
+			return errs.PhoneAlreadyExists
+		}
+		return fmt.Errorf("set user phone error: %w", res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return errs.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// VerifyEmail marks the email of the user verified, provided it is still
+// the email the code was sent to.
+func (repo *Repository) VerifyEmail(ctx context.Context, id, email string) error {
+	res := repo.db.WithContext(ctx).
+		Model(&domain.User{}).
+		Where("id = ? AND email = ? AND deleted_at IS NULL", id, email).
+		Update("email_verified", true)
+	if res.Error != nil {
+		return fmt.Errorf("verify email error: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return errs.ErrRecordNotFound
+	}
+
+	return nil
+}
+
 func (repo *Repository) GetUserByFbUid(ctx context.Context, uid string) (*domain.User, error) {
 	var user domain.User
 
@@ -123,3 +186,28 @@ func (repo *Repository) GetUserByFbUid(ctx context.Context, uid string) (*domain
 
 	return &user, nil
 }
+
+// LinkFirebase links the user to a Firebase user, unless it is linked to
+// one already. ErrRecordNotFound then.
+func (repo *Repository) LinkFirebase(ctx context.Context, id, uid string) error {
+	res := repo.db.WithContext(ctx).
+		Model(&domain.User{}).
+		Where("id = ? AND firebase_uid IS NULL AND deleted_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"firebase_uid": uid,
+			"updated_at":   time.Now(),
+		})
+
+	if res.Error != nil {
+		if strings.Contains(res.Error.Error(), "idx_users_firebase_uid") {
+			return errs.FirebaseUserAlreadyLinked
+		}
+		return fmt.Errorf("link firebase user error: %w", res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return errs.ErrRecordNotFound
+	}
+
+	return nil
+}
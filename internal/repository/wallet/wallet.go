@@ -0,0 +1,111 @@
+package wallet
+
+import (
+	"aulway/internal/domain"
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Repository {
+	return Repository{db: db}
+}
+
+// Register stores a device registration. It reports false when the device
+// was already registered for the pass, its push token is refreshed then.
+func (repo *Repository) Register(ctx context.Context, registration *domain.WalletRegistration) (bool, error) {
+	result := repo.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(registration)
+	if result.Error != nil {
+		return false, fmt.Errorf("register wallet device error: %w", result.Error)
+	}
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+
+	err := repo.db.WithContext(ctx).
+		Model(&domain.WalletRegistration{}).
+		Where("device_library_id = ? AND pass_type_id = ? AND serial_number = ?",
+			registration.DeviceLibraryID, registration.PassTypeID, registration.SerialNumber).
+		Update("push_token", registration.PushToken).Error
+	if err != nil {
+		return false, fmt.Errorf("refresh wallet push token error: %w", err)
+	}
+
+	return false, nil
+}
+
+func (repo *Repository) Unregister(ctx context.Context, deviceID, passTypeID, serial string) error {
+	err := repo.db.WithContext(ctx).
+		Where("device_library_id = ? AND pass_type_id = ? AND serial_number = ?", deviceID, passTypeID, serial).
+		Delete(&domain.WalletRegistration{}).Error
+	if err != nil {
+		return fmt.Errorf("unregister wallet device error: %w", err)
+	}
+
+	return nil
+}
+
+// DeletePushToken drops every registration of a device APNs no longer knows.
+func (repo *Repository) DeletePushToken(ctx context.Context, pushToken string) error {
+	err := repo.db.WithContext(ctx).
+		Where("push_token = ?", pushToken).
+		Delete(&domain.WalletRegistration{}).Error
+	if err != nil {
+		return fmt.Errorf("delete wallet push token error: %w", err)
+	}
+
+	return nil
+}
+
+// UpdatedSerials lists the passes of a device whose ticket changed after
+// the given time, along with the latest change among them.
+func (repo *Repository) UpdatedSerials(ctx context.Context, deviceID, passTypeID string, since time.Time) ([]string, time.Time, error) {
+	var rows []struct {
+		SerialNumber string
+		UpdatedAt    time.Time
+	}
+
+	err := repo.db.WithContext(ctx).
+		Table("wallet_registrations").
+		Select("wallet_registrations.serial_number, tickets.updated_at").
+		Joins("JOIN tickets ON tickets.id = wallet_registrations.serial_number").
+		Where("wallet_registrations.device_library_id = ? AND wallet_registrations.pass_type_id = ?", deviceID, passTypeID).
+		Where("tickets.updated_at > ?", since).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("list updated passes error: %w", err)
+	}
+
+	serials := make([]string, 0, len(rows))
+	var last time.Time
+	for _, row := range rows {
+		serials = append(serials, row.SerialNumber)
+		if row.UpdatedAt.After(last) {
+			last = row.UpdatedAt
+		}
+	}
+
+	return serials, last, nil
+}
+
+// PushTokens lists the devices holding a pass of the given tickets.
+func (repo *Repository) PushTokens(ctx context.Context, ticketIDs []string) ([]domain.WalletRegistration, error) {
+	registrations := make([]domain.WalletRegistration, 0)
+
+	err := repo.db.WithContext(ctx).
+		Where("serial_number IN ?", ticketIDs).
+		Find(&registrations).Error
+	if err != nil {
+		return nil, fmt.Errorf("list wallet push tokens error: %w", err)
+	}
+
+	return registrations, nil
+}
@@ -3,69 +3,83 @@ package service
 import (
 	"aulway/internal/domain"
 	"aulway/internal/handler/auth/model"
+	"aulway/internal/ratelimit"
+	settingsRepo "aulway/internal/repository/settings"
 	"aulway/internal/repository/user"
+	"aulway/internal/sms"
+	"aulway/internal/templates"
 	"aulway/internal/utils/config"
+	"aulway/internal/utils/errs"
 	"context"
+	"crypto/subtle"
 	"errors"
 	"fmt"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/redis/go-redis/v9"
 	"golang.org/x/crypto/bcrypt"
 	"math/rand"
-	"regexp"
 	"strings"
 	"time"
 )
 
-type Claims struct {
-	UserID string `json:"user_id"`
-	Role   string `json:"user_role"`
-	jwt.RegisteredClaims
-}
+// resetCodeTTL is how long a password reset code can be used.
+const resetCodeTTL = 10 * time.Minute
+
+// signupCodeTTL is how long the emailed code of a signup can be used.
+const signupCodeTTL = 10 * time.Minute
+
+// emailCodeInterval is the least time between verification codes emailed to
+// an address.
+const emailCodeInterval = time.Minute
+
+// codeAttempts is how many wrong tries a verification code takes before it
+// is dropped and a new one has to be requested.
+const codeAttempts = 5
 
 type Auth struct {
-	repo  user.Repository
-	redis *redis.Client
-	smpt  config.SMTP
+	repo     user.Repository
+	settings settingsRepo.Repository
+	redis    *redis.Client
+	smpt     config.SMTP
+	sms      sms.Sender
+	smsCfg   config.SMS
+	sessions *SessionService
+	lockout  *ratelimit.Lockout
 }
 
-func NewAuthService(userRepo user.Repository, redis *redis.Client, smtp config.SMTP) *Auth {
+func NewAuthService(userRepo user.Repository, settings settingsRepo.Repository, redis *redis.Client, smtp config.SMTP, sender sms.Sender, smsCfg config.SMS, sessions *SessionService, lockout *ratelimit.Lockout) *Auth {
 	return &Auth{
-		repo:  userRepo,
-		redis: redis,
-		smpt:  smtp,
+		repo:     userRepo,
+		settings: settings,
+		redis:    redis,
+		smpt:     smtp,
+		sms:      sender,
+		smsCfg:   smsCfg,
+		sessions: sessions,
+		lockout:  lockout,
 	}
 }
 
 func (s *Auth) SendResetCode(ctx context.Context, email string) error {
+	// riders signed up by phone have no email to send the code to
 	usr, err := s.repo.GetByEmail(ctx, email)
-	if err != nil {
+	if err != nil || usr.Email == "" {
 		return errors.New("user not found")
 	}
 
 	code := fmt.Sprintf("%06d", rand.Intn(1000000))
 
-	err = s.redis.Set(ctx, "reset_code:"+email, code, 10*time.Minute).Err()
-	if err != nil {
+	if err := StoreCode(ctx, s.redis, "reset_code:"+email, code, resetCodeTTL); err != nil {
 		return errors.New("failed to store reset code")
 	}
 
-	message := fmt.Sprintf("Aulway\r\nYour password reset code is: %s. It will expire in 10 minutes.", code)
-	return SendEmail(usr.Email, "Password Reset Code", message, s.smpt)
+	return SendCodeEmail(usr.Email, userLanguage(ctx, s.settings, usr.ID), templates.PasswordResetEmail, code, resetCodeTTL, s.smpt)
 }
 
 func (s *Auth) VerifyResetCode(ctx context.Context, req model.VerifyResetCodeRequest) error {
-	storedCode, err := s.redis.Get(ctx, "reset_code:"+req.Email).Result()
-	if err == redis.Nil {
-		return errors.New("reset code invalid or email not found")
-	} else if err != nil {
+	if err := s.checkCode(ctx, emailAccount(req.Email), "reset_code:"+req.Email, req.Code, resetCodeTTL); err != nil {
 		return err
 	}
 
-	if storedCode != req.Code {
-		return errors.New("invalid reset code")
-	}
-
 	encryptedPassword, err := bcrypt.GenerateFromPassword(
 		[]byte(req.NewPassword),
 		bcrypt.DefaultCost,
@@ -76,38 +90,147 @@ func (s *Auth) VerifyResetCode(ctx context.Context, req model.VerifyResetCodeReq
 		return errors.New("failed to reset password")
 	}
 
-	s.redis.Del(ctx, "reset_code:"+req.Email)
+	// whoever had the old password is signed out everywhere
+	usr, err := s.repo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		return err
+	}
 
-	return nil
+	// the code was emailed there, so the email is the user's
+	if !usr.EmailVerified {
+		if err := s.repo.VerifyEmail(ctx, usr.ID, usr.Email); err != nil {
+			return err
+		}
+	}
+
+	return s.sessions.RevokeAll(ctx, usr.ID)
+}
+
+// VerifySignupCode checks the code emailed to confirm a signup.
+func (s *Auth) VerifySignupCode(ctx context.Context, email, code string) error {
+	return s.checkCode(ctx, emailAccount(email), "email_verification:"+email, code, signupCodeTTL)
 }
 
-func (s *Auth) CreateAccessToken(ctx context.Context, user domain.User, jwtSecret string, expiry int) (string, error) {
-	expirationTime := time.Now().Add(time.Duration(expiry) * time.Hour)
-	claims := &Claims{
-		UserID: user.ID,
-		Role:   user.Role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
+// SendEmailCode emails a code to the email of the user, the email counts as
+// verified once the code comes back.
+func (s *Auth) SendEmailCode(ctx context.Context, userID string) error {
+	usr, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if usr.Email == "" {
+		return errs.ErrIncorrectEmailFormat
+	}
+	if usr.EmailVerified {
+		return nil
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	allowed, err := s.redis.SetNX(ctx, "email_code_cooldown:"+strings.ToLower(usr.Email), 1, emailCodeInterval).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check email code limit: %w", err)
+	}
+	if !allowed {
+		return errs.ErrTooManyEmailCodes
+	}
+
+	code := fmt.Sprintf("%06d", rand.Intn(1000000))
+	key := emailCodeKey(usr.ID, usr.Email)
 
-	accessToken, err := token.SignedString([]byte(jwtSecret))
+	if err := StoreCode(ctx, s.redis, key, code, signupCodeTTL); err != nil {
+		return fmt.Errorf("failed to store email code: %w", err)
+	}
+
+	return SendCodeEmail(usr.Email, userLanguage(ctx, s.settings, usr.ID), templates.VerificationEmail, code, signupCodeTTL, s.smpt)
+}
+
+// VerifyEmail checks the code emailed by SendEmailCode. The code only
+// verifies the email it was sent to.
+func (s *Auth) VerifyEmail(ctx context.Context, userID, code string) (*domain.User, error) {
+	usr, err := s.repo.Get(ctx, userID)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if err := s.checkCode(ctx, emailAccount(usr.Email), emailCodeKey(usr.ID, usr.Email), code, signupCodeTTL); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.VerifyEmail(ctx, usr.ID, usr.Email); err != nil {
+		return nil, err
 	}
+	usr.EmailVerified = true
 
-	return accessToken, nil
+	return usr, nil
 }
 
-func (s *Auth) ValidatePhone(phone string) error {
-	phone = strings.ReplaceAll(phone, " ", "")
-	phone = strings.ReplaceAll(phone, "-", "")
+// checkCode compares the code with the one sent, which is used up when it
+// matches and dropped after too many wrong tries. Wrong codes also count
+// towards locking the account out.
+// StoreCode saves a newly issued code under key. Failed tries against an
+// earlier code are forgotten, they do not count against the new one.
+func StoreCode(ctx context.Context, client *redis.Client, key, code string, ttl time.Duration) error {
+	pipe := client.TxPipeline()
+	pipe.Set(ctx, key, code, ttl)
+	pipe.Del(ctx, key+":attempts")
+	_, err := pipe.Exec(ctx)
+	return err
+}
 
-	re := regexp.MustCompile(`^(?:\+7|8)\d{10}$`)
+func (s *Auth) checkCode(ctx context.Context, account, key, code string, ttl time.Duration) error {
+	if err := s.lockout.Check(ctx, account); err != nil {
+		return err
+	}
 
-	if !re.MatchString(phone) {
+	stored, err := s.redis.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return errs.ErrInvalidCode
+	} else if err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(stored), []byte(code)) != 1 {
+		attempts, err := s.redis.Incr(ctx, key+":attempts").Result()
+		if err != nil {
+			return err
+		}
+		if attempts == 1 {
+			s.redis.Expire(ctx, key+":attempts", ttl)
+		}
+		if attempts >= codeAttempts {
+			s.redis.Del(ctx, key, key+":attempts")
+		}
+
+		if err := s.lockout.Fail(ctx, account); err != nil {
+			return err
+		}
+		return errs.ErrInvalidCode
+	}
+
+	// only one of concurrent checks gets to delete the code
+	deleted, err := s.redis.Del(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return errs.ErrInvalidCode
+	}
+	s.redis.Del(ctx, key+":attempts")
+	s.lockout.Reset(ctx, account)
+
+	return nil
+}
+
+func emailCodeKey(userID, email string) string {
+	return "email_code:" + userID + ":" + strings.ToLower(email)
+}
+
+// emailAccount names the email to the lockout.
+func emailAccount(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+func (s *Auth) ValidatePhone(phone string) error {
+	if _, err := NormalizePhone(phone); err != nil {
 		return errors.New("invalid phone number format")
 	}
 
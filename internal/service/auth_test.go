@@ -0,0 +1,35 @@
+package service
+
+import (
+	"aulway/internal/ratelimit"
+	"aulway/internal/utils/errs"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCodeForgetsFailedTriesOfTheOldOne(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	// the lockout stays out of the way, only the tries of the code count
+	auth := &Auth{redis: client, lockout: ratelimit.NewLockout(client, 100, time.Second, time.Second)}
+	ctx := context.Background()
+	key := "reset_code:rider@example.com"
+
+	require.NoError(t, StoreCode(ctx, client, key, "111111", time.Minute))
+	for i := 0; i < codeAttempts-1; i++ {
+		require.ErrorIs(t, auth.checkCode(ctx, "rider@example.com", key, "000000", time.Minute), errs.ErrInvalidCode)
+	}
+
+	require.NoError(t, StoreCode(ctx, client, key, "222222", time.Minute))
+	require.False(t, mr.Exists(key+":attempts"))
+
+	require.ErrorIs(t, auth.checkCode(ctx, "rider@example.com", key, "000000", time.Minute), errs.ErrInvalidCode)
+	require.NoError(t, auth.checkCode(ctx, "rider@example.com", key, "222222", time.Minute), "one typo must not void a fresh code")
+}
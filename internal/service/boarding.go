@@ -0,0 +1,328 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/boarding/model"
+	busRepo "aulway/internal/repository/bus"
+	repoErrs "aulway/internal/repository/errs"
+	routeRepo "aulway/internal/repository/route"
+	ticketRepo "aulway/internal/repository/ticket"
+	"aulway/internal/utils/errs"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type BoardingService struct {
+	TicketRepo ticketRepo.Repository
+	RouteRepo  routeRepo.Repository
+	BusRepo    busRepo.Repository
+	Signer     *TicketSigner
+	Manifests  *ManifestSigner
+	Opens      time.Duration
+}
+
+func NewBoardingService(ticketRepo ticketRepo.Repository, routeRepo routeRepo.Repository, busRepo busRepo.Repository, signer *TicketSigner, manifests *ManifestSigner, opens time.Duration) *BoardingService {
+	return &BoardingService{
+		TicketRepo: ticketRepo,
+		RouteRepo:  routeRepo,
+		BusRepo:    busRepo,
+		Signer:     signer,
+		Manifests:  manifests,
+		Opens:      opens,
+	}
+}
+
+// Validate checks a scanned ticket code and boards its passenger. A ticket
+// boards once, scanning it again is rejected with the time it was used, and
+// only from Opens before departure until the bus arrives.
+func (s *BoardingService) Validate(ctx context.Context, conductorID string, req model.ValidateRequest) (*domain.Ticket, error) {
+	if strings.HasPrefix(strings.TrimSpace(req.Token), "{") {
+		// codes printed before tickets were signed, the app shows the new one
+		return nil, fmt.Errorf("%w: the code is from before tickets were signed, open the ticket in the app for a new one", errs.ErrInvalidTicketToken)
+	}
+
+	claims, err := s.Signer.Verify(req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	ticket, err := s.TicketRepo.Get(ctx, claims.TicketID)
+	if errors.Is(err, repoErrs.ErrRecordNotFound) {
+		return nil, errs.ErrInvalidTicketToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ticket.RouteID != claims.RouteID || ticket.SeatNumber != claims.Seat {
+		return nil, errs.ErrInvalidTicketToken
+	}
+
+	if req.RouteID != "" && req.RouteID != ticket.RouteID {
+		return ticket, errs.ErrWrongRoute
+	}
+	if ticket.BoardedAt != nil {
+		return ticket, alreadyBoarded(ticket)
+	}
+	if ticket.Status != "approved" && ticket.Status != "no_show" {
+		return ticket, fmt.Errorf("%w: ticket is %s", errs.ErrTicketNotBoardable, ticket.Status)
+	}
+
+	route, err := s.RouteRepo.Get(ctx, ticket.RouteID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := boardingWindow(route, now, s.Opens); err != nil {
+		return ticket, err
+	}
+
+	boarded, err := s.TicketRepo.MarkBoarded(ctx, ticket.ID, conductorID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !boarded {
+		// another scan got there first or the ticket changed since it was read
+		ticket, err = s.TicketRepo.Get(ctx, ticket.ID)
+		if err != nil {
+			return nil, err
+		}
+		if ticket.BoardedAt != nil {
+			return ticket, alreadyBoarded(ticket)
+		}
+		return ticket, fmt.Errorf("%w: ticket is %s", errs.ErrTicketNotBoardable, ticket.Status)
+	}
+
+	ticket.Status = "boarded"
+	ticket.BoardedAt = &now
+	ticket.BoardedBy = &conductorID
+
+	return ticket, nil
+}
+
+// boardingWindow checks that a ticket of the route can board at the given
+// time: from opens before departure until arrival.
+func boardingWindow(route *domain.Route, at time.Time, opens time.Duration) error {
+	if at.Before(route.StartDate.Add(-opens)) {
+		return fmt.Errorf("%w: boarding opens at %s", errs.ErrOutsideBoardingWindow, route.StartDate.Add(-opens).Format(time.RFC3339))
+	}
+	if at.After(route.EndDate) {
+		return fmt.Errorf("%w: the route arrived at %s", errs.ErrOutsideBoardingWindow, route.EndDate.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func alreadyBoarded(ticket *domain.Ticket) error {
+	return fmt.Errorf("%w at %s", errs.ErrTicketAlreadyBoarded, ticket.BoardedAt.Format(time.RFC3339))
+}
+
+// Manifest lists the passengers with valid tickets on a route, signed so a
+// driver's device can tell it was issued by us.
+func (s *BoardingService) Manifest(ctx context.Context, routeID string) (*domain.Manifest, error) {
+	route, err := s.RouteRepo.Get(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	passengers, err := s.TicketRepo.GetManifest(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range passengers {
+		passengers[i].TokenHash = TokenHash(s.Signer.Sign(&domain.Ticket{
+			ID:         passengers[i].TicketID,
+			RouteID:    routeID,
+			SeatNumber: passengers[i].Seat,
+		}))
+	}
+
+	manifest := &domain.Manifest{
+		RouteID:     route.Id,
+		Departure:   route.Departure,
+		Destination: route.Destination,
+		StartDate:   route.StartDate,
+		GeneratedAt: time.Now().UTC(),
+		Passengers:  passengers,
+	}
+
+	body, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Signature = s.Manifests.Sign(body)
+
+	return manifest, nil
+}
+
+// ManifestCSV renders the manifest as CSV and returns its signature, which
+// covers the CSV bytes.
+func (s *BoardingService) ManifestCSV(ctx context.Context, routeID string) ([]byte, string, error) {
+	manifest, err := s.Manifest(ctx, routeID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"ticket_id", "passenger_name", "seat", "order_number", "token_hash", "boarded_at"})
+	for _, p := range manifest.Passengers {
+		boardedAt := ""
+		if p.BoardedAt != nil {
+			boardedAt = p.BoardedAt.UTC().Format(time.RFC3339)
+		}
+		w.Write([]string{p.TicketID, p.PassengerName, p.Seat, p.OrderNumber, p.TokenHash, boardedAt})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, "", fmt.Errorf("write manifest csv error: %w", err)
+	}
+
+	return buf.Bytes(), s.Manifests.Sign(buf.Bytes()), nil
+}
+
+// SyncScans reconciles the boarding scans a driver made offline. Scans are
+// applied oldest first, a ticket scanned more than once, or already boarded
+// online, is flagged as a duplicate. A scan made outside the boarding window
+// of the route is rejected.
+func (s *BoardingService) SyncScans(ctx context.Context, conductorID, routeID string, req model.SyncRequest) (*domain.ManifestSync, error) {
+	route, err := s.RouteRepo.Get(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := s.Manifest(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	byHash := make(map[string]domain.ManifestPassenger, len(manifest.Passengers))
+	for _, p := range manifest.Passengers {
+		byHash[p.TokenHash] = p
+	}
+
+	scans := append([]model.ScanRequest(nil), req.Scans...)
+	sort.SliceStable(scans, func(i, j int) bool {
+		return scans[i].ScannedAt.Before(scans[j].ScannedAt)
+	})
+
+	sync := &domain.ManifestSync{RouteID: routeID, Results: make([]domain.ScanResult, 0, len(scans))}
+	boarded := make(map[string]*time.Time)
+
+	for _, scan := range scans {
+		result := domain.ScanResult{TokenHash: scan.TokenHash, ScannedAt: scan.ScannedAt}
+		if scan.Token != "" {
+			result.TokenHash = TokenHash(scan.Token)
+		}
+		if result.ScannedAt.IsZero() {
+			result.ScannedAt = time.Now()
+		}
+
+		passenger, ok := byHash[result.TokenHash]
+		outside := boardingWindow(route, result.ScannedAt, s.Opens)
+		switch {
+		case !ok:
+			result.Result = domain.ScanUnknown
+			result.Reason = "ticket is not on the manifest of this route"
+		case boarded[passenger.TicketID] != nil:
+			result.Result = domain.ScanDuplicate
+			result.BoardedAt = boarded[passenger.TicketID]
+		case passenger.BoardedAt != nil:
+			result.Result = domain.ScanDuplicate
+			result.BoardedAt = passenger.BoardedAt
+			boarded[passenger.TicketID] = passenger.BoardedAt
+		case outside != nil:
+			result.Result = domain.ScanRejected
+			result.Reason = outside.Error()
+		default:
+			result.Result, result.BoardedAt, result.Reason, err = s.boardScanned(ctx, conductorID, passenger.TicketID, result.ScannedAt)
+			if err != nil {
+				return nil, err
+			}
+			if result.BoardedAt != nil {
+				boarded[passenger.TicketID] = result.BoardedAt
+			}
+		}
+		result.TicketID = passenger.TicketID
+
+		switch result.Result {
+		case domain.ScanBoarded:
+			sync.Boarded++
+		case domain.ScanDuplicate:
+			sync.Duplicates++
+		default:
+			sync.Rejected++
+		}
+		sync.Results = append(sync.Results, result)
+	}
+
+	return sync, nil
+}
+
+func (s *BoardingService) boardScanned(ctx context.Context, conductorID, ticketID string, at time.Time) (string, *time.Time, string, error) {
+	ok, err := s.TicketRepo.MarkBoarded(ctx, ticketID, conductorID, at)
+	if err != nil {
+		return "", nil, "", err
+	}
+	if ok {
+		return domain.ScanBoarded, &at, "", nil
+	}
+
+	// the ticket changed since the manifest was read
+	ticket, err := s.TicketRepo.Get(ctx, ticketID)
+	if err != nil {
+		return "", nil, "", err
+	}
+	if ticket.BoardedAt != nil {
+		return domain.ScanDuplicate, ticket.BoardedAt, "", nil
+	}
+
+	return domain.ScanRejected, nil, "ticket is " + ticket.Status, nil
+}
+
+// ManifestKey returns the public key devices verify manifests with.
+func (s *BoardingService) ManifestKey() string {
+	return s.Manifests.PublicKey()
+}
+
+// RouteStats counts who boarded a route, who did not show up and who has yet
+// to board.
+func (s *BoardingService) RouteStats(ctx context.Context, routeID string) (*domain.BoardingStats, error) {
+	route, err := s.RouteRepo.Get(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	bus, err := s.BusRepo.Get(ctx, route.BusId)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.TicketRepo.CountByStatus(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &domain.BoardingStats{
+		RouteID:   routeID,
+		Capacity:  bus.TotalSeats,
+		Boarded:   counts["boarded"],
+		NoShow:    counts["no_show"],
+		Pending:   counts["approved"],
+		Cancelled: counts["cancelled"],
+	}
+	stats.Sold = stats.Boarded + stats.NoShow + stats.Pending
+	if stats.Capacity > 0 {
+		stats.Occupancy = float64(stats.Boarded) / float64(stats.Capacity)
+	}
+	if due := stats.Boarded + stats.NoShow; due > 0 {
+		stats.ShowRate = float64(stats.Boarded) / float64(due)
+	}
+
+	return stats, nil
+}
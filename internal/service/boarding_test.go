@@ -0,0 +1,157 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/boarding/model"
+	ticketModel "aulway/internal/handler/ticket/model"
+	"aulway/internal/utils/errs"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateBoardsOnce(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 2)
+	ctx := context.Background()
+
+	purchase, err := f.service.BuyTickets(ctx, f.userIDs[0], f.routeID, "pm_card_visa", ticketModel.BuyTicketRequest{Quantity: 1})
+	require.NoError(t, err)
+
+	boarding := NewBoardingService(f.service.TicketRepo, f.service.RouteRepo, f.service.BusRepo, f.service.Signer, testManifestSigner(t), 2*time.Hour)
+	token := f.service.Signer.Sign(&purchase.Tickets[0])
+	conductorID := f.userIDs[1]
+
+	_, err = boarding.Validate(ctx, conductorID, model.ValidateRequest{Token: token, RouteID: "another-route"})
+	require.ErrorIs(t, err, errs.ErrWrongRoute)
+
+	// the bus leaves in three days, boarding is not open yet
+	_, err = boarding.Validate(ctx, conductorID, model.ValidateRequest{Token: token, RouteID: f.routeID})
+	require.ErrorIs(t, err, errs.ErrOutsideBoardingWindow)
+
+	departSoon(t, f)
+	ticket, err := boarding.Validate(ctx, conductorID, model.ValidateRequest{Token: token, RouteID: f.routeID})
+	require.NoError(t, err)
+	require.NotNil(t, ticket.BoardedAt)
+
+	_, err = boarding.Validate(ctx, conductorID, model.ValidateRequest{Token: token})
+	require.ErrorIs(t, err, errs.ErrTicketAlreadyBoarded)
+
+	_, err = boarding.Validate(ctx, conductorID, model.ValidateRequest{Token: NewTicketSigner("forged").Sign(&purchase.Tickets[0])})
+	require.ErrorIs(t, err, errs.ErrInvalidTicketToken)
+}
+
+func TestSyncScansFlagsDuplicates(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 2)
+	ctx := context.Background()
+
+	purchase, err := f.service.BuyTickets(ctx, f.userIDs[0], f.routeID, "pm_card_visa", ticketModel.BuyTicketRequest{Quantity: 2})
+	require.NoError(t, err)
+
+	departSoon(t, f)
+	boarding := NewBoardingService(f.service.TicketRepo, f.service.RouteRepo, f.service.BusRepo, f.service.Signer, testManifestSigner(t), 2*time.Hour)
+	manifest, err := boarding.Manifest(ctx, f.routeID)
+	require.NoError(t, err)
+	require.Len(t, manifest.Passengers, 2)
+	signature := manifest.Signature
+	manifest.Signature = ""
+	body, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	require.True(t, VerifyManifest(boarding.ManifestKey(), body, signature))
+	manifest.Signature = signature
+
+	first := f.service.Signer.Sign(&purchase.Tickets[0])
+	scannedAt := time.Now().Add(-time.Minute)
+	sync, err := boarding.SyncScans(ctx, f.userIDs[1], f.routeID, model.SyncRequest{Scans: []model.ScanRequest{
+		{Token: first, ScannedAt: scannedAt},
+		{TokenHash: TokenHash(first), ScannedAt: scannedAt.Add(time.Second)},
+		{TokenHash: manifest.Passengers[1].TokenHash, ScannedAt: scannedAt},
+		{TokenHash: "not-a-ticket", ScannedAt: scannedAt},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, 2, sync.Boarded)
+	require.Equal(t, 1, sync.Duplicates)
+	require.Equal(t, 1, sync.Rejected)
+
+	_, err = boarding.Validate(ctx, f.userIDs[1], model.ValidateRequest{Token: first})
+	require.ErrorIs(t, err, errs.ErrTicketAlreadyBoarded, "a synced scan boards the ticket for online checks too")
+}
+
+func TestNoShowJobAndStats(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 2)
+	ctx := context.Background()
+
+	purchase, err := f.service.BuyTickets(ctx, f.userIDs[0], f.routeID, "pm_card_visa", ticketModel.BuyTicketRequest{Quantity: 2})
+	require.NoError(t, err)
+
+	// the bus left an hour ago
+	require.NoError(t, f.db.Table("routes").Where("id = ?", f.routeID).Update("start_date", time.Now().Add(-time.Hour)).Error)
+
+	boarding := NewBoardingService(f.service.TicketRepo, f.service.RouteRepo, f.service.BusRepo, f.service.Signer, testManifestSigner(t), 2*time.Hour)
+	_, err = boarding.Validate(ctx, f.userIDs[1], model.ValidateRequest{Token: f.service.Signer.Sign(&purchase.Tickets[0])})
+	require.NoError(t, err)
+
+	marked, err := NewNoShowJob(f.service.TicketRepo, 30*time.Minute, time.Minute).MarkNoShows(ctx)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, marked, int64(1))
+
+	stats, err := boarding.RouteStats(ctx, f.routeID)
+	require.NoError(t, err)
+	require.Equal(t, 2, stats.Sold)
+	require.Equal(t, 1, stats.Boarded)
+	require.Equal(t, 1, stats.NoShow)
+	require.Zero(t, stats.Pending)
+	require.Equal(t, 0.5, stats.ShowRate)
+
+	late, err := boarding.Validate(ctx, f.userIDs[1], model.ValidateRequest{Token: f.service.Signer.Sign(&purchase.Tickets[1])})
+	require.NoError(t, err, "a late scan still proves the passenger travelled")
+	require.Equal(t, "boarded", late.Status)
+}
+
+// departSoon moves the departure of the fixture route half an hour ahead, so
+// boarding is open.
+func departSoon(t *testing.T, f *purchaseFixture) {
+	t.Helper()
+	require.NoError(t, f.db.Table("routes").Where("id = ?", f.routeID).Update("start_date", time.Now().Add(30*time.Minute)).Error)
+}
+
+func TestBoardingWindow(t *testing.T) {
+	departure := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	route := &domain.Route{StartDate: departure, EndDate: departure.Add(10 * time.Hour)}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		open bool
+	}{
+		{name: "the day before", at: departure.Add(-24 * time.Hour)},
+		{name: "just before opening", at: departure.Add(-2*time.Hour - time.Minute)},
+		{name: "at opening", at: departure.Add(-2 * time.Hour), open: true},
+		{name: "at departure", at: departure, open: true},
+		{name: "late, on the way", at: departure.Add(time.Hour), open: true},
+		{name: "at arrival", at: departure.Add(10 * time.Hour), open: true},
+		{name: "after arrival", at: departure.Add(10*time.Hour + time.Minute)},
+		{name: "a week later", at: departure.Add(7 * 24 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := boardingWindow(route, tt.at, 2*time.Hour)
+			if tt.open {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, errs.ErrOutsideBoardingWindow)
+		})
+	}
+}
+
+func TestValidateRejectsLegacyCodes(t *testing.T) {
+	boarding := &BoardingService{Signer: NewTicketSigner("test")}
+
+	_, err := boarding.Validate(context.Background(), "conductor-1", model.ValidateRequest{Token: ` {"id":"ticket-1","user_id":"user-1","payment_id":"payment-1"}`})
+	require.ErrorIs(t, err, errs.ErrInvalidTicketToken)
+	require.Contains(t, err.Error(), "open the ticket in the app")
+}
@@ -25,10 +25,38 @@ func (service *Bus) CreateBus(ctx context.Context, request model.CreateRequest)
 		return nil, fmt.Errorf("generate uuid error: %w", err)
 	}
 
+	var seats []domain.Seat
+	switch {
+	case len(request.Seats) > 0:
+		seats = make([]domain.Seat, 0, len(request.Seats))
+		for _, seat := range request.Seats {
+			class := seat.Class
+			if class == "" {
+				class = defaultSeatClass
+			}
+			seats = append(seats, domain.Seat{
+				Number: seat.Number,
+				Row:    seat.Row,
+				Column: seat.Column,
+				Class:  class,
+			})
+		}
+	case request.Rows > 0 && request.Columns > 0:
+		seats = generateSeats(request.Rows, request.Columns, request.TotalSeats)
+	default:
+		rows := (request.TotalSeats + defaultSeatColumns - 1) / defaultSeatColumns
+		seats = generateSeats(rows, defaultSeatColumns, request.TotalSeats)
+	}
+
+	rows, columns := layoutSize(seats)
+
 	response := &domain.Bus{
 		Id:         busId.String(),
 		Number:     request.Number,
-		TotalSeats: request.TotalSeats,
+		TotalSeats: len(seats),
+		Rows:       rows,
+		Columns:    columns,
+		Seats:      seats,
 	}
 
 	err = service.repo.Create(ctx, response)
@@ -40,7 +68,21 @@ func (service *Bus) GetByNumber(ctx context.Context, number string) (*domain.Bus
 }
 
 func (service *Bus) Get(ctx context.Context, id string) (*domain.Bus, error) {
-	return service.repo.Get(ctx, id)
+	bus, err := service.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	bus.Seats, err = seatLayout(ctx, service.repo, bus)
+	if err != nil {
+		return nil, err
+	}
+
+	if bus.Rows == 0 || bus.Columns == 0 {
+		bus.Rows, bus.Columns = layoutSize(bus.Seats)
+	}
+
+	return bus, nil
 }
 
 func (service *Bus) GetBusesList(ctx context.Context, page, pageSize int) ([]domain.Bus, error) {
@@ -2,19 +2,23 @@ package service
 
 import (
 	"aulway/internal/domain"
+	"aulway/internal/templates"
 	"aulway/internal/utils/config"
 	"bytes"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/jordan-wright/email"
+	"mime"
 	"net/smtp"
+	"time"
 )
 
 func SendEmail(to, subject, body string, smtpConfig config.SMTP) error {
 	from := smtpConfig.Username
 	msg := []byte("MIME-Version: 1.0\r\n" +
 		"Content-Type: text/html; charset=\"UTF-8\"\r\n" +
-		fmt.Sprintf("Subject: %s\r\n", subject) +
+		fmt.Sprintf("Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject)) +
 		"\r\n" + body)
 	auth := smtp.PlainAuth("", smtpConfig.Username, smtpConfig.Password, smtpConfig.Host)
 
@@ -26,25 +30,37 @@ func SendEmail(to, subject, body string, smtpConfig config.SMTP) error {
 	return nil
 }
 
-func SendEmailWithQR(to, subject string, tickets []domain.Ticket, smtpConfig config.SMTP, body string) error {
+// EmailAttachment is a file sent along with an email, like an e-ticket PDF.
+type EmailAttachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+func SendEmailWithQR(to, subject string, tickets []domain.Ticket, smtpConfig config.SMTP, body string, attachments ...EmailAttachment) error {
 	e := email.NewEmail()
 	e.From = smtpConfig.Username
 	e.To = []string{to}
 	e.Subject = subject
 	e.HTML = []byte(body)
 
-	for i, t := range tickets {
+	for _, t := range tickets {
 		if t.QRCode != "" {
 			qrBytes, err := base64.StdEncoding.DecodeString(t.QRCode)
 			if err != nil {
 				return fmt.Errorf("failed to decode QR: %w", err)
 			}
-			cid := fmt.Sprintf("qr%d.png", i+1)
-			_, err = e.Attach(bytes.NewReader(qrBytes), cid, "image/png")
+			_, err = e.Attach(bytes.NewReader(qrBytes), ticketQRName(&t), "image/png")
 			if err != nil {
 				return fmt.Errorf("failed to attach QR image: %w", err)
 			}
-			// CID is automatically assigned by filename, so use `cid:qr1.png` in the body
+			// CID is automatically assigned by filename, so use `cid:qr-1A.png` in the body
+		}
+	}
+
+	for _, attachment := range attachments {
+		if _, err := e.Attach(bytes.NewReader(attachment.Data), attachment.Name, attachment.ContentType); err != nil {
+			return fmt.Errorf("failed to attach %s: %w", attachment.Name, err)
 		}
 	}
 
@@ -55,3 +71,58 @@ func SendEmailWithQR(to, subject string, tickets []domain.Ticket, smtpConfig con
 	}
 	return nil
 }
+
+// ticketQRName names the QR image of a ticket in the purchase email. It is
+// keyed by seat, which is unique in an order, so the body and the attachments
+// match whatever order the tickets are loaded in.
+func ticketQRName(ticket *domain.Ticket) string {
+	return fmt.Sprintf("qr-%s.png", ticket.SeatNumber)
+}
+
+// ticketEmailData is what the purchase email of the tickets shows.
+func ticketEmailData(tickets []domain.Ticket, bus *domain.Bus, route *domain.Route) (templates.TicketsData, error) {
+	if len(tickets) == 0 {
+		return templates.TicketsData{}, errors.New("purchase email has no tickets")
+	}
+
+	data := templates.TicketsData{
+		OrderNumber: tickets[0].OrderNumber,
+		Route:       routeData(route),
+		Tickets:     make([]templates.TicketLine, 0, len(tickets)),
+	}
+	if bus != nil {
+		data.BusNumber = bus.Number
+	}
+	for i := range tickets {
+		line := templates.TicketLine{Seat: tickets[i].SeatNumber, Price: tickets[i].Price}
+		if tickets[i].QRCode != "" {
+			line.QR = ticketQRName(&tickets[i])
+		}
+		data.Tickets = append(data.Tickets, line)
+		data.Total += tickets[i].Price
+	}
+
+	return data, nil
+}
+
+func routeData(route *domain.Route) templates.RouteData {
+	return templates.RouteData{
+		Departure:           route.Departure,
+		Destination:         route.Destination,
+		DepartureLocation:   route.DepartureLocation,
+		DestinationLocation: route.DestinationLocation,
+		StartDate:           route.StartDate,
+		EndDate:             route.EndDate,
+	}
+}
+
+// SendCodeEmail sends a one-time code, like the email verification or the
+// password reset one, rendered in the locale.
+func SendCodeEmail(to, locale, name, code string, expiry time.Duration, smtpConfig config.SMTP) error {
+	subject, body, err := templates.Render(locale, name, templates.CodeData{Code: code, Minutes: int(expiry.Minutes())})
+	if err != nil {
+		return err
+	}
+
+	return SendEmail(to, subject, body, smtpConfig)
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/emailtemplate/model"
+	emailTemplateRepo "aulway/internal/repository/emailtemplate"
+	repoErrs "aulway/internal/repository/errs"
+	"aulway/internal/templates"
+	"aulway/internal/utils/errs"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// editableEmails are the emails admins can change the wording of.
+var editableEmails = []string{templates.TicketsEmail, templates.CancellationEmail, templates.ReminderEmail, templates.RouteChangeEmail}
+
+// EmailTemplateService renders emails with the templates admins edited,
+// falling back to the shipped ones, and manages the edited templates.
+type EmailTemplateService struct {
+	repo emailTemplateRepo.Repository
+
+	mu       sync.Mutex
+	compiled map[string]compiledTemplate
+}
+
+// compiledTemplate caches an edited template until a new version is saved.
+type compiledTemplate struct {
+	version  int
+	template *templates.Template
+}
+
+func NewEmailTemplateService(repo emailTemplateRepo.Repository) *EmailTemplateService {
+	return &EmailTemplateService{
+		repo:     repo,
+		compiled: make(map[string]compiledTemplate),
+	}
+}
+
+// Render renders an email in the locale. An edited template that cannot be
+// loaded or rendered is logged and the shipped one is used, so the email
+// still goes out.
+func (s *EmailTemplateService) Render(ctx context.Context, locale, name string, data any) (subject, body string, err error) {
+	locale = templates.Locale(locale)
+	if !slices.Contains(editableEmails, name) {
+		return templates.Render(locale, name, data)
+	}
+
+	edited, err := s.edited(ctx, name, locale)
+	if err == nil && edited != nil {
+		subject, body, err = edited.Execute(data)
+		if err == nil {
+			return subject, body, nil
+		}
+	}
+	if err != nil {
+		slog.Error("failed to render edited email template, using the shipped one",
+			slog.String("template", name), slog.String("locale", locale), slog.String("error", err.Error()))
+	}
+
+	return templates.Render(locale, name, data)
+}
+
+// edited returns the compiled edited template, nil when there is none.
+func (s *EmailTemplateService) edited(ctx context.Context, name, locale string) (*templates.Template, error) {
+	stored, err := s.repo.Get(ctx, name, locale)
+	if errors.Is(err, repoErrs.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := name + "/" + locale
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cached, ok := s.compiled[key]; ok && cached.version == stored.Version {
+		return cached.template, nil
+	}
+
+	compiled, err := templates.Compile(name, stored.Subject, stored.Body)
+	if err != nil {
+		return nil, err
+	}
+	s.compiled[key] = compiledTemplate{version: stored.Version, template: compiled}
+
+	return compiled, nil
+}
+
+// Schema lists the variables of every editable email.
+func (s *EmailTemplateService) Schema() map[string][]templates.Variable {
+	schema := make(map[string][]templates.Variable, len(editableEmails))
+	for _, name := range editableEmails {
+		schema[name] = templates.Schema(name)
+	}
+
+	return schema
+}
+
+// ListTemplates returns the current template of every editable email in
+// every locale, edited or shipped.
+func (s *EmailTemplateService) ListTemplates(ctx context.Context) ([]domain.EmailTemplate, error) {
+	stored, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]domain.EmailTemplate, 0, len(editableEmails)*len(templates.Locales))
+	for _, name := range editableEmails {
+		for _, locale := range templates.Locales {
+			i := slices.IndexFunc(stored, func(t domain.EmailTemplate) bool { return t.Name == name && t.Locale == locale })
+			if i >= 0 {
+				list = append(list, stored[i])
+				continue
+			}
+
+			builtin, err := builtinTemplate(name, locale)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, *builtin)
+		}
+	}
+
+	return list, nil
+}
+
+// GetTemplate returns the current template of an email.
+func (s *EmailTemplateService) GetTemplate(ctx context.Context, name, locale string) (*domain.EmailTemplate, error) {
+	if err := editable(name, locale); err != nil {
+		return nil, err
+	}
+
+	stored, err := s.repo.Get(ctx, name, locale)
+	if errors.Is(err, repoErrs.ErrRecordNotFound) {
+		return builtinTemplate(name, locale)
+	}
+
+	return stored, err
+}
+
+// SaveTemplate stores a new version of an email after checking it renders
+// the sample data.
+func (s *EmailTemplateService) SaveTemplate(ctx context.Context, name, locale, adminID string, req model.EmailTemplateRequest) (*domain.EmailTemplate, error) {
+	if err := editable(name, locale); err != nil {
+		return nil, err
+	}
+	if _, err := preview(name, req.Subject, req.Body); err != nil {
+		return nil, err
+	}
+
+	template := &domain.EmailTemplate{
+		Name:      name,
+		Locale:    locale,
+		Subject:   req.Subject,
+		Body:      req.Body,
+		UpdatedBy: adminID,
+	}
+	if err := s.repo.Save(ctx, template); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// DeleteTemplate reverts an email to the shipped template.
+func (s *EmailTemplateService) DeleteTemplate(ctx context.Context, name, locale string) error {
+	if err := editable(name, locale); err != nil {
+		return err
+	}
+
+	return s.repo.Delete(ctx, name, locale)
+}
+
+func (s *EmailTemplateService) ListVersions(ctx context.Context, name, locale string) ([]domain.EmailTemplateVersion, error) {
+	if err := editable(name, locale); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Versions(ctx, name, locale)
+}
+
+// RestoreVersion saves an earlier version again as the newest one.
+func (s *EmailTemplateService) RestoreVersion(ctx context.Context, name, locale string, version int, adminID string) (*domain.EmailTemplate, error) {
+	if err := editable(name, locale); err != nil {
+		return nil, err
+	}
+
+	old, err := s.repo.Version(ctx, name, locale, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.SaveTemplate(ctx, name, locale, adminID, model.EmailTemplateRequest{Subject: old.Subject, Body: old.Body})
+}
+
+// Preview renders the sources in the request, or the current template when
+// they are empty, against sample tickets.
+func (s *EmailTemplateService) Preview(ctx context.Context, name, locale string, req model.EmailTemplateRequest) (*domain.EmailPreview, error) {
+	if req.Subject == "" && req.Body == "" {
+		current, err := s.GetTemplate(ctx, name, locale)
+		if err != nil {
+			return nil, err
+		}
+		req.Subject, req.Body = current.Subject, current.Body
+	} else if err := editable(name, locale); err != nil {
+		return nil, err
+	}
+
+	return preview(name, req.Subject, req.Body)
+}
+
+func editable(name, locale string) error {
+	if !slices.Contains(editableEmails, name) || !templates.Supported(locale) {
+		return fmt.Errorf("%w: %s/%s", errs.ErrUnknownEmailTemplate, locale, name)
+	}
+
+	return nil
+}
+
+func builtinTemplate(name, locale string) (*domain.EmailTemplate, error) {
+	subject, body, err := templates.Source(locale, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.EmailTemplate{Name: name, Locale: locale, Subject: subject, Body: body, Builtin: true}, nil
+}
+
+// preview compiles the sources and renders them against sample data made
+// the way the real emails are.
+func preview(name, subject, body string) (*domain.EmailPreview, error) {
+	compiled, err := templates.Compile(name, subject, body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrInvalidEmailTemplate, err)
+	}
+
+	data, err := sampleEmailData(name)
+	if err != nil {
+		return nil, err
+	}
+
+	renderedSubject, renderedBody, err := compiled.Execute(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrInvalidEmailTemplate, err)
+	}
+
+	return &domain.EmailPreview{Subject: renderedSubject, Body: renderedBody}, nil
+}
+
+func sampleEmailData(name string) (any, error) {
+	start := time.Date(2025, time.June, 14, 8, 30, 0, 0, almaty)
+	route := &domain.Route{
+		Departure:           "Алматы",
+		Destination:         "Талдыкорган",
+		DepartureLocation:   "Сайран автовокзал, пр. Толе би 294",
+		DestinationLocation: "Автовокзал Талдыкорган, ул. Шевченко 138",
+		StartDate:           start,
+		EndDate:             start.Add(4*time.Hour + 15*time.Minute),
+		Price:               5500,
+	}
+	tickets := []domain.Ticket{
+		{SeatNumber: "3A", Price: 5500, OrderNumber: "AW-100234", QRCode: "sample", Status: "approved"},
+		{SeatNumber: "3B", Price: 5500, OrderNumber: "AW-100234", QRCode: "sample", Status: "approved"},
+	}
+
+	switch name {
+	case templates.TicketsEmail:
+		return ticketEmailData(tickets, &domain.Bus{Number: "A 123 BC 02"}, route)
+	case templates.CancellationEmail:
+		order := &domain.Order{Number: "AW-100234", Status: domain.OrderCancelled}
+		return cancellationEmailData(order, tickets, route, 8250), nil
+	case templates.ReminderEmail:
+		return templates.ReminderData{OrderNumber: "AW-100234", Route: routeData(route), BusNumber: "A 123 BC 02", Seats: []string{"3A", "3B"}}, nil
+	case templates.RouteChangeEmail:
+		moved := *route
+		moved.StartDate = start.Add(90 * time.Minute)
+		moved.EndDate = route.EndDate.Add(90 * time.Minute)
+		moved.BusId = "replacement"
+		data := routeChangeData(route, &moved)
+		data.OrderNumber, data.Seats = "AW-100234", []string{"3A", "3B"}
+		data.BusNumber, data.PreviousBusNumber = "B 456 CD 02", "A 123 BC 02"
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", errs.ErrUnknownEmailTemplate, name)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/emailtemplate/model"
+	emailTemplateRepository "aulway/internal/repository/emailtemplate"
+	repoErrs "aulway/internal/repository/errs"
+	"aulway/internal/templates"
+	"aulway/internal/utils/errs"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewRendersSampleOrder(t *testing.T) {
+	s := NewEmailTemplateService(emailTemplateRepository.Repository{})
+	ctx := context.Background()
+
+	preview, err := s.Preview(ctx, templates.TicketsEmail, templates.EN, model.EmailTemplateRequest{
+		Subject: "Order {{.OrderNumber}}",
+		Body:    `{{range .Tickets}}<p>{{.Seat}} <img src="cid:{{.QR}}"></p>{{end}}<p>{{.Route.Departure}}, {{date .Route.StartDate}}</p>`,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Order AW-100234", preview.Subject)
+	require.Contains(t, preview.Body, `<img src="cid:qr-3A.png">`)
+	require.Contains(t, preview.Body, "3B")
+	require.Contains(t, preview.Body, "Алматы, 14.06.2025")
+
+	_, err = s.Preview(ctx, templates.TicketsEmail, templates.EN, model.EmailTemplateRequest{Subject: "x", Body: "{{.Route.Price}}"})
+	require.ErrorIs(t, err, errs.ErrInvalidEmailTemplate)
+
+	// the schema does not know a value is a date, rendering the sample does
+	_, err = s.Preview(ctx, templates.TicketsEmail, templates.EN, model.EmailTemplateRequest{Subject: "x", Body: "{{date .OrderNumber}}"})
+	require.ErrorIs(t, err, errs.ErrInvalidEmailTemplate)
+
+	_, err = s.Preview(ctx, templates.VerificationEmail, templates.EN, model.EmailTemplateRequest{Subject: "x", Body: "{{.Code}}"})
+	require.ErrorIs(t, err, errs.ErrUnknownEmailTemplate)
+	_, err = s.Preview(ctx, templates.TicketsEmail, "de", model.EmailTemplateRequest{Subject: "x", Body: "x"})
+	require.ErrorIs(t, err, errs.ErrUnknownEmailTemplate)
+}
+
+func TestEditedTemplatesAreVersionedAndUsed(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	name, locale := templates.CancellationEmail, templates.KK
+	t.Cleanup(func() {
+		db.Where("name = ? AND locale = ?", name, locale).Delete(&domain.EmailTemplate{})
+		db.Where("name = ? AND locale = ?", name, locale).Delete(&domain.EmailTemplateVersion{})
+	})
+
+	s := NewEmailTemplateService(emailTemplateRepository.New(db))
+	data := cancellationEmailData(&domain.Order{Number: "AW-7"}, []domain.Ticket{{SeatNumber: "1A"}}, &domain.Route{Departure: "Алматы"}, 100)
+
+	builtinSubject, _, err := templates.Render(locale, name, data)
+	require.NoError(t, err)
+
+	first, err := s.SaveTemplate(ctx, name, locale, "admin-1", model.EmailTemplateRequest{Subject: "v1 {{.OrderNumber}}", Body: "<p>{{join .Seats \", \"}}</p>"})
+	require.NoError(t, err)
+	_, err = s.SaveTemplate(ctx, name, locale, "admin-2", model.EmailTemplateRequest{Subject: "v2 {{.OrderNumber}}", Body: "<p>{{.Refund}}</p>"})
+	require.NoError(t, err)
+
+	subject, body, err := s.Render(ctx, locale, name, data)
+	require.NoError(t, err)
+	require.Equal(t, "v2 AW-7", subject)
+	require.Contains(t, body, "<p>100</p>")
+
+	restored, err := s.RestoreVersion(ctx, name, locale, first.Version, "admin-3")
+	require.NoError(t, err)
+	require.Equal(t, 3, restored.Version)
+	subject, _, err = s.Render(ctx, locale, name, data)
+	require.NoError(t, err)
+	require.Equal(t, "v1 AW-7", subject)
+
+	// a template that stopped rendering does not hold back the email
+	require.NoError(t, db.Model(&domain.EmailTemplate{}).Where("name = ? AND locale = ?", name, locale).
+		Updates(map[string]any{"version": 4, "body": "{{.Missing}}"}).Error)
+	subject, _, err = s.Render(ctx, locale, name, data)
+	require.NoError(t, err)
+	require.Equal(t, builtinSubject, subject)
+
+	require.NoError(t, s.DeleteTemplate(ctx, name, locale))
+	require.ErrorIs(t, s.DeleteTemplate(ctx, name, locale), repoErrs.ErrRecordNotFound)
+
+	current, err := s.GetTemplate(ctx, name, locale)
+	require.NoError(t, err)
+	require.True(t, current.Builtin)
+
+	versions, err := s.ListVersions(ctx, name, locale)
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+	require.Equal(t, "admin-3", versions[0].CreatedBy)
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"aulway/internal/utils/config"
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTP is a local SMTP server that keeps the mail it receives. With
+// failing set it rejects every message after DATA like a server in trouble.
+type fakeSMTP struct {
+	listener net.Listener
+	failing  atomic.Bool
+
+	mu       sync.Mutex
+	received []fakeMail
+}
+
+type fakeMail struct {
+	To   string
+	Data string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &fakeSMTP{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	return server
+}
+
+func (s *fakeSMTP) config() config.SMTP {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return config.SMTP{Host: host, Port: port, Username: "noreply@aulway.kz", Password: "secret"}
+}
+
+func (s *fakeSMTP) mail() []fakeMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeMail(nil), s.received...)
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost fake SMTP")
+	var to string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case strings.HasPrefix(cmd, "AUTH"):
+			reply("235 authenticated")
+		case strings.HasPrefix(cmd, "MAIL FROM"):
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "RCPT TO"):
+			to = strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>")
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			if s.failing.Load() {
+				reply("451 try again later")
+				continue
+			}
+			s.mu.Lock()
+			s.received = append(s.received, fakeMail{To: to, Data: data.String()})
+			s.mu.Unlock()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
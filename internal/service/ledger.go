@@ -0,0 +1,107 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	repoErrs "aulway/internal/repository/errs"
+	ledgerRepo "aulway/internal/repository/ledger"
+	orderRepo "aulway/internal/repository/order"
+	paymentRepo "aulway/internal/repository/payment"
+	"context"
+)
+
+func NewLedgerService(ledgerRepo ledgerRepo.Repository, paymentRepo paymentRepo.Repository, orderRepo orderRepo.Repository, payments *PaymentRegistry) *LedgerService {
+	return &LedgerService{
+		LedgerRepo:  ledgerRepo,
+		PaymentRepo: paymentRepo,
+		OrderRepo:   orderRepo,
+		Payments:    payments,
+	}
+}
+
+type LedgerService struct {
+	LedgerRepo  ledgerRepo.Repository
+	PaymentRepo paymentRepo.Repository
+	OrderRepo   orderRepo.Repository
+	Payments    *PaymentRegistry
+}
+
+func (s *LedgerService) PaymentLedger(ctx context.Context, paymentID string) (*domain.LedgerBalance, error) {
+	if _, err := s.PaymentRepo.GetByID(ctx, paymentID); err != nil {
+		return nil, err
+	}
+
+	return s.balance(ctx, paymentID)
+}
+
+func (s *LedgerService) OrderLedger(ctx context.Context, orderNumber string) (*domain.LedgerBalance, error) {
+	order, err := s.OrderRepo.GetByNumber(ctx, orderNumber)
+	if err != nil {
+		return nil, err
+	}
+	if order.PaymentID == "" {
+		return nil, repoErrs.ErrRecordNotFound
+	}
+
+	return s.balance(ctx, order.PaymentID)
+}
+
+// CheckPayment compares the charged and refunded totals of the ledger with the
+// amounts the payment provider reports.
+func (s *LedgerService) CheckPayment(ctx context.Context, paymentID string) (*domain.LedgerCheck, error) {
+	payment, err := s.PaymentRepo.GetByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	processor, err := s.Payments.Get(payment.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := processor.Details(ctx, payment.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	ledger, err := s.balance(ctx, payment.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.LedgerCheck{
+		PaymentID:        payment.ID,
+		Provider:         payment.Provider,
+		LedgerCharged:    ledger.Charged,
+		ProviderCharged:  details.Captured,
+		LedgerRefunded:   ledger.Refunded,
+		ProviderRefunded: details.Refunded,
+		Consistent:       ledger.Charged == details.Captured && ledger.Refunded == details.Refunded,
+	}, nil
+}
+
+// balance adds up the entries of the payment. The totals are positive, the
+// balance is what is left of the money after refunds, chargebacks and fees.
+func (s *LedgerService) balance(ctx context.Context, paymentID string) (*domain.LedgerBalance, error) {
+	entries, err := s.LedgerRepo.GetByPaymentID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := &domain.LedgerBalance{PaymentID: paymentID, Entries: entries}
+	for i := range entries {
+		switch entries[i].Type {
+		case domain.LedgerCharge:
+			balance.Charged += entries[i].Amount
+		case domain.LedgerRefund, domain.LedgerPartialRefund:
+			balance.Refunded -= entries[i].Amount
+		case domain.LedgerChargeback:
+			balance.Chargebacks -= entries[i].Amount
+		case domain.LedgerFee:
+			balance.Fees -= entries[i].Amount
+		}
+		balance.Balance += entries[i].Amount
+		entries[i].Balance = balance.Balance
+	}
+
+	return balance, nil
+}
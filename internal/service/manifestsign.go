@@ -0,0 +1,52 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+)
+
+// ManifestSigner signs boarding manifests with Ed25519. Drivers' devices get
+// only the public key, so a device can check a manifest but cannot forge one
+// or sign ticket codes, which use another key.
+type ManifestSigner struct {
+	key ed25519.PrivateKey
+}
+
+// NewManifestSigner takes the base64 encoded 32-byte Ed25519 seed.
+func NewManifestSigner(seed string) (*ManifestSigner, error) {
+	raw, err := base64.StdEncoding.DecodeString(seed)
+	if err != nil {
+		return nil, fmt.Errorf("manifest signing key is not base64: %w", err)
+	}
+	if len(raw) != ed25519.SeedSize {
+		return nil, fmt.Errorf("manifest signing key must be a %d-byte Ed25519 seed, got %d bytes", ed25519.SeedSize, len(raw))
+	}
+
+	return &ManifestSigner{key: ed25519.NewKeyFromSeed(raw)}, nil
+}
+
+// Sign returns the base64 encoded signature of data.
+func (s *ManifestSigner) Sign(data []byte) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, data))
+}
+
+// PublicKey returns the base64 encoded key devices verify manifests with.
+func (s *ManifestSigner) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
+
+// VerifyManifest checks a signature made by Sign against a public key
+// returned by PublicKey.
+func VerifyManifest(publicKey string, data []byte, signature string) bool {
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return false
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	return ed25519.Verify(key, data, sig)
+}
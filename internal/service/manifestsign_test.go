@@ -0,0 +1,47 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testManifestSigner(t *testing.T) *ManifestSigner {
+	t.Helper()
+
+	signer, err := NewManifestSigner(base64.StdEncoding.EncodeToString(make([]byte, ed25519.SeedSize)))
+	require.NoError(t, err)
+
+	return signer
+}
+
+func TestManifestSignerVerifiesWithPublicKey(t *testing.T) {
+	signer := testManifestSigner(t)
+	manifest := []byte(`{"route_id":"route-1","passengers":[]}`)
+
+	signature := signer.Sign(manifest)
+	require.True(t, VerifyManifest(signer.PublicKey(), manifest, signature))
+
+	require.False(t, VerifyManifest(signer.PublicKey(), []byte(`{"route_id":"route-2","passengers":[]}`), signature), "edited manifest")
+
+	other, err := NewManifestSigner(base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", ed25519.SeedSize))))
+	require.NoError(t, err)
+	require.False(t, VerifyManifest(other.PublicKey(), manifest, signature), "other key")
+
+	// the HMAC the ticket key made is no longer accepted
+	require.False(t, VerifyManifest(signer.PublicKey(), manifest, NewTicketSigner("test").SignDocument(manifest)), "ticket key")
+}
+
+func TestNewManifestSignerRejectsBadKeys(t *testing.T) {
+	for name, key := range map[string]string{
+		"empty":      "",
+		"not base64": "not a key!",
+		"too short":  base64.StdEncoding.EncodeToString([]byte("short")),
+	} {
+		_, err := NewManifestSigner(key)
+		require.Error(t, err, name)
+	}
+}
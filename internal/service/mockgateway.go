@@ -0,0 +1,138 @@
+package service
+
+import (
+	"aulway/internal/mockgateway"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const MockGatewayProvider = "mock"
+
+// MockGatewayProcessor talks to a mockgateway server, it is the provider for
+// local runs and tests.
+type MockGatewayProcessor struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewMockGatewayProcessor(baseURL string) PaymentProcessor {
+	return &MockGatewayProcessor{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *MockGatewayProcessor) Name() string {
+	return MockGatewayProvider
+}
+
+func (p *MockGatewayProcessor) Currency() string {
+	return "KZT"
+}
+
+func (p *MockGatewayProcessor) Capabilities() Capabilities {
+	return Capabilities{PartialRefund: true, SCA: true}
+}
+
+func (p *MockGatewayProcessor) ProcessPayment(ctx context.Context, userID string, amount int, paymentMethodID string) (*PaymentResult, error) {
+	var payment mockgateway.Payment
+	err := p.do(ctx, http.MethodPost, "/v1/payments", mockgateway.CreatePaymentRequest{
+		Amount:        amount,
+		Currency:      p.Currency(),
+		PaymentMethod: paymentMethodID,
+		Reference:     userID,
+	}, &payment)
+	if err != nil {
+		return nil, fmt.Errorf("mock gateway payment error: %w", err)
+	}
+
+	return &PaymentResult{
+		Status:        mockPaymentStatus(payment.Status),
+		TransactionID: payment.ID,
+		ClientSecret:  payment.ClientSecret,
+	}, nil
+}
+
+func (p *MockGatewayProcessor) Details(ctx context.Context, transactionID string) (*PaymentDetails, error) {
+	var payment mockgateway.Payment
+	if err := p.do(ctx, http.MethodGet, "/v1/payments/"+transactionID, nil, &payment); err != nil {
+		return nil, fmt.Errorf("mock gateway payment details error: %w", err)
+	}
+
+	details := &PaymentDetails{
+		Status:   mockPaymentStatus(payment.Status),
+		Refunded: payment.Refunded,
+	}
+	if details.Status == PaymentSucceeded {
+		details.Captured = payment.Amount
+	}
+
+	return details, nil
+}
+
+func (p *MockGatewayProcessor) Cancel(ctx context.Context, transactionID string) error {
+	if err := p.do(ctx, http.MethodPost, "/v1/payments/"+transactionID+"/cancel", nil, nil); err != nil {
+		return fmt.Errorf("mock gateway cancel error: %w", err)
+	}
+
+	return nil
+}
+
+func (p *MockGatewayProcessor) Refund(ctx context.Context, transactionID string, amount int) (bool, error) {
+	err := p.do(ctx, http.MethodPost, "/v1/payments/"+transactionID+"/refunds", mockgateway.RefundRequest{Amount: amount}, nil)
+	if err != nil {
+		return false, fmt.Errorf("mock gateway refund error: %w", err)
+	}
+
+	return true, nil
+}
+
+func (p *MockGatewayProcessor) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, &payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var gatewayErr mockgateway.Error
+		json.NewDecoder(resp.Body).Decode(&gatewayErr)
+		return fmt.Errorf("%s: %s", gatewayErr.Code, gatewayErr.Message)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func mockPaymentStatus(status string) PaymentStatus {
+	switch status {
+	case mockgateway.StatusSucceeded:
+		return PaymentSucceeded
+	case mockgateway.StatusRequiresAction:
+		return PaymentRequiresAction
+	case mockgateway.StatusProcessing:
+		return PaymentProcessing
+	default:
+		return PaymentFailed
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"aulway/internal/mockgateway"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mockPayments serves a fresh mock gateway on localhost for the test and
+// returns a registry whose active provider talks to it.
+func mockPayments(t *testing.T) (*PaymentRegistry, *mockgateway.Gateway) {
+	t.Helper()
+
+	gateway := mockgateway.New()
+	server := httptest.NewServer(gateway.Handler())
+	t.Cleanup(server.Close)
+
+	registry, err := NewPaymentRegistry(MockGatewayProvider, NewMockGatewayProcessor(server.URL))
+	require.NoError(t, err)
+
+	return registry, gateway
+}
+
+// authenticate completes 3-D Secure for a payment as the customer's browser would.
+func authenticate(t *testing.T, processor PaymentProcessor, transactionID string, success bool) {
+	t.Helper()
+
+	body, _ := json.Marshal(mockgateway.AuthenticateRequest{Success: success})
+	url := processor.(*MockGatewayProcessor).baseURL + "/v1/payments/" + transactionID + "/authenticate"
+
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestPaymentRegistryRejectsUnknownProvider(t *testing.T) {
+	_, err := NewPaymentRegistry("kaspi", NewMockGatewayProcessor("http://127.0.0.1:1"))
+	require.Error(t, err)
+}
+
+func TestMockGatewayCharge(t *testing.T) {
+	payments, gateway := mockPayments(t)
+	processor := payments.Active()
+	ctx := context.Background()
+
+	result, err := processor.ProcessPayment(ctx, "user-1", 10000, "pm_card_visa")
+	require.NoError(t, err)
+	require.Equal(t, PaymentSucceeded, result.Status)
+	require.Empty(t, result.ClientSecret)
+
+	ok, err := processor.Refund(ctx, result.TransactionID, 4000)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, err = processor.Refund(ctx, result.TransactionID, 7000)
+	require.Error(t, err, "refunds beyond the charged amount must fail")
+
+	require.Equal(t, 4000, gateway.Payments()[0].Refunded)
+}
+
+func TestMockGatewayDecline(t *testing.T) {
+	payments, _ := mockPayments(t)
+
+	_, err := payments.Active().ProcessPayment(context.Background(), "user-1", 10000, mockgateway.MethodDeclined)
+	require.ErrorContains(t, err, "card_declined")
+}
+
+func TestMockGateway3DS(t *testing.T) {
+	payments, _ := mockPayments(t)
+	processor := payments.Active()
+	ctx := context.Background()
+
+	approved, err := processor.ProcessPayment(ctx, "user-1", 10000, mockgateway.Method3DS)
+	require.NoError(t, err)
+	require.Equal(t, PaymentRequiresAction, approved.Status)
+	require.NotEmpty(t, approved.ClientSecret)
+
+	_, err = processor.Refund(ctx, approved.TransactionID, 10000)
+	require.Error(t, err, "an unauthenticated payment has nothing to refund")
+
+	authenticate(t, processor, approved.TransactionID, true)
+	details, err := processor.Details(ctx, approved.TransactionID)
+	require.NoError(t, err)
+	require.Equal(t, PaymentSucceeded, details.Status)
+	require.Equal(t, 10000, details.Captured)
+
+	rejected, err := processor.ProcessPayment(ctx, "user-1", 10000, mockgateway.Method3DS)
+	require.NoError(t, err)
+	authenticate(t, processor, rejected.TransactionID, false)
+	details, err = processor.Details(ctx, rejected.TransactionID)
+	require.NoError(t, err)
+	require.Equal(t, PaymentFailed, details.Status)
+	require.Zero(t, details.Captured)
+
+	abandoned, err := processor.ProcessPayment(ctx, "user-1", 10000, mockgateway.Method3DS)
+	require.NoError(t, err)
+	require.NoError(t, processor.Cancel(ctx, abandoned.TransactionID))
+	details, err = processor.Details(ctx, abandoned.TransactionID)
+	require.NoError(t, err)
+	require.Equal(t, PaymentFailed, details.Status)
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	ticketRepo "aulway/internal/repository/ticket"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// NoShowJob marks the tickets nobody boarded with as no-shows once their
+// route has departed and the grace period for late scans is over.
+type NoShowJob struct {
+	TicketRepo ticketRepo.Repository
+	Grace      time.Duration
+	Interval   time.Duration
+}
+
+func NewNoShowJob(ticketRepo ticketRepo.Repository, grace, interval time.Duration) *NoShowJob {
+	return &NoShowJob{
+		TicketRepo: ticketRepo,
+		Grace:      grace,
+		Interval:   interval,
+	}
+}
+
+// Run marks no-shows every interval until ctx is done.
+func (j *NoShowJob) Run(ctx context.Context) error {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.MarkNoShows(ctx); err != nil {
+			slog.Error("failed to mark no-shows", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *NoShowJob) MarkNoShows(ctx context.Context) (int64, error) {
+	marked, err := j.TicketRepo.MarkNoShows(ctx, time.Now().Add(-j.Grace))
+	if err != nil {
+		return 0, err
+	}
+	if marked > 0 {
+		slog.Info("tickets marked as no-show", slog.Int64("count", marked))
+	}
+
+	return marked, nil
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	busRepo "aulway/internal/repository/bus"
+	notificationRepo "aulway/internal/repository/notification"
+	outboxRepo "aulway/internal/repository/outbox"
+	routeRepo "aulway/internal/repository/route"
+	settingsRepo "aulway/internal/repository/settings"
+	"aulway/internal/templates"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TripNotifier emails passengers about their trips: reminders ahead of the
+// departure and the changes made to the route after they bought tickets.
+// The emails go through the outbox and are pushed to the devices of the
+// passengers as well.
+type TripNotifier struct {
+	Repo         notificationRepo.Repository
+	OutboxRepo   outboxRepo.Repository
+	RouteRepo    routeRepo.Repository
+	BusRepo      busRepo.Repository
+	SettingsRepo settingsRepo.Repository
+	Templates    *EmailTemplateService
+	Push         *PushService
+}
+
+func NewTripNotifier(repo notificationRepo.Repository, outboxRepo outboxRepo.Repository, routeRepo routeRepo.Repository, busRepo busRepo.Repository, settingsRepo settingsRepo.Repository, emailTemplates *EmailTemplateService, pushes *PushService) *TripNotifier {
+	return &TripNotifier{
+		Repo:         repo,
+		OutboxRepo:   outboxRepo,
+		RouteRepo:    routeRepo,
+		BusRepo:      busRepo,
+		SettingsRepo: settingsRepo,
+		Templates:    emailTemplates,
+		Push:         pushes,
+	}
+}
+
+// RouteChanged emails every holder of approved tickets on the route when its
+// times, bus or addresses changed. Routes that already departed are left
+// alone. Failures are logged, the route is updated either way.
+func (n *TripNotifier) RouteChanged(ctx context.Context, before, after *domain.Route) {
+	data := routeChangeData(before, after)
+	if !data.TimeChanged && !data.BusChanged && !data.PickupChanged {
+		return
+	}
+	if now := time.Now(); before.StartDate.Before(now) && after.StartDate.Before(now) {
+		return
+	}
+
+	if err := n.notifyRouteChange(ctx, before, after, data); err != nil {
+		slog.Error("failed to notify passengers of route change", slog.String("route_id", after.Id), slog.String("error", err.Error()))
+	}
+}
+
+func (n *TripNotifier) notifyRouteChange(ctx context.Context, before, after *domain.Route, data templates.RouteChangeData) error {
+	recipients, err := n.Repo.RouteRecipients(ctx, after.Id)
+	if err != nil {
+		return err
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	data.BusNumber = n.busNumber(ctx, after.BusId)
+	if data.BusChanged {
+		data.PreviousBusNumber = n.busNumber(ctx, before.BusId)
+	}
+
+	messages := make([]*domain.OutboxMessage, 0, len(recipients))
+	tx := n.Repo.BeginTransaction()
+	for _, recipient := range recipients {
+		data.OrderNumber = recipient.OrderNumber
+		data.Seats = recipient.Seats
+
+		subject, body, err := n.Templates.Render(ctx, userLanguage(ctx, n.SettingsRepo, recipient.UserID), templates.RouteChangeEmail, data)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		message := newOutboxMessage(domain.OutboxRouteChange, recipient.Email, subject, body, recipient.OrderID)
+		if err := n.queue(ctx, tx, message); err != nil {
+			tx.Rollback()
+			return err
+		}
+		messages = append(messages, message)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit route change emails: %w", err)
+	}
+
+	for i, message := range messages {
+		n.Push.Notify(ctx, recipients[i].UserID, tripPush(message, after))
+	}
+
+	slog.Info("passengers notified of route change", slog.String("route_id", after.Id), slog.Int("orders", len(recipients)))
+	return nil
+}
+
+// Remind queues the reminder of an order lead ahead of its departure and
+// reports whether it did, false means the reminder was already sent.
+func (n *TripNotifier) Remind(ctx context.Context, recipient domain.TripRecipient, lead time.Duration) (bool, error) {
+	route, err := n.RouteRepo.Get(ctx, recipient.RouteID)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch route: %w", err)
+	}
+
+	data := templates.ReminderData{
+		OrderNumber: recipient.OrderNumber,
+		Route:       routeData(route),
+		BusNumber:   n.busNumber(ctx, route.BusId),
+		Seats:       recipient.Seats,
+	}
+	subject, body, err := n.Templates.Render(ctx, userLanguage(ctx, n.SettingsRepo, recipient.UserID), templates.ReminderEmail, data)
+	if err != nil {
+		return false, err
+	}
+
+	tx := n.Repo.BeginTransaction()
+	recorded, err := n.Repo.RecordReminder(ctx, tx, &domain.DepartureReminder{
+		OrderID:     recipient.OrderID,
+		LeadMinutes: int(lead.Minutes()),
+		StartDate:   recipient.StartDate,
+		CreatedAt:   time.Now(),
+	})
+	if err != nil || !recorded {
+		tx.Rollback()
+		return false, err
+	}
+
+	message := newOutboxMessage(domain.OutboxReminder, recipient.Email, subject, body, recipient.OrderID)
+	if err := n.queue(ctx, tx, message); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return false, fmt.Errorf("failed to commit reminder: %w", err)
+	}
+
+	n.Push.Notify(ctx, recipient.UserID, tripPush(message, route))
+
+	return true, nil
+}
+
+// queue adds the email to the outbox, riders who signed up with only a phone
+// have no email and get the push alone.
+func (n *TripNotifier) queue(ctx context.Context, tx *gorm.DB, message *domain.OutboxMessage) error {
+	if message.Recipient == "" {
+		return nil
+	}
+
+	return n.OutboxRepo.Create(ctx, tx, message)
+}
+
+// busNumber is the plate number of the bus, empty when it cannot be loaded
+// so the email still goes out.
+func (n *TripNotifier) busNumber(ctx context.Context, busID string) string {
+	bus, err := n.BusRepo.Get(ctx, busID)
+	if err != nil {
+		slog.Error("failed to fetch bus for trip email", slog.String("bus_id", busID), slog.String("error", err.Error()))
+		return ""
+	}
+
+	return bus.Number
+}
+
+// routeChangeData compares the route before and after an update, the order
+// and bus details are filled in per email.
+func routeChangeData(before, after *domain.Route) templates.RouteChangeData {
+	return templates.RouteChangeData{
+		Route:         routeData(after),
+		Previous:      routeData(before),
+		TimeChanged:   !before.StartDate.Equal(after.StartDate) || !before.EndDate.Equal(after.EndDate),
+		BusChanged:    before.BusId != after.BusId,
+		PickupChanged: before.DepartureLocation != after.DepartureLocation || before.DestinationLocation != after.DestinationLocation,
+	}
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/auth/model"
+	"aulway/internal/oidc"
+	repoErrs "aulway/internal/repository/errs"
+	"aulway/internal/repository/identity"
+	"aulway/internal/repository/user"
+	"aulway/internal/utils/errs"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/oauth2"
+)
+
+// oidcState is what is kept of a sign-in while the user is away at the
+// provider, under the state sent along.
+type oidcState struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// OIDCAuth signs users in with Google and Apple. An account at a provider is
+// linked on the first sign-in to the user who verified the same email with
+// us, or a user is signed up with it, so a user can have accounts of several
+// providers.
+type OIDCAuth struct {
+	Providers  map[string]*oidc.Provider
+	Identities identity.Repository
+	Users      user.Repository
+	Redis      *redis.Client
+	StateTTL   time.Duration
+}
+
+func NewOIDCAuth(providers map[string]*oidc.Provider, identityRepo identity.Repository, userRepo user.Repository, redis *redis.Client, stateTTL time.Duration) *OIDCAuth {
+	return &OIDCAuth{
+		Providers:  providers,
+		Identities: identityRepo,
+		Users:      userRepo,
+		Redis:      redis,
+		StateTTL:   stateTTL,
+	}
+}
+
+// Begin returns where to send the user to sign in with the provider, and the
+// hash of the state the browser has to bring back to the callback.
+func (s *OIDCAuth) Begin(ctx context.Context, providerName string) (*model.OIDCStart, error) {
+	provider, ok := s.Providers[providerName]
+	if !ok {
+		return nil, errs.ErrUnknownProvider
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := oidcState{Provider: providerName, Nonce: nonce, Verifier: oauth2.GenerateVerifier()}
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Redis.Set(ctx, oidcStateKey(state), data, s.StateTTL).Err(); err != nil {
+		return nil, fmt.Errorf("failed to store sign-in state: %w", err)
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, pending.Verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.OIDCStart{
+		URL:           authURL,
+		StateHash:     hashState(state),
+		CrossSitePost: provider.AuthParams["response_mode"] == "form_post",
+		Expires:       time.Now().Add(s.StateTTL),
+	}, nil
+}
+
+// Complete signs in the user the provider sent back. The state works once
+// and only in the browser the sign-in began in, stateHash is what that
+// browser kept. Created reports that a user was signed up.
+func (s *OIDCAuth) Complete(ctx context.Context, providerName, stateHash string, req model.OIDCCallbackRequest) (usr *domain.User, created bool, err error) {
+	provider, ok := s.Providers[providerName]
+	if !ok {
+		return nil, false, errs.ErrUnknownProvider
+	}
+
+	// a callback URL sent to someone else must not sign them in to the
+	// account of whoever began the sign-in
+	if subtle.ConstantTimeCompare([]byte(hashState(req.State)), []byte(stateHash)) != 1 {
+		return nil, false, errs.ErrInvalidSigninState
+	}
+
+	data, err := s.Redis.GetDel(ctx, oidcStateKey(req.State)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, errs.ErrInvalidSigninState
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get sign-in state: %w", err)
+	}
+
+	var pending oidcState
+	if err := json.Unmarshal(data, &pending); err != nil || pending.Provider != providerName {
+		return nil, false, errs.ErrInvalidSigninState
+	}
+
+	token, err := provider.Exchange(ctx, req.Code, pending.Verifier, pending.Nonce)
+	if errors.Is(err, oidc.ErrInvalidCode) || errors.Is(err, oidc.ErrInvalidIDToken) {
+		return nil, false, fmt.Errorf("%w: %w", errs.ErrSigninRefused, err)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	linked, err := s.Identities.GetBySubject(ctx, providerName, token.Subject)
+	if err == nil {
+		if err := s.Identities.Touch(ctx, linked.ID); err != nil {
+			return nil, false, err
+		}
+
+		usr, err := s.Users.Get(ctx, linked.UserID)
+		return usr, false, err
+	}
+	if !errors.Is(err, repoErrs.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	// the email is what finds the account, one the provider did not verify
+	// could be anyone's
+	if token.Email == "" || !token.EmailVerified {
+		return nil, false, errs.ErrEmailNotVerified
+	}
+
+	return s.link(ctx, providerName, token, req.User)
+}
+
+// ListIdentities returns the provider accounts linked to the user.
+func (s *OIDCAuth) ListIdentities(ctx context.Context, userID string) ([]domain.UserIdentity, error) {
+	return s.Identities.ListByUser(ctx, userID)
+}
+
+// Unlink removes the account of the provider from the user, unless the user
+// would have no way left to sign in.
+func (s *OIDCAuth) Unlink(ctx context.Context, userID, providerName string) error {
+	usr, err := s.Users.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	identities, err := s.Identities.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if usr.Password == "" && !usr.PhoneVerified && usr.FirebaseUID == nil && len(identities) <= 1 {
+		return errs.ErrLastSigninMethod
+	}
+
+	return s.Identities.Delete(ctx, userID, providerName)
+}
+
+// link links the provider account to the user with its email, signing one
+// up when there is none.
+func (s *OIDCAuth) link(ctx context.Context, providerName string, token *oidc.IDToken, appleUser string) (*domain.User, bool, error) {
+	identityID, err := uuid.NewV7()
+	if err != nil {
+		return nil, false, err
+	}
+
+	now := time.Now()
+	linked := &domain.UserIdentity{
+		ID:         identityID.String(),
+		Provider:   providerName,
+		Subject:    token.Subject,
+		Email:      token.Email,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}
+
+	usr, err := s.Users.GetByEmail(ctx, token.Email)
+	if err == nil && !usr.EmailVerified {
+		// the email was typed in without a code, it may not be theirs
+		return nil, false, errs.ErrAccountExists
+	}
+	if err == nil {
+		linked.UserID = usr.ID
+		err = s.Identities.Create(ctx, linked)
+		if errors.Is(err, repoErrs.IdentityAlreadyLinked) {
+			return s.linkedMeanwhile(ctx, providerName, token.Subject)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		slog.Info("provider account linked", slog.String("user_id", usr.ID), slog.String("provider", providerName))
+		return usr, false, nil
+	}
+	if !errors.Is(err, repoErrs.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	userID, err := uuid.NewV7()
+	if err != nil {
+		return nil, false, err
+	}
+
+	firstName, lastName := identityName(token, appleUser)
+	usr = &domain.User{
+		ID:            userID.String(),
+		Email:         token.Email,
+		EmailVerified: true,
+		FirstName:     firstName,
+		LastName:      lastName,
+		Role:          userRole,
+	}
+	linked.UserID = usr.ID
+
+	err = s.Identities.CreateWithUser(ctx, usr, linked)
+	if errors.Is(err, repoErrs.IdentityAlreadyLinked) {
+		return s.linkedMeanwhile(ctx, providerName, token.Subject)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	slog.Info("user signed up with provider", slog.String("user_id", usr.ID), slog.String("provider", providerName))
+	return usr, true, nil
+}
+
+// linkedMeanwhile returns the user a concurrent sign-in linked the provider
+// account to.
+func (s *OIDCAuth) linkedMeanwhile(ctx context.Context, providerName, subject string) (*domain.User, bool, error) {
+	linked, err := s.Identities.GetBySubject(ctx, providerName, subject)
+	if err != nil {
+		return nil, false, err
+	}
+
+	usr, err := s.Users.Get(ctx, linked.UserID)
+	return usr, false, err
+}
+
+// identityName is the name of the user in the ID token, Apple leaves it out
+// and sends it once along with the first sign-in instead.
+func identityName(token *oidc.IDToken, appleUser string) (firstName, lastName string) {
+	if token.GivenName != "" || token.FamilyName != "" {
+		return token.GivenName, token.FamilyName
+	}
+
+	if appleUser != "" {
+		var sent struct {
+			Name struct {
+				FirstName string `json:"firstName"`
+				LastName  string `json:"lastName"`
+			} `json:"name"`
+		}
+		if err := json.Unmarshal([]byte(appleUser), &sent); err == nil && sent.Name.FirstName != "" {
+			return sent.Name.FirstName, sent.Name.LastName
+		}
+	}
+
+	firstName, lastName, _ = strings.Cut(strings.TrimSpace(token.Name), " ")
+	return firstName, strings.TrimSpace(lastName)
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate sign-in state: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+func oidcStateKey(state string) string {
+	return "oidc_state:" + state
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/auth/model"
+	"aulway/internal/oidc"
+	"aulway/internal/oidc/oidctest"
+	repoErrs "aulway/internal/repository/errs"
+	identityRepository "aulway/internal/repository/identity"
+	userRepository "aulway/internal/repository/user"
+	"aulway/internal/utils/errs"
+	"context"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityName(t *testing.T) {
+	first, last := identityName(&oidc.IDToken{GivenName: "Aruzhan", FamilyName: "Seitkali", Name: "ignored"}, "")
+	require.Equal(t, []string{"Aruzhan", "Seitkali"}, []string{first, last})
+
+	// Apple sends the name only with the first sign-in
+	first, last = identityName(&oidc.IDToken{}, `{"name":{"firstName":"Dias","lastName":"Nurlanov"},"email":"dias@example.com"}`)
+	require.Equal(t, []string{"Dias", "Nurlanov"}, []string{first, last})
+
+	first, last = identityName(&oidc.IDToken{Name: " Aigerim  Bekova "}, "not json")
+	require.Equal(t, []string{"Aigerim", "Bekova"}, []string{first, last})
+}
+
+func TestOIDCSigninLinksAccountsByVerifiedEmail(t *testing.T) {
+	client := testRedis(t)
+	db := testDB(t)
+	ctx := context.Background()
+	run := rand.Int()
+
+	google := oidctest.NewIssuer(t, "aulway-google", "google-secret")
+	apple := oidctest.NewIssuer(t, "aulway-apple", "apple-secret")
+	users := userRepository.NewRepository(db)
+	auth := NewOIDCAuth(map[string]*oidc.Provider{
+		oidc.Google: oidc.NewProvider(oidc.Google, google.URL, "aulway-google", oidc.StaticSecret("google-secret"), "https://api.aulway.kz/auth/oidc/google/callback"),
+		oidc.Apple:  oidc.NewProvider(oidc.Apple, apple.URL, "aulway-apple", oidc.StaticSecret("apple-secret"), "https://api.aulway.kz/auth/oidc/apple/callback"),
+	}, identityRepository.New(db), users, client, time.Minute)
+
+	signin := func(issuer *oidctest.Issuer, provider string, user oidctest.User) (*domain.User, bool, error) {
+		start, err := auth.Begin(ctx, provider)
+		require.NoError(t, err)
+		code, state := issuer.Authorize(t, start.URL, user)
+
+		return auth.Complete(ctx, provider, start.StateHash, model.OIDCCallbackRequest{Code: code, State: state})
+	}
+
+	_, err := auth.Begin(ctx, "facebook")
+	require.ErrorIs(t, err, errs.ErrUnknownProvider)
+
+	// an unverified email finds no account
+	email := fmt.Sprintf("oidc-%d@example.com", run)
+	_, _, err = signin(google, oidc.Google, oidctest.User{Subject: fmt.Sprintf("g-%d", run), Email: email})
+	require.ErrorIs(t, err, errs.ErrEmailNotVerified)
+
+	// the first sign-in signs up
+	googleUser := oidctest.User{Subject: fmt.Sprintf("g-%d", run), Email: email, EmailVerified: true, Name: "Aruzhan Seitkali"}
+	signedUp, created, err := signin(google, oidc.Google, googleUser)
+	require.NoError(t, err)
+	require.True(t, created)
+	require.Equal(t, email, signedUp.Email)
+	require.True(t, signedUp.EmailVerified)
+	require.Equal(t, "Aruzhan", signedUp.FirstName)
+
+	again, created, err := signin(google, oidc.Google, googleUser)
+	require.NoError(t, err)
+	require.False(t, created)
+	require.Equal(t, signedUp.ID, again.ID)
+
+	// Apple with the same verified email links to the same account
+	appleUser := oidctest.User{Subject: fmt.Sprintf("a-%d", run), Email: email, EmailVerified: true}
+	linked, created, err := signin(apple, oidc.Apple, appleUser)
+	require.NoError(t, err)
+	require.False(t, created)
+	require.Equal(t, signedUp.ID, linked.ID)
+
+	identities, err := auth.ListIdentities(ctx, signedUp.ID)
+	require.NoError(t, err)
+	require.Len(t, identities, 2)
+	require.Equal(t, oidc.Google, identities[0].Provider)
+	require.Equal(t, oidc.Apple, identities[1].Provider)
+
+	// an email set without a code links nothing, whoever set it need not own it
+	claimed := &domain.User{ID: fmt.Sprintf("oidc-claimed-%d", run), Email: fmt.Sprintf("oidc-claimed-%d@example.com", run), Role: userRole}
+	require.NoError(t, db.Create(claimed).Error)
+	_, _, err = signin(google, oidc.Google, oidctest.User{Subject: fmt.Sprintf("g3-%d", run), Email: claimed.Email, EmailVerified: true})
+	require.ErrorIs(t, err, errs.ErrAccountExists)
+
+	// another Google account with the email cannot join the one linked
+	_, _, err = signin(google, oidc.Google, oidctest.User{Subject: fmt.Sprintf("g2-%d", run), Email: email, EmailVerified: true})
+	require.ErrorIs(t, err, repoErrs.ProviderAlreadyLinked)
+
+	// a state only works in the browser that began the sign-in
+	start, err := auth.Begin(ctx, oidc.Google)
+	require.NoError(t, err)
+	require.False(t, start.CrossSitePost)
+	code, state := google.Authorize(t, start.URL, googleUser)
+	_, _, err = auth.Complete(ctx, oidc.Google, "", model.OIDCCallbackRequest{Code: code, State: state})
+	require.ErrorIs(t, err, errs.ErrInvalidSigninState)
+	other, err := auth.Begin(ctx, oidc.Google)
+	require.NoError(t, err)
+	_, _, err = auth.Complete(ctx, oidc.Google, other.StateHash, model.OIDCCallbackRequest{Code: code, State: state})
+	require.ErrorIs(t, err, errs.ErrInvalidSigninState)
+
+	// a state works once and only for its provider
+	_, _, err = auth.Complete(ctx, oidc.Apple, start.StateHash, model.OIDCCallbackRequest{Code: code, State: state})
+	require.ErrorIs(t, err, errs.ErrInvalidSigninState)
+	_, _, err = auth.Complete(ctx, oidc.Google, start.StateHash, model.OIDCCallbackRequest{Code: code, State: state})
+	require.ErrorIs(t, err, errs.ErrInvalidSigninState)
+
+	// a code for another sign-in request is refused
+	start, err = auth.Begin(ctx, oidc.Google)
+	require.NoError(t, err)
+	code, _ = google.Authorize(t, start.URL, googleUser)
+	start, err = auth.Begin(ctx, oidc.Google)
+	require.NoError(t, err)
+	parsed, err := url.Parse(start.URL)
+	require.NoError(t, err)
+	_, _, err = auth.Complete(ctx, oidc.Google, start.StateHash, model.OIDCCallbackRequest{Code: code, State: parsed.Query().Get("state")})
+	require.ErrorIs(t, err, errs.ErrSigninRefused)
+
+	// the last way to sign in stays
+	require.NoError(t, auth.Unlink(ctx, signedUp.ID, oidc.Apple))
+	require.ErrorIs(t, auth.Unlink(ctx, signedUp.ID, oidc.Apple), repoErrs.ErrRecordNotFound)
+	require.ErrorIs(t, auth.Unlink(ctx, signedUp.ID, oidc.Google), errs.ErrLastSigninMethod)
+}
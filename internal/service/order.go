@@ -0,0 +1,410 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	repoErrs "aulway/internal/repository/errs"
+	"aulway/internal/templates"
+	"aulway/internal/utils/errs"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// GetOrder returns the order with its tickets, orders of other users are
+// reported as not found.
+func (s *TicketService) GetOrder(ctx context.Context, userID, orderID string) (*domain.Order, error) {
+	order, err := s.OrderRepo.Get(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, repoErrs.ErrRecordNotFound
+	}
+
+	order.Tickets, err = s.TicketRepo.ListByOrderID(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// CancelOrder cancels the given tickets of an order, or every ticket still
+// valid when ticketIDs is empty. The refund for all of them is stored with
+// the cancellation and made in one call to the payment provider once it is
+// committed, a refund the provider fails to make is retried by the
+// RefundSettler. The cancellation is emailed to email when it is set and
+// pushed to the devices of the user.
+func (s *TicketService) CancelOrder(ctx context.Context, userID, orderID string, ticketIDs []string, email string) (*domain.Order, error) {
+	found, err := s.OrderRepo.Get(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if found.UserID != userID {
+		return nil, repoErrs.ErrRecordNotFound
+	}
+
+	tx := s.TicketRepo.BeginTransaction()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// the payment is locked before the order and its tickets, in the same
+	// order the payment webhook takes them
+	var payment *domain.Payment
+	if found.PaymentID != "" {
+		payment, err = s.PaymentRepo.GetForUpdate(ctx, tx, found.PaymentID)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to get payment: %w", err)
+		}
+	}
+
+	order, err := s.OrderRepo.GetForUpdate(ctx, tx, orderID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// locking the tickets keeps two cancellations from refunding them twice
+	tickets, err := s.TicketRepo.GetByOrderID(ctx, tx, order.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	selected, err := ticketsToCancel(tickets, ticketIDs)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	route, err := s.RouteRepo.Get(ctx, order.RouteID)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to fetch route: %w", err)
+	}
+
+	quote, err := s.quote(ctx, order, selected, route)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if !quote.Cancellable {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: %s", errs.ErrCancellationNotAllowed, quote.Reason)
+	}
+
+	for _, refund := range quote.Tickets {
+		updates := map[string]interface{}{"status": "cancelled"}
+		if refund.Refund > 0 {
+			updates["payment_status"] = "refunded"
+		}
+		err = s.TicketRepo.Update(ctx, tx, updates, refund.TicketID)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to cancel ticket: %w", err)
+		}
+	}
+
+	err = s.RouteRepo.IncrementSeats(ctx, tx, order.RouteID, len(selected))
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update seat count: %w", err)
+	}
+
+	var refund *domain.Refund
+	if quote.Refund > 0 && payment != nil {
+		refund, err = s.Refunds.Create(ctx, tx, payment, quote)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		// the payment status follows from the charge.refunded webhook
+	}
+
+	if err := s.OrderRepo.RefreshStatus(ctx, tx, order.ID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	order, err = s.OrderRepo.GetForUpdate(ctx, tx, order.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	order.Tickets, err = s.TicketRepo.GetByOrderID(ctx, tx, order.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	data := cancellationEmailData(order, selected, route, quote.Refund)
+	subject, body, err := s.Templates.Render(ctx, userLanguage(ctx, s.SettingsRepo, userID), templates.CancellationEmail, data)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	message := newOutboxMessage(domain.OutboxCancellation, email, subject, body, order.ID)
+	if email != "" {
+		if err := s.OutboxRepo.Create(ctx, tx, message); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit cancellation: %w", err)
+	}
+
+	// the tickets are cancelled either way, a refund that fails now is retried
+	if refund != nil {
+		if err := s.Refunds.Settle(ctx, refund); err != nil {
+			slog.Error("refund of cancellation failed, will retry", slog.String("refund_id", refund.ID), slog.String("error", err.Error()))
+		}
+	}
+
+	s.Push.Notify(ctx, userID, tripPush(message, route))
+
+	cancelled := make([]string, 0, len(selected))
+	for _, ticket := range selected {
+		cancelled = append(cancelled, ticket.ID)
+	}
+	s.Passes.TicketsChanged(ctx, cancelled...)
+
+	return order, nil
+}
+
+// ticketsToCancel picks the tickets of the order named in ids, or all of the
+// valid ones when ids is empty. A ticket that was used to board, or whose bus
+// left without its passenger, cannot be cancelled either way.
+func ticketsToCancel(tickets []domain.Ticket, ids []string) ([]domain.Ticket, error) {
+	if len(ids) == 0 {
+		selected := make([]domain.Ticket, 0, len(tickets))
+		for _, ticket := range tickets {
+			if ticket.Status == "cancelled" {
+				continue
+			}
+			if err := cancellable(ticket); err != nil {
+				return nil, err
+			}
+			selected = append(selected, ticket)
+		}
+		if len(selected) == 0 {
+			return nil, errors.New("order already cancelled")
+		}
+		return selected, nil
+	}
+
+	byID := make(map[string]domain.Ticket, len(tickets))
+	for _, ticket := range tickets {
+		byID[ticket.ID] = ticket
+	}
+
+	selected := make([]domain.Ticket, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		ticket, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("ticket %s is not part of the order: %w", id, repoErrs.ErrRecordNotFound)
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if ticket.Status == "cancelled" {
+			return nil, errors.New("ticket already cancelled")
+		}
+		if err := cancellable(ticket); err != nil {
+			return nil, err
+		}
+		selected = append(selected, ticket)
+	}
+
+	return selected, nil
+}
+
+func cancellable(ticket domain.Ticket) error {
+	switch ticket.Status {
+	case "awaiting":
+		return errors.New("ticket payment is not finished yet")
+	case "boarded", "no_show":
+		return fmt.Errorf("%w: ticket %s is %s", errs.ErrCancellationNotAllowed, ticket.ID, ticket.Status)
+	}
+	return nil
+}
+
+// QuoteCancellation shows what cancelling the tickets of an order would
+// refund, with the same selection of tickets as CancelOrder.
+func (s *TicketService) QuoteCancellation(ctx context.Context, userID, orderID string, ticketIDs []string) (*domain.RefundQuote, error) {
+	order, err := s.GetOrder(ctx, userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := ticketsToCancel(order.Tickets, ticketIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	route, err := s.RouteRepo.Get(ctx, order.RouteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch route: %w", err)
+	}
+
+	return s.quote(ctx, order, selected, route)
+}
+
+// QuoteTicketCancellation shows what cancelling a single ticket would refund.
+func (s *TicketService) QuoteTicketCancellation(ctx context.Context, userID, ticketID string) (*domain.RefundQuote, error) {
+	ticket, err := s.TicketRepo.Get(ctx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	if ticket.UserID != userID {
+		return nil, repoErrs.ErrRecordNotFound
+	}
+
+	return s.QuoteCancellation(ctx, userID, ticket.OrderID, []string{ticket.ID})
+}
+
+// quote applies the refund policy of the route to the tickets.
+func (s *TicketService) quote(ctx context.Context, order *domain.Order, tickets []domain.Ticket, route *domain.Route) (*domain.RefundQuote, error) {
+	policy, err := s.PolicyRepo.ForRoute(ctx, route.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refund policy: %w", err)
+	}
+
+	return quoteRefund(order, tickets, route, policy, time.Now()), nil
+}
+
+// quoteRefund is what cancelling the tickets at now refunds under the policy.
+// Only paid tickets are refunded, the others are released without a refund.
+func quoteRefund(order *domain.Order, tickets []domain.Ticket, route *domain.Route, policy *domain.RefundPolicy, now time.Time) *domain.RefundQuote {
+	hours := route.StartDate.Sub(now).Hours()
+	quote := &domain.RefundQuote{
+		OrderID:              order.ID,
+		PolicyID:             policy.ID,
+		PolicyName:           policy.Name,
+		HoursBeforeDeparture: int(hours),
+		Cancellable:          true,
+		Tickets:              make([]domain.TicketRefund, 0, len(tickets)),
+	}
+
+	tier, ok := policy.Tier(hours)
+	switch {
+	case hours <= 0:
+		quote.Cancellable = false
+		quote.Reason = "the bus has already departed"
+	case policy.NonRefundable:
+		quote.Reason = "the fare is non-refundable"
+	case !ok:
+		quote.Cancellable = false
+		quote.Reason = fmt.Sprintf("cancellation not allowed less than %d hours before departure", minHoursBefore(policy))
+	default:
+		quote.RefundPercent = tier.RefundPercent
+	}
+
+	for _, ticket := range tickets {
+		refund := domain.TicketRefund{
+			TicketID:   ticket.ID,
+			SeatNumber: ticket.SeatNumber,
+			Price:      ticket.Price,
+		}
+		if ticket.PaymentStatus == "paid" {
+			quote.Paid += ticket.Price
+			refund.Refund = ticket.Price * quote.RefundPercent / 100
+		}
+		quote.Refund += refund.Refund
+		quote.Tickets = append(quote.Tickets, refund)
+	}
+
+	return quote
+}
+
+func minHoursBefore(policy *domain.RefundPolicy) int {
+	hours := 0
+	for i, tier := range policy.Tiers {
+		if i == 0 || tier.MinHoursBefore < hours {
+			hours = tier.MinHoursBefore
+		}
+	}
+
+	return hours
+}
+
+// cancellationEmailData is what the email about the tickets cancelled from
+// an order shows.
+func cancellationEmailData(order *domain.Order, cancelled []domain.Ticket, route *domain.Route, refund int) templates.CancellationData {
+	seats := make([]string, 0, len(cancelled))
+	for _, ticket := range cancelled {
+		seats = append(seats, ticket.SeatNumber)
+	}
+
+	return templates.CancellationData{
+		OrderNumber: order.Number,
+		Route:       routeData(route),
+		Seats:       seats,
+		Refund:      refund,
+		WholeOrder:  order.Status == domain.OrderCancelled,
+	}
+}
+
+// OrderReceipt renders the receipt of an order as an HTML document in the
+// language of the user, with the amounts taken from the payment ledger.
+func (s *TicketService) OrderReceipt(ctx context.Context, userID, orderID string) (*domain.Order, string, error) {
+	order, err := s.GetOrder(ctx, userID, orderID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	route, err := s.RouteRepo.Get(ctx, order.RouteID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch route: %w", err)
+	}
+
+	charged, refunded, currency := order.Total, 0, "KZT"
+	if order.PaymentID != "" {
+		entries, err := s.LedgerRepo.GetByPaymentID(ctx, order.PaymentID)
+		if err != nil {
+			return nil, "", err
+		}
+		charged = 0
+		for _, entry := range entries {
+			currency = entry.Currency
+			switch entry.Type {
+			case domain.LedgerCharge:
+				charged += entry.Amount
+			case domain.LedgerRefund, domain.LedgerPartialRefund:
+				refunded -= entry.Amount
+			}
+		}
+	}
+
+	lines := make([]templates.ReceiptLine, 0, len(order.Tickets))
+	for _, ticket := range order.Tickets {
+		lines = append(lines, templates.ReceiptLine{Seat: ticket.SeatNumber, Price: ticket.Price, Status: ticket.Status})
+	}
+
+	_, receipt, err := templates.Render(userLanguage(ctx, s.SettingsRepo, userID), templates.ReceiptDocument, templates.ReceiptData{
+		OrderNumber: order.Number,
+		CreatedAt:   order.CreatedAt,
+		Route:       routeData(route),
+		Tickets:     lines,
+		Currency:    currency,
+		Charged:     charged,
+		Refunded:    refunded,
+		Total:       charged - refunded,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return order, receipt, nil
+}
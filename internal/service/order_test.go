@@ -0,0 +1,121 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	repoErrs "aulway/internal/repository/errs"
+	"aulway/internal/utils/errs"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTicketsToCancel(t *testing.T) {
+	order := func(statuses ...string) []domain.Ticket {
+		tickets := make([]domain.Ticket, len(statuses))
+		for i, status := range statuses {
+			tickets[i] = domain.Ticket{ID: string(rune('a' + i)), Status: status}
+		}
+		return tickets
+	}
+
+	tests := []struct {
+		name       string
+		tickets    []domain.Ticket
+		ids        []string
+		want       []string
+		wantErr    bool
+		notAllowed bool
+		notFound   bool
+	}{
+		{name: "whole order", tickets: order("approved", "pending", "cancelled"), want: []string{"a", "b"}},
+		{name: "whole order already cancelled", tickets: order("cancelled", "cancelled"), wantErr: true},
+		{name: "whole order still paying", tickets: order("approved", "awaiting"), wantErr: true},
+		{name: "whole order with a boarded ticket", tickets: order("approved", "boarded"), wantErr: true, notAllowed: true},
+		{name: "whole order with a no-show", tickets: order("no_show", "approved"), wantErr: true, notAllowed: true},
+		{name: "named tickets", tickets: order("approved", "approved", "approved"), ids: []string{"c", "a", "c"}, want: []string{"c", "a"}},
+		{name: "named ticket cancelled", tickets: order("approved", "cancelled"), ids: []string{"b"}, wantErr: true},
+		{name: "named ticket still paying", tickets: order("awaiting"), ids: []string{"a"}, wantErr: true},
+		{name: "named ticket boarded", tickets: order("approved", "boarded"), ids: []string{"a", "b"}, wantErr: true, notAllowed: true},
+		{name: "named ticket no-show", tickets: order("no_show"), ids: []string{"a"}, wantErr: true, notAllowed: true},
+		{name: "ticket of another order", tickets: order("approved"), ids: []string{"z"}, wantErr: true, notFound: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := ticketsToCancel(tt.tickets, tt.ids)
+			if tt.wantErr {
+				require.Error(t, err)
+				require.Equal(t, tt.notAllowed, errors.Is(err, errs.ErrCancellationNotAllowed))
+				require.Equal(t, tt.notFound, errors.Is(err, repoErrs.ErrRecordNotFound))
+				return
+			}
+			require.NoError(t, err)
+
+			ids := make([]string, len(selected))
+			for i := range selected {
+				ids[i] = selected[i].ID
+			}
+			require.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func TestQuoteRefund(t *testing.T) {
+	now := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	standard := &domain.RefundPolicy{ID: "policy-1", Name: "Standard", Tiers: []domain.RefundTier{
+		{MinHoursBefore: 72, RefundPercent: 100},
+		{MinHoursBefore: 24, RefundPercent: 50},
+	}}
+	nonRefundable := &domain.RefundPolicy{ID: "policy-2", Name: "Saver", NonRefundable: true}
+	paid := []domain.Ticket{
+		{ID: "t-1", SeatNumber: "1A", Price: 5000, PaymentStatus: "paid"},
+		{ID: "t-2", SeatNumber: "1B", Price: 3333, PaymentStatus: "paid"},
+	}
+
+	tests := []struct {
+		name        string
+		policy      *domain.RefundPolicy
+		tickets     []domain.Ticket
+		departsIn   time.Duration
+		cancellable bool
+		percent     int
+		paid        int
+		refunds     []int
+		reason      string
+	}{
+		{name: "full refund", policy: standard, tickets: paid, departsIn: 100 * time.Hour, cancellable: true, percent: 100, paid: 8333, refunds: []int{5000, 3333}},
+		{name: "on the tier boundary", policy: standard, tickets: paid, departsIn: 72 * time.Hour, cancellable: true, percent: 100, paid: 8333, refunds: []int{5000, 3333}},
+		{name: "half refund rounds down", policy: standard, tickets: paid, departsIn: 30 * time.Hour, cancellable: true, percent: 50, paid: 8333, refunds: []int{2500, 1666}},
+		{name: "too close to departure", policy: standard, tickets: paid, departsIn: 23 * time.Hour, percent: 0, paid: 8333, refunds: []int{0, 0}, reason: "less than 24 hours"},
+		{name: "departed", policy: standard, tickets: paid, departsIn: -time.Hour, paid: 8333, refunds: []int{0, 0}, reason: "departed"},
+		{name: "non-refundable", policy: nonRefundable, tickets: paid, departsIn: 100 * time.Hour, cancellable: true, paid: 8333, refunds: []int{0, 0}, reason: "non-refundable"},
+		{name: "unpaid ticket is not refunded", policy: standard, departsIn: 100 * time.Hour, cancellable: true, percent: 100, paid: 5000, refunds: []int{5000, 0},
+			tickets: []domain.Ticket{paid[0], {ID: "t-3", SeatNumber: "2A", Price: 5000, PaymentStatus: "pending"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := &domain.Route{Id: "route-1", StartDate: now.Add(tt.departsIn)}
+			quote := quoteRefund(&domain.Order{ID: "order-1"}, tt.tickets, route, tt.policy, now)
+
+			require.Equal(t, "order-1", quote.OrderID)
+			require.Equal(t, tt.policy.ID, quote.PolicyID)
+			require.Equal(t, int(tt.departsIn.Hours()), quote.HoursBeforeDeparture)
+			require.Equal(t, tt.cancellable, quote.Cancellable)
+			require.Equal(t, tt.percent, quote.RefundPercent)
+			require.Equal(t, tt.paid, quote.Paid)
+			require.Contains(t, quote.Reason, tt.reason)
+
+			total := 0
+			require.Len(t, quote.Tickets, len(tt.refunds))
+			for i, refund := range tt.refunds {
+				require.Equal(t, tt.tickets[i].ID, quote.Tickets[i].TicketID)
+				require.Equal(t, refund, quote.Tickets[i].Refund)
+				total += refund
+			}
+			require.Equal(t, total, quote.Refund)
+		})
+	}
+}
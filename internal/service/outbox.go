@@ -0,0 +1,202 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	busRepo "aulway/internal/repository/bus"
+	outboxRepo "aulway/internal/repository/outbox"
+	routeRepo "aulway/internal/repository/route"
+	ticketRepo "aulway/internal/repository/ticket"
+	"aulway/internal/utils/config"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"time"
+)
+
+const (
+	outboxBatch    = 20
+	outboxLease    = 5 * time.Minute
+	outboxMaxDelay = 6 * time.Hour
+)
+
+// newOutboxMessage prepares an email to be stored in the transaction of the
+// change it reports.
+func newOutboxMessage(kind, recipient, subject, body, orderID string) *domain.OutboxMessage {
+	id, _ := uuid.NewV7()
+	now := time.Now()
+
+	return &domain.OutboxMessage{
+		ID:            id.String(),
+		Kind:          kind,
+		Recipient:     recipient,
+		Subject:       subject,
+		Body:          body,
+		OrderID:       orderID,
+		Status:        domain.OutboxPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// OutboxService lets admins look into the outbox and resend messages.
+type OutboxService struct {
+	Repo outboxRepo.Repository
+}
+
+func NewOutboxService(repo outboxRepo.Repository) *OutboxService {
+	return &OutboxService{Repo: repo}
+}
+
+func (s *OutboxService) List(ctx context.Context, status string, page, pageSize int) ([]domain.OutboxMessage, error) {
+	return s.Repo.List(ctx, status, page, pageSize)
+}
+
+// Resend queues a message again with a fresh set of attempts, the worker
+// picks it up on its next run.
+func (s *OutboxService) Resend(ctx context.Context, id string) (*domain.OutboxMessage, error) {
+	return s.Repo.Requeue(ctx, id)
+}
+
+// OutboxWorker sends the emails of the outbox. A failed send is retried with
+// exponential backoff, after MaxAttempts the message is dead-lettered.
+type OutboxWorker struct {
+	Repo        outboxRepo.Repository
+	TicketRepo  ticketRepo.Repository
+	RouteRepo   routeRepo.Repository
+	BusRepo     busRepo.Repository
+	Signer      *TicketSigner
+	SMTP        config.SMTP
+	Interval    time.Duration
+	BaseDelay   time.Duration
+	MaxAttempts int
+}
+
+func NewOutboxWorker(repo outboxRepo.Repository, ticketRepo ticketRepo.Repository, routeRepo routeRepo.Repository, busRepo busRepo.Repository, signer *TicketSigner, smtp config.SMTP, interval, baseDelay time.Duration, maxAttempts int) *OutboxWorker {
+	return &OutboxWorker{
+		Repo:        repo,
+		TicketRepo:  ticketRepo,
+		RouteRepo:   routeRepo,
+		BusRepo:     busRepo,
+		Signer:      signer,
+		SMTP:        smtp,
+		Interval:    interval,
+		BaseDelay:   baseDelay,
+		MaxAttempts: maxAttempts,
+	}
+}
+
+// Run sends due messages every interval until ctx is done.
+func (w *OutboxWorker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.Process(ctx); err != nil {
+			slog.Error("failed to process outbox", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Process sends the messages that are due and returns how many went out.
+func (w *OutboxWorker) Process(ctx context.Context) (int, error) {
+	sent := 0
+	for {
+		messages, err := w.Repo.Claim(ctx, outboxBatch, outboxLease)
+		if err != nil {
+			return sent, err
+		}
+		if len(messages) == 0 {
+			return sent, nil
+		}
+
+		for i := range messages {
+			if w.attempt(ctx, &messages[i]) {
+				sent++
+			}
+		}
+	}
+}
+
+func (w *OutboxWorker) attempt(ctx context.Context, message *domain.OutboxMessage) bool {
+	sendErr := w.Deliver(ctx, message)
+	if sendErr == nil {
+		if err := w.Repo.MarkSent(ctx, message.ID, time.Now()); err != nil {
+			slog.Error("failed to mark email sent", slog.String("message_id", message.ID), slog.String("error", err.Error()))
+		}
+		return true
+	}
+
+	dead := message.Attempts >= w.MaxAttempts
+	next := time.Now().Add(w.Backoff(message.Attempts))
+	if err := w.Repo.MarkFailed(ctx, message.ID, sendErr.Error(), next, dead); err != nil {
+		slog.Error("failed to record email failure", slog.String("message_id", message.ID), slog.String("error", err.Error()))
+	}
+
+	if dead {
+		slog.Error("email dead-lettered", slog.String("message_id", message.ID), slog.Int("attempts", message.Attempts), slog.String("error", sendErr.Error()))
+	} else {
+		slog.Warn("email send failed, will retry", slog.String("message_id", message.ID), slog.Time("next_attempt_at", next), slog.String("error", sendErr.Error()))
+	}
+
+	return false
+}
+
+// Backoff is the wait before the retry that follows the given attempt:
+// BaseDelay doubled per attempt, at most six hours.
+func (w *OutboxWorker) Backoff(attempts int) time.Duration {
+	delay := w.BaseDelay
+	for i := 1; i < attempts && delay < outboxMaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, outboxMaxDelay)
+}
+
+// Deliver sends a message over SMTP. The tickets of a purchase email are
+// attached as they are now, so a resent email has their current PDFs.
+func (w *OutboxWorker) Deliver(ctx context.Context, message *domain.OutboxMessage) error {
+	if message.Kind != domain.OutboxTickets {
+		return SendEmail(message.Recipient, message.Subject, message.Body, w.SMTP)
+	}
+
+	tickets, err := w.TicketRepo.ListByOrderID(ctx, message.OrderID)
+	if err != nil {
+		return err
+	}
+	if len(tickets) == 0 {
+		return fmt.Errorf("order %s has no tickets", message.OrderID)
+	}
+
+	route, err := w.RouteRepo.Get(ctx, tickets[0].RouteID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch route: %w", err)
+	}
+
+	bus, err := w.BusRepo.Get(ctx, route.BusId)
+	if err != nil {
+		return fmt.Errorf("failed to fetch bus: %w", err)
+	}
+
+	pdfs := make([]EmailAttachment, 0, len(tickets))
+	for i := range tickets {
+		ticket := &tickets[i]
+		if ticket.Status == "cancelled" {
+			continue
+		}
+		pdf, err := RenderTicketPDF(ticket, route, bus, w.Signer.Sign(ticket))
+		if err != nil {
+			return err
+		}
+		pdfs = append(pdfs, EmailAttachment{Name: TicketPDFName(ticket), ContentType: "application/pdf", Data: pdf})
+	}
+
+	return SendEmailWithQR(message.Recipient, message.Subject, tickets, w.SMTP, message.Body, pdfs...)
+}
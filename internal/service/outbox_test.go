@@ -0,0 +1,174 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	settingsModel "aulway/internal/handler/settings/model"
+	"aulway/internal/handler/ticket/model"
+	busRepository "aulway/internal/repository/bus"
+	outboxRepository "aulway/internal/repository/outbox"
+	routeRepository "aulway/internal/repository/route"
+	settingsRepository "aulway/internal/repository/settings"
+	ticketRepository "aulway/internal/repository/ticket"
+	"aulway/internal/templates"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxBackoffDoublesUpToCap(t *testing.T) {
+	w := &OutboxWorker{BaseDelay: 30 * time.Second}
+
+	require.Equal(t, 30*time.Second, w.Backoff(1))
+	require.Equal(t, time.Minute, w.Backoff(2))
+	require.Equal(t, 4*time.Minute, w.Backoff(4))
+	require.Equal(t, outboxMaxDelay, w.Backoff(30))
+}
+
+func TestDeliverSendsOverSMTP(t *testing.T) {
+	server := newFakeSMTP(t)
+	w := &OutboxWorker{SMTP: server.config()}
+	message := newOutboxMessage(domain.OutboxCancellation, "passenger@example.com", "Заказ отменён", "<p>order cancelled</p>", "")
+
+	require.NoError(t, w.Deliver(context.Background(), message))
+
+	mail := server.mail()
+	require.Len(t, mail, 1)
+	require.Equal(t, "passenger@example.com", mail[0].To)
+	require.Contains(t, mail[0].Data, "order cancelled")
+
+	server.failing.Store(true)
+	require.Error(t, w.Deliver(context.Background(), message))
+	require.Len(t, server.mail(), 1)
+}
+
+func TestPurchaseEmailIsRetriedDeadLetteredAndResent(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 1)
+	ctx := context.Background()
+	server := newFakeSMTP(t)
+	server.failing.Store(true)
+
+	purchase, err := f.service.BuyTickets(ctx, f.userIDs[0], f.routeID, "pm_card_visa", model.BuyTicketRequest{Quantity: 2, UserEmail: "buyer@example.com"})
+	require.NoError(t, err)
+
+	// the email is stored with the purchase, nothing is sent yet
+	var message domain.OutboxMessage
+	require.NoError(t, f.db.Where("order_id = ?", purchase.OrderID).First(&message).Error)
+	require.Equal(t, domain.OutboxTickets, message.Kind)
+	require.Equal(t, domain.OutboxPending, message.Status)
+
+	repo := outboxRepository.New(f.db)
+	worker := NewOutboxWorker(repo, ticketRepository.New(f.db), routeRepository.New(f.db), busRepository.New(f.db),
+		NewTicketSigner("test"), server.config(), time.Second, time.Hour, 3)
+
+	reload := func() {
+		require.NoError(t, f.db.First(&message, "id = ?", message.ID).Error)
+	}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		_, err := worker.Process(ctx)
+		require.NoError(t, err)
+
+		reload()
+		require.Equal(t, attempt, message.Attempts)
+		require.Contains(t, message.LastError, "451")
+		if attempt < 3 {
+			require.Equal(t, domain.OutboxPending, message.Status)
+			require.True(t, message.NextAttemptAt.After(time.Now()), "retry is not backed off")
+			require.NoError(t, f.db.Model(&message).Update("next_attempt_at", time.Now().Add(-time.Second)).Error)
+		}
+	}
+	require.Equal(t, domain.OutboxDead, message.Status)
+
+	_, err = worker.Process(ctx)
+	require.NoError(t, err)
+	reload()
+	require.Equal(t, 3, message.Attempts, "dead messages are not retried")
+
+	resent, err := NewOutboxService(repo).Resend(ctx, message.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.OutboxPending, resent.Status)
+	require.Zero(t, resent.Attempts)
+
+	server.failing.Store(false)
+	_, err = worker.Process(ctx)
+	require.NoError(t, err)
+
+	reload()
+	require.Equal(t, domain.OutboxSent, message.Status)
+	require.NotNil(t, message.SentAt)
+
+	var delivered *fakeMail
+	for _, mail := range server.mail() {
+		if mail.To == "buyer@example.com" && strings.Contains(mail.Data, purchase.OrderNumber) {
+			delivered = &mail
+		}
+	}
+	require.NotNil(t, delivered, "purchase email was not delivered")
+	for _, ticket := range purchase.Tickets {
+		require.Contains(t, delivered.Data, ticketQRName(&ticket))
+		require.Contains(t, delivered.Data, TicketPDFName(&ticket))
+	}
+}
+
+func TestCancellationEmailIsQueuedOnlyWithTheCancellation(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 1)
+	ctx := context.Background()
+	userID := f.userIDs[0]
+
+	purchase, err := f.service.BuyTickets(ctx, userID, f.routeID, "pm_card_visa", model.BuyTicketRequest{Quantity: 1})
+	require.NoError(t, err)
+
+	var count int64
+	require.NoError(t, f.db.Model(&domain.OutboxMessage{}).Where("order_id = ?", purchase.OrderID).Count(&count).Error)
+	require.Zero(t, count, "no email is queued without a recipient")
+
+	_, err = f.service.CancelOrder(ctx, userID, purchase.OrderID, nil, "buyer@example.com")
+	require.NoError(t, err)
+
+	// cancelling again fails and leaves no second email behind
+	_, err = f.service.CancelOrder(ctx, userID, purchase.OrderID, nil, "buyer@example.com")
+	require.Error(t, err)
+
+	var messages []domain.OutboxMessage
+	require.NoError(t, f.db.Where("order_id = ?", purchase.OrderID).Find(&messages).Error)
+	require.Len(t, messages, 1)
+	require.Equal(t, domain.OutboxCancellation, messages[0].Kind)
+	require.Equal(t, "Заказ "+purchase.OrderNumber+" отменён", messages[0].Subject)
+}
+
+func TestEmailsAreRenderedInUserLanguage(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 1)
+	ctx := context.Background()
+	userID := f.userIDs[0]
+
+	settings := NewSettingsService(settingsRepository.New(f.db))
+	current, err := settings.GetSettings(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, templates.Default, current.Language)
+
+	_, err = settings.UpdateSettings(ctx, userID, settingsModel.UpdateSettingsRequest{Language: "de"})
+	require.Error(t, err)
+	current, err = settings.UpdateSettings(ctx, userID, settingsModel.UpdateSettingsRequest{Language: templates.KK})
+	require.NoError(t, err)
+	require.Equal(t, templates.KK, current.Language)
+
+	purchase, err := f.service.BuyTickets(ctx, userID, f.routeID, "pm_card_visa", model.BuyTicketRequest{Quantity: 1, UserEmail: "buyer@example.com"})
+	require.NoError(t, err)
+
+	// changing the language again updates the same settings
+	_, err = settings.UpdateSettings(ctx, userID, settingsModel.UpdateSettingsRequest{Language: templates.EN})
+	require.NoError(t, err)
+	_, err = f.service.CancelOrder(ctx, userID, purchase.OrderID, nil, "buyer@example.com")
+	require.NoError(t, err)
+
+	var messages []domain.OutboxMessage
+	require.NoError(t, f.db.Where("order_id = ?", purchase.OrderID).Order("created_at").Find(&messages).Error)
+	require.Len(t, messages, 2)
+	require.Equal(t, "AulWay билеттеріңіз – "+purchase.OrderNumber+" тапсырысы", messages[0].Subject)
+	require.Contains(t, messages[0].Body, "Тапсырыс нөмірі")
+	require.Equal(t, "Order "+purchase.OrderNumber+" cancelled", messages[1].Subject)
+	require.Contains(t, messages[1].Body, "Your order has been cancelled")
+}
@@ -7,16 +7,33 @@ import (
 	_ "github.com/stripe/stripe-go/v76/charge" // ⚠️ Required to avoid runtime issues
 	"github.com/stripe/stripe-go/v76/paymentintent"
 	"github.com/stripe/stripe-go/v76/refund"
+	"strings"
 )
 
-type StripeProcessor struct{}
+const StripeProvider = "stripe"
 
-func NewStripeProcessor() PaymentProcessor {
-	return &StripeProcessor{}
+type StripeProcessor struct {
+	key string
 }
 
-func (s *StripeProcessor) Refund(ctx context.Context, transactionID, stripeKey string, amount int) (bool, error) {
-	stripe.Key = stripeKey
+func NewStripeProcessor(key string) PaymentProcessor {
+	return &StripeProcessor{key: key}
+}
+
+func (s *StripeProcessor) Name() string {
+	return StripeProvider
+}
+
+func (s *StripeProcessor) Currency() string {
+	return "KZT"
+}
+
+func (s *StripeProcessor) Capabilities() Capabilities {
+	return Capabilities{PartialRefund: true, SCA: true}
+}
+
+func (s *StripeProcessor) Refund(ctx context.Context, transactionID string, amount int) (bool, error) {
+	stripe.Key = s.key
 
 	refundParams := &stripe.RefundParams{
 		PaymentIntent: stripe.String(transactionID),
@@ -31,12 +48,14 @@ func (s *StripeProcessor) Refund(ctx context.Context, transactionID, stripeKey s
 	return true, nil
 }
 
-func (s *StripeProcessor) ProcessPayment(ctx context.Context, userID string, amount int, paymentMethodID string, stripeKey string) (bool, string, error) {
-	stripe.Key = stripeKey
+func (s *StripeProcessor) ProcessPayment(ctx context.Context, userID string, amount int, paymentMethodID string) (*PaymentResult, error) {
+	stripe.Key = s.key
 
+	// redirects stay off, cards that need 3-D Secure are authenticated by
+	// stripe.js on the client with the returned client secret
 	params := &stripe.PaymentIntentParams{
 		Amount:        stripe.Int64(int64(amount * 100)),
-		Currency:      stripe.String(string(stripe.CurrencyKZT)),
+		Currency:      stripe.String(strings.ToLower(s.Currency())),
 		PaymentMethod: stripe.String(paymentMethodID),
 		Confirm:       stripe.Bool(true),
 
@@ -48,12 +67,55 @@ func (s *StripeProcessor) ProcessPayment(ctx context.Context, userID string, amo
 
 	pi, err := paymentintent.New(params)
 	if err != nil {
-		return false, "", fmt.Errorf("stripe payment error: %w", err)
+		return nil, fmt.Errorf("stripe payment error: %w", err)
 	}
 
-	if pi.Status != stripe.PaymentIntentStatusSucceeded {
-		return false, "", fmt.Errorf("payment not successful: %s", pi.Status)
+	return &PaymentResult{
+		Status:        paymentStatusOf(pi.Status),
+		TransactionID: pi.ID,
+		ClientSecret:  pi.ClientSecret,
+	}, nil
+}
+
+func (s *StripeProcessor) Details(ctx context.Context, transactionID string) (*PaymentDetails, error) {
+	stripe.Key = s.key
+
+	params := &stripe.PaymentIntentParams{}
+	params.AddExpand("latest_charge")
+
+	pi, err := paymentintent.Get(transactionID, params)
+	if err != nil {
+		return nil, fmt.Errorf("stripe payment details error: %w", err)
+	}
+
+	details := &PaymentDetails{Status: paymentStatusOf(pi.Status)}
+	if pi.LatestCharge != nil {
+		details.Captured = int(pi.LatestCharge.AmountCaptured / 100)
+		details.Refunded = int(pi.LatestCharge.AmountRefunded / 100)
 	}
 
-	return true, pi.ID, nil
+	return details, nil
+}
+
+func (s *StripeProcessor) Cancel(ctx context.Context, transactionID string) error {
+	stripe.Key = s.key
+
+	if _, err := paymentintent.Cancel(transactionID, nil); err != nil {
+		return fmt.Errorf("stripe cancel error: %w", err)
+	}
+
+	return nil
+}
+
+func paymentStatusOf(status stripe.PaymentIntentStatus) PaymentStatus {
+	switch status {
+	case stripe.PaymentIntentStatusSucceeded:
+		return PaymentSucceeded
+	case stripe.PaymentIntentStatusRequiresAction, stripe.PaymentIntentStatusRequiresConfirmation:
+		return PaymentRequiresAction
+	case stripe.PaymentIntentStatusProcessing:
+		return PaymentProcessing
+	default:
+		return PaymentFailed
+	}
 }
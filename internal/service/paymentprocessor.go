@@ -1,8 +1,93 @@
 package service
 
-import "context"
+import (
+	"aulway/internal/utils/config"
+	"context"
+	"fmt"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentSucceeded      PaymentStatus = "succeeded"
+	PaymentRequiresAction PaymentStatus = "requires_action"
+	PaymentProcessing     PaymentStatus = "processing"
+	PaymentFailed         PaymentStatus = "failed"
+)
+
+// PaymentResult is the outcome of charging a card. ClientSecret is set when
+// the customer still has to authenticate the payment on the client.
+type PaymentResult struct {
+	Status        PaymentStatus
+	TransactionID string
+	ClientSecret  string
+}
+
+// PaymentDetails is what the provider holds for a payment, amounts are in the
+// same units as domain.Payment.
+type PaymentDetails struct {
+	Status   PaymentStatus
+	Captured int
+	Refunded int
+}
+
+// Capabilities lists what a payment provider supports beyond plain charges.
+type Capabilities struct {
+	// PartialRefund allows refunding one ticket of a multi ticket payment
+	PartialRefund bool
+	// SCA allows payments that wait for 3-D Secure authentication
+	SCA bool
+}
 
 type PaymentProcessor interface {
-	ProcessPayment(ctx context.Context, userID string, amount int, paymentMethodID, stripeKey string) (bool, string, error)
-	Refund(ctx context.Context, transactionID, stripeKey string, amount int) (bool, error)
+	Name() string
+	Currency() string
+	Capabilities() Capabilities
+	ProcessPayment(ctx context.Context, userID string, amount int, paymentMethodID string) (*PaymentResult, error)
+	Details(ctx context.Context, transactionID string) (*PaymentDetails, error)
+	Cancel(ctx context.Context, transactionID string) error
+	Refund(ctx context.Context, transactionID string, amount int) (bool, error)
+}
+
+// PaymentRegistry holds the payment providers by name. New purchases go to
+// the active one, existing payments are served by the provider that took them.
+type PaymentRegistry struct {
+	providers map[string]PaymentProcessor
+	active    string
+}
+
+func NewPaymentRegistry(active string, providers ...PaymentProcessor) (*PaymentRegistry, error) {
+	r := &PaymentRegistry{
+		providers: make(map[string]PaymentProcessor, len(providers)),
+		active:    active,
+	}
+	for _, p := range providers {
+		r.providers[p.Name()] = p
+	}
+
+	if _, ok := r.providers[active]; !ok {
+		return nil, fmt.Errorf("unknown payment provider %q", active)
+	}
+
+	return r, nil
+}
+
+// PaymentsFromConfig registers every provider and activates the configured one.
+func PaymentsFromConfig(c config.Config) (*PaymentRegistry, error) {
+	return NewPaymentRegistry(c.PaymentProvider,
+		NewStripeProcessor(c.StripeKey),
+		NewMockGatewayProcessor("http://"+c.MockGatewayAddress),
+	)
+}
+
+func (r *PaymentRegistry) Active() PaymentProcessor {
+	return r.providers[r.active]
+}
+
+func (r *PaymentRegistry) Get(name string) (PaymentProcessor, error) {
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown payment provider %q", name)
+	}
+	return p, nil
 }
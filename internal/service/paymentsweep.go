@@ -0,0 +1,146 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	orderRepo "aulway/internal/repository/order"
+	paymentRepo "aulway/internal/repository/payment"
+	routeRepo "aulway/internal/repository/route"
+	ticketRepo "aulway/internal/repository/ticket"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const paymentSweepBatch = 100
+
+// PaymentSweeper gives up the purchases nobody finished: pending payments the
+// customer did not authenticate before their deadline and initiated ones a
+// purchase left behind. Their intent is cancelled with the provider and their
+// seats go back to the route.
+type PaymentSweeper struct {
+	PaymentRepo paymentRepo.Repository
+	TicketRepo  ticketRepo.Repository
+	RouteRepo   routeRepo.Repository
+	OrderRepo   orderRepo.Repository
+	Payments    *PaymentRegistry
+	TTL         time.Duration
+	Interval    time.Duration
+}
+
+func NewPaymentSweeper(paymentRepo paymentRepo.Repository, ticketRepo ticketRepo.Repository, routeRepo routeRepo.Repository, orderRepo orderRepo.Repository, payments *PaymentRegistry, ttl, interval time.Duration) *PaymentSweeper {
+	return &PaymentSweeper{
+		PaymentRepo: paymentRepo,
+		TicketRepo:  ticketRepo,
+		RouteRepo:   routeRepo,
+		OrderRepo:   orderRepo,
+		Payments:    payments,
+		TTL:         ttl,
+		Interval:    interval,
+	}
+}
+
+// Run sweeps expired payments every interval until ctx is done.
+func (j *PaymentSweeper) Run(ctx context.Context) error {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.Sweep(ctx); err != nil {
+			slog.Error("failed to sweep expired payments", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep releases a batch of expired payments and returns how many were
+// released. A payment that cannot be cancelled yet is tried again on the next
+// sweep.
+func (j *PaymentSweeper) Sweep(ctx context.Context) (int, error) {
+	now := time.Now()
+	payments, err := j.PaymentRepo.ListExpired(ctx, now, now.Add(-j.TTL), paymentSweepBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for i := range payments {
+		ok, err := j.expire(ctx, &payments[i])
+		if err != nil {
+			slog.Warn("failed to expire payment", slog.String("payment_id", payments[i].ID), slog.String("error", err.Error()))
+			continue
+		}
+		if ok {
+			released++
+		}
+	}
+
+	if released > 0 {
+		slog.Info("expired payments released", slog.Int("count", released))
+	}
+
+	return released, nil
+}
+
+// expire cancels the intent of a payment and releases its tickets. A payment
+// that went through meanwhile is left to the webhook or ConfirmPayment, which
+// issue its tickets.
+func (j *PaymentSweeper) expire(ctx context.Context, expired *domain.Payment) (bool, error) {
+	if expired.TransactionID != "" {
+		processor, err := j.Payments.Get(expired.Provider)
+		if err != nil {
+			return false, err
+		}
+
+		if err := processor.Cancel(ctx, expired.TransactionID); err != nil {
+			details, detailsErr := processor.Details(ctx, expired.TransactionID)
+			if detailsErr == nil && details.Status == PaymentSucceeded {
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to cancel payment intent: %w", err)
+		}
+	}
+
+	tx := j.TicketRepo.BeginTransaction()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// the payment row lock serializes the sweep with confirmation and webhooks
+	payment, err := j.PaymentRepo.GetForUpdate(ctx, tx, expired.ID)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if payment.Status != expired.Status {
+		tx.Rollback()
+		return false, nil
+	}
+
+	tickets, err := j.TicketRepo.GetByPaymentID(ctx, tx, payment.ID)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := releaseTickets(ctx, tx, j.TicketRepo, j.RouteRepo, j.OrderRepo, tickets, "failed"); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := j.PaymentRepo.Update(ctx, tx, map[string]interface{}{"status": "failed"}, payment.ID); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return false, fmt.Errorf("failed to commit expired payment: %w", err)
+	}
+	return true, nil
+}
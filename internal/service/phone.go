@@ -0,0 +1,190 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/auth/model"
+	userModel "aulway/internal/handler/user/model"
+	repoErrs "aulway/internal/repository/errs"
+	"aulway/internal/templates"
+	"aulway/internal/utils/errs"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// phoneCodeWindow is the period the hourly limit of codes is counted over.
+const phoneCodeWindow = time.Hour
+
+var phonePattern = regexp.MustCompile(`^(?:\+7|8)(\d{10})$`)
+
+// phoneCodeTexts are the texts of the verification SMS by locale, they take
+// the code and its lifetime in minutes.
+var phoneCodeTexts = map[string]string{
+	templates.EN: "Aulway: your code is %s, it is valid for %d min. Do not share it with anyone.",
+	templates.RU: "Aulway: ваш код %s, действует %d мин. Никому его не сообщайте.",
+	templates.KK: "Aulway: сіздің кодыңыз %s, %d мин жарамды. Оны ешкімге айтпаңыз.",
+}
+
+// NormalizePhone brings a Kazakh number written as +7 or 8 followed by ten
+// digits to the +7XXXXXXXXXX form phones are stored in.
+func NormalizePhone(phone string) (string, error) {
+	phone = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(phone)
+
+	match := phonePattern.FindStringSubmatch(phone)
+	if match == nil {
+		return "", errs.ErrIncorrectPhoneFormat
+	}
+
+	return "+7" + match[1], nil
+}
+
+// SendSigninCode texts a code to the phone, the code signs in the user the
+// phone is verified for or signs up a new one.
+func (s *Auth) SendSigninCode(ctx context.Context, phone, locale string) error {
+	phone, err := NormalizePhone(phone)
+	if err != nil {
+		return err
+	}
+
+	return s.sendPhoneCode(ctx, signinCodeKey(phone), phone, locale)
+}
+
+// SigninWithPhone checks the sign-in code of the phone and returns its user.
+// A phone no one has verified yet gets a new account, created reports that.
+func (s *Auth) SigninWithPhone(ctx context.Context, req model.PhoneSigninRequest) (usr *domain.User, created bool, err error) {
+	phone, err := NormalizePhone(req.Phone)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := s.checkCode(ctx, phoneAccount(phone), signinCodeKey(phone), req.Code, s.smsCfg.CodeTTL); err != nil {
+		return nil, false, err
+	}
+
+	usr, err = s.repo.GetByVerifiedPhone(ctx, phone)
+	if err == nil {
+		return usr, false, nil
+	}
+	if !errors.Is(err, repoErrs.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	userid, err := uuid.NewV7()
+	if err != nil {
+		return nil, false, err
+	}
+
+	usr = &domain.User{
+		ID:            userid.String(),
+		Phone:         phone,
+		PhoneVerified: true,
+		Role:          userRole,
+	}
+	if err := s.repo.Create(ctx, usr); err != nil {
+		return nil, false, err
+	}
+
+	return usr, true, nil
+}
+
+// SendPhoneChangeCode texts a code to the new phone of the user, it is set
+// once the code comes back. A phone verified by someone else is refused.
+func (s *Auth) SendPhoneChangeCode(ctx context.Context, userID string, req userModel.PhoneChangeRequest) error {
+	phone, err := NormalizePhone(req.Phone)
+	if err != nil {
+		return err
+	}
+
+	owner, err := s.repo.GetByVerifiedPhone(ctx, phone)
+	if err == nil && owner.ID != userID {
+		return repoErrs.PhoneAlreadyExists
+	}
+	if err != nil && !errors.Is(err, repoErrs.ErrRecordNotFound) {
+		return err
+	}
+
+	return s.sendPhoneCode(ctx, phoneChangeCodeKey(userID, phone), phone, userLanguage(ctx, s.settings, userID))
+}
+
+// VerifyPhoneChange checks the code sent to the new phone and makes it the
+// verified phone of the user.
+func (s *Auth) VerifyPhoneChange(ctx context.Context, userID string, req userModel.VerifyPhoneChangeRequest) (*domain.User, error) {
+	phone, err := NormalizePhone(req.Phone)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCode(ctx, phoneAccount(phone), phoneChangeCodeKey(userID, phone), req.Code, s.smsCfg.CodeTTL); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetVerifiedPhone(ctx, userID, phone); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Get(ctx, userID)
+}
+
+func (s *Auth) sendPhoneCode(ctx context.Context, key, phone, locale string) error {
+	if err := s.limitPhoneCodes(ctx, phone); err != nil {
+		return err
+	}
+
+	code := fmt.Sprintf("%06d", rand.Intn(1000000))
+
+	if err := StoreCode(ctx, s.redis, key, code, s.smsCfg.CodeTTL); err != nil {
+		return fmt.Errorf("failed to store phone code: %w", err)
+	}
+
+	text := fmt.Sprintf(phoneCodeTexts[templates.Locale(locale)], code, int(s.smsCfg.CodeTTL.Minutes()))
+	if err := s.sms.Send(ctx, phone, text); err != nil {
+		return fmt.Errorf("failed to send phone code: %w", err)
+	}
+
+	return nil
+}
+
+// limitPhoneCodes lets a phone get one code per resend interval and no more
+// than the hourly limit, whatever the codes are for.
+func (s *Auth) limitPhoneCodes(ctx context.Context, phone string) error {
+	allowed, err := s.redis.SetNX(ctx, "phone_code_cooldown:"+phone, 1, s.smsCfg.ResendInterval).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check phone code limit: %w", err)
+	}
+	if !allowed {
+		return errs.ErrTooManyCodeRequests
+	}
+
+	key := "phone_code_count:" + phone
+	count, err := s.redis.Incr(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check phone code limit: %w", err)
+	}
+	if count == 1 {
+		s.redis.Expire(ctx, key, phoneCodeWindow)
+	}
+	if count > int64(s.smsCfg.HourlyLimit) {
+		return errs.ErrTooManyCodeRequests
+	}
+
+	return nil
+}
+
+func signinCodeKey(phone string) string {
+	return "phone_code:signin:" + phone
+}
+
+func phoneChangeCodeKey(userID, phone string) string {
+	return "phone_code:change:" + userID + ":" + phone
+}
+
+// phoneAccount names the phone to the lockout.
+func phoneAccount(phone string) string {
+	return "phone:" + phone
+}
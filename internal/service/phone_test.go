@@ -0,0 +1,154 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/auth/model"
+	userModel "aulway/internal/handler/user/model"
+	repoErrs "aulway/internal/repository/errs"
+	settingsRepository "aulway/internal/repository/settings"
+	userRepository "aulway/internal/repository/user"
+	"aulway/internal/sms"
+	"aulway/internal/utils/config"
+	"aulway/internal/utils/errs"
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func testRedis(t *testing.T) *redis.Client {
+	t.Helper()
+
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR is not set")
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	require.NoError(t, client.Ping(context.Background()).Err())
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+// testPhone is a phone no other test run uses.
+func testPhone() string {
+	return fmt.Sprintf("+7700%07d", rand.Intn(10000000))
+}
+
+var smsCode = regexp.MustCompile(`\d{6}`)
+
+// lastCode is the code of the last SMS sent to the phone.
+func lastCode(t *testing.T, fake *sms.Fake, phone string) string {
+	t.Helper()
+
+	sent := fake.Sent()
+	for i := len(sent) - 1; i >= 0; i-- {
+		if sent[i].Phone == phone {
+			return smsCode.FindString(sent[i].Text)
+		}
+	}
+
+	t.Fatalf("no sms sent to %s", phone)
+	return ""
+}
+
+func TestNormalizePhone(t *testing.T) {
+	for input, want := range map[string]string{
+		"+77011234567":     "+77011234567",
+		"87011234567":      "+77011234567",
+		"8 (701) 123-4567": "+77011234567",
+		"+7 701 123 45 67": "+77011234567",
+	} {
+		phone, err := NormalizePhone(input)
+		require.NoError(t, err, input)
+		require.Equal(t, want, phone, input)
+	}
+
+	for _, input := range []string{"", "7011234567", "+7701123456", "+17011234567", "8701123456a"} {
+		_, err := NormalizePhone(input)
+		require.ErrorIs(t, err, errs.ErrIncorrectPhoneFormat, input)
+	}
+}
+
+func TestPhoneCodesAreRateLimited(t *testing.T) {
+	client := testRedis(t)
+	ctx := context.Background()
+	fake := &sms.Fake{}
+	auth := NewAuthService(userRepository.Repository{}, settingsRepository.Repository{}, client, config.SMTP{}, fake,
+		config.SMS{CodeTTL: time.Minute, ResendInterval: time.Second, HourlyLimit: 2}, nil, nil)
+	phone := testPhone()
+
+	require.NoError(t, auth.SendSigninCode(ctx, phone, "kk"))
+	require.Contains(t, fake.Sent()[0].Text, "кодыңыз")
+
+	// the resend interval applies to the number in any format
+	require.ErrorIs(t, auth.SendSigninCode(ctx, "8"+phone[2:], "ru"), errs.ErrTooManyCodeRequests)
+
+	time.Sleep(1100 * time.Millisecond)
+	require.NoError(t, auth.SendSigninCode(ctx, phone, "ru"))
+
+	time.Sleep(1100 * time.Millisecond)
+	require.ErrorIs(t, auth.SendSigninCode(ctx, phone, "ru"), errs.ErrTooManyCodeRequests)
+	require.Len(t, fake.Sent(), 2)
+}
+
+func TestPhoneSigninSignsUpAndChangesPhone(t *testing.T) {
+	client := testRedis(t)
+	db := testDB(t)
+	ctx := context.Background()
+	fake := &sms.Fake{}
+	auth := NewAuthService(userRepository.NewRepository(db), settingsRepository.New(db), client, config.SMTP{}, fake,
+		config.SMS{CodeTTL: time.Minute, ResendInterval: time.Millisecond, HourlyLimit: 10}, nil, nil)
+	phone := testPhone()
+
+	require.NoError(t, auth.SendSigninCode(ctx, phone, "en"))
+	code := lastCode(t, fake, phone)
+
+	// wrong codes are refused and the code is dropped after too many of them
+	for range codeAttempts {
+		_, _, err := auth.SigninWithPhone(ctx, model.PhoneSigninRequest{Phone: phone, Code: "000000x"})
+		require.ErrorIs(t, err, errs.ErrInvalidCode)
+	}
+	_, _, err := auth.SigninWithPhone(ctx, model.PhoneSigninRequest{Phone: phone, Code: code})
+	require.ErrorIs(t, err, errs.ErrInvalidCode)
+
+	// the first sign-in creates the account, the next one finds it
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, auth.SendSigninCode(ctx, phone, "en"))
+	signedUp, created, err := auth.SigninWithPhone(ctx, model.PhoneSigninRequest{Phone: phone, Code: lastCode(t, fake, phone)})
+	require.NoError(t, err)
+	require.True(t, created)
+	require.True(t, signedUp.PhoneVerified)
+	require.Empty(t, signedUp.Email)
+
+	// a code is used up once it signs in
+	_, _, err = auth.SigninWithPhone(ctx, model.PhoneSigninRequest{Phone: phone, Code: lastCode(t, fake, phone)})
+	require.ErrorIs(t, err, errs.ErrInvalidCode)
+
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, auth.SendSigninCode(ctx, "8"+phone[2:], "en"))
+	signedIn, created, err := auth.SigninWithPhone(ctx, model.PhoneSigninRequest{Phone: phone, Code: lastCode(t, fake, phone)})
+	require.NoError(t, err)
+	require.False(t, created)
+	require.Equal(t, signedUp.ID, signedIn.ID)
+
+	// another rider cannot take the verified phone
+	other := &domain.User{ID: fmt.Sprintf("phone-test-%d", rand.Int()), Email: fmt.Sprintf("phone-%d@example.com", rand.Int()), Role: userRole}
+	require.NoError(t, db.Create(other).Error)
+	err = auth.SendPhoneChangeCode(ctx, other.ID, userModel.PhoneChangeRequest{Phone: phone})
+	require.ErrorIs(t, err, repoErrs.PhoneAlreadyExists)
+
+	newPhone := testPhone()
+	require.NoError(t, auth.SendPhoneChangeCode(ctx, other.ID, userModel.PhoneChangeRequest{Phone: newPhone}))
+	changed, err := auth.VerifyPhoneChange(ctx, other.ID, userModel.VerifyPhoneChangeRequest{Phone: newPhone, Code: lastCode(t, fake, newPhone)})
+	require.NoError(t, err)
+	require.Equal(t, newPhone, changed.Phone)
+	require.True(t, changed.PhoneVerified)
+}
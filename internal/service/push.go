@@ -0,0 +1,101 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/device/model"
+	"aulway/internal/push"
+	deviceRepo "aulway/internal/repository/device"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// PushService keeps the devices of users and pushes notifications to them.
+// Pushes are best effort and go out in the background, the email of the
+// same event is the one that is retried. Without a sender nothing is pushed.
+type PushService struct {
+	Repo   deviceRepo.Repository
+	Sender push.Sender
+}
+
+func NewPushService(repo deviceRepo.Repository, sender push.Sender) *PushService {
+	return &PushService{
+		Repo:   repo,
+		Sender: sender,
+	}
+}
+
+func (s *PushService) RegisterDevice(ctx context.Context, userID string, req model.RegisterDeviceRequest) (*domain.Device, error) {
+	now := time.Now()
+	device := &domain.Device{
+		Token:     req.Token,
+		UserID:    userID,
+		Platform:  req.Platform,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.Repo.Save(ctx, device); err != nil {
+		return nil, err
+	}
+
+	return device, nil
+}
+
+func (s *PushService) ListDevices(ctx context.Context, userID string) ([]domain.Device, error) {
+	return s.Repo.ListByUser(ctx, userID)
+}
+
+func (s *PushService) UnregisterDevice(ctx context.Context, userID, token string) error {
+	return s.Repo.Delete(ctx, userID, token)
+}
+
+// Notify pushes the notification to the devices of the user in the
+// background, failures are logged.
+func (s *PushService) Notify(ctx context.Context, userID string, notification push.Notification) {
+	if s == nil || s.Sender == nil {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := s.Deliver(ctx, userID, notification); err != nil {
+			slog.Error("failed to push notification", slog.String("user_id", userID), slog.String("error", err.Error()))
+		}
+	}()
+}
+
+// Deliver pushes the notification to the devices of the user and forgets
+// the devices FCM no longer knows.
+func (s *PushService) Deliver(ctx context.Context, userID string, notification push.Notification) error {
+	devices, err := s.Repo.ListByUser(ctx, userID)
+	if err != nil || len(devices) == 0 {
+		return err
+	}
+
+	tokens := make([]string, 0, len(devices))
+	for _, device := range devices {
+		tokens = append(tokens, device.Token)
+	}
+
+	stale, sendErr := s.Sender.Send(ctx, tokens, notification)
+	if err := s.Repo.DeleteTokens(ctx, stale); err != nil {
+		slog.Error("failed to forget stale device tokens", slog.String("user_id", userID), slog.String("error", err.Error()))
+	}
+
+	return sendErr
+}
+
+// tripPush is the push of an email about a trip: the email subject, with
+// the route and its departure as the text. The app gets the kind of the
+// email and the order in the data.
+func tripPush(message *domain.OutboxMessage, route *domain.Route) push.Notification {
+	return push.Notification{
+		Title: message.Subject,
+		Body:  fmt.Sprintf("%s → %s, %s", route.Departure, route.Destination, route.StartDate.In(almaty).Format("02.01.2006 15:04")),
+		Data: map[string]string{
+			"type":     message.Kind,
+			"order_id": message.OrderID,
+		},
+	}
+}
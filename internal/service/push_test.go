@@ -0,0 +1,75 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	deviceModel "aulway/internal/handler/device/model"
+	"aulway/internal/handler/ticket/model"
+	"aulway/internal/push"
+	deviceRepository "aulway/internal/repository/device"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTripPushShowsRouteAndDeparture(t *testing.T) {
+	message := newOutboxMessage(domain.OutboxReminder, "passenger@example.com", "Ваша поездка Алматы → Талдыкорган", "<p></p>", "order-1")
+	route := &domain.Route{Departure: "Алматы", Destination: "Талдыкорган", StartDate: time.Date(2025, 6, 14, 3, 30, 0, 0, time.UTC)}
+
+	notification := tripPush(message, route)
+	require.Equal(t, message.Subject, notification.Title)
+	require.Equal(t, "Алматы → Талдыкорган, 14.06.2025 08:30", notification.Body)
+	require.Equal(t, map[string]string{"type": domain.OutboxReminder, "order_id": "order-1"}, notification.Data)
+}
+
+func TestPushServiceWithoutSenderIsDisabled(t *testing.T) {
+	var disabled *PushService
+	disabled.Notify(context.Background(), "user-1", push.Notification{Title: "x"})
+
+	NewPushService(deviceRepository.Repository{}, nil).Notify(context.Background(), "user-1", push.Notification{Title: "x"})
+}
+
+func TestPurchaseAndCancellationArePushed(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 1)
+	ctx := context.Background()
+	userID := f.userIDs[0]
+	f.pushes.Stale = []string{"stale-token"}
+
+	devices := f.service.Push
+	_, err := devices.RegisterDevice(ctx, userID, deviceModel.RegisterDeviceRequest{Token: "phone-token", Platform: domain.PlatformAndroid})
+	require.NoError(t, err)
+	_, err = devices.RegisterDevice(ctx, userID, deviceModel.RegisterDeviceRequest{Token: "stale-token", Platform: domain.PlatformIOS})
+	require.NoError(t, err)
+
+	pushed := func(kind string) []push.Sent {
+		found := make([]push.Sent, 0)
+		for _, sent := range f.pushes.Sent() {
+			if sent.Notification.Data["type"] == kind {
+				found = append(found, sent)
+			}
+		}
+		return found
+	}
+
+	// pushes go out even when no email is asked for
+	purchase, err := f.service.BuyTickets(ctx, userID, f.routeID, "pm_card_visa", model.BuyTicketRequest{Quantity: 1})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(pushed(domain.OutboxTickets)) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	sent := pushed(domain.OutboxTickets)[0]
+	require.ElementsMatch(t, []string{"phone-token", "stale-token"}, sent.Tokens)
+	require.Equal(t, purchase.OrderID, sent.Notification.Data["order_id"])
+	require.Contains(t, sent.Notification.Title, purchase.OrderNumber)
+
+	// the token FCM no longer knows is forgotten
+	require.Eventually(t, func() bool {
+		list, err := devices.ListDevices(ctx, userID)
+		return err == nil && len(list) == 1 && list[0].Token == "phone-token"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err = f.service.CancelOrder(ctx, userID, purchase.OrderID, nil, "")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(pushed(domain.OutboxCancellation)) == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"phone-token"}, pushed(domain.OutboxCancellation)[0].Tokens)
+}
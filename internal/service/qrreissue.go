@@ -0,0 +1,72 @@
+package service
+
+import (
+	ticketRepo "aulway/internal/repository/ticket"
+	"context"
+	"log/slog"
+)
+
+const qrReissueBatch = 100
+
+// QRReissuer replaces the QR codes of tickets bought before codes were signed.
+// Those hold the whole ticket as JSON, user and payment included, and
+// conductors cannot validate them.
+type QRReissuer struct {
+	TicketRepo ticketRepo.Repository
+	Signer     *TicketSigner
+}
+
+func NewQRReissuer(ticketRepo ticketRepo.Repository, signer *TicketSigner) *QRReissuer {
+	return &QRReissuer{
+		TicketRepo: ticketRepo,
+		Signer:     signer,
+	}
+}
+
+// Run reissues the stale codes once, then waits for ctx to be done like the
+// other jobs.
+func (r *QRReissuer) Run(ctx context.Context) error {
+	if _, err := r.Reissue(ctx); err != nil {
+		slog.Error("failed to reissue ticket QR codes", slog.String("error", err.Error()))
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+// Reissue signs a new code for every ticket with a code of an older version
+// and returns how many were replaced. A ticket that fails is left for the
+// next start.
+func (r *QRReissuer) Reissue(ctx context.Context) (int, error) {
+	reissued := 0
+	afterID := ""
+	for {
+		tickets, err := r.TicketRepo.ListStaleQRCodes(ctx, ticketQRVersion, afterID, qrReissueBatch)
+		if err != nil {
+			return reissued, err
+		}
+		if len(tickets) == 0 {
+			break
+		}
+
+		for i := range tickets {
+			afterID = tickets[i].ID
+
+			qrCode, err := generateQRCode(r.Signer.Sign(&tickets[i]))
+			if err == nil {
+				err = r.TicketRepo.UpdateQRCode(ctx, tickets[i].ID, qrCode, ticketQRVersion)
+			}
+			if err != nil {
+				slog.Warn("failed to reissue ticket QR code", slog.String("ticket_id", tickets[i].ID), slog.String("error", err.Error()))
+				continue
+			}
+			reissued++
+		}
+	}
+
+	if reissued > 0 {
+		slog.Info("ticket QR codes reissued", slog.Int("count", reissued))
+	}
+
+	return reissued, nil
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	ticketModel "aulway/internal/handler/ticket/model"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQRReissuerReplacesLegacyCodes(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 1)
+	ctx := context.Background()
+
+	purchase, err := f.service.BuyTickets(ctx, f.userIDs[0], f.routeID, "pm_card_visa", ticketModel.BuyTicketRequest{Quantity: 2})
+	require.NoError(t, err)
+	legacy, current := purchase.Tickets[0], purchase.Tickets[1]
+
+	// a code from before signing, the whole ticket as JSON
+	require.NoError(t, f.db.Model(&domain.Ticket{}).Where("id = ?", legacy.ID).
+		Updates(map[string]interface{}{"qr_code": "eyJpZCI6InRpY2tldC0xIn0=", "qr_version": 0}).Error)
+
+	reissued, err := NewQRReissuer(f.service.TicketRepo, f.service.Signer).Reissue(ctx)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, reissued, 1)
+
+	ticket, err := f.service.TicketRepo.Get(ctx, legacy.ID)
+	require.NoError(t, err)
+	require.Equal(t, ticketQRVersion, ticket.QRVersion)
+	want, err := generateQRCode(f.service.Signer.Sign(ticket))
+	require.NoError(t, err)
+	require.Equal(t, want, ticket.QRCode)
+
+	untouched, err := f.service.TicketRepo.Get(ctx, current.ID)
+	require.NoError(t, err)
+	require.Equal(t, current.QRCode, untouched.QRCode, "signed codes are kept")
+}
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	repoErrs "aulway/internal/repository/errs"
+	ledgerRepo "aulway/internal/repository/ledger"
+	orderRepo "aulway/internal/repository/order"
+	paymentRepo "aulway/internal/repository/payment"
+	refundRepo "aulway/internal/repository/refund"
+	routeRepo "aulway/internal/repository/route"
+	ticketRepo "aulway/internal/repository/ticket"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stripe/stripe-go/v76"
+	"gorm.io/gorm"
+	"log/slog"
+)
+
+func NewPaymentReconciler(paymentRepo paymentRepo.Repository, ticketRepo ticketRepo.Repository, routeRepo routeRepo.Repository, ledgerRepo ledgerRepo.Repository, orderRepo orderRepo.Repository, refundRepo refundRepo.Repository) *PaymentReconciler {
+	return &PaymentReconciler{
+		PaymentRepo: paymentRepo,
+		TicketRepo:  ticketRepo,
+		RouteRepo:   routeRepo,
+		LedgerRepo:  ledgerRepo,
+		OrderRepo:   orderRepo,
+		RefundRepo:  refundRepo,
+	}
+}
+
+// PaymentReconciler brings payments and their tickets in line with what
+// Stripe reports through webhooks.
+type PaymentReconciler struct {
+	PaymentRepo paymentRepo.Repository
+	TicketRepo  ticketRepo.Repository
+	RouteRepo   routeRepo.Repository
+	LedgerRepo  ledgerRepo.Repository
+	OrderRepo   orderRepo.Repository
+	RefundRepo  refundRepo.Repository
+}
+
+func (s *PaymentReconciler) HandleStripeEvent(ctx context.Context, event stripe.Event) error {
+	ref, err := paymentIntentOf(event)
+	if err != nil {
+		return err
+	}
+	if ref.TransactionID == "" {
+		slog.Info("ignoring stripe event", slog.String("event_id", event.ID), slog.String("type", string(event.Type)))
+		return nil
+	}
+
+	tx := s.TicketRepo.BeginTransaction()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	first, err := s.PaymentRepo.RecordEvent(ctx, tx, event.ID, string(event.Type))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if !first {
+		tx.Rollback()
+		return nil
+	}
+
+	// the payment row lock serializes webhooks with the confirm endpoint
+	payment, err := s.PaymentRepo.GetByTransactionID(ctx, tx, ref.TransactionID)
+	if errors.Is(err, repoErrs.ErrRecordNotFound) {
+		// the purchase records the transaction only once the provider answered,
+		// the event is not recorded so that Stripe delivers it again
+		tx.Rollback()
+		return fmt.Errorf("no payment for transaction %s yet: %w", ref.TransactionID, err)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	tickets, err := s.TicketRepo.GetByPaymentID(ctx, tx, payment.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	status := payment.Status
+
+	switch event.Type {
+	case stripe.EventTypePaymentIntentSucceeded:
+		status = "successful"
+		if err := approveTickets(ctx, tx, s.TicketRepo, s.OrderRepo, tickets); err != nil {
+			tx.Rollback()
+			return err
+		}
+		// purchases that did not need authentication were charged in the ledger already
+		if payment.Status == "pending" {
+			if err := s.LedgerRepo.Append(ctx, tx, chargeEntry(payment, event.ID)); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	case stripe.EventTypePaymentIntentPaymentFailed, stripe.EventTypePaymentIntentCanceled:
+		// a failed authentication attempt can be retried, only a payment that
+		// is still waiting gives its seats back
+		if payment.Status == "pending" {
+			status = "failed"
+			if err := releaseTickets(ctx, tx, s.TicketRepo, s.RouteRepo, s.OrderRepo, tickets, "failed"); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	case stripe.EventTypeChargeRefunded:
+		if err := s.recordRefund(ctx, tx, payment, ref, event.ID); err != nil {
+			tx.Rollback()
+			return err
+		}
+		// partial refunds come from single ticket cancellations, which already
+		// updated their ticket, only a full refund settles the whole payment
+		if ref.FullRefund {
+			status = "refunded"
+			if err := releaseTickets(ctx, tx, s.TicketRepo, s.RouteRepo, s.OrderRepo, tickets, "refunded"); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	case stripe.EventTypeChargeDisputeCreated:
+		status = "disputed"
+		err = s.LedgerRepo.Append(ctx, tx, &domain.LedgerEntry{
+			PaymentID: payment.ID,
+			Type:      domain.LedgerChargeback,
+			Amount:    -ref.Amount,
+			Currency:  payment.Currency,
+			Reference: event.ID,
+		})
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		for _, ticket := range tickets {
+			if ticket.Status == "cancelled" {
+				continue
+			}
+			err = s.TicketRepo.Update(ctx, tx, map[string]interface{}{
+				"payment_status": "disputed",
+			}, ticket.ID)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+
+	if status != payment.Status {
+		if err := s.PaymentRepo.Update(ctx, tx, map[string]interface{}{"status": status}, payment.ID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit stripe event: %w", err)
+	}
+	return nil
+}
+
+// recordRefund adds the part of the refunded amount Stripe reports that is not
+// in the ledger yet, which covers refunds made outside of ticket cancellation.
+// Refunds of cancellations that are not settled yet record themselves.
+func (s *PaymentReconciler) recordRefund(ctx context.Context, tx *gorm.DB, payment *domain.Payment, ref stripeEventRef, reference string) error {
+	recorded, err := s.LedgerRepo.Sum(ctx, tx, payment.ID, domain.LedgerRefund, domain.LedgerPartialRefund)
+	if err != nil {
+		return err
+	}
+
+	pending, err := s.RefundRepo.PendingAmount(ctx, tx, payment.ID)
+	if err != nil {
+		return err
+	}
+
+	missing := ref.Amount + recorded - pending
+	if missing <= 0 {
+		return nil
+	}
+
+	entry := &domain.LedgerEntry{
+		PaymentID: payment.ID,
+		Type:      domain.LedgerPartialRefund,
+		Amount:    -missing,
+		Currency:  payment.Currency,
+		Reference: reference,
+	}
+	if ref.FullRefund {
+		entry.Type = domain.LedgerRefund
+	}
+
+	return s.LedgerRepo.Append(ctx, tx, entry)
+}
+
+// approveTickets issues the tickets that were waiting for their payment.
+func approveTickets(ctx context.Context, tx *gorm.DB, tickets ticketRepo.Repository, orders orderRepo.Repository, list []domain.Ticket) error {
+	for _, ticket := range list {
+		if ticket.Status != "awaiting" {
+			continue
+		}
+		err := tickets.Update(ctx, tx, map[string]interface{}{
+			"status":         "approved",
+			"payment_status": "paid",
+		}, ticket.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return refreshOrders(ctx, tx, orders, list)
+}
+
+// releaseTickets cancels the tickets that are still live and gives their
+// seats back to the route.
+func releaseTickets(ctx context.Context, tx *gorm.DB, tickets ticketRepo.Repository, routes routeRepo.Repository, orders orderRepo.Repository, list []domain.Ticket, paymentStatus string) error {
+	seats := make(map[string]int)
+	for _, ticket := range list {
+		if ticket.Status == "cancelled" {
+			continue
+		}
+		err := tickets.Update(ctx, tx, map[string]interface{}{
+			"status":         "cancelled",
+			"payment_status": paymentStatus,
+		}, ticket.ID)
+		if err != nil {
+			return err
+		}
+		seats[ticket.RouteID]++
+	}
+
+	for routeID, count := range seats {
+		if err := routes.IncrementSeats(ctx, tx, routeID, count); err != nil {
+			return err
+		}
+	}
+	return refreshOrders(ctx, tx, orders, list)
+}
+
+// refreshOrders updates the status of the orders the tickets belong to.
+func refreshOrders(ctx context.Context, tx *gorm.DB, orders orderRepo.Repository, list []domain.Ticket) error {
+	refreshed := make(map[string]bool)
+	for _, ticket := range list {
+		if ticket.OrderID == "" || refreshed[ticket.OrderID] {
+			continue
+		}
+		if err := orders.RefreshStatus(ctx, tx, ticket.OrderID); err != nil {
+			return err
+		}
+		refreshed[ticket.OrderID] = true
+	}
+	return nil
+}
+
+// stripeEventRef is what an event says about a payment. Amount is the total
+// refunded amount for charge.refunded and the disputed amount for disputes.
+type stripeEventRef struct {
+	TransactionID string
+	FullRefund    bool
+	Amount        int
+}
+
+// paymentIntentOf returns the payment intent an event is about, or an empty id
+// for events that are not reconciled.
+func paymentIntentOf(event stripe.Event) (stripeEventRef, error) {
+	var ref stripeEventRef
+	if event.Data == nil {
+		return ref, nil
+	}
+
+	switch event.Type {
+	case stripe.EventTypePaymentIntentSucceeded, stripe.EventTypePaymentIntentPaymentFailed, stripe.EventTypePaymentIntentCanceled:
+		var pi stripe.PaymentIntent
+		if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
+			return ref, fmt.Errorf("failed to parse payment intent: %w", err)
+		}
+		ref.TransactionID = pi.ID
+	case stripe.EventTypeChargeRefunded:
+		var ch stripe.Charge
+		if err := json.Unmarshal(event.Data.Raw, &ch); err != nil {
+			return ref, fmt.Errorf("failed to parse charge: %w", err)
+		}
+		if ch.PaymentIntent != nil {
+			ref.TransactionID = ch.PaymentIntent.ID
+		}
+		ref.FullRefund = ch.Refunded
+		ref.Amount = int(ch.AmountRefunded / 100)
+	case stripe.EventTypeChargeDisputeCreated:
+		var dispute stripe.Dispute
+		if err := json.Unmarshal(event.Data.Raw, &dispute); err != nil {
+			return ref, fmt.Errorf("failed to parse dispute: %w", err)
+		}
+		if dispute.PaymentIntent != nil {
+			ref.TransactionID = dispute.PaymentIntent.ID
+		} else if dispute.Charge != nil && dispute.Charge.PaymentIntent != nil {
+			ref.TransactionID = dispute.Charge.PaymentIntent.ID
+		}
+		ref.Amount = int(dispute.Amount / 100)
+	}
+	return ref, nil
+}
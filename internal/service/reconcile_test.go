@@ -0,0 +1,37 @@
+package service
+
+import (
+	"encoding/json"
+	"github.com/stripe/stripe-go/v76"
+	"testing"
+)
+
+func TestPaymentIntentOf(t *testing.T) {
+	cases := []struct {
+		name      string
+		eventType stripe.EventType
+		object    string
+		want      stripeEventRef
+	}{
+		{"succeeded", stripe.EventTypePaymentIntentSucceeded, `{"id":"pi_1","object":"payment_intent"}`, stripeEventRef{TransactionID: "pi_1"}},
+		{"failed", stripe.EventTypePaymentIntentPaymentFailed, `{"id":"pi_2","object":"payment_intent"}`, stripeEventRef{TransactionID: "pi_2"}},
+		{"full refund", stripe.EventTypeChargeRefunded, `{"id":"ch_1","object":"charge","payment_intent":"pi_3","refunded":true,"amount_refunded":1000000}`, stripeEventRef{TransactionID: "pi_3", FullRefund: true, Amount: 10000}},
+		{"partial refund", stripe.EventTypeChargeRefunded, `{"id":"ch_2","object":"charge","payment_intent":"pi_4","refunded":false,"amount_refunded":500000}`, stripeEventRef{TransactionID: "pi_4", Amount: 5000}},
+		{"dispute", stripe.EventTypeChargeDisputeCreated, `{"id":"dp_1","object":"dispute","charge":"ch_3","payment_intent":"pi_5","amount":1000000}`, stripeEventRef{TransactionID: "pi_5", Amount: 10000}},
+		{"other", stripe.EventTypeCustomerCreated, `{"id":"cus_1","object":"customer"}`, stripeEventRef{}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			event := stripe.Event{Type: tc.eventType, Data: &stripe.EventData{Raw: json.RawMessage(tc.object)}}
+
+			ref, err := paymentIntentOf(event)
+			if err != nil {
+				t.Fatalf("paymentIntentOf: %v", err)
+			}
+			if ref != tc.want {
+				t.Fatalf("got %+v, want %+v", ref, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	ledgerRepo "aulway/internal/repository/ledger"
+	paymentRepo "aulway/internal/repository/payment"
+	refundRepo "aulway/internal/repository/refund"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"log/slog"
+	"time"
+)
+
+const (
+	refundBatch    = 20
+	refundLease    = 5 * time.Minute
+	refundMaxDelay = 6 * time.Hour
+)
+
+// RefundSettler makes the refunds of cancelled tickets with the payment
+// provider. A cancellation stores its refund and settles it once committed,
+// Run retries the refunds the provider failed to make.
+type RefundSettler struct {
+	RefundRepo  refundRepo.Repository
+	PaymentRepo paymentRepo.Repository
+	LedgerRepo  ledgerRepo.Repository
+	Payments    *PaymentRegistry
+	Interval    time.Duration
+	BaseDelay   time.Duration
+}
+
+func NewRefundSettler(refundRepo refundRepo.Repository, paymentRepo paymentRepo.Repository, ledgerRepo ledgerRepo.Repository, payments *PaymentRegistry, interval, baseDelay time.Duration) *RefundSettler {
+	return &RefundSettler{
+		RefundRepo:  refundRepo,
+		PaymentRepo: paymentRepo,
+		LedgerRepo:  ledgerRepo,
+		Payments:    payments,
+		Interval:    interval,
+		BaseDelay:   baseDelay,
+	}
+}
+
+// Create stores the refund of a quote in the transaction of the cancellation.
+// It counts as attempted, the cancellation settles it right after commit and
+// the workers leave it alone for a lease.
+func (s *RefundSettler) Create(ctx context.Context, tx *gorm.DB, payment *domain.Payment, quote *domain.RefundQuote) (*domain.Refund, error) {
+	processor, err := s.Payments.Get(payment.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	partial := quote.Refund < payment.Amount
+	if partial && !processor.Capabilities().PartialRefund {
+		return nil, fmt.Errorf("payment provider %s cannot refund a part of an order", processor.Name())
+	}
+
+	tickets := make([]domain.TicketRefund, 0, len(quote.Tickets))
+	for _, ticket := range quote.Tickets {
+		if ticket.Refund > 0 {
+			tickets = append(tickets, ticket)
+		}
+	}
+
+	id, _ := uuid.NewV7()
+	now := time.Now()
+	refund := &domain.Refund{
+		ID:            id.String(),
+		PaymentID:     payment.ID,
+		Amount:        quote.Refund,
+		Partial:       partial,
+		Tickets:       tickets,
+		Status:        domain.RefundPending,
+		Attempts:      1,
+		NextAttemptAt: now.Add(refundLease),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := s.RefundRepo.Create(ctx, tx, refund); err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+// Run retries due refunds every interval until ctx is done.
+func (s *RefundSettler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Process(ctx); err != nil {
+			slog.Error("failed to process refunds", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Process settles the refunds that are due and returns how many went through.
+func (s *RefundSettler) Process(ctx context.Context) (int, error) {
+	settled := 0
+	for {
+		refunds, err := s.RefundRepo.Claim(ctx, refundBatch, refundLease)
+		if err != nil {
+			return settled, err
+		}
+		if len(refunds) == 0 {
+			return settled, nil
+		}
+
+		for i := range refunds {
+			if err := s.Settle(ctx, &refunds[i]); err != nil {
+				slog.Warn("refund failed, will retry", slog.String("refund_id", refunds[i].ID), slog.String("error", err.Error()))
+				continue
+			}
+			settled++
+		}
+	}
+}
+
+// Settle makes a claimed refund with the provider and records it in the
+// ledger. A failed refund is scheduled for another attempt.
+func (s *RefundSettler) Settle(ctx context.Context, refund *domain.Refund) error {
+	payment, err := s.PaymentRepo.GetByID(ctx, refund.PaymentID)
+	if err != nil {
+		return err
+	}
+
+	processor, err := s.Payments.Get(payment.Provider)
+	if err != nil {
+		return err
+	}
+
+	made := false
+	if refund.Attempts > 1 {
+		made, err = s.madeBefore(ctx, processor, payment, refund)
+		if err != nil {
+			return s.retry(ctx, refund, err)
+		}
+	}
+
+	if !made {
+		ok, err := processor.Refund(ctx, payment.TransactionID, refund.Amount)
+		if err == nil && !ok {
+			err = errors.New("the provider did not make the refund")
+		}
+		if err != nil {
+			return s.retry(ctx, refund, err)
+		}
+	}
+
+	return s.record(ctx, payment, refund)
+}
+
+// madeBefore reports whether an earlier attempt went through although its
+// answer was lost: the provider refunded more than the ledger knows about.
+func (s *RefundSettler) madeBefore(ctx context.Context, processor PaymentProcessor, payment *domain.Payment, refund *domain.Refund) (bool, error) {
+	details, err := processor.Details(ctx, payment.TransactionID)
+	if err != nil {
+		return false, err
+	}
+
+	tx := s.RefundRepo.BeginTransaction()
+	defer tx.Rollback()
+
+	recorded, err := s.LedgerRepo.Sum(ctx, tx, payment.ID, domain.LedgerRefund, domain.LedgerPartialRefund)
+	if err != nil {
+		return false, err
+	}
+
+	return details.Refunded+recorded >= refund.Amount, nil
+}
+
+// record appends a ledger entry per refunded ticket. The payment row lock
+// keeps the refund webhook from counting the refund a second time.
+func (s *RefundSettler) record(ctx context.Context, payment *domain.Payment, refund *domain.Refund) error {
+	tx := s.RefundRepo.BeginTransaction()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if _, err := s.PaymentRepo.GetForUpdate(ctx, tx, payment.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	first, err := s.RefundRepo.MarkSucceeded(ctx, tx, refund.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if !first {
+		tx.Rollback()
+		return nil
+	}
+
+	entryType := domain.LedgerRefund
+	if refund.Partial {
+		entryType = domain.LedgerPartialRefund
+	}
+	for i := range refund.Tickets {
+		entry := &domain.LedgerEntry{
+			PaymentID: payment.ID,
+			TicketID:  &refund.Tickets[i].TicketID,
+			Type:      entryType,
+			Amount:    -refund.Tickets[i].Refund,
+			Currency:  payment.Currency,
+			Reference: refund.ID,
+		}
+		if err := s.LedgerRepo.Append(ctx, tx, entry); err != nil {
+			tx.Rollback()
+			slog.Error("refund was made but could not be recorded", slog.String("refund_id", refund.ID), slog.String("error", err.Error()))
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		slog.Error("refund was made but could not be recorded", slog.String("refund_id", refund.ID), slog.String("error", err.Error()))
+		return fmt.Errorf("failed to commit refund: %w", err)
+	}
+
+	return nil
+}
+
+func (s *RefundSettler) retry(ctx context.Context, refund *domain.Refund, refundErr error) error {
+	next := time.Now().Add(s.Backoff(refund.Attempts))
+	if err := s.RefundRepo.MarkFailed(ctx, refund.ID, refundErr.Error(), next); err != nil {
+		slog.Error("failed to record refund failure", slog.String("refund_id", refund.ID), slog.String("error", err.Error()))
+	}
+
+	return fmt.Errorf("refund failed: %w", refundErr)
+}
+
+// Backoff is the wait before the retry that follows the given attempt:
+// BaseDelay doubled per attempt, at most six hours.
+func (s *RefundSettler) Backoff(attempts int) time.Duration {
+	delay := s.BaseDelay
+	for i := 1; i < attempts && delay < refundMaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, refundMaxDelay)
+}
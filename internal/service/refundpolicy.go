@@ -0,0 +1,96 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/refundpolicy/model"
+	"aulway/internal/repository/refundpolicy"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"sort"
+)
+
+// DefaultRefundPolicyID is the policy that applies to routes without an
+// override, it can be changed but not deleted.
+const DefaultRefundPolicyID = "default"
+
+type RefundPolicyService struct {
+	repo refundpolicy.Repository
+}
+
+func NewRefundPolicyService(repo refundpolicy.Repository) *RefundPolicyService {
+	return &RefundPolicyService{repo: repo}
+}
+
+func (s *RefundPolicyService) CreatePolicy(ctx context.Context, req model.RefundPolicyRequest) (*domain.RefundPolicy, error) {
+	if req.RouteID == "" {
+		return nil, errors.New("only the default policy applies to every route, set route_id")
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("generate uuid error: %w", err)
+	}
+
+	policy := policyFromRequest(req)
+	policy.ID = id.String()
+
+	if err := s.repo.Create(ctx, policy); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+func (s *RefundPolicyService) GetPolicy(ctx context.Context, id string) (*domain.RefundPolicy, error) {
+	return s.repo.Get(ctx, id)
+}
+
+func (s *RefundPolicyService) ListPolicies(ctx context.Context) ([]domain.RefundPolicy, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *RefundPolicyService) UpdatePolicy(ctx context.Context, id string, req model.RefundPolicyRequest) (*domain.RefundPolicy, error) {
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	if (id == DefaultRefundPolicyID) != (req.RouteID == "") {
+		return nil, errors.New("only the default policy has no route_id")
+	}
+
+	policy := policyFromRequest(req)
+	policy.ID = id
+
+	if err := s.repo.Update(ctx, policy); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Get(ctx, id)
+}
+
+func (s *RefundPolicyService) DeletePolicy(ctx context.Context, id string) error {
+	if id == DefaultRefundPolicyID {
+		return errors.New("the default refund policy cannot be deleted")
+	}
+
+	return s.repo.Delete(ctx, id)
+}
+
+func policyFromRequest(req model.RefundPolicyRequest) *domain.RefundPolicy {
+	tiers := append([]domain.RefundTier(nil), req.Tiers...)
+	sort.Slice(tiers, func(i, j int) bool {
+		return tiers[i].MinHoursBefore > tiers[j].MinHoursBefore
+	})
+
+	policy := &domain.RefundPolicy{
+		Name:          req.Name,
+		NonRefundable: req.NonRefundable,
+		Tiers:         tiers,
+	}
+	if req.RouteID != "" {
+		policy.RouteID = &req.RouteID
+	}
+
+	return policy
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+const reminderBatch = 200
+
+// DefaultReminderLeads are used when no lead times are configured.
+var DefaultReminderLeads = []time.Duration{24 * time.Hour, 2 * time.Hour}
+
+// ReminderJob emails passengers ahead of their departure, once per lead
+// time. When several reminders are due at once, after downtime or for a trip
+// moved closer, only the one with the shortest lead is sent.
+type ReminderJob struct {
+	Notifier *TripNotifier
+	Leads    []time.Duration
+	Interval time.Duration
+}
+
+func NewReminderJob(notifier *TripNotifier, leads []time.Duration, interval time.Duration) *ReminderJob {
+	if len(leads) == 0 {
+		leads = DefaultReminderLeads
+	}
+	leads = slices.Clone(leads)
+	slices.Sort(leads)
+
+	return &ReminderJob{
+		Notifier: notifier,
+		Leads:    leads,
+		Interval: interval,
+	}
+}
+
+// Run sends due reminders every interval until ctx is done.
+func (j *ReminderJob) Run(ctx context.Context) error {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.SendReminders(ctx, time.Now()); err != nil {
+			slog.Error("failed to send departure reminders", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendReminders queues the reminders due at now and returns how many. A
+// lead's reminders are due for trips departing within it but not within the
+// next shorter lead, whose reminder replaces them.
+func (j *ReminderJob) SendReminders(ctx context.Context, now time.Time) (int, error) {
+	sent := 0
+	shorter := time.Duration(0)
+	for _, lead := range j.Leads {
+		recipients, err := j.Notifier.Repo.DueReminders(ctx, lead, now.Add(shorter), now.Add(lead), reminderBatch)
+		if err != nil {
+			return sent, err
+		}
+
+		for _, recipient := range recipients {
+			reminded, err := j.Notifier.Remind(ctx, recipient, lead)
+			if err != nil {
+				slog.Error("failed to send departure reminder", slog.String("order_id", recipient.OrderID), slog.String("error", err.Error()))
+				continue
+			}
+			if reminded {
+				sent++
+			}
+		}
+		shorter = lead
+	}
+
+	if sent > 0 {
+		slog.Info("departure reminders queued", slog.Int("count", sent))
+	}
+
+	return sent, nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	routeModel "aulway/internal/handler/route/model"
+	"aulway/internal/handler/ticket/model"
+	busRepository "aulway/internal/repository/bus"
+	emailTemplateRepository "aulway/internal/repository/emailtemplate"
+	notificationRepository "aulway/internal/repository/notification"
+	outboxRepository "aulway/internal/repository/outbox"
+	routeRepository "aulway/internal/repository/route"
+	settingsRepository "aulway/internal/repository/settings"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteChangeDataFlagsWhatChanged(t *testing.T) {
+	before := domain.Route{StartDate: time.Now(), EndDate: time.Now().Add(time.Hour), BusId: "bus-1", DepartureLocation: "Sairan", DestinationLocation: "Bus station"}
+
+	tests := []struct {
+		name                          string
+		change                        func(r *domain.Route)
+		wantTime, wantBus, wantPickup bool
+	}{
+		{name: "price only", change: func(r *domain.Route) { r.Price = 9000 }},
+		{name: "departure time", change: func(r *domain.Route) { r.StartDate = r.StartDate.Add(30 * time.Minute) }, wantTime: true},
+		{name: "arrival time", change: func(r *domain.Route) { r.EndDate = r.EndDate.Add(30 * time.Minute) }, wantTime: true},
+		{name: "bus", change: func(r *domain.Route) { r.BusId = "bus-2" }, wantBus: true},
+		{name: "pickup point", change: func(r *domain.Route) { r.DepartureLocation = "Sayakhat" }, wantPickup: true},
+		{name: "drop-off point", change: func(r *domain.Route) { r.DestinationLocation = "Airport" }, wantPickup: true},
+		{name: "time and pickup point", change: func(r *domain.Route) {
+			r.StartDate = r.StartDate.Add(time.Hour)
+			r.DepartureLocation = "Sayakhat"
+		}, wantTime: true, wantPickup: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after := before
+			tt.change(&after)
+
+			data := routeChangeData(&before, &after)
+			require.Equal(t, tt.wantTime, data.TimeChanged)
+			require.Equal(t, tt.wantBus, data.BusChanged)
+			require.Equal(t, tt.wantPickup, data.PickupChanged)
+			require.Equal(t, after.DestinationLocation, data.Route.DestinationLocation)
+			require.Equal(t, before.DestinationLocation, data.Previous.DestinationLocation)
+		})
+	}
+}
+
+func TestRemindersAndRouteChangesAreEmailed(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 1)
+	ctx := context.Background()
+
+	purchase, err := f.service.BuyTickets(ctx, f.userIDs[0], f.routeID, "pm_card_visa", model.BuyTicketRequest{Quantity: 2, UserEmail: "buyer@example.com"})
+	require.NoError(t, err)
+
+	routeRepo := routeRepository.New(f.db)
+	trips := NewTripNotifier(notificationRepository.New(f.db), outboxRepository.New(f.db), routeRepo, busRepository.New(f.db),
+		settingsRepository.New(f.db), NewEmailTemplateService(emailTemplateRepository.New(f.db)), nil)
+	job := NewReminderJob(trips, nil, time.Minute)
+
+	messages := func(kind string) []domain.OutboxMessage {
+		var found []domain.OutboxMessage
+		require.NoError(t, f.db.Where("order_id = ? AND kind = ?", purchase.OrderID, kind).Order("created_at").Find(&found).Error)
+		return found
+	}
+
+	route, err := routeRepo.Get(ctx, f.routeID)
+	require.NoError(t, err)
+	var buyer domain.User
+	require.NoError(t, f.db.First(&buyer, "id = ?", f.userIDs[0]).Error)
+
+	// a day ahead only the 24h reminder is due, and only once
+	_, err = job.SendReminders(ctx, route.StartDate.Add(-23*time.Hour))
+	require.NoError(t, err)
+	_, err = job.SendReminders(ctx, route.StartDate.Add(-22*time.Hour))
+	require.NoError(t, err)
+
+	reminders := messages(domain.OutboxReminder)
+	require.Len(t, reminders, 1)
+	require.Equal(t, buyer.Email, reminders[0].Recipient)
+	require.Contains(t, reminders[0].Body, purchase.Route.Departure)
+
+	// the route is moved by an hour, its passengers are told
+	routes := NewRouteService(routeRepo, noSeatHolds{}, noPassUpdates{}, trips)
+	moved := route.StartDate.Add(time.Hour)
+	require.NoError(t, routes.Update(ctx, routeModel.UpdateRouteRequest{
+		StartDate: moved,
+		EndDate:   route.EndDate.Add(time.Hour),
+		Price:     route.Price,
+	}, route.Id))
+
+	changes := messages(domain.OutboxRouteChange)
+	require.Len(t, changes, 1)
+	require.Equal(t, buyer.Email, changes[0].Recipient)
+
+	// a price change alone is not worth an email
+	require.NoError(t, routes.Update(ctx, routeModel.UpdateRouteRequest{Price: route.Price + 500}, route.Id))
+	require.Len(t, messages(domain.OutboxRouteChange), 1)
+
+	// the reminders follow the new departure time
+	_, err = job.SendReminders(ctx, moved.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, messages(domain.OutboxReminder), 2)
+}
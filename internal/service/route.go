@@ -13,12 +13,18 @@ import (
 )
 
 type Route struct {
-	repo route.Repository
+	repo   route.Repository
+	holds  SeatHoldStore
+	passes PassUpdates
+	trips  *TripNotifier
 }
 
-func NewRouteService(routeRepo route.Repository) *Route {
+func NewRouteService(routeRepo route.Repository, holds SeatHoldStore, passes PassUpdates, trips *TripNotifier) *Route {
 	return &Route{
-		repo: routeRepo,
+		repo:   routeRepo,
+		holds:  holds,
+		passes: passes,
+		trips:  trips,
 	}
 }
 
@@ -46,15 +52,27 @@ func (service *Route) CreateRoute(ctx context.Context, request model.CreateRoute
 }
 
 func (service *Route) GetRoute(ctx context.Context, id string) (*domain.Route, error) {
-	return service.repo.Get(ctx, id)
+	route, err := service.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	held, err := service.holds.CountHeld(ctx, route.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	route.AvailableSeats -= held[route.Id]
+	return route, nil
 }
 
 func (service *Route) Delete(ctx context.Context, id string) error {
 	return service.repo.Delete(ctx, id)
 }
 
-func (service *Route) Update(ctx context.Context, req model.UpdateRouteRequest, id string) error {
-
+// routeUpdates lists the columns an update request sets, fields left empty
+// keep their value.
+func routeUpdates(req model.UpdateRouteRequest) map[string]interface{} {
 	updates := make(map[string]interface{})
 
 	if req.Departure != "" {
@@ -66,7 +84,7 @@ func (service *Route) Update(ctx context.Context, req model.UpdateRouteRequest,
 	if req.DepartureLocation != "" {
 		updates["departure_location"] = CapitalizeFirst(req.DepartureLocation)
 	}
-	if req.Destination != "" {
+	if req.DestinationLocation != "" {
 		updates["destination_location"] = CapitalizeFirst(req.DestinationLocation)
 	}
 	if !req.StartDate.IsZero() {
@@ -82,17 +100,57 @@ func (service *Route) Update(ctx context.Context, req model.UpdateRouteRequest,
 		updates["price"] = req.Price
 	}
 
+	return updates
+}
+
+func (service *Route) Update(ctx context.Context, req model.UpdateRouteRequest, id string) error {
+	updates := routeUpdates(req)
 	if len(updates) == 0 {
 		return nil
 	}
 
-	return service.repo.Update(ctx, updates, id)
+	before, err := service.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := service.repo.Update(ctx, updates, id); err != nil {
+		return err
+	}
+
+	// tickets added to a wallet show the times, places and bus of the route
+	delete(updates, "price")
+	if len(updates) == 0 {
+		return nil
+	}
+	service.passes.RouteChanged(ctx, id)
+
+	after, err := service.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	service.trips.RouteChanged(ctx, before, after)
+
+	return nil
 }
 
 func (service *Route) GetRoutesListt(ctx context.Context, userId, departure, destination string, date time.Time, passengers, page, pageSize int) ([]domain.Route, int, error) {
 	departure = CapitalizeFirst(departure)
 	destination = CapitalizeFirst(destination)
-	return service.repo.GetRoutesList(ctx, userId, departure, destination, date, passengers, page, pageSize)
+
+	// seats held during checkout are not bookable by other passengers, the
+	// query leaves them out so the pages and the total agree
+	routeIDs, err := service.repo.SearchRouteIDs(ctx, departure, destination, date)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	held, err := service.holds.CountHeld(ctx, routeIDs...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return service.repo.GetRoutesList(ctx, userId, departure, destination, date, held, passengers, page, pageSize)
 }
 
 func (service *Route) GetAllRoutesList(ctx context.Context, page, pageSize int) ([]domain.Route, error) {
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/route/model"
+	routeRepository "aulway/internal/repository/route"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteUpdates(t *testing.T) {
+	tests := []struct {
+		name string
+		req  model.UpdateRouteRequest
+		want map[string]interface{}
+	}{
+		{name: "drop-off point only", req: model.UpdateRouteRequest{DestinationLocation: "sayran bus station", Price: -1},
+			want: map[string]interface{}{"destination_location": "Sayran Bus Station"}},
+		{name: "destination city keeps the drop-off point", req: model.UpdateRouteRequest{Destination: "astana", Price: -1},
+			want: map[string]interface{}{"destination": "Astana"}},
+		{name: "pickup point only", req: model.UpdateRouteRequest{DepartureLocation: "sairan", Price: -1},
+			want: map[string]interface{}{"departure_location": "Sairan"}},
+		{name: "free route", req: model.UpdateRouteRequest{BusId: "bus-1"},
+			want: map[string]interface{}{"bus_id": "bus-1", "price": 0}},
+		{name: "nothing", req: model.UpdateRouteRequest{Price: -1}, want: map[string]interface{}{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, routeUpdates(tt.req))
+		})
+	}
+}
+
+func TestSearchLeavesOutHeldSeats(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 1)
+	ctx := context.Background()
+
+	// a city of its own keeps other routes out of the totals
+	city := CapitalizeFirst("test " + uuid.NewString()[:8])
+	require.NoError(t, f.db.Table("routes").Where("id = ?", f.routeID).Update("departure", city).Error)
+	var route domain.Route
+	require.NoError(t, f.db.First(&route, "id = ?", f.routeID).Error)
+
+	holds, _ := testSeatHolds(t)
+	require.NoError(t, holds.Acquire(ctx, &domain.SeatHold{ID: "hold-1", RouteID: f.routeID, Seats: []string{"1A", "1B", "1C"}}, time.Minute))
+	routes := NewRouteService(routeRepository.New(f.db), holds, noPassUpdates{}, nil)
+
+	found, total, err := routes.GetRoutesListt(ctx, f.userIDs[0], city, route.Destination, route.StartDate, 1, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Len(t, found, 1)
+	require.Equal(t, 1, found[0].AvailableSeats)
+
+	found, total, err = routes.GetRoutesListt(ctx, f.userIDs[0], city, route.Destination, route.StartDate, 2, 1, 10)
+	require.NoError(t, err)
+	require.Zero(t, total, "the total counts only the routes the passengers fit on")
+	require.Empty(t, found)
+}
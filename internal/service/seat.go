@@ -0,0 +1,297 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/ticket/model"
+	busRepo "aulway/internal/repository/bus"
+	"aulway/internal/utils/errs"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	defaultSeatClass   = "standard"
+	defaultSeatColumns = 4
+)
+
+// generateSeats lays seats out row by row and labels them "1A", "1B", ...
+// When total is positive the layout is cut after that many seats.
+func generateSeats(rows, columns, total int) []domain.Seat {
+	if total <= 0 || total > rows*columns {
+		total = rows * columns
+	}
+
+	seats := make([]domain.Seat, 0, total)
+	for row := 1; row <= rows && len(seats) < total; row++ {
+		for column := 1; column <= columns && len(seats) < total; column++ {
+			seats = append(seats, domain.Seat{
+				Number: fmt.Sprintf("%d%c", row, 'A'+column-1),
+				Row:    row,
+				Column: column,
+				Class:  defaultSeatClass,
+			})
+		}
+	}
+
+	return seats
+}
+
+// seatLayout returns the stored layout of the bus. Buses created before seat
+// layouts existed get a generated one based on their total seat count.
+func seatLayout(ctx context.Context, repo busRepo.Repository, bus *domain.Bus) ([]domain.Seat, error) {
+	seats, err := repo.GetSeats(ctx, bus.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(seats) > 0 {
+		return seats, nil
+	}
+
+	rows := (bus.TotalSeats + defaultSeatColumns - 1) / defaultSeatColumns
+	seats = generateSeats(rows, defaultSeatColumns, bus.TotalSeats)
+	for i := range seats {
+		seats[i].BusId = bus.Id
+	}
+
+	return seats, nil
+}
+
+func layoutSize(seats []domain.Seat) (rows, columns int) {
+	for _, seat := range seats {
+		rows = max(rows, seat.Row)
+		columns = max(columns, seat.Column)
+	}
+	return rows, columns
+}
+
+// unavailableSeats returns the seats that are sold or held by anyone but the
+// given hold, together with the number of seats held by others.
+func (s *TicketService) unavailableSeats(ctx context.Context, routeID, ownHoldID string) (map[string]bool, int, error) {
+	sold, err := s.TicketRepo.GetTakenSeats(ctx, routeID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	held, err := s.Holds.HeldSeats(ctx, routeID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	unavailable := make(map[string]bool, len(sold)+len(held))
+	for _, seat := range sold {
+		unavailable[seat] = true
+	}
+
+	heldByOthers := 0
+	for seat, holdID := range held {
+		if holdID != ownHoldID && !unavailable[seat] {
+			unavailable[seat] = true
+			heldByOthers++
+		}
+	}
+
+	return unavailable, heldByOthers, nil
+}
+
+// pickSeats checks the requested seats against the bus layout, or picks the
+// first free ones when only a quantity was given.
+func (s *TicketService) pickSeats(ctx context.Context, route *domain.Route, bus *domain.Bus, requested []string, quantity int, ownHoldID string) ([]string, error) {
+	layout, err := seatLayout(ctx, s.BusRepo, bus)
+	if err != nil {
+		return nil, err
+	}
+
+	unavailable, heldByOthers, err := s.unavailableSeats(ctx, route.Id, ownHoldID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(requested) > 0 {
+		quantity = len(requested)
+	}
+	if route.AvailableSeats-heldByOthers < quantity {
+		return nil, errs.ErrNoSeatsAvailable
+	}
+
+	if len(requested) == 0 {
+		seats := make([]string, 0, quantity)
+		for _, seat := range layout {
+			if len(seats) == quantity {
+				break
+			}
+			if !unavailable[seat.Number] {
+				seats = append(seats, seat.Number)
+			}
+		}
+		if len(seats) < quantity {
+			return nil, errs.ErrNoSeatsAvailable
+		}
+		return seats, nil
+	}
+
+	known := make(map[string]bool, len(layout))
+	for _, seat := range layout {
+		known[seat.Number] = true
+	}
+
+	picked := make(map[string]bool, len(requested))
+	for _, seat := range requested {
+		if !known[seat] {
+			return nil, fmt.Errorf("seat %s: %w", seat, errs.ErrUnknownSeat)
+		}
+		if unavailable[seat] || picked[seat] {
+			return nil, fmt.Errorf("seat %s: %w", seat, errs.ErrSeatTaken)
+		}
+		picked[seat] = true
+	}
+
+	return requested, nil
+}
+
+func (s *TicketService) GetSeatMap(ctx context.Context, routeID string) (*domain.SeatMap, error) {
+	route, err := s.RouteRepo.Get(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	bus, err := s.BusRepo.Get(ctx, route.BusId)
+	if err != nil {
+		return nil, err
+	}
+
+	layout, err := seatLayout(ctx, s.BusRepo, bus)
+	if err != nil {
+		return nil, err
+	}
+
+	sold, err := s.TicketRepo.GetTakenSeats(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	held, err := s.Holds.HeldSeats(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	taken := make(map[string]bool, len(sold))
+	for _, seat := range sold {
+		taken[seat] = true
+	}
+
+	seatMap := &domain.SeatMap{
+		RouteID:        route.Id,
+		BusID:          bus.Id,
+		AvailableSeats: route.AvailableSeats,
+		Seats:          make([]domain.SeatState, 0, len(layout)),
+	}
+	seatMap.Rows, seatMap.Columns = layoutSize(layout)
+
+	for _, seat := range layout {
+		_, isHeld := held[seat.Number]
+		isHeld = isHeld && !taken[seat.Number]
+		if isHeld {
+			seatMap.AvailableSeats--
+		}
+
+		seatMap.Seats = append(seatMap.Seats, domain.SeatState{
+			Seat:  seat,
+			Taken: taken[seat.Number] || isHeld,
+			Held:  isHeld,
+		})
+	}
+
+	return seatMap, nil
+}
+
+// HoldSeats reserves seats of a route for the user until the purchase is made
+// or the hold expires.
+func (s *TicketService) HoldSeats(ctx context.Context, userID, routeID string, req model.HoldSeatsRequest) (*domain.SeatHold, error) {
+	if len(req.Seats) == 0 && req.Quantity <= 0 {
+		return nil, errors.New("quantity must be positive")
+	}
+
+	tx := s.TicketRepo.BeginTransaction()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// the route lock of reserve, a purchase of the route either sold its seats
+	// before the hold picks them or sees the hold once it is taken
+	route, err := s.RouteRepo.GetForUpdate(ctx, tx, routeID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if !route.StartDate.After(time.Now()) {
+		tx.Rollback()
+		return nil, errors.New("route has already departed")
+	}
+
+	bus, err := s.BusRepo.Get(ctx, route.BusId)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	seats, err := s.pickSeats(ctx, route, bus, req.Seats, req.Quantity, "")
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	holdId, err := uuid.NewV7()
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("generate uuid error: %w", err)
+	}
+
+	hold := &domain.SeatHold{
+		ID:      holdId.String(),
+		RouteID: routeID,
+		UserID:  userID,
+		Seats:   seats,
+	}
+
+	if err := s.Holds.Acquire(ctx, hold, s.HoldTTL); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		s.Holds.Release(ctx, hold)
+		return nil, fmt.Errorf("failed to commit seat hold: %w", err)
+	}
+
+	return hold, nil
+}
+
+func (s *TicketService) ReleaseHold(ctx context.Context, userID, holdID string) error {
+	hold, err := s.ownHold(ctx, userID, holdID)
+	if err != nil {
+		return err
+	}
+
+	return s.Holds.Release(ctx, hold)
+}
+
+func (s *TicketService) ownHold(ctx context.Context, userID, holdID string) (*domain.SeatHold, error) {
+	hold, err := s.Holds.Get(ctx, holdID)
+	if err != nil {
+		return nil, err
+	}
+
+	if hold.UserID != userID {
+		return nil, errs.ErrHoldNotFound
+	}
+
+	return hold, nil
+}
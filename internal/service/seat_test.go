@@ -0,0 +1,47 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSeats(t *testing.T) {
+	tests := []struct {
+		name                 string
+		rows, columns, total int
+		want                 []string
+	}{
+		{name: "full layout", rows: 2, columns: 3, want: []string{"1A", "1B", "1C", "2A", "2B", "2C"}},
+		{name: "cut after total", rows: 3, columns: 4, total: 5, want: []string{"1A", "1B", "1C", "1D", "2A"}},
+		{name: "total larger than the layout", rows: 1, columns: 2, total: 10, want: []string{"1A", "1B"}},
+		{name: "negative total", rows: 1, columns: 2, total: -1, want: []string{"1A", "1B"}},
+		{name: "no rows", rows: 0, columns: 4, total: 3, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seats := generateSeats(tt.rows, tt.columns, tt.total)
+
+			numbers := make([]string, 0, len(seats))
+			for _, seat := range seats {
+				numbers = append(numbers, seat.Number)
+				require.Equal(t, defaultSeatClass, seat.Class)
+			}
+			require.Equal(t, tt.want, numbers)
+		})
+	}
+}
+
+func TestGenerateSeatsPositions(t *testing.T) {
+	seats := generateSeats(10, 4, 38)
+
+	require.Len(t, seats, 38)
+	require.Equal(t, domain.Seat{Number: "3C", Row: 3, Column: 3, Class: defaultSeatClass}, seats[10])
+	require.Equal(t, "10B", seats[37].Number)
+
+	rows, columns := layoutSize(seats)
+	require.Equal(t, 10, rows)
+	require.Equal(t, 4, columns)
+}
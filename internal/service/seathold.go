@@ -0,0 +1,195 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/utils/errs"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// SeatHoldStore keeps seats reserved for a buyer while they go through checkout.
+// Holds expire on their own, which frees the seats without any cleanup job.
+type SeatHoldStore interface {
+	Acquire(ctx context.Context, hold *domain.SeatHold, ttl time.Duration) error
+	Get(ctx context.Context, holdID string) (*domain.SeatHold, error)
+	Release(ctx context.Context, hold *domain.SeatHold) error
+	HeldSeats(ctx context.Context, routeID string) (map[string]string, error)
+	CountHeld(ctx context.Context, routeIDs ...string) (map[string]int, error)
+}
+
+// acquireHoldScript sets every seat key only if none of them is held yet.
+// KEYS: hold, route holds, seats...; ARGV: hold id, hold json, ttl ms, expiry ms, seat numbers...
+var acquireHoldScript = redis.NewScript(`
+for i = 3, #KEYS do
+	if redis.call('EXISTS', KEYS[i]) == 1 then
+		return 0
+	end
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+for i = 3, #KEYS do
+	redis.call('SET', KEYS[i], ARGV[1], 'PX', ARGV[3])
+	redis.call('ZADD', KEYS[2], ARGV[4], ARGV[i + 2])
+end
+redis.call('PEXPIRE', KEYS[2], ARGV[3])
+return 1
+`)
+
+// releaseHoldScript drops the seats that still belong to the hold.
+// KEYS: hold, route holds, seats...; ARGV: hold id, seat numbers...
+var releaseHoldScript = redis.NewScript(`
+for i = 3, #KEYS do
+	if redis.call('GET', KEYS[i]) == ARGV[1] then
+		redis.call('DEL', KEYS[i])
+		redis.call('ZREM', KEYS[2], ARGV[i - 1])
+	end
+end
+redis.call('DEL', KEYS[1])
+return 1
+`)
+
+type redisSeatHolds struct {
+	redis *redis.Client
+}
+
+func NewSeatHoldStore(redis *redis.Client) SeatHoldStore {
+	return &redisSeatHolds{redis: redis}
+}
+
+func holdKey(holdID string) string {
+	return "seat_hold:" + holdID
+}
+
+func routeHoldsKey(routeID string) string {
+	return "route_holds:" + routeID
+}
+
+func heldSeatKey(routeID, seat string) string {
+	return "held_seat:" + routeID + ":" + seat
+}
+
+func (h *redisSeatHolds) holdKeys(hold *domain.SeatHold) []string {
+	keys := []string{holdKey(hold.ID), routeHoldsKey(hold.RouteID)}
+	for _, seat := range hold.Seats {
+		keys = append(keys, heldSeatKey(hold.RouteID, seat))
+	}
+	return keys
+}
+
+func (h *redisSeatHolds) Acquire(ctx context.Context, hold *domain.SeatHold, ttl time.Duration) error {
+	hold.ExpiresAt = time.Now().Add(ttl)
+
+	payload, err := json.Marshal(hold)
+	if err != nil {
+		return err
+	}
+
+	args := []interface{}{hold.ID, payload, ttl.Milliseconds(), hold.ExpiresAt.UnixMilli()}
+	for _, seat := range hold.Seats {
+		args = append(args, seat)
+	}
+
+	acquired, err := acquireHoldScript.Run(ctx, h.redis, h.holdKeys(hold), args...).Int()
+	if err != nil {
+		return fmt.Errorf("acquire seat hold error: %w", err)
+	}
+
+	if acquired == 0 {
+		return errs.ErrSeatTaken
+	}
+
+	return nil
+}
+
+func (h *redisSeatHolds) Get(ctx context.Context, holdID string) (*domain.SeatHold, error) {
+	payload, err := h.redis.Get(ctx, holdKey(holdID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, errs.ErrHoldNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get seat hold error: %w", err)
+	}
+
+	hold := new(domain.SeatHold)
+	if err := json.Unmarshal(payload, hold); err != nil {
+		return nil, fmt.Errorf("decode seat hold error: %w", err)
+	}
+
+	return hold, nil
+}
+
+func (h *redisSeatHolds) Release(ctx context.Context, hold *domain.SeatHold) error {
+	args := []interface{}{hold.ID}
+	for _, seat := range hold.Seats {
+		args = append(args, seat)
+	}
+
+	if err := releaseHoldScript.Run(ctx, h.redis, h.holdKeys(hold), args...).Err(); err != nil {
+		return fmt.Errorf("release seat hold error: %w", err)
+	}
+
+	return nil
+}
+
+// HeldSeats maps every currently held seat of the route to the hold owning it.
+func (h *redisSeatHolds) HeldSeats(ctx context.Context, routeID string) (map[string]string, error) {
+	seats, err := h.redis.ZRangeByScore(ctx, routeHoldsKey(routeID), &redis.ZRangeBy{
+		Min: strconv.FormatInt(time.Now().UnixMilli(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("get held seats error: %w", err)
+	}
+
+	held := make(map[string]string, len(seats))
+	if len(seats) == 0 {
+		return held, nil
+	}
+
+	keys := make([]string, 0, len(seats))
+	for _, seat := range seats {
+		keys = append(keys, heldSeatKey(routeID, seat))
+	}
+
+	holdIDs, err := h.redis.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("get held seats error: %w", err)
+	}
+
+	for i, holdID := range holdIDs {
+		if id, ok := holdID.(string); ok {
+			held[seats[i]] = id
+		}
+	}
+
+	return held, nil
+}
+
+func (h *redisSeatHolds) CountHeld(ctx context.Context, routeIDs ...string) (map[string]int, error) {
+	counts := make(map[string]int, len(routeIDs))
+	if len(routeIDs) == 0 {
+		return counts, nil
+	}
+
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	pipe := h.redis.Pipeline()
+	cmds := make([]*redis.IntCmd, 0, len(routeIDs))
+	for _, routeID := range routeIDs {
+		cmds = append(cmds, pipe.ZCount(ctx, routeHoldsKey(routeID), now, "+inf"))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("count held seats error: %w", err)
+	}
+
+	for i, routeID := range routeIDs {
+		counts[routeID] = int(cmds[i].Val())
+	}
+
+	return counts, nil
+}
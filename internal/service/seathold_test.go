@@ -0,0 +1,125 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/utils/errs"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func testSeatHolds(t *testing.T) (SeatHoldStore, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewSeatHoldStore(client), mr
+}
+
+func TestSeatHoldRefusesHeldSeats(t *testing.T) {
+	holds, _ := testSeatHolds(t)
+	ctx := context.Background()
+
+	first := &domain.SeatHold{ID: "hold-1", RouteID: "route-1", UserID: "user-1", Seats: []string{"1A", "1B"}}
+	require.NoError(t, holds.Acquire(ctx, first, time.Minute))
+
+	// one taken seat is enough to refuse the whole hold
+	overlap := &domain.SeatHold{ID: "hold-2", RouteID: "route-1", UserID: "user-2", Seats: []string{"2A", "1B"}}
+	require.ErrorIs(t, holds.Acquire(ctx, overlap, time.Minute), errs.ErrSeatTaken)
+
+	held, err := holds.HeldSeats(ctx, "route-1")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"1A": "hold-1", "1B": "hold-1"}, held, "a refused hold takes no seat")
+
+	_, err = holds.Get(ctx, "hold-2")
+	require.ErrorIs(t, err, errs.ErrHoldNotFound)
+
+	// the same seat on another route is free
+	other := &domain.SeatHold{ID: "hold-3", RouteID: "route-2", UserID: "user-2", Seats: []string{"1B"}}
+	require.NoError(t, holds.Acquire(ctx, other, time.Minute))
+
+	got, err := holds.Get(ctx, "hold-1")
+	require.NoError(t, err)
+	require.Equal(t, first.Seats, got.Seats)
+	require.Equal(t, "user-1", got.UserID)
+}
+
+func TestSeatHoldReleaseKeepsSeatsOfOtherHolds(t *testing.T) {
+	holds, _ := testSeatHolds(t)
+	ctx := context.Background()
+
+	owner := &domain.SeatHold{ID: "hold-1", RouteID: "route-1", Seats: []string{"1A", "1B"}}
+	require.NoError(t, holds.Acquire(ctx, owner, time.Minute))
+
+	// a hold naming seats it does not own frees none of them
+	stranger := &domain.SeatHold{ID: "hold-2", RouteID: "route-1", Seats: []string{"1A"}}
+	require.NoError(t, holds.Release(ctx, stranger))
+
+	held, err := holds.HeldSeats(ctx, "route-1")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"1A": "hold-1", "1B": "hold-1"}, held)
+	require.ErrorIs(t, holds.Acquire(ctx, &domain.SeatHold{ID: "hold-3", RouteID: "route-1", Seats: []string{"1A"}}, time.Minute), errs.ErrSeatTaken)
+
+	require.NoError(t, holds.Release(ctx, owner))
+
+	held, err = holds.HeldSeats(ctx, "route-1")
+	require.NoError(t, err)
+	require.Empty(t, held)
+	_, err = holds.Get(ctx, "hold-1")
+	require.ErrorIs(t, err, errs.ErrHoldNotFound)
+	require.NoError(t, holds.Acquire(ctx, &domain.SeatHold{ID: "hold-3", RouteID: "route-1", Seats: []string{"1A"}}, time.Minute))
+}
+
+func TestSeatHoldExpires(t *testing.T) {
+	holds, mr := testSeatHolds(t)
+	ctx := context.Background()
+
+	short := &domain.SeatHold{ID: "hold-1", RouteID: "route-1", Seats: []string{"1A"}}
+	require.NoError(t, holds.Acquire(ctx, short, 50*time.Millisecond))
+	long := &domain.SeatHold{ID: "hold-2", RouteID: "route-1", Seats: []string{"2A", "2B"}}
+	require.NoError(t, holds.Acquire(ctx, long, time.Hour))
+
+	// the hold lapses in redis and its seats drop out of the route once due
+	time.Sleep(60 * time.Millisecond)
+	mr.FastForward(60 * time.Millisecond)
+
+	_, err := holds.Get(ctx, "hold-1")
+	require.ErrorIs(t, err, errs.ErrHoldNotFound)
+
+	held, err := holds.HeldSeats(ctx, "route-1")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"2A": "hold-2", "2B": "hold-2"}, held)
+
+	counts, err := holds.CountHeld(ctx, "route-1")
+	require.NoError(t, err)
+	require.Equal(t, 2, counts["route-1"])
+
+	require.NoError(t, holds.Acquire(ctx, &domain.SeatHold{ID: "hold-3", RouteID: "route-1", Seats: []string{"1A"}}, time.Minute))
+
+	// once every hold is gone so is the route's list
+	mr.FastForward(2 * time.Hour)
+	require.False(t, mr.Exists(routeHoldsKey("route-1")))
+}
+
+func TestSeatHoldCountHeld(t *testing.T) {
+	holds, _ := testSeatHolds(t)
+	ctx := context.Background()
+
+	require.NoError(t, holds.Acquire(ctx, &domain.SeatHold{ID: "hold-1", RouteID: "route-1", Seats: []string{"1A", "1B"}}, time.Minute))
+	require.NoError(t, holds.Acquire(ctx, &domain.SeatHold{ID: "hold-2", RouteID: "route-1", Seats: []string{"3C"}}, time.Minute))
+	require.NoError(t, holds.Acquire(ctx, &domain.SeatHold{ID: "hold-3", RouteID: "route-2", Seats: []string{"1A"}}, time.Minute))
+
+	counts, err := holds.CountHeld(ctx, "route-1", "route-2", "route-3")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"route-1": 3, "route-2": 1, "route-3": 0}, counts)
+
+	counts, err = holds.CountHeld(ctx)
+	require.NoError(t, err)
+	require.Empty(t, counts)
+}
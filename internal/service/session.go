@@ -0,0 +1,288 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/auth/model"
+	repoErrs "aulway/internal/repository/errs"
+	sessionRepo "aulway/internal/repository/session"
+	"aulway/internal/repository/user"
+	"aulway/internal/utils/errs"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// maxUserAgent is the longest user agent kept with a session.
+const maxUserAgent = 512
+
+type Claims struct {
+	UserID    string `json:"user_id"`
+	Role      string `json:"user_role"`
+	SessionID string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// SessionService signs users in on their devices. It issues short-lived
+// access tokens with refresh tokens that are replaced on every refresh, and
+// ends sessions on sign-out, password changes and account deletion.
+type SessionService struct {
+	Repo       sessionRepo.Repository
+	UserRepo   user.Repository
+	Redis      *redis.Client
+	Secret     string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+func NewSessionService(repo sessionRepo.Repository, userRepo user.Repository, redis *redis.Client, secret string, accessTTL, refreshTTL time.Duration) *SessionService {
+	return &SessionService{
+		Repo:       repo,
+		UserRepo:   userRepo,
+		Redis:      redis,
+		Secret:     secret,
+		AccessTTL:  accessTTL,
+		RefreshTTL: refreshTTL,
+	}
+}
+
+// Start opens a session for the user on the device and returns its tokens.
+func (s *SessionService) Start(ctx context.Context, usr domain.User, userAgent, ip string) (*model.TokenResponse, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := newRefreshToken(id.String())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &domain.Session{
+		ID:          id.String(),
+		UserID:      usr.ID,
+		RefreshHash: hashRefreshToken(refreshToken),
+		UserAgent:   truncate(userAgent, maxUserAgent),
+		IP:          ip,
+		CreatedAt:   now,
+		LastUsedAt:  now,
+		ExpiresAt:   now.Add(s.RefreshTTL),
+	}
+	if err := s.Repo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return s.tokens(usr, session.ID, refreshToken)
+}
+
+// Refresh trades a refresh token for new tokens of its session. A refresh
+// token that was already traded in means it leaked, the session is ended.
+func (s *SessionService) Refresh(ctx context.Context, refreshToken, userAgent, ip string) (*model.TokenResponse, error) {
+	sessionID, _, found := strings.Cut(refreshToken, ".")
+	if !found {
+		return nil, errs.ErrInvalidRefreshToken
+	}
+
+	session, err := s.Repo.Get(ctx, sessionID)
+	if errors.Is(err, repoErrs.ErrRecordNotFound) {
+		return nil, errs.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	previousHash := hashRefreshToken(refreshToken)
+	if session.RefreshHash != previousHash {
+		slog.Warn("refresh token reused, ending session", slog.String("session_id", session.ID), slog.String("user_id", session.UserID))
+		if err := s.Revoke(ctx, session.UserID, session.ID); err != nil && !errors.Is(err, repoErrs.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, errs.ErrInvalidRefreshToken
+	}
+
+	usr, err := s.UserRepo.Get(ctx, session.UserID)
+	if errors.Is(err, repoErrs.ErrRecordNotFound) {
+		return nil, errs.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := newRefreshToken(session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session.RefreshHash = hashRefreshToken(next)
+	session.UserAgent = truncate(userAgent, maxUserAgent)
+	session.IP = ip
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(s.RefreshTTL)
+
+	rotated, err := s.Repo.Rotate(ctx, session, previousHash)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, errs.ErrInvalidRefreshToken
+	}
+
+	return s.tokens(*usr, session.ID, next)
+}
+
+// ListSessions returns the active sessions of the user, current is the
+// session of the token asking.
+func (s *SessionService) ListSessions(ctx context.Context, userID, current string) ([]domain.Session, error) {
+	sessions, err := s.Repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+
+	return sessions, nil
+}
+
+// Revoke ends a session of the user, its access tokens stop working at once.
+func (s *SessionService) Revoke(ctx context.Context, userID, sessionID string) error {
+	if err := s.Repo.Revoke(ctx, userID, sessionID); err != nil {
+		return err
+	}
+
+	return s.markRevoked(ctx, sessionID)
+}
+
+// RevokeAll ends every session of the user.
+func (s *SessionService) RevokeAll(ctx context.Context, userID string) error {
+	ids, err := s.Repo.RevokeAll(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := s.markRevoked(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Verify checks an access token issued by Start or Refresh and that its
+// session was not ended. Tokens signed otherwise are ErrUnsupportedToken.
+func (s *SessionService) Verify(ctx context.Context, token string) (*domain.Principal, error) {
+	if tokenAlg(token) != jwt.SigningMethodHS256.Alg() {
+		return nil, errs.ErrUnsupportedToken
+	}
+
+	claims := &Claims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if _, err := parser.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) { return []byte(s.Secret), nil }); err != nil {
+		return nil, errs.ErrInvalidToken
+	}
+
+	// tokens issued before sessions existed cannot be revoked
+	if claims.UserID == "" || claims.Role == "" || claims.SessionID == "" {
+		return nil, errs.ErrInvalidToken
+	}
+
+	revoked, err := s.Revoked(ctx, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errs.ErrSessionEnded
+	}
+
+	return &domain.Principal{UserID: claims.UserID, Role: claims.Role, SessionID: claims.SessionID}, nil
+}
+
+// Revoked reports whether the session was ended while its access tokens
+// may still be valid.
+func (s *SessionService) Revoked(ctx context.Context, sessionID string) (bool, error) {
+	n, err := s.Redis.Exists(ctx, revokedSessionKey(sessionID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+// markRevoked remembers the session as ended for as long as an access token
+// issued for it can live.
+func (s *SessionService) markRevoked(ctx context.Context, sessionID string) error {
+	if err := s.Redis.Set(ctx, revokedSessionKey(sessionID), 1, s.AccessTTL).Err(); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SessionService) tokens(usr domain.User, sessionID, refreshToken string) (*model.TokenResponse, error) {
+	accessToken, err := s.accessToken(usr, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(s.AccessTTL.Seconds()),
+	}, nil
+}
+
+func (s *SessionService) accessToken(usr domain.User, sessionID string) (string, error) {
+	claims := &Claims{
+		UserID:    usr.ID,
+		Role:      usr.Role,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.AccessTTL)),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.Secret))
+}
+
+// newRefreshToken is the session id and a random secret, the id finds the
+// session and the hash of the whole token is checked against it.
+func newRefreshToken(sessionID string) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	return sessionID + "." + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func revokedSessionKey(sessionID string) string {
+	return "revoked_session:" + sessionID
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	return s[:n]
+}
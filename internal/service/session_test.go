@@ -0,0 +1,91 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	repoErrs "aulway/internal/repository/errs"
+	sessionRepository "aulway/internal/repository/session"
+	userRepository "aulway/internal/repository/user"
+	"aulway/internal/utils/errs"
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessTokenNamesItsSession(t *testing.T) {
+	sessions := NewSessionService(sessionRepository.Repository{}, userRepository.Repository{}, nil, "secret", 15*time.Minute, time.Hour)
+
+	tokens, err := sessions.tokens(domain.User{ID: "user-1", Role: userRole}, "session-1", "session-1.secret")
+	require.NoError(t, err)
+	require.Equal(t, 900, tokens.ExpiresIn)
+
+	claims := &Claims{}
+	_, err = jwt.ParseWithClaims(tokens.AccessToken, claims, func(*jwt.Token) (interface{}, error) { return []byte("secret"), nil })
+	require.NoError(t, err)
+	require.Equal(t, "user-1", claims.UserID)
+	require.Equal(t, "session-1", claims.SessionID)
+	require.WithinDuration(t, time.Now().Add(15*time.Minute), claims.ExpiresAt.Time, 5*time.Second)
+
+	refresh, err := newRefreshToken("session-1")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(refresh, "session-1."))
+	require.NotEqual(t, hashRefreshToken(refresh), hashRefreshToken(refresh+"x"))
+}
+
+func TestRefreshTokensRotateAndSessionsRevoke(t *testing.T) {
+	client := testRedis(t)
+	db := testDB(t)
+	ctx := context.Background()
+	users := userRepository.NewRepository(db)
+	sessions := NewSessionService(sessionRepository.New(db), users, client, "secret", time.Minute, time.Hour)
+
+	usr := &domain.User{ID: fmt.Sprintf("session-test-%d", rand.Int()), Email: fmt.Sprintf("session-%d@example.com", rand.Int()), Role: userRole}
+	require.NoError(t, db.Create(usr).Error)
+
+	phone, err := sessions.Start(ctx, *usr, "Aulway/2.3 (Android 14)", "95.56.10.1")
+	require.NoError(t, err)
+	laptop, err := sessions.Start(ctx, *usr, "Mozilla/5.0", "95.56.10.2")
+	require.NoError(t, err)
+
+	// a refresh token works once
+	refreshed, err := sessions.Refresh(ctx, phone.RefreshToken, "Aulway/2.4 (Android 14)", "95.56.10.3")
+	require.NoError(t, err)
+	require.NotEqual(t, phone.RefreshToken, refreshed.RefreshToken)
+
+	phoneID, _, _ := strings.Cut(phone.RefreshToken, ".")
+	list, err := sessions.ListSessions(ctx, usr.ID, phoneID)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, phoneID, list[0].ID)
+	require.True(t, list[0].Current)
+	require.Equal(t, "Aulway/2.4 (Android 14)", list[0].UserAgent)
+
+	// trading it in again means it leaked, the session ends
+	_, err = sessions.Refresh(ctx, phone.RefreshToken, "", "")
+	require.ErrorIs(t, err, errs.ErrInvalidRefreshToken)
+	_, err = sessions.Refresh(ctx, refreshed.RefreshToken, "", "")
+	require.ErrorIs(t, err, errs.ErrInvalidRefreshToken)
+
+	revoked, err := sessions.Revoked(ctx, phoneID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	laptopID, _, _ := strings.Cut(laptop.RefreshToken, ".")
+	revoked, err = sessions.Revoked(ctx, laptopID)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	// deleting the account signs it out everywhere
+	require.NoError(t, NewUserService(users, sessions, nil).DeleteUser(ctx, usr.ID))
+	revoked, err = sessions.Revoked(ctx, laptopID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+	_, err = sessions.Refresh(ctx, laptop.RefreshToken, "", "")
+	require.ErrorIs(t, err, errs.ErrInvalidRefreshToken)
+	require.ErrorIs(t, sessions.Revoke(ctx, usr.ID, laptopID), repoErrs.ErrRecordNotFound)
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/settings/model"
+	repoErrs "aulway/internal/repository/errs"
+	settingsRepo "aulway/internal/repository/settings"
+	"aulway/internal/templates"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"log/slog"
+)
+
+type SettingsService struct {
+	repo settingsRepo.Repository
+}
+
+func NewSettingsService(repo settingsRepo.Repository) *SettingsService {
+	return &SettingsService{repo: repo}
+}
+
+// GetSettings returns the settings of a user, the defaults when they never
+// changed them.
+func (s *SettingsService) GetSettings(ctx context.Context, userID string) (*domain.Settings, error) {
+	settings, err := s.repo.Get(ctx, userID)
+	if errors.Is(err, repoErrs.ErrRecordNotFound) {
+		return &domain.Settings{UserID: userID, Language: templates.Default}, nil
+	}
+
+	return settings, err
+}
+
+func (s *SettingsService) UpdateSettings(ctx context.Context, userID string, req model.UpdateSettingsRequest) (*domain.Settings, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetLanguage(ctx, &domain.Settings{ID: uuid.NewString(), UserID: userID, Language: req.Language}); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Get(ctx, userID)
+}
+
+// userLanguage is the locale the emails of a user are rendered in. A failed
+// lookup should not hold back the email, it is sent in the default language.
+func userLanguage(ctx context.Context, repo settingsRepo.Repository, userID string) string {
+	settings, err := repo.Get(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, repoErrs.ErrRecordNotFound) {
+			slog.Error("failed to get user language", slog.String("user_id", userID), slog.String("error", err.Error()))
+		}
+		return templates.Default
+	}
+
+	return templates.Locale(settings.Language)
+}
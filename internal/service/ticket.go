@@ -2,168 +2,569 @@ package service
 
 import (
 	"aulway/internal/domain"
+	"aulway/internal/handler/ticket/model"
 	busRepo "aulway/internal/repository/bus"
+	repoErrs "aulway/internal/repository/errs"
+	ledgerRepo "aulway/internal/repository/ledger"
+	orderRepo "aulway/internal/repository/order"
+	outboxRepo "aulway/internal/repository/outbox"
 	paymentRepo "aulway/internal/repository/payment"
+	refundPolicyRepo "aulway/internal/repository/refundpolicy"
 	routeRepo "aulway/internal/repository/route"
+	settingsRepo "aulway/internal/repository/settings"
 	ticketRepo "aulway/internal/repository/ticket"
+	"aulway/internal/templates"
 	"aulway/internal/utils/errs"
-	"bytes"
 	"context"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/skip2/go-qrcode"
-	"image/jpeg"
-	"math/rand"
+	"log/slog"
 	"time"
 )
 
-func NewTicketService(ticketRepo ticketRepo.Repository, paymentRepo paymentRepo.Repository, routeRepo routeRepo.Repository, processor PaymentProcessor, busRepo busRepo.Repository) *TicketService {
+func NewTicketService(ticketRepo ticketRepo.Repository, paymentRepo paymentRepo.Repository, routeRepo routeRepo.Repository, ledgerRepo ledgerRepo.Repository, orderRepo orderRepo.Repository, policyRepo refundPolicyRepo.Repository, outboxRepo outboxRepo.Repository, settingsRepo settingsRepo.Repository, emailTemplates *EmailTemplateService, payments *PaymentRegistry, signer *TicketSigner, busRepo busRepo.Repository, holds SeatHoldStore, holdTTL, paymentTTL time.Duration, passes PassUpdates, pushes *PushService, refunds *RefundSettler) *TicketService {
 	return &TicketService{
-		TicketRepo:       ticketRepo,
-		RouteRepo:        routeRepo,
-		PaymentRepo:      paymentRepo,
-		PaymentProcessor: processor,
-		BusRepo:          busRepo,
+		TicketRepo:   ticketRepo,
+		RouteRepo:    routeRepo,
+		PaymentRepo:  paymentRepo,
+		LedgerRepo:   ledgerRepo,
+		OrderRepo:    orderRepo,
+		PolicyRepo:   policyRepo,
+		OutboxRepo:   outboxRepo,
+		SettingsRepo: settingsRepo,
+		Templates:    emailTemplates,
+		Payments:     payments,
+		Signer:       signer,
+		BusRepo:      busRepo,
+		Holds:        holds,
+		HoldTTL:      holdTTL,
+		PaymentTTL:   paymentTTL,
+		Passes:       passes,
+		Push:         pushes,
+		Refunds:      refunds,
 	}
 }
 
 type TicketService struct {
-	TicketRepo       ticketRepo.Repository
-	RouteRepo        routeRepo.Repository
-	PaymentRepo      paymentRepo.Repository
-	PaymentProcessor PaymentProcessor
-	BusRepo          busRepo.Repository
+	TicketRepo   ticketRepo.Repository
+	RouteRepo    routeRepo.Repository
+	PaymentRepo  paymentRepo.Repository
+	LedgerRepo   ledgerRepo.Repository
+	OrderRepo    orderRepo.Repository
+	PolicyRepo   refundPolicyRepo.Repository
+	OutboxRepo   outboxRepo.Repository
+	SettingsRepo settingsRepo.Repository
+	Templates    *EmailTemplateService
+	Payments     *PaymentRegistry
+	Signer       *TicketSigner
+	BusRepo      busRepo.Repository
+	Holds        SeatHoldStore
+	HoldTTL      time.Duration
+	PaymentTTL   time.Duration
+	Passes       PassUpdates
+	Push         *PushService
+	Refunds      *RefundSettler
 }
 
 //4242 4242 4242 4242 (Visa) – Succeeds
 //4000 0000 0000 9995 (Declined)
 
-func (s *TicketService) BuyTickets(ctx context.Context, userID, routeID, paymentMethodID string, quantity int, stripeKey string) ([]domain.Ticket, *domain.Bus, *domain.Route, error) {
-	if quantity <= 0 {
-		return nil, nil, nil, errors.New("quantity must be positive")
+// BuyTickets reserves the seats, charges the card and issues the tickets.
+// The seats are reserved and committed before the provider is asked, so the
+// route is not locked while the card is charged, and given back when the
+// charge fails. When the card needs authentication the tickets stay awaiting
+// their payment and the returned purchase carries the client secret to finish
+// it with; ConfirmPayment or the payment webhook then settles them.
+func (s *TicketService) BuyTickets(ctx context.Context, userID, routeID, paymentMethodID string, req model.BuyTicketRequest) (*domain.Purchase, error) {
+	if len(req.Seats) == 0 && req.Quantity <= 0 {
+		return nil, errors.New("quantity must be positive")
 	}
 
+	processor := s.Payments.Active()
+
+	res, err := s.reserve(ctx, userID, routeID, processor, req)
+	if err != nil {
+		return nil, err
+	}
+
+	result, paymentErr := processor.ProcessPayment(ctx, userID, res.payment.Amount, paymentMethodID)
+	if paymentErr != nil {
+		s.release(ctx, res, "")
+		return nil, fmt.Errorf("payment failed: %w", paymentErr)
+	}
+	if result.Status == PaymentFailed {
+		s.release(ctx, res, result.TransactionID)
+		return nil, fmt.Errorf("payment was not successful")
+	}
+	if result.Status == PaymentRequiresAction && !processor.Capabilities().SCA {
+		s.undoCharge(ctx, processor, result, res.payment.Amount)
+		s.release(ctx, res, result.TransactionID)
+		return nil, fmt.Errorf("payment provider %s cannot authenticate the card", processor.Name())
+	}
+
+	message, err := s.finalize(ctx, res, result, req.UserEmail)
+	if err != nil {
+		// the card is charged or authorized, so the money has to go back
+		s.undoCharge(ctx, processor, result, res.payment.Amount)
+		s.release(ctx, res, result.TransactionID)
+		return nil, err
+	}
+
+	if message != nil {
+		s.Push.Notify(ctx, userID, tripPush(message, res.route))
+	}
+
+	if res.hold != nil {
+		if err := s.Holds.Release(ctx, res.hold); err != nil {
+			slog.Error("failed to release confirmed seat hold", slog.String("hold_id", res.hold.ID), slog.String("error", err.Error()))
+		}
+	}
+
+	return &domain.Purchase{
+		OrderID:       res.order.ID,
+		OrderNumber:   res.order.Number,
+		PaymentID:     res.payment.ID,
+		PaymentStatus: res.payment.Status,
+		ClientSecret:  result.ClientSecret,
+		Tickets:       res.tickets,
+		Bus:           res.bus,
+		Route:         res.route,
+		Issued:        result.Status == PaymentSucceeded,
+	}, nil
+}
+
+// reservation is a purchase whose seats are taken while its card is charged.
+type reservation struct {
+	payment *domain.Payment
+	order   *domain.Order
+	tickets []domain.Ticket
+	route   *domain.Route
+	bus     *domain.Bus
+	hold    *domain.SeatHold
+}
+
+// reserve takes the seats for a purchase: the tickets are created awaiting
+// an initiated payment, which has no transaction until the card is charged.
+func (s *TicketService) reserve(ctx context.Context, userID, routeID string, processor PaymentProcessor, req model.BuyTicketRequest) (*reservation, error) {
 	tx := s.TicketRepo.BeginTransaction()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
+			panic(r)
 		}
-		tx.Commit()
 	}()
 
-	route, err := s.RouteRepo.Get(ctx, routeID)
+	// the route row stays locked until commit, so reservations of the same
+	// route are serialized and see each other's seats
+	route, err := s.RouteRepo.GetForUpdate(ctx, tx, routeID)
 	if err != nil {
 		tx.Rollback()
-		return nil, nil, nil, err
+		return nil, err
 	}
-	if route.AvailableSeats < quantity {
+
+	bus, err := s.BusRepo.Get(ctx, route.BusId)
+	if err != nil {
 		tx.Rollback()
-		return nil, nil, nil, errs.ErrNoSeatsAvailable
+		return nil, err
 	}
 
-	totalAmount := route.Price * quantity
+	var hold *domain.SeatHold
+	requested := req.Seats
+	if req.HoldID != "" {
+		hold, err = s.ownHold(ctx, userID, req.HoldID)
+		if err != nil || hold.RouteID != routeID {
+			tx.Rollback()
+			return nil, errs.ErrHoldNotFound
+		}
+		requested = hold.Seats
+	}
 
-	success, transactionId, paymentErr := s.PaymentProcessor.ProcessPayment(ctx, userID, totalAmount, paymentMethodID, stripeKey)
-	if paymentErr != nil {
+	seats, err := s.pickSeats(ctx, route, bus, requested, req.Quantity, req.HoldID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = s.RouteRepo.DecrementSeats(ctx, tx, route.Id, len(seats))
+	if errors.Is(err, errs.ErrNoSeatsAvailable) {
 		tx.Rollback()
-		return nil, nil, nil, fmt.Errorf("payment failed: %w", paymentErr)
+		return nil, err
 	}
-	if !success {
+	if err != nil {
 		tx.Rollback()
-		return nil, nil, nil, fmt.Errorf("payment was not successful")
+		return nil, fmt.Errorf("failed to update route seats: %w", err)
 	}
 
 	paymentId, _ := uuid.NewV7()
 	payment := &domain.Payment{
-		ID:            paymentId.String(),
-		UserID:        userID,
-		Amount:        totalAmount,
-		Status:        "successful",
-		TransactionID: transactionId,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
+		ID:        paymentId.String(),
+		UserID:    userID,
+		Amount:    route.Price * len(seats),
+		Status:    "initiated",
+		Provider:  processor.Name(),
+		Currency:  processor.Currency(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := s.PaymentRepo.Create(ctx, tx, payment); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create payment: %w", err)
 	}
 
-	err = s.PaymentRepo.Create(ctx, tx, payment)
+	orderNumber, err := s.OrderRepo.NextNumber(ctx, tx)
 	if err != nil {
 		tx.Rollback()
-		return nil, nil, nil, fmt.Errorf("failed to create payment: %w", err)
+		return nil, err
 	}
 
-	var tickets []domain.Ticket
+	orderId, _ := uuid.NewV7()
+	order := &domain.Order{
+		ID:        orderId.String(),
+		Number:    orderNumber,
+		UserID:    userID,
+		RouteID:   routeID,
+		PaymentID: payment.ID,
+		Status:    domain.OrderAwaiting,
+		Total:     payment.Amount,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := s.OrderRepo.Create(ctx, tx, order); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
-	for i := 0; i < quantity; i++ {
+	tickets := make([]domain.Ticket, 0, len(seats))
+	for _, seat := range seats {
 		ticketId, _ := uuid.NewV7()
 		ticket := domain.Ticket{
 			ID:            ticketId.String(),
 			UserID:        userID,
 			RouteID:       routeID,
 			Price:         route.Price,
-			Status:        "approved",
-			PaymentStatus: "paid",
+			SeatNumber:    seat,
+			Status:        "awaiting",
+			PaymentStatus: "pending",
 			CreatedAt:     time.Now(),
+			OrderID:       order.ID,
+			OrderNumber:   order.Number,
+			PaymentID:     payment.ID,
 		}
 
-		ticket.OrderNumber = generateOrderNumber()
-		ticket.PaymentID = payment.ID
-
-		qrCodePath, err := generateQRCode(&ticket)
+		qrCodePath, err := generateQRCode(s.Signer.Sign(&ticket))
 		if err != nil {
 			tx.Rollback()
-			return nil, nil, nil, fmt.Errorf("failed to generate QR code: %w", err)
+			return nil, fmt.Errorf("failed to generate QR code: %w", err)
 		}
 		ticket.QRCode = qrCodePath
+		ticket.QRVersion = ticketQRVersion
 
 		err = s.TicketRepo.Create(ctx, tx, &ticket)
+		if errors.Is(err, errs.ErrSeatTaken) {
+			tx.Rollback()
+			return nil, fmt.Errorf("seat %s: %w", seat, err)
+		}
 		if err != nil {
 			tx.Rollback()
-			return nil, nil, nil, fmt.Errorf("failed to create ticket: %w", err)
+			return nil, fmt.Errorf("failed to create ticket: %w", err)
 		}
 
 		tickets = append(tickets, ticket)
 	}
 
-	err = s.RouteRepo.UpdateSeat(ctx, tx, map[string]interface{}{
-		"available_seats": route.AvailableSeats - quantity,
-	}, route.Id)
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit reservation: %w", err)
+	}
+
+	return &reservation{payment: payment, order: order, tickets: tickets, route: route, bus: bus, hold: hold}, nil
+}
+
+// finalize records the answer of the provider for a reservation. A payment
+// that went through issues the tickets and queues their email, one that
+// waits for authentication leaves them awaiting.
+func (s *TicketService) finalize(ctx context.Context, res *reservation, result *PaymentResult, email string) (*domain.OutboxMessage, error) {
+	tx := s.TicketRepo.BeginTransaction()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	payment, err := s.PaymentRepo.GetForUpdate(ctx, tx, res.payment.ID)
 	if err != nil {
 		tx.Rollback()
-		return nil, nil, nil, fmt.Errorf("failed to update route seats: %w", err)
+		return nil, err
+	}
+	if payment.Status != "initiated" {
+		tx.Rollback()
+		return nil, fmt.Errorf("purchase was released before the payment went through")
 	}
 
-	bus, err := s.BusRepo.Get(ctx, route.BusId)
+	payment.TransactionID = result.TransactionID
+	payment.Status = "successful"
+	updates := map[string]interface{}{"transaction_id": payment.TransactionID}
+	if result.Status != PaymentSucceeded {
+		// the payment sweeper releases the seats when nobody authenticates
+		expiresAt := time.Now().Add(s.PaymentTTL)
+		payment.Status, payment.ExpiresAt = "pending", &expiresAt
+		updates["expires_at"] = expiresAt
+	}
+	updates["status"] = payment.Status
+	err = s.PaymentRepo.Update(ctx, tx, updates, payment.ID)
 	if err != nil {
 		tx.Rollback()
-		return nil, nil, nil, err
+		return nil, fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	// tickets that wait for card authentication are sent once confirmed
+	var message *domain.OutboxMessage
+	if result.Status == PaymentSucceeded {
+		if err := approveTickets(ctx, tx, s.TicketRepo, s.OrderRepo, res.tickets); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := s.LedgerRepo.Append(ctx, tx, chargeEntry(payment, payment.TransactionID)); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		for i := range res.tickets {
+			res.tickets[i].Status, res.tickets[i].PaymentStatus = "approved", "paid"
+		}
+		res.order.Status = domain.OrderActive
+
+		message, err = s.ticketEmail(ctx, email, payment.UserID, res.order.ID, res.tickets, res.bus, res.route)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if email != "" {
+			if err := s.OutboxRepo.Create(ctx, tx, message); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit purchase: %w", err)
 	}
 
-	tx.Commit()
-	return tickets, bus, route, nil
+	res.payment = payment
+	return message, nil
 }
 
-func generateQRCode(ticket *domain.Ticket) (string, error) {
-	ticketJSON, err := json.Marshal(ticket)
+// release gives the seats of a reservation whose payment did not go through
+// back to the route. A reservation released meanwhile is left alone.
+func (s *TicketService) release(ctx context.Context, res *reservation, transactionID string) {
+	// the purchase is over for the client, the seats go back even if it left
+	ctx = context.WithoutCancel(ctx)
+
+	err := func() error {
+		tx := s.TicketRepo.BeginTransaction()
+		defer func() {
+			if r := recover(); r != nil {
+				tx.Rollback()
+				panic(r)
+			}
+		}()
+
+		payment, err := s.PaymentRepo.GetForUpdate(ctx, tx, res.payment.ID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if payment.Status != "initiated" {
+			tx.Rollback()
+			return nil
+		}
+
+		if err := releaseTickets(ctx, tx, s.TicketRepo, s.RouteRepo, s.OrderRepo, res.tickets, "failed"); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		updates := map[string]interface{}{"status": "failed"}
+		if transactionID != "" {
+			updates["transaction_id"] = transactionID
+		}
+		if err := s.PaymentRepo.Update(ctx, tx, updates, payment.ID); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		return tx.Commit().Error
+	}()
 	if err != nil {
-		return "", err
+		slog.Error("failed to release reserved seats", slog.String("payment_id", res.payment.ID), slog.String("error", err.Error()))
+	}
+}
+
+// undoCharge gives back the money of a payment that cannot be completed.
+func (s *TicketService) undoCharge(ctx context.Context, processor PaymentProcessor, result *PaymentResult, amount int) {
+	ctx = context.WithoutCancel(ctx)
+
+	if result.Status == PaymentSucceeded {
+		if _, err := processor.Refund(ctx, result.TransactionID, amount); err != nil {
+			slog.Error("failed to refund aborted purchase", slog.String("transaction_id", result.TransactionID), slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	if err := processor.Cancel(ctx, result.TransactionID); err != nil {
+		slog.Error("failed to cancel aborted purchase", slog.String("transaction_id", result.TransactionID), slog.String("error", err.Error()))
+	}
+}
+
+// ConfirmPayment settles a purchase after the customer authenticated its
+// payment: the tickets are issued once the payment succeeded and released when
+// it failed, issued tickets are emailed to email when it is set and pushed to
+// the devices of the user. A payment
+// that is still in progress is returned unchanged.
+func (s *TicketService) ConfirmPayment(ctx context.Context, userID, paymentID, email string) (*domain.Purchase, error) {
+	tx := s.TicketRepo.BeginTransaction()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// the payment row lock serializes confirmation with the payment webhook
+	payment, err := s.PaymentRepo.GetForUpdate(ctx, tx, paymentID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if payment.UserID != userID {
+		tx.Rollback()
+		return nil, repoErrs.ErrRecordNotFound
 	}
 
-	qr, err := qrcode.New(string(ticketJSON), qrcode.Medium)
+	tickets, err := s.TicketRepo.GetByPaymentID(ctx, tx, payment.ID)
 	if err != nil {
-		return "", err
+		tx.Rollback()
+		return nil, err
 	}
 
-	img := qr.Image(183)
-	var buf bytes.Buffer
-	if err := jpeg.Encode(&buf, img, nil); err != nil {
-		return "", err
+	issued := false
+	if payment.Status == "pending" {
+		processor, err := s.Payments.Get(payment.Provider)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		details, err := processor.Details(ctx, payment.TransactionID)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		switch details.Status {
+		case PaymentSucceeded:
+			payment.Status = "successful"
+			issued = true
+			err = approveTickets(ctx, tx, s.TicketRepo, s.OrderRepo, tickets)
+			if err == nil {
+				err = s.LedgerRepo.Append(ctx, tx, chargeEntry(payment, payment.TransactionID))
+			}
+		case PaymentFailed:
+			payment.Status = "failed"
+			err = releaseTickets(ctx, tx, s.TicketRepo, s.RouteRepo, s.OrderRepo, tickets, "failed")
+		}
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		if payment.Status != "pending" {
+			if err := s.PaymentRepo.Update(ctx, tx, map[string]interface{}{"status": payment.Status}, payment.ID); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+
+			tickets, err = s.TicketRepo.GetByPaymentID(ctx, tx, payment.ID)
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+	}
+
+	purchase := &domain.Purchase{
+		PaymentID:     payment.ID,
+		PaymentStatus: payment.Status,
+		Tickets:       tickets,
+		Issued:        issued,
+	}
+	var message *domain.OutboxMessage
+	if len(tickets) > 0 {
+		purchase.OrderID, purchase.OrderNumber = tickets[0].OrderID, tickets[0].OrderNumber
+		purchase.Route, err = s.RouteRepo.Get(ctx, tickets[0].RouteID)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		purchase.Bus, err = s.BusRepo.Get(ctx, purchase.Route.BusId)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		if issued {
+			message, err = s.ticketEmail(ctx, email, userID, purchase.OrderID, tickets, purchase.Bus, purchase.Route)
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			if email != "" {
+				if err := s.OutboxRepo.Create(ctx, tx, message); err != nil {
+					tx.Rollback()
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit payment confirmation: %w", err)
+	}
+
+	if message != nil {
+		s.Push.Notify(ctx, userID, tripPush(message, purchase.Route))
+	}
+
+	return purchase, nil
+}
+
+// ticketEmail is the purchase email of an order in the buyer's language, its
+// tickets are attached when it is sent.
+func (s *TicketService) ticketEmail(ctx context.Context, recipient, userID, orderID string, tickets []domain.Ticket, bus *domain.Bus, route *domain.Route) (*domain.OutboxMessage, error) {
+	data, err := ticketEmailData(tickets, bus, route)
+	if err != nil {
+		return nil, err
+	}
+	subject, body, err := s.Templates.Render(ctx, userLanguage(ctx, s.SettingsRepo, userID), templates.TicketsEmail, data)
+	if err != nil {
+		return nil, err
 	}
 
-	qrBase64 := base64.StdEncoding.EncodeToString(buf.Bytes())
+	return newOutboxMessage(domain.OutboxTickets, recipient, subject, body, orderID), nil
+}
+
+// generateQRCode renders the signed token of the ticket, the only thing a
+// conductor needs to check it, as a base64 PNG.
+func generateQRCode(token string) (string, error) {
+	png, err := qrcode.Encode(token, qrcode.Medium, 183)
+	if err != nil {
+		return "", err
+	}
 
-	return qrBase64, nil
+	return base64.StdEncoding.EncodeToString(png), nil
 }
 
 func (s *TicketService) GetUpcomingTickets(ctx context.Context, userID string, now time.Time) ([]domain.Ticket, error) {
@@ -178,93 +579,83 @@ func (s *TicketService) TicketDetails(ctx context.Context, ticketId string) (*do
 	return s.TicketRepo.Get(ctx, ticketId)
 }
 
-func (s *TicketService) GetTicketsSortBy(
-	ctx context.Context,
-	sortBy, ord string,
-	page, pageSize int,
-) ([]domain.Ticket, error) {
-	return s.TicketRepo.GetTicketsSortBy(ctx, sortBy, ord, page, pageSize)
-}
-
-func generateOrderNumber() string {
-	return fmt.Sprintf("ORD-%d-%04d", time.Now().Unix(), rand.Intn(10000))
-}
-
-func (s *TicketService) CancelTicket(ctx context.Context, userID, ticketID, stripeKey string) (*domain.Ticket, string, error) {
+// TicketPDF renders the printable e-ticket of a valid ticket.
+func (s *TicketService) TicketPDF(ctx context.Context, userID, ticketID string) (*domain.Ticket, []byte, error) {
 	ticket, err := s.TicketRepo.Get(ctx, ticketID)
 	if err != nil {
-		return nil, "", fmt.Errorf("ticket not found: %w", err)
+		return nil, nil, err
 	}
-
 	if ticket.UserID != userID {
-		return nil, "", fmt.Errorf("unauthorized cancel attempt")
+		return nil, nil, repoErrs.ErrRecordNotFound
 	}
-	if ticket.Status == "cancelled" {
-		return nil, "", errors.New("ticket already cancelled")
+	if ticket.Status == "cancelled" || ticket.Status == "awaiting" {
+		return nil, nil, fmt.Errorf("%w: ticket is %s", errs.ErrTicketNotBoardable, ticket.Status)
 	}
 
 	route, err := s.RouteRepo.Get(ctx, ticket.RouteID)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to fetch route: %w", err)
+		return nil, nil, fmt.Errorf("failed to fetch route: %w", err)
 	}
 
-	if time.Until(route.StartDate) < 24*time.Hour {
-		return nil, "", fmt.Errorf("cancellation not allowed less than 24 hours before departure")
+	bus, err := s.BusRepo.Get(ctx, route.BusId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch bus: %w", err)
 	}
 
-	tx := s.TicketRepo.BeginTransaction()
+	pdf, err := RenderTicketPDF(ticket, route, bus, s.Signer.Sign(ticket))
+	if err != nil {
+		return nil, nil, err
+	}
 
-	if ticket.PaymentStatus == "paid" {
-		payment, err := s.PaymentRepo.GetByID(ctx, ticket.PaymentID)
-		if err != nil {
-			tx.Rollback()
-			return nil, "", fmt.Errorf("failed to get payment: %w", err)
-		}
+	return ticket, pdf, nil
+}
 
-		refundSuccess, refundErr := s.PaymentProcessor.Refund(ctx, payment.TransactionID, stripeKey, ticket.Price)
-		if refundErr != nil || !refundSuccess {
-			tx.Rollback()
-			return nil, "", fmt.Errorf("refund failed: %w", refundErr)
-		}
+// TicketPDFName is the file name of the PDF of a ticket, for the download and
+// the email attachment alike.
+func TicketPDFName(ticket *domain.Ticket) string {
+	return fmt.Sprintf("ticket-%s-%s.pdf", ticket.OrderNumber, ticket.SeatNumber)
+}
+
+func (s *TicketService) GetTicketsSortBy(
+	ctx context.Context,
+	sortBy, ord string,
+	page, pageSize int,
+) ([]domain.Ticket, error) {
+	return s.TicketRepo.GetTicketsSortBy(ctx, sortBy, ord, page, pageSize)
+}
 
-		//err = s.PaymentRepo.Update(ctx, tx, map[string]interface{}{
-		//	"status": "refunded",
-		//}, payment.ID)
-		//if err != nil {
-		//	tx.Rollback()
-		//	return fmt.Errorf("failed to update payment status: %w", err)
-		//}
+// chargeEntry is the ledger entry of a payment that was taken in full.
+func chargeEntry(payment *domain.Payment, reference string) *domain.LedgerEntry {
+	return &domain.LedgerEntry{
+		PaymentID: payment.ID,
+		Type:      domain.LedgerCharge,
+		Amount:    payment.Amount,
+		Currency:  payment.Currency,
+		Reference: reference,
 	}
+}
 
-	err = s.TicketRepo.Update(ctx, tx, map[string]interface{}{
-		"status":         "cancelled",
-		"payment_status": "refunded",
-	}, ticket.ID)
+// CancelTicket cancels a single ticket, the rest of its order stays valid.
+func (s *TicketService) CancelTicket(ctx context.Context, userID, ticketID, email string) (*domain.Ticket, error) {
+	ticket, err := s.TicketRepo.Get(ctx, ticketID)
 	if err != nil {
-		tx.Rollback()
-		return nil, "", fmt.Errorf("failed to cancel ticket: %w", err)
+		return nil, fmt.Errorf("ticket not found: %w", err)
+	}
+	if ticket.UserID != userID {
+		return nil, fmt.Errorf("unauthorized cancel attempt")
 	}
 
-	err = s.RouteRepo.IncrementSeats(ctx, tx, ticket.RouteID, 1)
+	order, err := s.CancelOrder(ctx, userID, ticket.OrderID, []string{ticket.ID}, email)
 	if err != nil {
-		tx.Rollback()
-		return nil, "", fmt.Errorf("failed to update seat count: %w", err)
+		return nil, err
 	}
 
-	msg := buildCancellationEmail(ticket, route)
-
-	tx.Commit()
-	return ticket, msg, nil
-}
-
-func buildCancellationEmail(ticket *domain.Ticket, route *domain.Route) string {
-	return fmt.Sprintf(`<html><body style="font-family: Arial, sans-serif;">
-		<h2 style="color:#dc3545;">Ваш билет был отменён</h2>
-		<p>Номер билета: <strong>%s</strong></p>
-		<p>Маршрут: <strong>%s → %s</strong></p>
-		<p>Статус: <strong>Отменён</strong></p>
-		<p>Спасибо, что пользуетесь AulWay</p>
-	</body></html>`, ticket.OrderNumber, route.Departure, route.Destination)
+	for i := range order.Tickets {
+		if order.Tickets[i].ID == ticket.ID {
+			return &order.Tickets[i], nil
+		}
+	}
+	return ticket, nil
 }
 
 func (s *TicketService) GetCancelledTickets(ctx context.Context, userID string) ([]domain.Ticket, error) {
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/handler/ticket/model"
+	"aulway/internal/mockgateway"
+	"aulway/internal/push"
+	busRepository "aulway/internal/repository/bus"
+	deviceRepository "aulway/internal/repository/device"
+	emailTemplateRepository "aulway/internal/repository/emailtemplate"
+	ledgerRepository "aulway/internal/repository/ledger"
+	orderRepository "aulway/internal/repository/order"
+	outboxRepository "aulway/internal/repository/outbox"
+	paymentRepository "aulway/internal/repository/payment"
+	refundRepository "aulway/internal/repository/refund"
+	refundPolicyRepository "aulway/internal/repository/refundpolicy"
+	routeRepository "aulway/internal/repository/route"
+	settingsRepository "aulway/internal/repository/settings"
+	ticketRepository "aulway/internal/repository/ticket"
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// The concurrency tests need a database with all migrations applied, e.g.
+// TEST_POSTGRES_DSN="host=localhost port=5432 user=postgres password=12345 dbname=aulway_test sslmode=disable"
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(50)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	return db
+}
+
+type noSeatHolds struct{}
+
+func (noSeatHolds) Acquire(context.Context, *domain.SeatHold, time.Duration) error { return nil }
+
+func (noSeatHolds) Get(context.Context, string) (*domain.SeatHold, error) { return nil, nil }
+
+func (noSeatHolds) Release(context.Context, *domain.SeatHold) error { return nil }
+
+func (noSeatHolds) HeldSeats(context.Context, string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+func (noSeatHolds) CountHeld(context.Context, ...string) (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
+type noPassUpdates struct{}
+
+func (noPassUpdates) TicketsChanged(context.Context, ...string) {}
+
+func (noPassUpdates) RouteChanged(context.Context, string) {}
+
+type purchaseFixture struct {
+	service *TicketService
+	gateway *mockgateway.Gateway
+	db      *gorm.DB
+	userIDs []string
+	routeID string
+	pushes  *push.Fake
+}
+
+func newPurchaseFixture(t *testing.T, seats, buyers int) *purchaseFixture {
+	t.Helper()
+
+	db := testDB(t)
+	ctx := context.Background()
+	suffix := uuid.NewString()[:8]
+
+	busRepo := busRepository.New(db)
+	bus := &domain.Bus{
+		Id:         uuid.NewString(),
+		Number:     "TEST-" + suffix,
+		TotalSeats: seats,
+		Seats:      generateSeats((seats+defaultSeatColumns-1)/defaultSeatColumns, defaultSeatColumns, seats),
+	}
+	bus.Rows, bus.Columns = layoutSize(bus.Seats)
+	require.NoError(t, busRepo.Create(ctx, bus))
+
+	userIDs := make([]string, 0, buyers)
+	for i := 0; i < buyers; i++ {
+		usr := &domain.User{
+			ID:        uuid.NewString(),
+			Email:     uuid.NewString() + "@example.com",
+			Phone:     "+77000000000",
+			Password:  "x",
+			FirstName: "Test",
+			LastName:  "Buyer",
+			Role:      userRole,
+		}
+		require.NoError(t, db.Create(usr).Error)
+		userIDs = append(userIDs, usr.ID)
+	}
+
+	routeRepo := routeRepository.New(db)
+	route := &domain.Route{
+		Id:             uuid.NewString(),
+		Departure:      "Almaty",
+		Destination:    "Astana",
+		StartDate:      time.Now().Add(72 * time.Hour),
+		EndDate:        time.Now().Add(84 * time.Hour),
+		AvailableSeats: seats,
+		BusId:          bus.Id,
+		Price:          5000,
+	}
+	require.NoError(t, routeRepo.Create(ctx, route))
+
+	t.Cleanup(func() {
+		// the ledger refuses deletes, replica mode skips its trigger for the cleanup
+		db.Transaction(func(tx *gorm.DB) error {
+			tx.Exec("SET LOCAL session_replication_role = replica")
+			return tx.Where("payment_id IN (?)", tx.Model(&domain.Payment{}).Select("id").Where("user_id IN ?", userIDs)).Delete(&domain.LedgerEntry{}).Error
+		})
+		db.Where("payment_id IN (?)", db.Model(&domain.Payment{}).Select("id").Where("user_id IN ?", userIDs)).Delete(&domain.Refund{})
+		db.Where("route_id = ?", route.Id).Delete(&domain.Ticket{})
+		db.Where("user_id IN ?", userIDs).Delete(&domain.Order{})
+		db.Where("user_id IN ?", userIDs).Delete(&domain.Payment{})
+		db.Where("id = ?", route.Id).Delete(&domain.Route{})
+		db.Where("bus_id = ?", bus.Id).Delete(&domain.Seat{})
+		db.Where("id = ?", bus.Id).Delete(&domain.Bus{})
+		db.Unscoped().Where("id IN ?", userIDs).Delete(&domain.User{})
+	})
+
+	payments, gateway := mockPayments(t)
+	pushes := &push.Fake{}
+	refunds := NewRefundSettler(refundRepository.New(db), paymentRepository.New(db), ledgerRepository.New(db), payments, time.Minute, time.Minute)
+	service := NewTicketService(ticketRepository.New(db), paymentRepository.New(db), routeRepo, ledgerRepository.New(db), orderRepository.New(db), refundPolicyRepository.New(db), outboxRepository.New(db), settingsRepository.New(db), NewEmailTemplateService(emailTemplateRepository.New(db)), payments, NewTicketSigner("test"), busRepo, noSeatHolds{}, time.Minute, time.Hour, noPassUpdates{}, NewPushService(deviceRepository.New(db), pushes), refunds)
+
+	return &purchaseFixture{service: service, gateway: gateway, db: db, userIDs: userIDs, routeID: route.Id, pushes: pushes}
+}
+
+func (f *purchaseFixture) buyInParallel(reqFor func(i int) model.BuyTicketRequest) int64 {
+	var (
+		wg        sync.WaitGroup
+		succeeded atomic.Int64
+		start     = make(chan struct{})
+	)
+
+	for i, userID := range f.userIDs {
+		wg.Add(1)
+		go func(i int, userID string) {
+			defer wg.Done()
+			<-start
+
+			_, err := f.service.BuyTickets(context.Background(), userID, f.routeID, "pm_card_visa", reqFor(i))
+			if err == nil {
+				succeeded.Add(1)
+			}
+		}(i, userID)
+	}
+
+	close(start)
+	wg.Wait()
+
+	return succeeded.Load()
+}
+
+func (f *purchaseFixture) assertNotOversold(t *testing.T, seats int, sold int64) {
+	t.Helper()
+
+	var route domain.Route
+	require.NoError(t, f.db.First(&route, "id = ?", f.routeID).Error)
+	require.GreaterOrEqual(t, route.AvailableSeats, 0)
+	require.Equal(t, seats-int(sold), route.AvailableSeats)
+
+	var live int64
+	require.NoError(t, f.db.Model(&domain.Ticket{}).Where("route_id = ? AND status <> ?", f.routeID, "cancelled").Count(&live).Error)
+	require.Equal(t, sold, live)
+
+	var distinctSeats int64
+	require.NoError(t, f.db.Model(&domain.Ticket{}).Where("route_id = ? AND status <> ?", f.routeID, "cancelled").Distinct("seat_number").Count(&distinctSeats).Error)
+	require.Equal(t, live, distinctSeats)
+}
+
+func TestBuyTicketsParallelNeverOversells(t *testing.T) {
+	const seats, buyers = 5, 30
+
+	f := newPurchaseFixture(t, seats, buyers)
+
+	sold := f.buyInParallel(func(int) model.BuyTicketRequest {
+		return model.BuyTicketRequest{Quantity: 1}
+	})
+
+	require.Equal(t, int64(seats), sold)
+	f.assertNotOversold(t, seats, sold)
+}
+
+func TestBuyTicketsParallelSameSeat(t *testing.T) {
+	const seats, buyers = 8, 20
+
+	f := newPurchaseFixture(t, seats, buyers)
+
+	sold := f.buyInParallel(func(int) model.BuyTicketRequest {
+		return model.BuyTicketRequest{Seats: []string{"1A"}}
+	})
+
+	require.Equal(t, int64(1), sold)
+	f.assertNotOversold(t, seats, sold)
+}
+
+func TestHoldSeatsParallelWithPurchasesOfTheSameSeat(t *testing.T) {
+	const seats, buyers = 8, 20
+
+	f := newPurchaseFixture(t, seats, buyers)
+	f.service.Holds, _ = testSeatHolds(t)
+
+	var (
+		wg    sync.WaitGroup
+		won   atomic.Int64
+		start = make(chan struct{})
+	)
+	for i, userID := range f.userIDs {
+		wg.Add(1)
+		go func(i int, userID string) {
+			defer wg.Done()
+			<-start
+
+			var err error
+			if i%2 == 0 {
+				_, err = f.service.HoldSeats(context.Background(), userID, f.routeID, model.HoldSeatsRequest{Seats: []string{"1A"}})
+			} else {
+				_, err = f.service.BuyTickets(context.Background(), userID, f.routeID, "pm_card_visa", model.BuyTicketRequest{Seats: []string{"1A"}})
+			}
+			if err == nil {
+				won.Add(1)
+			}
+		}(i, userID)
+	}
+	close(start)
+	wg.Wait()
+
+	require.Equal(t, int64(1), won.Load(), "a seat is either held or sold, never both")
+}
+
+func TestBuyTicketsParallelMixedQuantities(t *testing.T) {
+	const seats, buyers = 10, 25
+
+	f := newPurchaseFixture(t, seats, buyers)
+
+	f.buyInParallel(func(i int) model.BuyTicketRequest {
+		return model.BuyTicketRequest{Quantity: 1 + i%3}
+	})
+
+	var route domain.Route
+	require.NoError(t, f.db.First(&route, "id = ?", f.routeID).Error)
+
+	var live int64
+	require.NoError(t, f.db.Model(&domain.Ticket{}).Where("route_id = ? AND status <> ?", f.routeID, "cancelled").Count(&live).Error)
+	f.assertNotOversold(t, seats, live)
+	for _, p := range f.gateway.Payments() {
+		require.Zero(t, p.Refunded, "no purchase should have been charged and rolled back")
+	}
+}
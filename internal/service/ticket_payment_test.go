@@ -0,0 +1,410 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	refundModel "aulway/internal/handler/refundpolicy/model"
+	settingsModel "aulway/internal/handler/settings/model"
+	"aulway/internal/handler/ticket/model"
+	"aulway/internal/mockgateway"
+	refundRepository "aulway/internal/repository/refund"
+	"aulway/internal/templates"
+	"aulway/internal/utils/errs"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/stripe/stripe-go/v76"
+)
+
+func TestBuyTicketsWith3DS(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 1)
+	ctx := context.Background()
+	userID := f.userIDs[0]
+
+	purchase, err := f.service.BuyTickets(ctx, userID, f.routeID, mockgateway.Method3DS, model.BuyTicketRequest{Seats: []string{"1A", "1B"}})
+	require.NoError(t, err)
+	require.False(t, purchase.Issued)
+	require.Equal(t, "pending", purchase.PaymentStatus)
+	require.NotEmpty(t, purchase.ClientSecret)
+	for _, ticket := range purchase.Tickets {
+		require.Equal(t, "awaiting", ticket.Status)
+	}
+
+	confirmed, err := f.service.ConfirmPayment(ctx, userID, purchase.PaymentID, "")
+	require.NoError(t, err)
+	require.Equal(t, "pending", confirmed.PaymentStatus, "nothing changes before the customer authenticates")
+
+	var payment domain.Payment
+	require.NoError(t, f.db.First(&payment, "id = ?", purchase.PaymentID).Error)
+	authenticate(t, f.service.Payments.Active(), payment.TransactionID, true)
+
+	confirmed, err = f.service.ConfirmPayment(ctx, userID, purchase.PaymentID, "")
+	require.NoError(t, err)
+	require.True(t, confirmed.Issued)
+	require.Equal(t, "successful", confirmed.PaymentStatus)
+	for _, ticket := range confirmed.Tickets {
+		require.Equal(t, "approved", ticket.Status)
+		require.Equal(t, "paid", ticket.PaymentStatus)
+	}
+
+	again, err := f.service.ConfirmPayment(ctx, userID, purchase.PaymentID, "")
+	require.NoError(t, err)
+	require.False(t, again.Issued, "confirming twice must not issue the tickets twice")
+
+	f.assertNotOversold(t, 4, 2)
+}
+
+func TestBuyTicketsWithFailed3DSReleasesSeats(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 1)
+	ctx := context.Background()
+	userID := f.userIDs[0]
+
+	purchase, err := f.service.BuyTickets(ctx, userID, f.routeID, mockgateway.Method3DS, model.BuyTicketRequest{Quantity: 3})
+	require.NoError(t, err)
+
+	var payment domain.Payment
+	require.NoError(t, f.db.First(&payment, "id = ?", purchase.PaymentID).Error)
+	authenticate(t, f.service.Payments.Active(), payment.TransactionID, false)
+
+	confirmed, err := f.service.ConfirmPayment(ctx, userID, purchase.PaymentID, "")
+	require.NoError(t, err)
+	require.Equal(t, "failed", confirmed.PaymentStatus)
+	for _, ticket := range confirmed.Tickets {
+		require.Equal(t, "cancelled", ticket.Status)
+	}
+
+	f.assertNotOversold(t, 4, 0)
+}
+
+func TestPaymentSweeperReleasesUnauthenticatedPurchase(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 1)
+	ctx := context.Background()
+	userID := f.userIDs[0]
+
+	purchase, err := f.service.BuyTickets(ctx, userID, f.routeID, mockgateway.Method3DS, model.BuyTicketRequest{Quantity: 2})
+	require.NoError(t, err)
+
+	var payment domain.Payment
+	require.NoError(t, f.db.First(&payment, "id = ?", purchase.PaymentID).Error)
+	require.NotNil(t, payment.ExpiresAt, "a pending payment has a deadline")
+
+	s := f.service
+	sweeper := NewPaymentSweeper(s.PaymentRepo, s.TicketRepo, s.RouteRepo, s.OrderRepo, s.Payments, time.Hour, time.Minute)
+	released, err := sweeper.Sweep(ctx)
+	require.NoError(t, err)
+	require.Zero(t, released, "the deadline is not over yet")
+
+	require.NoError(t, f.db.Model(&domain.Payment{}).Where("id = ?", payment.ID).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	released, err = sweeper.Sweep(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, released)
+
+	require.Equal(t, mockgateway.StatusCanceled, f.gateway.Payments()[0].Status)
+	require.NoError(t, f.db.First(&payment, "id = ?", purchase.PaymentID).Error)
+	require.Equal(t, "failed", payment.Status)
+
+	var order domain.Order
+	require.NoError(t, f.db.First(&order, "id = ?", purchase.OrderID).Error)
+	require.NotEqual(t, domain.OrderAwaiting, order.Status)
+
+	f.assertNotOversold(t, 4, 0)
+}
+
+func TestPaymentSweeperLeavesSucceededPayment(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 1)
+	ctx := context.Background()
+	userID := f.userIDs[0]
+
+	purchase, err := f.service.BuyTickets(ctx, userID, f.routeID, mockgateway.Method3DS, model.BuyTicketRequest{Quantity: 2})
+	require.NoError(t, err)
+
+	var payment domain.Payment
+	require.NoError(t, f.db.First(&payment, "id = ?", purchase.PaymentID).Error)
+	authenticate(t, f.service.Payments.Active(), payment.TransactionID, true)
+	require.NoError(t, f.db.Model(&domain.Payment{}).Where("id = ?", payment.ID).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+
+	s := f.service
+	sweeper := NewPaymentSweeper(s.PaymentRepo, s.TicketRepo, s.RouteRepo, s.OrderRepo, s.Payments, time.Hour, time.Minute)
+	released, err := sweeper.Sweep(ctx)
+	require.NoError(t, err)
+	require.Zero(t, released, "a charged card is settled by the confirmation, not released")
+
+	confirmed, err := f.service.ConfirmPayment(ctx, userID, purchase.PaymentID, "")
+	require.NoError(t, err)
+	require.True(t, confirmed.Issued)
+
+	f.assertNotOversold(t, 4, 2)
+}
+
+func TestCancelTicketRefundsOneTicket(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 1)
+	ctx := context.Background()
+	userID := f.userIDs[0]
+
+	purchase, err := f.service.BuyTickets(ctx, userID, f.routeID, "pm_card_visa", model.BuyTicketRequest{Quantity: 2})
+	require.NoError(t, err)
+	require.True(t, purchase.Issued)
+
+	_, err = f.service.CancelTicket(ctx, userID, purchase.Tickets[0].ID, "")
+	require.NoError(t, err)
+
+	payments := f.gateway.Payments()
+	require.Len(t, payments, 1)
+	require.Equal(t, purchase.Tickets[0].Price, payments[0].Refunded)
+
+	ledgers := NewLedgerService(f.service.LedgerRepo, f.service.PaymentRepo, f.service.OrderRepo, f.service.Payments)
+	ledger, err := ledgers.PaymentLedger(ctx, purchase.PaymentID)
+	require.NoError(t, err)
+	require.Len(t, ledger.Entries, 2)
+	require.Equal(t, domain.LedgerCharge, ledger.Entries[0].Type)
+	require.Equal(t, domain.LedgerPartialRefund, ledger.Entries[1].Type)
+	require.Equal(t, purchase.Tickets[0].ID, *ledger.Entries[1].TicketID)
+	require.Equal(t, purchase.Tickets[1].Price, ledger.Balance)
+
+	check, err := ledgers.CheckPayment(ctx, purchase.PaymentID)
+	require.NoError(t, err)
+	require.True(t, check.Consistent, "%+v", check)
+
+	f.assertNotOversold(t, 4, 1)
+}
+
+func TestCancelOrderRefundsRemainingTicketsAtOnce(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 1)
+	ctx := context.Background()
+	userID := f.userIDs[0]
+
+	purchase, err := f.service.BuyTickets(ctx, userID, f.routeID, "pm_card_visa", model.BuyTicketRequest{Quantity: 3})
+	require.NoError(t, err)
+	require.NotEmpty(t, purchase.OrderID)
+
+	order, err := f.service.CancelOrder(ctx, userID, purchase.OrderID, []string{purchase.Tickets[0].ID}, "")
+	require.NoError(t, err)
+	require.Equal(t, domain.OrderPartiallyCancelled, order.Status)
+
+	order, err = f.service.CancelOrder(ctx, userID, purchase.OrderID, nil, "")
+	require.NoError(t, err)
+	require.Equal(t, domain.OrderCancelled, order.Status)
+	for _, ticket := range order.Tickets {
+		require.Equal(t, "cancelled", ticket.Status)
+	}
+
+	_, err = f.service.CancelOrder(ctx, userID, purchase.OrderID, nil, "")
+	require.Error(t, err, "a cancelled order has nothing left to refund")
+
+	payments := f.gateway.Payments()
+	require.Len(t, payments, 1)
+	require.Equal(t, payments[0].Amount, payments[0].Refunded)
+
+	ledgers := NewLedgerService(f.service.LedgerRepo, f.service.PaymentRepo, f.service.OrderRepo, f.service.Payments)
+	ledger, err := ledgers.OrderLedger(ctx, order.Number)
+	require.NoError(t, err)
+	require.Len(t, ledger.Entries, 4, "a charge and a refund entry per ticket")
+	require.Zero(t, ledger.Balance)
+
+	f.assertNotOversold(t, 4, 0)
+}
+
+func TestOrderReceiptInUserLanguage(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 1)
+	ctx := context.Background()
+	userID := f.userIDs[0]
+
+	purchase, err := f.service.BuyTickets(ctx, userID, f.routeID, "pm_card_visa", model.BuyTicketRequest{Quantity: 2})
+	require.NoError(t, err)
+	_, err = f.service.CancelOrder(ctx, userID, purchase.OrderID, []string{purchase.Tickets[0].ID}, "")
+	require.NoError(t, err)
+
+	_, receipt, err := f.service.OrderReceipt(ctx, userID, purchase.OrderID)
+	require.NoError(t, err)
+	require.Contains(t, receipt, "Номер заказа", "users without settings get the default language")
+
+	_, err = NewSettingsService(f.service.SettingsRepo).UpdateSettings(ctx, userID, settingsModel.UpdateSettingsRequest{Language: templates.EN})
+	require.NoError(t, err)
+
+	_, receipt, err = f.service.OrderReceipt(ctx, userID, purchase.OrderID)
+	require.NoError(t, err)
+	require.Contains(t, receipt, "Order number")
+	require.Contains(t, receipt, "Cancelled")
+	require.Contains(t, receipt, "Paid:</strong> 10000 KZT")
+}
+
+func TestCancelOrderFollowsRouteRefundPolicy(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 1)
+	ctx := context.Background()
+	userID := f.userIDs[0]
+
+	// the fixture route leaves in 72 hours, which falls in the 50% tier
+	policies := NewRefundPolicyService(f.service.PolicyRepo)
+	_, err := policies.CreatePolicy(ctx, refundModel.RefundPolicyRequest{
+		Name:    "Half after 48h",
+		RouteID: f.routeID,
+		Tiers: []domain.RefundTier{
+			{MinHoursBefore: 96, RefundPercent: 100},
+			{MinHoursBefore: 48, RefundPercent: 50},
+		},
+	})
+	require.NoError(t, err)
+
+	purchase, err := f.service.BuyTickets(ctx, userID, f.routeID, "pm_card_visa", model.BuyTicketRequest{Quantity: 2})
+	require.NoError(t, err)
+
+	quote, err := f.service.QuoteCancellation(ctx, userID, purchase.OrderID, nil)
+	require.NoError(t, err)
+	require.True(t, quote.Cancellable)
+	require.Equal(t, 50, quote.RefundPercent)
+	require.Equal(t, quote.Paid/2, quote.Refund)
+
+	_, err = f.service.CancelOrder(ctx, userID, purchase.OrderID, nil, "")
+	require.NoError(t, err)
+	require.Equal(t, quote.Refund, f.gateway.Payments()[0].Refunded, "the refund matches the quote")
+
+	f.assertNotOversold(t, 4, 0)
+}
+
+func TestCancelTicketRejectedByRefundPolicy(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 1)
+	ctx := context.Background()
+	userID := f.userIDs[0]
+
+	policies := NewRefundPolicyService(f.service.PolicyRepo)
+	_, err := policies.CreatePolicy(ctx, refundModel.RefundPolicyRequest{
+		Name:    "Only a week ahead",
+		RouteID: f.routeID,
+		Tiers:   []domain.RefundTier{{MinHoursBefore: 168, RefundPercent: 100}},
+	})
+	require.NoError(t, err)
+
+	purchase, err := f.service.BuyTickets(ctx, userID, f.routeID, "pm_card_visa", model.BuyTicketRequest{Quantity: 1})
+	require.NoError(t, err)
+
+	quote, err := f.service.QuoteTicketCancellation(ctx, userID, purchase.Tickets[0].ID)
+	require.NoError(t, err)
+	require.False(t, quote.Cancellable)
+
+	_, err = f.service.CancelTicket(ctx, userID, purchase.Tickets[0].ID, "")
+	require.ErrorIs(t, err, errs.ErrCancellationNotAllowed)
+	require.Zero(t, f.gateway.Payments()[0].Refunded)
+
+	f.assertNotOversold(t, 4, 1)
+}
+
+func TestStripeEventBeforeTransactionIsRecordedIsRedelivered(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 1)
+	ctx := context.Background()
+	userID := f.userIDs[0]
+
+	purchase, err := f.service.BuyTickets(ctx, userID, f.routeID, mockgateway.Method3DS, model.BuyTicketRequest{Quantity: 1})
+	require.NoError(t, err)
+
+	s := f.service
+	reconciler := NewPaymentReconciler(s.PaymentRepo, s.TicketRepo, s.RouteRepo, s.LedgerRepo, s.OrderRepo, refundRepository.New(f.db))
+	intentID := "pi_" + uuid.NewString()
+	event := stripe.Event{
+		ID:   "evt_" + uuid.NewString(),
+		Type: stripe.EventTypePaymentIntentSucceeded,
+		Data: &stripe.EventData{Raw: json.RawMessage(`{"id":"` + intentID + `","object":"payment_intent"}`)},
+	}
+	t.Cleanup(func() { f.db.Exec("DELETE FROM stripe_events WHERE id = ?", event.ID) })
+
+	// the webhook is faster than the purchase recording the transaction
+	require.Error(t, reconciler.HandleStripeEvent(ctx, event))
+
+	require.NoError(t, f.db.Model(&domain.Payment{}).Where("id = ?", purchase.PaymentID).Update("transaction_id", intentID).Error)
+	require.NoError(t, reconciler.HandleStripeEvent(ctx, event), "the redelivered event is not taken for a duplicate")
+
+	var payment domain.Payment
+	require.NoError(t, f.db.First(&payment, "id = ?", purchase.PaymentID).Error)
+	require.Equal(t, "successful", payment.Status)
+}
+
+func TestBuyTicketsDeclinedCard(t *testing.T) {
+	f := newPurchaseFixture(t, 4, 1)
+
+	_, err := f.service.BuyTickets(context.Background(), f.userIDs[0], f.routeID, mockgateway.MethodDeclined, model.BuyTicketRequest{Quantity: 1})
+	require.Error(t, err)
+
+	// the reservation made before the charge is given back
+	var payment domain.Payment
+	require.NoError(t, f.db.First(&payment, "user_id = ?", f.userIDs[0]).Error)
+	require.Equal(t, "failed", payment.Status)
+	var order domain.Order
+	require.NoError(t, f.db.First(&order, "payment_id = ?", payment.ID).Error)
+	require.Equal(t, domain.OrderCancelled, order.Status)
+
+	f.assertNotOversold(t, 4, 0)
+}
+
+// flakyRefunds fails the first refunds it is asked for. With made set the
+// refund goes through and only its answer is lost.
+type flakyRefunds struct {
+	PaymentProcessor
+	failures int
+	made     bool
+}
+
+func (p *flakyRefunds) Refund(ctx context.Context, transactionID string, amount int) (bool, error) {
+	if p.failures == 0 {
+		return p.PaymentProcessor.Refund(ctx, transactionID, amount)
+	}
+	p.failures--
+	if p.made {
+		if _, err := p.PaymentProcessor.Refund(ctx, transactionID, amount); err != nil {
+			return false, err
+		}
+	}
+	return false, errors.New("connection reset")
+}
+
+func TestCancelOrderRetriesFailedRefund(t *testing.T) {
+	for _, made := range []bool{false, true} {
+		t.Run(fmt.Sprintf("made=%v", made), func(t *testing.T) {
+			f := newPurchaseFixture(t, 4, 1)
+			ctx := context.Background()
+			userID := f.userIDs[0]
+
+			purchase, err := f.service.BuyTickets(ctx, userID, f.routeID, "pm_card_visa", model.BuyTicketRequest{Quantity: 2})
+			require.NoError(t, err)
+
+			payments, err := NewPaymentRegistry(MockGatewayProvider, &flakyRefunds{PaymentProcessor: f.service.Payments.Active(), failures: 1, made: made})
+			require.NoError(t, err)
+			f.service.Payments, f.service.Refunds.Payments = payments, payments
+
+			// the cancellation stands although the provider failed the refund
+			order, err := f.service.CancelOrder(ctx, userID, purchase.OrderID, nil, "")
+			require.NoError(t, err)
+			require.Equal(t, domain.OrderCancelled, order.Status)
+
+			var refund domain.Refund
+			require.NoError(t, f.db.First(&refund, "payment_id = ?", purchase.PaymentID).Error)
+			require.Equal(t, domain.RefundPending, refund.Status)
+			require.Equal(t, "connection reset", refund.LastError)
+
+			ledgers := NewLedgerService(f.service.LedgerRepo, f.service.PaymentRepo, f.service.OrderRepo, f.service.Payments)
+			ledger, err := ledgers.PaymentLedger(ctx, purchase.PaymentID)
+			require.NoError(t, err)
+			require.Zero(t, ledger.Refunded)
+
+			require.NoError(t, f.db.Model(&domain.Refund{}).Where("id = ?", refund.ID).Update("next_attempt_at", time.Now().Add(-time.Second)).Error)
+			settled, err := f.service.Refunds.Process(ctx)
+			require.NoError(t, err)
+			require.Equal(t, 1, settled)
+
+			// a refund made on the lost attempt is not made a second time
+			require.Equal(t, purchase.Tickets[0].Price*2, f.gateway.Payments()[0].Refunded)
+			ledger, err = ledgers.PaymentLedger(ctx, purchase.PaymentID)
+			require.NoError(t, err)
+			require.Zero(t, ledger.Balance)
+			check, err := ledgers.CheckPayment(ctx, purchase.PaymentID)
+			require.NoError(t, err)
+			require.True(t, check.Consistent, "%+v", check)
+
+			require.NoError(t, f.db.First(&refund, "id = ?", refund.ID).Error)
+			require.Equal(t, domain.RefundSucceeded, refund.Status)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"bytes"
+	"fmt"
+	"github.com/go-pdf/fpdf"
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"time"
+)
+
+const (
+	pdfFont   = "go"
+	pdfMargin = 15.0
+)
+
+var almaty = time.FixedZone("Almaty", 5*60*60)
+
+// RenderTicketPDF draws a printable e-ticket. The QR code holds the signed
+// token of the ticket, the same one conductors scan from the email. The Go
+// fonts are embedded so Cyrillic and Kazakh place names render.
+func RenderTicketPDF(ticket *domain.Ticket, route *domain.Route, bus *domain.Bus, token string) ([]byte, error) {
+	qr, err := qrcode.Encode(token, qrcode.Medium, 512)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate QR code: %w", err)
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("AulWay e-ticket "+ticket.OrderNumber, true)
+	pdf.SetAuthor("AulWay", true)
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.AddUTF8FontFromBytes(pdfFont, "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes(pdfFont, "B", gobold.TTF)
+	pdf.AddPage()
+
+	width, _ := pdf.GetPageSize()
+	content := width - 2*pdfMargin
+
+	// brand header
+	pdf.SetFillColor(45, 137, 239)
+	pdf.Rect(0, 0, width, 28, "F")
+	pdf.SetTextColor(255, 255, 255)
+	pdf.SetFont(pdfFont, "B", 22)
+	pdf.SetXY(pdfMargin, 8)
+	pdf.CellFormat(content/2, 12, "AulWay", "", 0, "L", false, 0, "")
+	pdf.SetFont(pdfFont, "", 12)
+	pdf.CellFormat(content/2, 12, "Электронный билет / E-ticket", "", 0, "R", false, 0, "")
+
+	pdf.SetTextColor(33, 37, 41)
+	pdf.SetXY(pdfMargin, 38)
+	pdf.SetFont(pdfFont, "B", 18)
+	pdf.CellFormat(content, 10, route.Departure+" → "+route.Destination, "", 1, "L", false, 0, "")
+
+	pdf.Ln(4)
+	rows := [][2]string{
+		{"Номер заказа", ticket.OrderNumber},
+		{"Автобус №", bus.Number},
+		{"Место", ticket.SeatNumber},
+		{"Отправление", route.StartDate.In(almaty).Format("02 Jan 2006 15:04") + " (GMT+05 Алматы)"},
+		{"Прибытие", route.EndDate.In(almaty).Format("02 Jan 2006 15:04") + " (GMT+05 Алматы)"},
+		{"Адрес посадки", route.DepartureLocation},
+		{"Адрес высадки", route.DestinationLocation},
+		{"Цена", fmt.Sprintf("%d ₸", ticket.Price)},
+	}
+
+	qrSize := 60.0
+	labelWidth := 40.0
+	valueWidth := content - labelWidth - qrSize - 5
+	top := pdf.GetY()
+	for _, row := range rows {
+		pdf.SetX(pdfMargin)
+		pdf.SetFont(pdfFont, "", 10)
+		pdf.SetTextColor(108, 117, 125)
+		pdf.CellFormat(labelWidth, 9, row[0], "", 0, "L", false, 0, "")
+		pdf.SetFont(pdfFont, "B", 12)
+		pdf.SetTextColor(33, 37, 41)
+		pdf.MultiCell(valueWidth, 9, row[1], "", "L", false)
+	}
+
+	pdf.RegisterImageOptionsReader("qr", fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(qr))
+	pdf.ImageOptions("qr", width-pdfMargin-qrSize, top, qrSize, qrSize, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	pdf.SetFont(pdfFont, "", 8)
+	pdf.SetXY(width-pdfMargin-qrSize, top+qrSize+1)
+	pdf.CellFormat(qrSize, 5, "Покажите код при посадке", "", 0, "C", false, 0, "")
+
+	pdf.SetY(pdf.GetY() + 12)
+	pdf.SetDrawColor(222, 226, 230)
+	pdf.Line(pdfMargin, pdf.GetY(), width-pdfMargin, pdf.GetY())
+	pdf.Ln(4)
+	pdf.SetFont(pdfFont, "", 9)
+	pdf.SetTextColor(108, 117, 125)
+	pdf.MultiCell(content, 5, "Билет действителен только для указанного места и рейса. "+
+		"Приходите на посадку не позднее чем за 15 минут до отправления. Спасибо, что пользуетесь AulWay!", "", "L", false)
+
+	if err := pdf.Error(); err != nil {
+		return nil, fmt.Errorf("failed to render ticket pdf: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render ticket pdf: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTicketPDF(t *testing.T) {
+	start := time.Date(2025, 12, 12, 8, 0, 0, 0, time.UTC)
+	ticket := &domain.Ticket{ID: "ticket-1", RouteID: "route-1", SeatNumber: "3B", Price: 5000, OrderNumber: "AW-00000042"}
+	route := &domain.Route{
+		Id:                  "route-1",
+		Departure:           "Алматы",
+		Destination:         "Астана",
+		DepartureLocation:   "Сайран автовокзал",
+		DestinationLocation: "Астана автовокзал",
+		StartDate:           start,
+		EndDate:             start.Add(12 * time.Hour),
+	}
+
+	pdf, err := RenderTicketPDF(ticket, route, &domain.Bus{Number: "777 ABC 02"}, NewTicketSigner("secret").Sign(ticket))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(pdf, []byte("%PDF-")))
+	require.Greater(t, len(pdf), 5000, "fonts and the QR image are embedded")
+}
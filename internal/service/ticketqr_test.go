@@ -0,0 +1,43 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/utils/errs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTicketSignerRoundTrip(t *testing.T) {
+	signer := NewTicketSigner("secret")
+	ticket := &domain.Ticket{ID: "ticket-1", RouteID: "route-1", SeatNumber: "3B", UserID: "user-1", PaymentID: "payment-1"}
+
+	token := signer.Sign(ticket)
+	require.NotContains(t, token, "user-1", "the token must not leak the passenger")
+	require.Less(t, len(token), 120)
+
+	claims, err := signer.Verify(token)
+	require.NoError(t, err)
+	require.Equal(t, &TicketClaims{TicketID: "ticket-1", RouteID: "route-1", Seat: "3B"}, claims)
+}
+
+func TestTicketSignerRejectsForgeries(t *testing.T) {
+	signer := NewTicketSigner("secret")
+	token := signer.Sign(&domain.Ticket{ID: "ticket-1", RouteID: "route-1", SeatNumber: "3B"})
+	parts := strings.Split(token, ".")
+
+	forged := NewTicketSigner("other").Sign(&domain.Ticket{ID: "ticket-1", RouteID: "route-1", SeatNumber: "3B"})
+	otherSeat := strings.Split(NewTicketSigner("other").Sign(&domain.Ticket{ID: "ticket-1", RouteID: "route-1", SeatNumber: "1A"}), ".")[1]
+	swapped := parts[0] + "." + otherSeat + "." + parts[2]
+
+	for name, token := range map[string]string{
+		"other key":      forged,
+		"edited payload": swapped,
+		"no signature":   parts[0] + "." + parts[1],
+		"old json code":  `{"id":"ticket-1","route_id":"route-1"}`,
+	} {
+		_, err := signer.Verify(token)
+		require.ErrorIs(t, err, errs.ErrInvalidTicketToken, name)
+	}
+}
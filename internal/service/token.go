@@ -0,0 +1,189 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/firebase"
+	repoErrs "aulway/internal/repository/errs"
+	"aulway/internal/repository/user"
+	"aulway/internal/utils/errs"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+// TokenVerifier turns a bearer token into who it was issued to. A verifier
+// returns ErrUnsupportedToken for tokens it does not issue, so the next one
+// can try.
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (*domain.Principal, error)
+}
+
+// TokenChain accepts a token the first verifier that supports it accepts.
+type TokenChain []TokenVerifier
+
+func (c TokenChain) Verify(ctx context.Context, token string) (*domain.Principal, error) {
+	for _, verifier := range c {
+		principal, err := verifier.Verify(ctx, token)
+		if errors.Is(err, errs.ErrUnsupportedToken) {
+			continue
+		}
+
+		return principal, err
+	}
+
+	return nil, errs.ErrInvalidToken
+}
+
+// FirebaseAuth accepts Firebase ID tokens. Their users are found by Firebase
+// uid, linked by verified email or phone on their first request, or signed
+// up. An account whose email was never verified with us is not linked, the
+// email could have been typed in by someone else.
+type FirebaseAuth struct {
+	Verifier *firebase.IDTokenVerifier
+	Repo     user.Repository
+}
+
+func NewFirebaseAuth(verifier *firebase.IDTokenVerifier, userRepo user.Repository) *FirebaseAuth {
+	return &FirebaseAuth{
+		Verifier: verifier,
+		Repo:     userRepo,
+	}
+}
+
+func (f *FirebaseAuth) Verify(ctx context.Context, token string) (*domain.Principal, error) {
+	if tokenAlg(token) != jwt.SigningMethodRS256.Alg() {
+		return nil, errs.ErrUnsupportedToken
+	}
+
+	idToken, err := f.Verifier.Verify(ctx, token)
+	if errors.Is(err, firebase.ErrInvalidIDToken) {
+		slog.Debug("firebase token rejected", slog.String("error", err.Error()))
+		return nil, errs.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	usr, err := f.user(ctx, idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.Principal{UserID: usr.ID, Role: usr.Role}, nil
+}
+
+// user returns the user of the Firebase user, linking or creating it on the
+// first request.
+func (f *FirebaseAuth) user(ctx context.Context, idToken *firebase.IDToken) (*domain.User, error) {
+	usr, err := f.Repo.GetUserByFbUid(ctx, idToken.UID())
+	if !errors.Is(err, repoErrs.ErrRecordNotFound) {
+		return usr, err
+	}
+
+	// an email Firebase did not verify could be anyone's, and so could one
+	// the user did not verify with us
+	if idToken.Email != "" && idToken.EmailVerified {
+		usr, err := f.Repo.GetByEmail(ctx, idToken.Email)
+		if err == nil && !usr.EmailVerified {
+			return nil, errs.ErrAccountExists
+		}
+		if err == nil {
+			return f.link(ctx, usr, idToken)
+		}
+		if !errors.Is(err, repoErrs.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	// Firebase only puts phones it verified in tokens
+	if phone, err := NormalizePhone(idToken.PhoneNumber); err == nil {
+		usr, err := f.Repo.GetByVerifiedPhone(ctx, phone)
+		if err == nil {
+			return f.link(ctx, usr, idToken)
+		}
+		if !errors.Is(err, repoErrs.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	return f.signup(ctx, idToken)
+}
+
+func (f *FirebaseAuth) link(ctx context.Context, usr *domain.User, idToken *firebase.IDToken) (*domain.User, error) {
+	err := f.Repo.LinkFirebase(ctx, usr.ID, idToken.UID())
+	if errors.Is(err, repoErrs.ErrRecordNotFound) || errors.Is(err, repoErrs.FirebaseUserAlreadyLinked) {
+		// another request linked it meanwhile, or the account belongs to
+		// another Firebase user
+		return f.linked(ctx, idToken.UID())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("firebase user linked", slog.String("user_id", usr.ID), slog.String("provider", idToken.Firebase.SignInProvider))
+	return usr, nil
+}
+
+func (f *FirebaseAuth) signup(ctx context.Context, idToken *firebase.IDToken) (*domain.User, error) {
+	userid, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+
+	uid := idToken.UID()
+	firstName, lastName, _ := strings.Cut(strings.TrimSpace(idToken.Name), " ")
+	usr := &domain.User{
+		ID:          userid.String(),
+		FirebaseUID: &uid,
+		FirstName:   firstName,
+		LastName:    strings.TrimSpace(lastName),
+		Role:        userRole,
+	}
+	if idToken.EmailVerified {
+		usr.Email = idToken.Email
+		usr.EmailVerified = usr.Email != ""
+	}
+	if phone, err := NormalizePhone(idToken.PhoneNumber); err == nil {
+		usr.Phone = phone
+		usr.PhoneVerified = true
+	}
+
+	err = f.Repo.Create(ctx, usr)
+	if errors.Is(err, repoErrs.FirebaseUserAlreadyLinked) {
+		return f.linked(ctx, uid)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign up firebase user: %w", err)
+	}
+
+	slog.Info("firebase user signed up", slog.String("user_id", usr.ID), slog.String("provider", idToken.Firebase.SignInProvider))
+	return usr, nil
+}
+
+// linked returns the user the Firebase user got linked to by a concurrent
+// request, the token is refused when there is none.
+func (f *FirebaseAuth) linked(ctx context.Context, uid string) (*domain.User, error) {
+	usr, err := f.Repo.GetUserByFbUid(ctx, uid)
+	if errors.Is(err, repoErrs.ErrRecordNotFound) {
+		return nil, errs.ErrInvalidToken
+	}
+
+	return usr, err
+}
+
+// tokenAlg is the signing algorithm named in the header of a JWT, empty when
+// it is not one.
+func tokenAlg(token string) string {
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		return ""
+	}
+
+	alg, _ := parsed.Header["alg"].(string)
+	return alg
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	"aulway/internal/firebase"
+	userModel "aulway/internal/handler/user/model"
+	sessionRepository "aulway/internal/repository/session"
+	userRepository "aulway/internal/repository/user"
+	"aulway/internal/utils/errs"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	mrand "math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+)
+
+type verifierFunc func(ctx context.Context, token string) (*domain.Principal, error)
+
+func (f verifierFunc) Verify(ctx context.Context, token string) (*domain.Principal, error) {
+	return f(ctx, token)
+}
+
+func TestTokenChainTriesVerifiersInTurn(t *testing.T) {
+	unsupported := verifierFunc(func(context.Context, string) (*domain.Principal, error) {
+		return nil, errs.ErrUnsupportedToken
+	})
+	accepting := verifierFunc(func(_ context.Context, token string) (*domain.Principal, error) {
+		return &domain.Principal{UserID: token}, nil
+	})
+	rejecting := verifierFunc(func(context.Context, string) (*domain.Principal, error) {
+		return nil, errs.ErrInvalidToken
+	})
+	ctx := context.Background()
+
+	principal, err := TokenChain{unsupported, accepting}.Verify(ctx, "user-1")
+	require.NoError(t, err)
+	require.Equal(t, "user-1", principal.UserID)
+
+	// the first verifier supporting the token decides
+	_, err = TokenChain{rejecting, accepting}.Verify(ctx, "user-1")
+	require.ErrorIs(t, err, errs.ErrInvalidToken)
+
+	_, err = TokenChain{unsupported}.Verify(ctx, "user-1")
+	require.ErrorIs(t, err, errs.ErrInvalidToken)
+}
+
+func TestVerifiersTellTheirTokensApart(t *testing.T) {
+	sessions := NewSessionService(sessionRepository.Repository{}, userRepository.Repository{}, nil, "secret", time.Minute, time.Hour)
+	ctx := context.Background()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rs256, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{Subject: "uid"}).SignedString(key)
+	require.NoError(t, err)
+
+	_, err = sessions.Verify(ctx, rs256)
+	require.ErrorIs(t, err, errs.ErrUnsupportedToken)
+	_, err = sessions.Verify(ctx, "not a token")
+	require.ErrorIs(t, err, errs.ErrUnsupportedToken)
+
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{UserID: "user-1", Role: userRole, SessionID: "session-1"}).SignedString([]byte("other"))
+	require.NoError(t, err)
+	_, err = sessions.Verify(ctx, forged)
+	require.ErrorIs(t, err, errs.ErrInvalidToken)
+
+	// tokens from before sessions name none
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{UserID: "user-1", Role: userRole}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = sessions.Verify(ctx, legacy)
+	require.ErrorIs(t, err, errs.ErrInvalidToken)
+
+	// Firebase keeps away from local tokens
+	firebaseAuth := NewFirebaseAuth(firebase.NewIDTokenVerifier("aulway-test"), userRepository.Repository{})
+	_, err = firebaseAuth.Verify(ctx, legacy)
+	require.ErrorIs(t, err, errs.ErrUnsupportedToken)
+}
+
+// firebaseIssuer mints Firebase ID tokens of the project with a local key and
+// serves its certificate.
+type firebaseIssuer struct {
+	project string
+	key     *rsa.PrivateKey
+	url     string
+}
+
+func newFirebaseIssuer(t *testing.T, project string) *firebaseIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "securetoken.system.gserviceaccount.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		json.NewEncoder(w).Encode(map[string]string{"test-key": string(cert)})
+	}))
+	t.Cleanup(server.Close)
+
+	return &firebaseIssuer{project: project, key: key, url: server.URL}
+}
+
+func (i *firebaseIssuer) verifier() *firebase.IDTokenVerifier {
+	verifier := firebase.NewIDTokenVerifier(i.project)
+	verifier.CertsURL = i.url
+
+	return verifier
+}
+
+func (i *firebaseIssuer) token(t *testing.T, uid, email string, emailVerified bool, phone string) string {
+	t.Helper()
+
+	now := time.Now()
+	claims := firebase.IDToken{
+		Email:         email,
+		EmailVerified: emailVerified,
+		PhoneNumber:   phone,
+		Name:          "Aruzhan Seitkali",
+		AuthTime:      now.Unix(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "https://securetoken.google.com/" + i.project,
+			Audience:  jwt.ClaimStrings{i.project},
+			Subject:   uid,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	signed, err := token.SignedString(i.key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func TestFirebaseUsersAreLinkedOrSignedUp(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	users := userRepository.NewRepository(db)
+	issuer := newFirebaseIssuer(t, "aulway-test")
+	firebaseAuth := NewFirebaseAuth(issuer.verifier(), users)
+	run := mrand.Int()
+
+	// an email set on an account without a code links nothing, whoever set it
+	// need not own it
+	existing := &domain.User{ID: fmt.Sprintf("firebase-test-%d", run), Email: fmt.Sprintf("firebase-%d@example.com", run), EmailVerified: true, Role: userRole}
+	require.NoError(t, db.Create(existing).Error)
+	claimed := fmt.Sprintf("claimed-%d@example.com", run)
+	_, err := NewUserService(users, nil, nil).UpdateUser(ctx, userModel.UpdateUserRequest{Email: &claimed}, existing.ID)
+	require.NoError(t, err)
+
+	linkedUID := fmt.Sprintf("uid-linked-%d", run)
+	_, err = firebaseAuth.Verify(ctx, issuer.token(t, linkedUID, claimed, true, ""))
+	require.ErrorIs(t, err, errs.ErrAccountExists)
+
+	// a verified email links the existing account
+	require.NoError(t, users.VerifyEmail(ctx, existing.ID, claimed))
+	existing.Email = claimed
+	principal, err := firebaseAuth.Verify(ctx, issuer.token(t, linkedUID, existing.Email, true, ""))
+	require.NoError(t, err)
+	require.Equal(t, existing.ID, principal.UserID)
+	require.Equal(t, userRole, principal.Role)
+	require.Empty(t, principal.SessionID)
+
+	linked, err := users.Get(ctx, existing.ID)
+	require.NoError(t, err)
+	require.Equal(t, linkedUID, *linked.FirebaseUID)
+
+	// another Firebase user with the same email does not take it over
+	_, err = firebaseAuth.Verify(ctx, issuer.token(t, fmt.Sprintf("uid-other-%d", run), existing.Email, true, ""))
+	require.ErrorIs(t, err, errs.ErrInvalidToken)
+
+	// an unverified email signs up a new account without it
+	newUID := fmt.Sprintf("uid-new-%d", run)
+	phone := testPhone()
+	principal, err = firebaseAuth.Verify(ctx, issuer.token(t, newUID, fmt.Sprintf("unverified-%d@example.com", run), false, phone))
+	require.NoError(t, err)
+	require.NotEqual(t, existing.ID, principal.UserID)
+
+	signedUp, err := users.Get(ctx, principal.UserID)
+	require.NoError(t, err)
+	require.Empty(t, signedUp.Email)
+	require.Equal(t, phone, signedUp.Phone)
+	require.True(t, signedUp.PhoneVerified)
+	require.Equal(t, "Aruzhan", signedUp.FirstName)
+	require.Equal(t, "Seitkali", signedUp.LastName)
+
+	// and is found by uid from then on
+	again, err := firebaseAuth.Verify(ctx, issuer.token(t, newUID, "", false, ""))
+	require.NoError(t, err)
+	require.Equal(t, principal.UserID, again.UserID)
+
+	// a verified phone links the account it is verified for
+	rider := &domain.User{ID: fmt.Sprintf("firebase-phone-test-%d", run), Phone: testPhone(), PhoneVerified: true, Role: userRole}
+	require.NoError(t, db.Create(rider).Error)
+	byPhone, err := firebaseAuth.Verify(ctx, issuer.token(t, fmt.Sprintf("uid-phone-%d", run), "", false, "8"+rider.Phone[2:]))
+	require.NoError(t, err)
+	require.Equal(t, rider.ID, byPhone.UserID)
+
+	// the chain hands Firebase tokens on past local access tokens
+	sessions := NewSessionService(sessionRepository.New(db), users, nil, "secret", time.Minute, time.Hour)
+	chained, err := TokenChain{sessions, firebaseAuth}.Verify(ctx, issuer.token(t, newUID, "", false, ""))
+	require.NoError(t, err)
+	require.Equal(t, principal.UserID, chained.UserID)
+}
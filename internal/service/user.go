@@ -4,6 +4,7 @@ import (
 	"aulway/internal/domain"
 	auth "aulway/internal/handler/auth/model"
 	"aulway/internal/handler/user/model"
+	"aulway/internal/ratelimit"
 	"aulway/internal/repository/errs"
 	"aulway/internal/repository/user"
 	"context"
@@ -16,12 +17,16 @@ import (
 const userRole = "user"
 
 type User struct {
-	repo user.Repository
+	repo     user.Repository
+	sessions *SessionService
+	lockout  *ratelimit.Lockout
 }
 
-func NewUserService(userRepo user.Repository) *User {
+func NewUserService(userRepo user.Repository, sessions *SessionService, lockout *ratelimit.Lockout) *User {
 	return &User{
-		repo: userRepo,
+		repo:     userRepo,
+		sessions: sessions,
+		lockout:  lockout,
 	}
 }
 
@@ -54,6 +59,7 @@ func (service *User) CreateUser(ctx context.Context, request auth.SignupRequest)
 	usr := &domain.User{
 		ID:                   userid.String(),
 		Email:                request.Email,
+		EmailVerified:        true,
 		Role:                 userRole,
 		Password:             string(encryptedPassword),
 		RequirePasswordReset: false,
@@ -63,16 +69,28 @@ func (service *User) CreateUser(ctx context.Context, request auth.SignupRequest)
 	return usr, err
 }
 
+// ValidateUser checks the password of the user, wrong passwords lock the
+// account out for longer and longer.
 func (service *User) ValidateUser(ctx context.Context, signin auth.SigninRequest) (*domain.User, error) {
+	account := emailAccount(signin.Email)
+	if err := service.lockout.Check(ctx, account); err != nil {
+		return nil, err
+	}
+
 	usr, err := service.repo.GetByEmail(ctx, signin.Email)
 	if err != nil {
 		return nil, errs.ErrRecordNotFound
 	}
 
 	if bcrypt.CompareHashAndPassword([]byte(usr.Password), []byte(signin.Password)) != nil {
+		if err := service.lockout.Fail(ctx, account); err != nil {
+			return nil, err
+		}
 		return nil, errors.New("invalid email or password")
 	}
 
+	service.lockout.Reset(ctx, account)
+
 	return usr, nil
 }
 
@@ -85,16 +103,28 @@ func (service *User) UpdateUser(ctx context.Context, req model.UpdateUserRequest
 	if req.LastName != nil {
 		updates["last_name"] = *req.LastName
 	}
-	if req.Email != nil {
-		if err := req.ValidateEmail(*req.Email); err != nil {
+	if req.Email != nil || req.Phone != nil {
+		current, err := service.repo.Get(ctx, id)
+		if err != nil {
 			return nil, err
 		}
 
-		updates["email"] = *req.Email
+		// a changed email links provider accounts only after it is verified
+		// again, anyone could type in someone else's
+		if req.Email != nil && *req.Email != current.Email {
+			if err := req.ValidateEmail(*req.Email); err != nil {
+				return nil, err
+			}
 
-	}
-	if req.Phone != nil {
-		updates["phone"] = *req.Phone
+			updates["email"] = *req.Email
+			updates["email_verified"] = false
+		}
+
+		// a changed phone signs in only after it is verified again
+		if req.Phone != nil && *req.Phone != current.Phone {
+			updates["phone"] = *req.Phone
+			updates["phone_verified"] = false
+		}
 	}
 
 	err := service.repo.Update(ctx, updates, id)
@@ -130,7 +160,11 @@ func (service *User) ResetPassword(ctx context.Context, req model.ResetPasswordR
 	}
 
 	err = service.repo.UpdatePassword(ctx, usr.Email, string(encryptedPassword), requirePasswordReset)
-	return err
+	if err != nil {
+		return err
+	}
+
+	return service.sessions.RevokeAll(ctx, usr.ID)
 }
 
 func (service *User) GetUsers(ctx context.Context, page, pageSize int) ([]domain.User, error) {
@@ -138,5 +172,9 @@ func (service *User) GetUsers(ctx context.Context, page, pageSize int) ([]domain
 }
 
 func (service *User) DeleteUser(ctx context.Context, id string) error {
-	return service.repo.Delete(ctx, id)
+	if err := service.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	return service.sessions.RevokeAll(ctx, id)
 }
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"aulway/internal/domain"
+	busRepo "aulway/internal/repository/bus"
+	repoErrs "aulway/internal/repository/errs"
+	routeRepo "aulway/internal/repository/route"
+	ticketRepo "aulway/internal/repository/ticket"
+	walletRepo "aulway/internal/repository/wallet"
+	"aulway/internal/utils/errs"
+	"aulway/internal/wallet"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PassUpdates is told about changes the wallet passes of tickets show.
+type PassUpdates interface {
+	TicketsChanged(ctx context.Context, ticketIDs ...string)
+	RouteChanged(ctx context.Context, routeID string)
+}
+
+// WalletService issues Apple Wallet and Google Wallet passes of tickets and
+// keeps the saved passes up to date. Either wallet is optional, its
+// endpoints answer ErrWalletNotConfigured without the credentials.
+type WalletService struct {
+	TicketRepo ticketRepo.Repository
+	RouteRepo  routeRepo.Repository
+	BusRepo    busRepo.Repository
+	WalletRepo walletRepo.Repository
+	Signer     *TicketSigner
+	Apple      *wallet.Apple
+	APNs       *wallet.APNs
+	Google     *wallet.Google
+}
+
+func NewWalletService(ticketRepo ticketRepo.Repository, routeRepo routeRepo.Repository, busRepo busRepo.Repository, walletRepo walletRepo.Repository, signer *TicketSigner, apple *wallet.Apple, google *wallet.Google) *WalletService {
+	s := &WalletService{
+		TicketRepo: ticketRepo,
+		RouteRepo:  routeRepo,
+		BusRepo:    busRepo,
+		WalletRepo: walletRepo,
+		Signer:     signer,
+		Apple:      apple,
+		Google:     google,
+	}
+	if apple != nil {
+		s.APNs = wallet.NewAPNs(wallet.APNsHost, apple.Certificate())
+	}
+
+	return s
+}
+
+// ApplePass builds the signed .pkpass bundle of a ticket.
+func (s *WalletService) ApplePass(ctx context.Context, userID, ticketID string) (*domain.Ticket, []byte, error) {
+	if s.Apple == nil {
+		return nil, nil, errs.ErrWalletNotConfigured
+	}
+
+	ticket, err := s.ownTicket(ctx, userID, ticketID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pass, err := s.applePass(ctx, ticket)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ticket, pass, nil
+}
+
+// GoogleLink returns the "Add to Google Wallet" link of a ticket.
+func (s *WalletService) GoogleLink(ctx context.Context, userID, ticketID string) (*domain.GoogleWalletLink, error) {
+	if s.Google == nil {
+		return nil, errs.ErrWalletNotConfigured
+	}
+
+	ticket, err := s.ownTicket(ctx, userID, ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	route, err := s.RouteRepo.Get(ctx, ticket.RouteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch route: %w", err)
+	}
+
+	url, err := s.Google.SaveURL(s.Google.TransitObject(ticket, route, s.Signer.Sign(ticket)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.GoogleWalletLink{SaveURL: url}, nil
+}
+
+// ownTicket loads a ticket of the user that can be added to a wallet.
+func (s *WalletService) ownTicket(ctx context.Context, userID, ticketID string) (*domain.Ticket, error) {
+	ticket, err := s.TicketRepo.Get(ctx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	if ticket.UserID != userID {
+		return nil, repoErrs.ErrRecordNotFound
+	}
+	if !wallet.Valid(ticket) {
+		return nil, fmt.Errorf("%w: ticket is %s", errs.ErrTicketNotBoardable, ticket.Status)
+	}
+
+	return ticket, nil
+}
+
+func (s *WalletService) applePass(ctx context.Context, ticket *domain.Ticket) ([]byte, error) {
+	route, err := s.RouteRepo.Get(ctx, ticket.RouteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch route: %w", err)
+	}
+
+	bus, err := s.BusRepo.Get(ctx, route.BusId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bus: %w", err)
+	}
+
+	pass := s.Apple.Pass(ticket, route, bus, s.Signer.Sign(ticket), s.passAuthToken(ticket.ID))
+	return s.Apple.Bundle(pass)
+}
+
+// passAuthToken is the authenticationToken of a pass. It is derived from the
+// serial number, so it needs no storage and is the same in every download.
+func (s *WalletService) passAuthToken(serial string) string {
+	return s.Signer.SignDocument([]byte("wallet:" + serial))
+}
+
+// Authorize checks the "ApplePass <token>" Authorization header a device
+// sends for a pass.
+func (s *WalletService) Authorize(passTypeID, serial, authorization string) error {
+	if s.Apple == nil {
+		return errs.ErrWalletNotConfigured
+	}
+
+	token, ok := strings.CutPrefix(authorization, "ApplePass ")
+	if !ok || passTypeID != s.Apple.PassTypeID ||
+		subtle.ConstantTimeCompare([]byte(token), []byte(s.passAuthToken(serial))) != 1 {
+		return errs.ErrWalletUnauthorized
+	}
+
+	return nil
+}
+
+// RegisterDevice subscribes a device to the updates of a pass. It reports
+// false when the device was already registered.
+func (s *WalletService) RegisterDevice(ctx context.Context, deviceID, passTypeID, serial, pushToken string) (bool, error) {
+	return s.WalletRepo.Register(ctx, &domain.WalletRegistration{
+		DeviceLibraryID: deviceID,
+		PassTypeID:      passTypeID,
+		SerialNumber:    serial,
+		PushToken:       pushToken,
+	})
+}
+
+func (s *WalletService) UnregisterDevice(ctx context.Context, deviceID, passTypeID, serial string) error {
+	return s.WalletRepo.Unregister(ctx, deviceID, passTypeID, serial)
+}
+
+// UpdatedPasses lists the passes of a device changed since the tag of its
+// previous request, nil when nothing changed. The tag is a Unix time in
+// microseconds.
+func (s *WalletService) UpdatedPasses(ctx context.Context, deviceID, passTypeID, since string) (*domain.UpdatedPasses, error) {
+	var after time.Time
+	if since != "" {
+		micros, err := strconv.ParseInt(since, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid passesUpdatedSince: %w", err)
+		}
+		after = time.UnixMicro(micros)
+	}
+
+	serials, last, err := s.WalletRepo.UpdatedSerials(ctx, deviceID, passTypeID, after)
+	if err != nil {
+		return nil, err
+	}
+	if len(serials) == 0 {
+		return nil, nil
+	}
+
+	return &domain.UpdatedPasses{
+		SerialNumbers: serials,
+		LastUpdated:   strconv.FormatInt(last.UnixMicro(), 10),
+	}, nil
+}
+
+// LatestPass builds the current version of a pass for a registered device.
+// Cancelled tickets are still served, their pass shows as voided.
+func (s *WalletService) LatestPass(ctx context.Context, serial string) ([]byte, time.Time, error) {
+	if s.Apple == nil {
+		return nil, time.Time{}, errs.ErrWalletNotConfigured
+	}
+
+	ticket, err := s.TicketRepo.Get(ctx, serial)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	pass, err := s.applePass(ctx, ticket)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return pass, ticket.UpdatedAt, nil
+}
+
+// RouteChanged marks the tickets of the route as changed and sends their
+// passes the update in the background.
+func (s *WalletService) RouteChanged(ctx context.Context, routeID string) {
+	tickets, err := s.TicketRepo.TouchByRoute(ctx, routeID)
+	if err != nil {
+		slog.Error("failed to mark route tickets changed", slog.String("route_id", routeID), slog.String("error", err.Error()))
+		return
+	}
+	if len(tickets) == 0 || (s.APNs == nil && s.Google == nil) {
+		return
+	}
+
+	go s.push(context.WithoutCancel(ctx), tickets)
+}
+
+// TicketsChanged sends the passes of the tickets their update in the
+// background.
+func (s *WalletService) TicketsChanged(ctx context.Context, ticketIDs ...string) {
+	if len(ticketIDs) == 0 || (s.APNs == nil && s.Google == nil) {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		tickets := make([]domain.Ticket, 0, len(ticketIDs))
+		for _, id := range ticketIDs {
+			ticket, err := s.TicketRepo.Get(ctx, id)
+			if err != nil {
+				slog.Error("failed to load ticket for pass update", slog.String("ticket_id", id), slog.String("error", err.Error()))
+				continue
+			}
+			tickets = append(tickets, *ticket)
+		}
+		s.push(ctx, tickets)
+	}()
+}
+
+// push notifies the devices holding an Apple pass of the tickets, they then
+// download it again, and patches the Google Wallet objects in place.
+func (s *WalletService) push(ctx context.Context, tickets []domain.Ticket) {
+	ids := make([]string, 0, len(tickets))
+	for _, ticket := range tickets {
+		ids = append(ids, ticket.ID)
+	}
+
+	if s.APNs != nil {
+		registrations, err := s.WalletRepo.PushTokens(ctx, ids)
+		if err != nil {
+			slog.Error("failed to list wallet devices", slog.String("error", err.Error()))
+		}
+
+		pushed := make(map[string]bool, len(registrations))
+		for _, registration := range registrations {
+			if pushed[registration.PushToken] {
+				continue
+			}
+			pushed[registration.PushToken] = true
+
+			err := s.APNs.Push(ctx, registration.PassTypeID, registration.PushToken)
+			if errors.Is(err, wallet.ErrDeviceUnregistered) {
+				err = s.WalletRepo.DeletePushToken(ctx, registration.PushToken)
+			}
+			if err != nil {
+				slog.Error("failed to push pass update", slog.String("device", registration.DeviceLibraryID), slog.String("error", err.Error()))
+			}
+		}
+	}
+
+	if s.Google != nil {
+		routes := make(map[string]*domain.Route)
+		for i := range tickets {
+			ticket := &tickets[i]
+			route, ok := routes[ticket.RouteID]
+			if !ok {
+				var err error
+				route, err = s.RouteRepo.Get(ctx, ticket.RouteID)
+				if err != nil {
+					slog.Error("failed to fetch route for pass update", slog.String("route_id", ticket.RouteID), slog.String("error", err.Error()))
+					continue
+				}
+				routes[ticket.RouteID] = route
+			}
+
+			if err := s.Google.Update(ctx, s.Google.TransitObject(ticket, route, s.Signer.Sign(ticket))); err != nil {
+				slog.Error("failed to update google wallet object", slog.String("ticket_id", ticket.ID), slog.String("error", err.Error()))
+			}
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package sms
+
+import (
+	"aulway/internal/utils/config"
+	"aulway/internal/utils/errs"
+	"context"
+)
+
+// FromConfig returns the Mobizon sender of the configured account. Without an
+// API key every send fails with errs.ErrSMSNotConfigured, codes are never
+// handed to a sender that drops them.
+func FromConfig(cfg config.SMS) Sender {
+	if cfg.APIKey == "" {
+		return disabled{}
+	}
+
+	return NewMobizon(cfg.BaseURL, cfg.APIKey, cfg.From)
+}
+
+// disabled is the sender when no SMS gateway is configured.
+type disabled struct{}
+
+func (disabled) Send(context.Context, string, string) error {
+	return errs.ErrSMSNotConfigured
+}
@@ -0,0 +1,23 @@
+package sms
+
+import (
+	"aulway/internal/utils/config"
+	"aulway/internal/utils/errs"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromConfigWithoutKeyRefusesToSend(t *testing.T) {
+	sender := FromConfig(config.SMS{})
+
+	err := sender.Send(context.Background(), "+77001234567", "code 123456")
+	require.ErrorIs(t, err, errs.ErrSMSNotConfigured)
+}
+
+func TestFromConfigWithKeyUsesMobizon(t *testing.T) {
+	sender := FromConfig(config.SMS{APIKey: "key", BaseURL: "https://api.mobizon.kz"})
+
+	require.IsType(t, &Mobizon{}, sender)
+}
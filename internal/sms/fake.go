@@ -0,0 +1,36 @@
+package sms
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Fake keeps the messages it is given instead of sending them, for tests.
+type Fake struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+// Message is a text the fake was given.
+type Message struct {
+	Phone string
+	Text  string
+}
+
+func (f *Fake) Send(_ context.Context, phone, text string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sent = append(f.sent, Message{Phone: phone, Text: text})
+
+	return nil
+}
+
+// Sent returns the messages given so far.
+func (f *Fake) Sent() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.sent)
+}
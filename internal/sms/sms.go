@@ -0,0 +1,87 @@
+// Package sms sends text messages to phone numbers, the verification codes of
+// phone sign-in and phone changes go through it.
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Sender delivers a text message to a phone number in +7XXXXXXXXXX form.
+type Sender interface {
+	Send(ctx context.Context, phone, text string) error
+}
+
+// Mobizon sends through the Mobizon gateway.
+type Mobizon struct {
+	baseURL string
+	apiKey  string
+	from    string
+	client  *http.Client
+}
+
+func NewMobizon(baseURL, apiKey, from string) *Mobizon {
+	return &Mobizon{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		from:    from,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// mobizonResponse is the envelope of every Mobizon answer, code 0 is success.
+type mobizonResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (m *Mobizon) Send(ctx context.Context, phone, text string) error {
+	query := url.Values{
+		"output": {"json"},
+		"api":    {"v1"},
+		"apiKey": {m.apiKey},
+	}
+	form := url.Values{
+		"recipient": {strings.TrimPrefix(phone, "+")},
+		"text":      {text},
+	}
+	if m.from != "" {
+		form.Set("from", m.from)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.baseURL+"/service/message/sendsmsmessage?"+query.Encode(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("mobizon request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("mobizon send: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if err != nil {
+		return fmt.Errorf("mobizon response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("mobizon send: status %d", resp.StatusCode)
+	}
+
+	var result mobizonResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("mobizon response: %w", err)
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("mobizon send: code %d: %s", result.Code, result.Message)
+	}
+
+	return nil
+}
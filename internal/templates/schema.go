@@ -0,0 +1,249 @@
+package templates
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template/parse"
+	"time"
+)
+
+// schemas is the data each message is rendered with.
+var schemas = map[string]reflect.Type{
+	TicketsEmail:       reflect.TypeOf(TicketsData{}),
+	CancellationEmail:  reflect.TypeOf(CancellationData{}),
+	VerificationEmail:  reflect.TypeOf(CodeData{}),
+	PasswordResetEmail: reflect.TypeOf(CodeData{}),
+	ReminderEmail:      reflect.TypeOf(ReminderData{}),
+	RouteChangeEmail:   reflect.TypeOf(RouteChangeData{}),
+	ReceiptDocument:    reflect.TypeOf(ReceiptData{}),
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Variable is a value a message template can use.
+type Variable struct {
+	Name        string `json:"name" example:".Tickets[].Seat"`
+	Type        string `json:"type" example:"string"`
+	Description string `json:"description"`
+}
+
+// Schema lists the variables of a message, nil when there is no such
+// message. Fields of list items are named with [], they are used inside
+// range.
+func Schema(name string) []Variable {
+	data, ok := schemas[name]
+	if !ok {
+		return nil
+	}
+
+	return variables("", data)
+}
+
+func variables(prefix string, t reflect.Type) []Variable {
+	vars := make([]Variable, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := prefix + "." + field.Name
+
+		switch {
+		case field.Type == timeType:
+			vars = append(vars, Variable{Name: name, Type: "time", Description: field.Tag.Get("desc")})
+		case field.Type.Kind() == reflect.Struct:
+			vars = append(vars, variables(name, field.Type)...)
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+			vars = append(vars, Variable{Name: name, Type: "list", Description: field.Tag.Get("desc")})
+			vars = append(vars, variables(name+"[]", field.Type.Elem())...)
+		case field.Type.Kind() == reflect.Slice:
+			vars = append(vars, Variable{Name: name, Type: "list of " + field.Type.Elem().Kind().String(), Description: field.Tag.Get("desc")})
+		default:
+			vars = append(vars, Variable{Name: name, Type: field.Type.Kind().String(), Description: field.Tag.Get("desc")})
+		}
+	}
+
+	return vars
+}
+
+// check walks a parsed template and makes sure every field it reads exists
+// in data, including inside range and with. Values the checker cannot type,
+// like the result of index, are not followed.
+func check(tree *parse.Tree, data reflect.Type) error {
+	c := &checker{vars: map[string]reflect.Type{"$": data}}
+	return c.walk(tree.Root, data)
+}
+
+type checker struct {
+	vars map[string]reflect.Type
+}
+
+func (c *checker) walk(node parse.Node, dot reflect.Type) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := c.walk(child, dot); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		_, err := c.pipe(n.Pipe, dot)
+		return err
+	case *parse.IfNode:
+		if _, err := c.pipe(n.Pipe, dot); err != nil {
+			return err
+		}
+		return c.branches(n.List, n.ElseList, dot, dot)
+	case *parse.WithNode:
+		t, err := c.pipe(n.Pipe, dot)
+		if err != nil {
+			return err
+		}
+		return c.branches(n.List, n.ElseList, t, dot)
+	case *parse.RangeNode:
+		t, err := c.pipe(n.Pipe, dot)
+		if err != nil {
+			return err
+		}
+		elem := element(t)
+		switch len(n.Pipe.Decl) {
+		case 1:
+			c.vars[n.Pipe.Decl[0].Ident[0]] = elem
+		case 2:
+			c.vars[n.Pipe.Decl[0].Ident[0]] = reflect.TypeOf(0)
+			c.vars[n.Pipe.Decl[1].Ident[0]] = elem
+		}
+		return c.branches(n.List, n.ElseList, elem, dot)
+	case *parse.TemplateNode:
+		return fmt.Errorf("{{template %q}} is not allowed", n.Name)
+	}
+
+	return nil
+}
+
+func (c *checker) branches(list, elseList *parse.ListNode, dot, elseDot reflect.Type) error {
+	if err := c.walk(list, dot); err != nil {
+		return err
+	}
+	return c.walk(elseList, elseDot)
+}
+
+// pipe checks a pipeline and returns the type of its value, nil when it is
+// not known.
+func (c *checker) pipe(pipe *parse.PipeNode, dot reflect.Type) (reflect.Type, error) {
+	if pipe == nil {
+		return nil, nil
+	}
+
+	var t reflect.Type
+	for _, cmd := range pipe.Cmds {
+		var err error
+		if t, err = c.command(cmd, dot); err != nil {
+			return nil, err
+		}
+	}
+
+	if !pipe.IsAssign {
+		for _, decl := range pipe.Decl {
+			c.vars[decl.Ident[0]] = t
+		}
+	}
+
+	return t, nil
+}
+
+func (c *checker) command(cmd *parse.CommandNode, dot reflect.Type) (reflect.Type, error) {
+	types := make([]reflect.Type, 0, len(cmd.Args))
+	for _, arg := range cmd.Args {
+		t, err := c.arg(arg, dot)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+
+	fn, ok := cmd.Args[0].(*parse.IdentifierNode)
+	if !ok {
+		return types[0], nil
+	}
+
+	switch fn.Ident {
+	case "date", "clock", "join", "print", "printf", "println", "html", "js", "urlquery":
+		return reflect.TypeOf(""), nil
+	case "len":
+		return reflect.TypeOf(0), nil
+	case "eq", "ne", "lt", "le", "gt", "ge", "not":
+		return reflect.TypeOf(false), nil
+	}
+
+	return nil, nil
+}
+
+func (c *checker) arg(node parse.Node, dot reflect.Type) (reflect.Type, error) {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return dot, nil
+	case *parse.FieldNode:
+		return field(dot, n.Ident, "")
+	case *parse.VariableNode:
+		return field(c.vars[n.Ident[0]], n.Ident[1:], n.Ident[0])
+	case *parse.ChainNode:
+		t, err := c.arg(n.Node, dot)
+		if err != nil {
+			return nil, err
+		}
+		return field(t, n.Field, n.Node.String())
+	case *parse.PipeNode:
+		return c.pipe(n, dot)
+	case *parse.StringNode:
+		return reflect.TypeOf(""), nil
+	case *parse.BoolNode:
+		return reflect.TypeOf(false), nil
+	}
+
+	return nil, nil
+}
+
+// field follows a chain of field names from t.
+func field(t reflect.Type, names []string, path string) (reflect.Type, error) {
+	for _, name := range names {
+		path += "." + name
+		if t == nil {
+			return nil, nil
+		}
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+
+		if method, ok := t.MethodByName(name); ok && method.Type.NumOut() > 0 {
+			t = method.Type.Out(0)
+			continue
+		}
+		if t.Kind() != reflect.Struct || t == timeType {
+			return nil, fmt.Errorf("%s: %s has no fields", path, strings.TrimSuffix(path, "."+name))
+		}
+
+		f, ok := t.FieldByName(name)
+		if !ok || !f.IsExported() {
+			return nil, fmt.Errorf("unknown variable %s", path)
+		}
+		t = f.Type
+	}
+
+	return t, nil
+}
+
+func element(t reflect.Type) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return t.Elem()
+	case reflect.Int:
+		return t
+	}
+
+	return nil
+}
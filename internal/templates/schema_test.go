@@ -0,0 +1,104 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuiltinSourcesCompile(t *testing.T) {
+	data := map[string]any{
+		TicketsEmail:       TicketsData{OrderNumber: "AW-1", Route: testRoute(), Tickets: []TicketLine{{Seat: "1A", Price: 5000, QR: "qr-1A.png"}}, Total: 5000},
+		CancellationEmail:  CancellationData{OrderNumber: "AW-1", Route: testRoute(), Seats: []string{"1A"}},
+		VerificationEmail:  CodeData{Code: "123456", Minutes: 10},
+		PasswordResetEmail: CodeData{Code: "123456", Minutes: 10},
+		ReminderEmail:      ReminderData{OrderNumber: "AW-1", Route: testRoute(), Seats: []string{"1A"}},
+		RouteChangeEmail:   RouteChangeData{OrderNumber: "AW-1", Route: testRoute(), Previous: testRoute(), Seats: []string{"1A"}, TimeChanged: true},
+		ReceiptDocument:    ReceiptData{OrderNumber: "AW-1", CreatedAt: testRoute().StartDate, Route: testRoute(), Tickets: []ReceiptLine{{Seat: "1A", Price: 5000, Status: "approved"}}, Currency: "KZT"},
+	}
+
+	for _, locale := range Locales {
+		for _, name := range Names {
+			subject, body, err := Source(locale, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			compiled, err := Compile(name, subject, body)
+			if err != nil {
+				t.Fatalf("%s/%s does not compile from its source: %v", locale, name, err)
+			}
+
+			gotSubject, gotBody, err := compiled.Execute(data[name])
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantSubject, wantBody, _ := Render(locale, name, data[name])
+			if gotSubject != wantSubject || strings.Join(strings.Fields(gotBody), " ") != strings.Join(strings.Fields(wantBody), " ") {
+				t.Fatalf("%s/%s renders differently from its source", locale, name)
+			}
+		}
+	}
+}
+
+func TestCompileChecksVariables(t *testing.T) {
+	valid := []string{
+		`{{.OrderNumber}} {{.Route.Departure}} {{date .Route.StartDate}}`,
+		`{{range .Tickets}}<img src="cid:{{.QR}}"> {{.Seat}} {{$.OrderNumber}}{{end}}`,
+		`{{range $i, $t := .Tickets}}{{$i}} {{$t.Price}}{{end}}`,
+		`{{with .Route}}{{.Destination}}{{end}} {{.Route.StartDate.Year}}`,
+		`{{$route := .Route}}{{$route.DepartureLocation}} {{len .Tickets}}`,
+	}
+	for _, body := range valid {
+		if _, err := Compile(TicketsEmail, "{{.OrderNumber}}", body); err != nil {
+			t.Errorf("%s: %v", body, err)
+		}
+	}
+
+	invalid := map[string]string{
+		`{{.Route.Departur}}`:                        ".Route.Departur",
+		`{{range .Tickets}}{{.Seet}}{{end}}`:         ".Seet",
+		`{{range $t := .Tickets}}{{$t.Code}}{{end}}`: "$t.Code",
+		`{{with .Route}}{{.OrderNumber}}{{end}}`:     ".OrderNumber",
+		`{{.OrderNumber.Length}}`:                    ".OrderNumber.Length",
+		`{{.Refund}}`:                                ".Refund",
+		`{{template "layout" .}}`:                    "not allowed",
+		`{{define "layout"}}hijacked{{end}}`:         "not allowed",
+		`{{.OrderNumber`:                             "body",
+	}
+	for body, want := range invalid {
+		_, err := Compile(TicketsEmail, "{{.OrderNumber}}", body)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error %v, want one about %s", body, err, want)
+		}
+	}
+
+	if _, err := Compile(CancellationEmail, "{{.Total}}", "{{.Refund}}"); err == nil || !strings.Contains(err.Error(), "subject") {
+		t.Errorf("unknown subject variable: %v", err)
+	}
+	if _, err := Compile("welcome", "", ""); err == nil {
+		t.Error("unknown message compiled")
+	}
+}
+
+func TestSchemaListsTicketFields(t *testing.T) {
+	names := make(map[string]string)
+	for _, v := range Schema(TicketsEmail) {
+		names[v.Name] = v.Type
+	}
+
+	for name, typ := range map[string]string{
+		".OrderNumber":     "string",
+		".Route.Departure": "string",
+		".Route.StartDate": "time",
+		".Tickets":         "list",
+		".Tickets[].QR":    "string",
+		".Tickets[].Price": "int",
+		".Total":           "int",
+	} {
+		if names[name] != typ {
+			t.Errorf("%s is %q in the schema, want %s", name, names[name], typ)
+		}
+	}
+	if Schema("welcome") != nil {
+		t.Error("unknown message has a schema")
+	}
+}
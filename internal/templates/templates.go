@@ -0,0 +1,304 @@
+// Package templates renders the emails sent to users, and the documents they
+// download, in their language. Every locale has its own directory with one
+// file per message; a file defines the "subject" and the "content" of the
+// body, which is put in layout.html.
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+const (
+	EN = "en"
+	RU = "ru"
+	KK = "kk"
+
+	// Default is used for users that have not picked a language.
+	Default = RU
+)
+
+// Locales are the supported languages, as stored in settings.language.
+var Locales = []string{EN, RU, KK}
+
+// Message names.
+const (
+	TicketsEmail       = "tickets"
+	CancellationEmail  = "cancellation"
+	VerificationEmail  = "verification"
+	PasswordResetEmail = "password_reset"
+	ReminderEmail      = "reminder"
+	RouteChangeEmail   = "route_change"
+	// ReceiptDocument is the receipt of an order, its subject is the title.
+	ReceiptDocument = "receipt"
+)
+
+// Names are the messages there are templates for.
+var Names = []string{TicketsEmail, CancellationEmail, VerificationEmail, PasswordResetEmail, ReminderEmail, RouteChangeEmail, ReceiptDocument}
+
+//go:embed layout.html en ru kk
+var files embed.FS
+
+var almaty = time.FixedZone("Almaty", 5*60*60)
+
+var funcs = map[string]any{
+	"date":  func(t time.Time) string { return t.In(almaty).Format("02.01.2006") },
+	"clock": func(t time.Time) string { return t.In(almaty).Format("15:04") },
+	"join":  strings.Join,
+}
+
+// Template is a compiled message. The body is HTML and escapes what it is
+// given, the subject is a header and is plain text.
+type Template struct {
+	subject *texttemplate.Template
+	body    *htmltemplate.Template
+}
+
+var builtin = parseBuiltin()
+
+func parseBuiltin() map[string]map[string]*Template {
+	parsed := make(map[string]map[string]*Template, len(Locales))
+	for _, locale := range Locales {
+		parsed[locale] = make(map[string]*Template, len(Names))
+		for _, name := range Names {
+			file := locale + "/" + name + ".html"
+			parsed[locale][name] = &Template{
+				subject: texttemplate.Must(texttemplate.New(name).Funcs(funcs).ParseFS(files, file)),
+				body:    htmltemplate.Must(layout().ParseFS(files, file)),
+			}
+		}
+	}
+
+	return parsed
+}
+
+func layout() *htmltemplate.Template {
+	return htmltemplate.Must(htmltemplate.New("layout.html").Funcs(funcs).ParseFS(files, "layout.html"))
+}
+
+// Builtin returns the message as shipped, for a supported locale.
+func Builtin(locale, name string) (*Template, bool) {
+	tmpl, ok := builtin[locale][name]
+	return tmpl, ok
+}
+
+// Compile builds a message from the sources of its subject and body content,
+// the body is put in the shared layout. Every variable the sources use is
+// checked against the data the message is rendered with.
+func Compile(name, subject, body string) (*Template, error) {
+	data, ok := schemas[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+
+	subjectTmpl, err := texttemplate.New("subject").Funcs(funcs).Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("subject: %w", err)
+	}
+	content, err := texttemplate.New("content").Funcs(funcs).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("body: %w", err)
+	}
+
+	for _, t := range append(subjectTmpl.Templates(), content.Templates()...) {
+		if t.Name() != "subject" && t.Name() != "content" {
+			return nil, fmt.Errorf("{{define %q}} is not allowed", t.Name())
+		}
+	}
+
+	if err := check(subjectTmpl.Lookup("subject").Tree, data); err != nil {
+		return nil, fmt.Errorf("subject: %w", err)
+	}
+	if err := check(content.Tree, data); err != nil {
+		return nil, fmt.Errorf("body: %w", err)
+	}
+
+	bodyTmpl, err := layout().AddParseTree("content", content.Tree)
+	if err != nil {
+		return nil, fmt.Errorf("body: %w", err)
+	}
+
+	return &Template{subject: subjectTmpl, body: bodyTmpl.Lookup("layout.html")}, nil
+}
+
+// Source returns the sources of the subject and body content of a message
+// as shipped, the starting point of an edited one.
+func Source(locale, name string) (subject, body string, err error) {
+	tmpl, ok := Builtin(locale, name)
+	if !ok {
+		return "", "", fmt.Errorf("unknown email template %s/%s", locale, name)
+	}
+
+	// the text template is never escaped, its trees are still the parsed ones
+	return tmpl.subject.Lookup("subject").Tree.Root.String(), strings.TrimSpace(tmpl.subject.Lookup("content").Tree.Root.String()), nil
+}
+
+// Execute renders the subject and the body of the message.
+func (t *Template) Execute(data any) (subject, body string, err error) {
+	var buf bytes.Buffer
+	if err := t.subject.ExecuteTemplate(&buf, "subject", data); err != nil {
+		return "", "", fmt.Errorf("render subject: %w", err)
+	}
+	// a subject is a single header line
+	subject = strings.Join(strings.Fields(buf.String()), " ")
+
+	buf.Reset()
+	if err := t.body.ExecuteTemplate(&buf, "layout", data); err != nil {
+		return "", "", fmt.Errorf("render body: %w", err)
+	}
+
+	return subject, buf.String(), nil
+}
+
+// Render renders a message as shipped in the given locale, or in Default
+// when the locale is not supported.
+func Render(locale, name string, data any) (subject, body string, err error) {
+	tmpl, ok := Builtin(Locale(locale), name)
+	if !ok {
+		return "", "", fmt.Errorf("unknown email template %q", name)
+	}
+
+	return tmpl.Execute(data)
+}
+
+// Supported reports whether emails can be rendered in the locale.
+func Supported(locale string) bool {
+	return slices.Contains(Locales, locale)
+}
+
+// Locale returns the locale if it is supported and Default otherwise.
+func Locale(locale string) string {
+	if Supported(locale) {
+		return locale
+	}
+	return Default
+}
+
+// Match picks the supported locale the client prefers most from an
+// Accept-Language header, Default when there is none.
+func Match(acceptLanguage string) string {
+	type tag struct {
+		locale string
+		q      float64
+	}
+
+	tags := make([]tag, 0)
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		lang, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		lang, _, _ = strings.Cut(strings.ToLower(lang), "-")
+		if lang == "kz" {
+			lang = KK
+		}
+		if !Supported(lang) {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			tags = append(tags, tag{locale: lang, q: q})
+		}
+	}
+	if len(tags) == 0 {
+		return Default
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	return tags[0].locale
+}
+
+// TicketsData is rendered by TicketsEmail, the purchase confirmation.
+type TicketsData struct {
+	OrderNumber string       `desc:"Order number, like AW-100234"`
+	Route       RouteData    `desc:"Trip of the order"`
+	BusNumber   string       `desc:"Plate number of the bus"`
+	Tickets     []TicketLine `desc:"Tickets of the order, use with range"`
+	Total       int          `desc:"Sum of the ticket prices in tenge"`
+}
+
+// RouteData is the trip of an email. Times are shown in Almaty time with the
+// date and clock functions.
+type RouteData struct {
+	Departure           string    `desc:"City of departure"`
+	Destination         string    `desc:"City of arrival"`
+	DepartureLocation   string    `desc:"Pickup address"`
+	DestinationLocation string    `desc:"Drop-off address"`
+	StartDate           time.Time `desc:"Departure time"`
+	EndDate             time.Time `desc:"Arrival time"`
+}
+
+// TicketLine is a ticket of the purchase email.
+type TicketLine struct {
+	Seat  string `desc:"Seat number"`
+	Price int    `desc:"Price in tenge"`
+	QR    string `desc:"Content ID of the attached QR code, show it with <img src=\"cid:{{.QR}}\">; empty when the ticket has none"`
+}
+
+// CancellationData is rendered by CancellationEmail.
+type CancellationData struct {
+	OrderNumber string    `desc:"Order number, like AW-100234"`
+	Route       RouteData `desc:"Trip of the order"`
+	Seats       []string  `desc:"Cancelled seat numbers, use with join"`
+	Refund      int       `desc:"Refunded amount in tenge"`
+	WholeOrder  bool      `desc:"Set when no valid tickets are left in the order"`
+}
+
+// ReminderData is rendered by ReminderEmail, sent ahead of the departure.
+type ReminderData struct {
+	OrderNumber string    `desc:"Order number, like AW-100234"`
+	Route       RouteData `desc:"Trip of the order"`
+	BusNumber   string    `desc:"Plate number of the bus"`
+	Seats       []string  `desc:"Seat numbers of the valid tickets, use with join"`
+}
+
+// RouteChangeData is rendered by RouteChangeEmail when the time, the bus or
+// the pickup of a trip changed after the tickets were bought.
+type RouteChangeData struct {
+	OrderNumber       string    `desc:"Order number, like AW-100234"`
+	Route             RouteData `desc:"Trip as it is now"`
+	Previous          RouteData `desc:"Trip as it was when the tickets were bought or last changed"`
+	BusNumber         string    `desc:"Plate number of the bus"`
+	PreviousBusNumber string    `desc:"Plate number of the bus before the change"`
+	Seats             []string  `desc:"Seat numbers of the valid tickets, use with join"`
+	TimeChanged       bool      `desc:"Set when the departure or arrival time changed"`
+	BusChanged        bool      `desc:"Set when the bus changed"`
+	PickupChanged     bool      `desc:"Set when the pickup or drop-off address changed"`
+}
+
+// ReceiptData is rendered by ReceiptDocument. Amounts are in the currency of
+// the payment.
+type ReceiptData struct {
+	OrderNumber string        `desc:"Order number, like AW-100234"`
+	CreatedAt   time.Time     `desc:"Time the order was placed"`
+	Route       RouteData     `desc:"Trip of the order"`
+	Tickets     []ReceiptLine `desc:"Tickets of the order, use with range"`
+	Currency    string        `desc:"Currency code, like KZT"`
+	Charged     int           `desc:"Amount paid"`
+	Refunded    int           `desc:"Amount refunded"`
+	Total       int           `desc:"Amount paid less the refunds"`
+}
+
+// ReceiptLine is a ticket of the receipt.
+type ReceiptLine struct {
+	Seat   string `desc:"Seat number"`
+	Price  int    `desc:"Price of the ticket"`
+	Status string `desc:"approved, boarded, no_show, cancelled or awaiting"`
+}
+
+// CodeData is rendered by VerificationEmail and PasswordResetEmail.
+type CodeData struct {
+	Code    string `desc:"One-time code"`
+	Minutes int    `desc:"Minutes the code can be used for"`
+}
@@ -0,0 +1,212 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func testRoute() RouteData {
+	start := time.Date(2025, 12, 12, 3, 0, 0, 0, time.UTC)
+	return RouteData{
+		Departure:           "Алматы",
+		Destination:         "Талдыкорган",
+		DepartureLocation:   "Сайран автовокзал",
+		DestinationLocation: `Автовокзал <b>"Жетісу"</b> & Co`,
+		StartDate:           start,
+		EndDate:             start.Add(4 * time.Hour),
+	}
+}
+
+func TestEveryMessageRendersInEveryLocale(t *testing.T) {
+	data := map[string]any{
+		TicketsEmail: TicketsData{
+			OrderNumber: "AW-100234",
+			Route:       testRoute(),
+			BusNumber:   "A 123 BC",
+			Tickets:     []TicketLine{{Seat: "3B", Price: 5000, QR: "qr-3B.png"}, {Seat: "3C", Price: 5000}},
+			Total:       10000,
+		},
+		CancellationEmail:  CancellationData{OrderNumber: "AW-100234", Route: testRoute(), Seats: []string{"3B"}, Refund: 2500},
+		VerificationEmail:  CodeData{Code: "042137", Minutes: 10},
+		PasswordResetEmail: CodeData{Code: "042137", Minutes: 10},
+		ReminderEmail:      ReminderData{OrderNumber: "AW-100234", Route: testRoute(), BusNumber: "A 123 BC", Seats: []string{"3B", "3C"}},
+		RouteChangeEmail: RouteChangeData{
+			OrderNumber: "AW-100234", Route: testRoute(), Previous: testRoute(), Seats: []string{"3B"},
+			BusNumber: "A 123 BC", PreviousBusNumber: "B 456 CD", TimeChanged: true, BusChanged: true, PickupChanged: true,
+		},
+		ReceiptDocument: testReceipt(),
+	}
+
+	for _, locale := range Locales {
+		for name, d := range data {
+			subject, body, err := Render(locale, name, d)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", locale, name, err)
+			}
+			if subject == "" || strings.ContainsAny(subject, "\r\n") {
+				t.Fatalf("%s/%s: subject %q is not a single line", locale, name, subject)
+			}
+			if !strings.HasPrefix(body, "<html>") || !strings.HasSuffix(body, "</html>") {
+				t.Fatalf("%s/%s: body is not wrapped in the layout", locale, name)
+			}
+		}
+	}
+}
+
+func TestTicketsEmailIsLocalized(t *testing.T) {
+	data := TicketsData{
+		OrderNumber: "AW-100234",
+		Route:       testRoute(),
+		BusNumber:   "A 123 BC",
+		Tickets:     []TicketLine{{Seat: "3B", Price: 5000, QR: "qr-3B.png"}},
+		Total:       5000,
+	}
+
+	want := map[string]string{EN: "Order number", RU: "Номер заказа", KK: "Тапсырыс нөмірі"}
+	for locale, label := range want {
+		_, body, err := Render(locale, TicketsEmail, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(body, label) {
+			t.Fatalf("%s body has no %q", locale, label)
+		}
+		if !strings.Contains(body, "12.12.2025") || !strings.Contains(body, "08:00") {
+			t.Fatalf("%s body does not show the departure in Almaty time", locale)
+		}
+		if !strings.Contains(body, `src="cid:qr-3B.png"`) {
+			t.Fatalf("%s body does not reference the QR attachment", locale)
+		}
+	}
+}
+
+func testReceipt() ReceiptData {
+	return ReceiptData{
+		OrderNumber: "AW-100234",
+		CreatedAt:   time.Date(2025, 12, 1, 10, 15, 0, 0, time.UTC),
+		Route:       testRoute(),
+		Tickets:     []ReceiptLine{{Seat: "3B", Price: 5000, Status: "approved"}, {Seat: "3C", Price: 5000, Status: "cancelled"}},
+		Currency:    "KZT",
+		Charged:     10000,
+		Refunded:    5000,
+		Total:       5000,
+	}
+}
+
+func TestReceiptIsLocalized(t *testing.T) {
+	want := map[string][]string{
+		EN: {"Order number", "Valid", "Cancelled", "Refunded:</strong> 5000 KZT"},
+		RU: {"Номер заказа", "Действителен", "Отменён", "Возвращено:</strong> 5000 KZT"},
+		KK: {"Тапсырыс нөмірі", "Жарамды", "Жойылды", "Қайтарылды:</strong> 5000 KZT"},
+	}
+	for locale, labels := range want {
+		title, body, err := Render(locale, ReceiptDocument, testReceipt())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(title, "AW-100234") {
+			t.Fatalf("%s title %q has no order number", locale, title)
+		}
+		for _, label := range labels {
+			if !strings.Contains(body, label) {
+				t.Fatalf("%s receipt has no %q", locale, label)
+			}
+		}
+		if !strings.Contains(body, "01.12.2025") || !strings.Contains(body, "15:15") {
+			t.Fatalf("%s receipt does not show the order time in Almaty time", locale)
+		}
+	}
+}
+
+func TestRouteChangeEmailShowsWhatChanged(t *testing.T) {
+	previous := testRoute()
+	route := previous
+	route.StartDate = previous.StartDate.Add(90 * time.Minute)
+	route.EndDate = previous.EndDate.Add(90 * time.Minute)
+
+	_, body, err := Render(EN, RouteChangeEmail, RouteChangeData{
+		OrderNumber: "AW-100234",
+		Route:       route,
+		Previous:    previous,
+		BusNumber:   "A 123 BC",
+		Seats:       []string{"3B"},
+		TimeChanged: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(body, "09:30 (was 12.12.2025 at 08:00)") {
+		t.Fatal("body does not show the new and the old departure")
+	}
+	if strings.Contains(body, "Bus No.") || strings.Contains(body, "Pickup") {
+		t.Fatal("body shows parts of the trip that did not change")
+	}
+}
+
+func TestBodyEscapesData(t *testing.T) {
+	_, body, err := Render(EN, TicketsEmail, TicketsData{
+		OrderNumber: "<script>alert(1)</script>",
+		Route:       testRoute(),
+		Tickets:     []TicketLine{{Seat: "3B", Price: 5000}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(body, "<script>") || strings.Contains(body, "<b>") {
+		t.Fatal("markup in the data is not escaped")
+	}
+	if !strings.Contains(body, "&lt;script&gt;") || !strings.Contains(body, "&amp; Co") {
+		t.Fatal("escaped data is missing from the body")
+	}
+}
+
+func TestSubjectIsPlainText(t *testing.T) {
+	subject, _, err := Render(EN, CancellationEmail, CancellationData{
+		OrderNumber: "AW-1 & <2>\r\nBcc: someone@example.com",
+		Route:       testRoute(),
+		WholeOrder:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if subject != "Order AW-1 & <2> Bcc: someone@example.com cancelled" {
+		t.Fatalf("subject = %q", subject)
+	}
+}
+
+func TestUnsupportedLocaleFallsBackToDefault(t *testing.T) {
+	got, _, err := Render("de", VerificationEmail, CodeData{Code: "1", Minutes: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _, _ := Render(Default, VerificationEmail, CodeData{Code: "1", Minutes: 10})
+
+	if got != want {
+		t.Fatalf("subject = %q, want the %s one %q", got, Default, want)
+	}
+	if _, _, err := Render(EN, "welcome", nil); err == nil {
+		t.Fatal("an unknown template rendered")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	cases := map[string]string{
+		"":                           Default,
+		"de-DE,de;q=0.9":             Default,
+		"en-US,en;q=0.9":             EN,
+		"kk-KZ":                      KK,
+		"kz":                         KK,
+		"de;q=1, ru;q=0.5, en;q=0.8": EN,
+		"en;q=0, ru":                 RU,
+	}
+
+	for header, want := range cases {
+		if got := Match(header); got != want {
+			t.Errorf("Match(%q) = %s, want %s", header, got, want)
+		}
+	}
+}
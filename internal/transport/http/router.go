@@ -1,36 +1,70 @@
 package http
 
 import (
+	"aulway/internal/firebase"
 	"aulway/internal/handler/auth"
+	"aulway/internal/handler/boarding"
 	"aulway/internal/handler/bus"
+	"aulway/internal/handler/device"
+	"aulway/internal/handler/emailtemplate"
 	favorite "aulway/internal/handler/favorites"
 	"aulway/internal/handler/healthz"
+	"aulway/internal/handler/identity"
+	"aulway/internal/handler/ledger"
+	"aulway/internal/handler/order"
+	"aulway/internal/handler/outbox"
 	"aulway/internal/handler/page"
+	"aulway/internal/handler/refundpolicy"
 	"aulway/internal/handler/route"
+	"aulway/internal/handler/session"
+	"aulway/internal/handler/settings"
 	"aulway/internal/handler/ticket"
 	"aulway/internal/handler/user"
+	"aulway/internal/handler/wallet"
+	"aulway/internal/handler/webhook"
+	"aulway/internal/oidc"
+	"aulway/internal/push"
+	"aulway/internal/ratelimit"
 	busRepostory "aulway/internal/repository/bus"
+	deviceRepository "aulway/internal/repository/device"
+	emailTemplateRepository "aulway/internal/repository/emailtemplate"
 	favRepository "aulway/internal/repository/favorite"
+	identityRepository "aulway/internal/repository/identity"
+	ledgerRepository "aulway/internal/repository/ledger"
+	notificationRepository "aulway/internal/repository/notification"
+	orderRepository "aulway/internal/repository/order"
+	outboxRepository "aulway/internal/repository/outbox"
 	pageRepository "aulway/internal/repository/page"
 	paymentRepostory "aulway/internal/repository/payment"
+	refundRepository "aulway/internal/repository/refund"
+	refundPolicyRepository "aulway/internal/repository/refundpolicy"
 	routeRepostory "aulway/internal/repository/route"
+	sessionRepository "aulway/internal/repository/session"
+	settingsRepository "aulway/internal/repository/settings"
 	ticketRepository "aulway/internal/repository/ticket"
 	userRepository "aulway/internal/repository/user"
+	walletRepository "aulway/internal/repository/wallet"
 	"aulway/internal/service"
+	"aulway/internal/sms"
 	middleware "aulway/internal/transport/middlware"
 	"aulway/internal/utils/config"
 	"aulway/internal/utils/logger"
+	walletpass "aulway/internal/wallet"
+	"context"
 	"github.com/labstack/echo/v4"
 	echoMiddleware "github.com/labstack/echo/v4/middleware"
 	"github.com/redis/go-redis/v9"
 	echoSwagger "github.com/swaggo/echo-swagger"
 	"gorm.io/gorm"
+	"log/slog"
+	"net"
 
 	_ "aulway/docs"
 )
 
 const (
-	AdminRole = "admin"
+	AdminRole     = "admin"
+	ConductorRole = "conductor"
 )
 
 type Router struct {
@@ -47,24 +81,98 @@ func NewRouter(c config.Config, db *gorm.DB, redis *redis.Client) *Router {
 	}
 }
 
-func (r *Router) Build() *echo.Echo {
+// Jobs run in the background next to the HTTP server. They are built with the
+// same services and repositories as the handlers.
+type Jobs struct {
+	NoShows         *service.NoShowJob
+	QRCodes         *service.QRReissuer
+	Outbox          *service.OutboxWorker
+	Refunds         *service.RefundSettler
+	ExpiredPayments *service.PaymentSweeper
+	Reminders       *service.ReminderJob
+}
+
+func (r *Router) Build() (*echo.Echo, *Jobs) {
 	userRepo := userRepository.NewRepository(r.db)
-	userService := service.NewUserService(userRepo)
+	sessionService := service.NewSessionService(sessionRepository.New(r.db), userRepo, r.redis, r.c.JWTTokenSecret, r.c.AccessTokenTTL, r.c.RefreshTokenTTL)
+	lockout := ratelimit.NewLockout(r.redis, r.c.RateLimit.LockoutThreshold, r.c.RateLimit.LockoutBase, r.c.RateLimit.LockoutMax)
+	userService := service.NewUserService(userRepo, sessionService, lockout)
+
+	tokens := service.TokenChain{sessionService}
+	if r.c.Firebase.ProjectID != "" {
+		tokens = append(tokens, service.NewFirebaseAuth(firebase.NewIDTokenVerifier(r.c.Firebase.ProjectID), userRepo))
+	}
+
+	settingsRepo := settingsRepository.New(r.db)
+	settingsService := service.NewSettingsService(settingsRepo)
 
-	authService := service.NewAuthService(userRepo, r.redis, r.c.SMTP)
+	oidcProviders, err := oidc.FromConfig(r.c.OIDC)
+	if err != nil {
+		slog.Error("sign-in providers setup failed:", "error", err.Error())
+		panic(err)
+	}
+	oidcAuth := service.NewOIDCAuth(oidcProviders, identityRepository.New(r.db), userRepo, r.redis, r.c.OIDC.StateTTL)
+
+	authService := service.NewAuthService(userRepo, settingsRepo, r.redis, r.c.SMTP, sms.FromConfig(r.c.SMS), r.c.SMS, sessionService, lockout)
 
 	busRepo := busRepostory.New(r.db)
 	busService := service.NewBusService(busRepo)
 
+	seatHolds := service.NewSeatHoldStore(r.redis)
+
 	routeRepo := routeRepostory.New(r.db)
-	routeService := service.NewRouteService(routeRepo)
+	ticketRepo := ticketRepository.New(r.db)
+	ticketSigner := service.NewTicketSigner(r.c.TicketSigningKey)
+	manifestSigner, err := service.NewManifestSigner(r.c.ManifestSigningKey)
+	if err != nil {
+		slog.Error("manifest signer setup failed:", "error", err.Error())
+		panic(err)
+	}
+
+	appleWallet, googleWallet, err := walletpass.FromConfig(context.Background(), r.c.Wallet)
+	if err != nil {
+		slog.Error("wallet setup failed:", "error", err.Error())
+		panic(err)
+	}
+	walletService := service.NewWalletService(ticketRepo, routeRepo, busRepo, walletRepository.New(r.db), ticketSigner, appleWallet, googleWallet)
+
+	outboxRepo := outboxRepository.New(r.db)
+	outboxService := service.NewOutboxService(outboxRepo)
+
+	emailTemplateService := service.NewEmailTemplateService(emailTemplateRepository.New(r.db))
+
+	pushSender, err := push.FromConfig(context.Background(), r.c.Firebase)
+	if err != nil {
+		slog.Error("push notifications setup failed:", "error", err.Error())
+		panic(err)
+	}
+	pushService := service.NewPushService(deviceRepository.New(r.db), pushSender)
+
+	tripNotifier := service.NewTripNotifier(notificationRepository.New(r.db), outboxRepo, routeRepo, busRepo, settingsRepo, emailTemplateService, pushService)
+
+	routeService := service.NewRouteService(routeRepo, seatHolds, walletService, tripNotifier)
 
 	paymentRepo := paymentRepostory.New(r.db)
-	//paymentService := service.NewFPaymentProcessor()
-	paymentService := service.NewStripeProcessor()
+	payments, err := service.PaymentsFromConfig(r.c)
+	if err != nil {
+		slog.Error("payment provider setup failed:", "error", err.Error())
+		panic(err)
+	}
 
-	ticketRepo := ticketRepository.New(r.db)
-	ticketService := service.NewTicketService(ticketRepo, paymentRepo, routeRepo, paymentService, busRepo)
+	ledgerRepo := ledgerRepository.New(r.db)
+	orderRepo := orderRepository.New(r.db)
+	refundRepo := refundRepository.New(r.db)
+	refunds := service.NewRefundSettler(refundRepo, paymentRepo, ledgerRepo, payments, r.c.RefundInterval, r.c.RefundRetryDelay)
+
+	refundPolicyRepo := refundPolicyRepository.New(r.db)
+	refundPolicyService := service.NewRefundPolicyService(refundPolicyRepo)
+
+	ticketService := service.NewTicketService(ticketRepo, paymentRepo, routeRepo, ledgerRepo, orderRepo, refundPolicyRepo, outboxRepo, settingsRepo, emailTemplateService, payments, ticketSigner, busRepo, seatHolds, r.c.SeatHoldTTL, r.c.PaymentTTL, walletService, pushService, refunds)
+
+	boardingService := service.NewBoardingService(ticketRepo, routeRepo, busRepo, ticketSigner, manifestSigner, r.c.BoardingOpens)
+
+	reconciler := service.NewPaymentReconciler(paymentRepo, ticketRepo, routeRepo, ledgerRepo, orderRepo, refundRepo)
+	ledgerService := service.NewLedgerService(ledgerRepo, paymentRepo, orderRepo, payments)
 
 	pageRepo := pageRepository.New(r.db)
 	pageService := service.NewPageService(pageRepo)
@@ -72,6 +180,16 @@ func (r *Router) Build() *echo.Echo {
 	favRepo := favRepository.New(r.db)
 	favService := service.NewFavoriteService(favRepo)
 
+	jobs := &Jobs{
+		NoShows: service.NewNoShowJob(ticketRepo, r.c.NoShowGrace, r.c.NoShowInterval),
+		QRCodes: service.NewQRReissuer(ticketRepo, ticketSigner),
+		Outbox: service.NewOutboxWorker(outboxRepo, ticketRepo, routeRepo, busRepo, ticketSigner, r.c.SMTP,
+			r.c.OutboxInterval, r.c.OutboxRetryDelay, r.c.OutboxMaxAttempts),
+		Refunds:         refunds,
+		ExpiredPayments: service.NewPaymentSweeper(paymentRepo, ticketRepo, routeRepo, orderRepo, payments, r.c.PaymentTTL, r.c.PaymentSweepInterval),
+		Reminders:       service.NewReminderJob(tripNotifier, r.c.ReminderLeads, r.c.ReminderInterval),
+	}
+
 	timeoutWithConfig := echoMiddleware.TimeoutWithConfig(
 		echoMiddleware.TimeoutConfig{
 			Skipper:      echoMiddleware.DefaultSkipper,
@@ -82,6 +200,7 @@ func (r *Router) Build() *echo.Echo {
 	e := echo.New()
 	e.HideBanner = true
 	e.Debug = true
+	e.IPExtractor = clientIP(r.c.RateLimit.TrustedProxies)
 
 	aulLogger := logger.New()
 	e.Use(aulLogger.LogRequest)
@@ -91,27 +210,70 @@ func (r *Router) Build() *echo.Echo {
 	e.Use(echoMiddleware.CORSWithConfig(echoMiddleware.CORSConfig{
 		AllowOrigins: []string{"http://0.0.0.0:8080", "http://localhost:5173"},
 		AllowMethods: []string{echo.GET, echo.POST, echo.PUT, echo.DELETE, echo.OPTIONS},
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
+		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, middleware.IdempotencyKeyHeader},
 	}))
 
 	e.GET("/health", healthz.CheckHealth())
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
-	e.POST("/auth/signup", auth.SignupHandler(r.redis, r.c, userService))
-	e.POST("/auth/signup/verify", auth.VerifyEmailHandler(r.redis, userService, authService, r.c))
-	e.POST("/auth/signin", auth.SigninHandler(authService, userService, r.c))
-	e.POST("/auth/forgot-password", auth.ForgotPasswordHandler(authService))
-	e.POST("/auth/forgot-password/verify", auth.VerifyForgotPasswordHandler(authService))
+	if r.c.StripeWebhookSecret != "" {
+		e.POST("/webhooks/stripe", webhook.StripeWebhookHandler(reconciler, r.c.StripeWebhookSecret))
+	}
+
+	// every auth endpoint counts towards the limit of the client IP, those
+	// naming an account towards the limit of the account as well
+	limiter := ratelimit.NewLimiter(r.redis)
+	perEmail := middleware.RateLimitAccount(limiter, "auth", r.c.RateLimit.AccountLimit, r.c.RateLimit.AccountWindow, "email")
+	perPhone := middleware.RateLimitAccount(limiter, "auth", r.c.RateLimit.AccountLimit, r.c.RateLimit.AccountWindow, "phone")
+	authRoutes := e.Group("/auth", middleware.RateLimit(limiter, "auth", r.c.RateLimit.IPLimit, r.c.RateLimit.IPWindow))
 
-	publicProtected := e.Group("/api", middleware.JWTAuth(r.c.JWTTokenSecret))
+	authRoutes.POST("/signup", auth.SignupHandler(r.redis, r.c, userService), perEmail)
+	authRoutes.POST("/signup/verify", auth.VerifyEmailHandler(authService, userService, sessionService), perEmail)
+	authRoutes.POST("/signin", auth.SigninHandler(sessionService, userService), perEmail)
+	authRoutes.POST("/forgot-password", auth.ForgotPasswordHandler(authService), perEmail)
+	authRoutes.POST("/forgot-password/verify", auth.VerifyForgotPasswordHandler(authService), perEmail)
+	authRoutes.POST("/phone", auth.SendPhoneCodeHandler(authService), perPhone)
+	authRoutes.POST("/phone/verify", auth.PhoneSigninHandler(authService, sessionService), perPhone)
+	authRoutes.POST("/refresh", auth.RefreshHandler(sessionService))
+	authRoutes.POST("/logout", auth.LogoutHandler(sessionService), middleware.JWTAuth(tokens))
+	authRoutes.GET("/oidc/:provider", auth.OIDCSigninHandler(oidcAuth))
+	authRoutes.GET("/oidc/:provider/callback", auth.OIDCCallbackHandler(oidcAuth, sessionService))
+	authRoutes.POST("/oidc/:provider/callback", auth.OIDCCallbackHandler(oidcAuth, sessionService))
 
-	adminProtected := e.Group("/api", middleware.JWTAuth(r.c.JWTTokenSecret), middleware.AccessCheck(AdminRole))
+	// Apple Wallet web service, devices authenticate with the token in the pass
+	passes := e.Group("/wallet/v1")
+	passes.POST("/devices/:deviceId/registrations/:passTypeId/:serialNumber", wallet.RegisterDeviceHandler(walletService))
+	passes.DELETE("/devices/:deviceId/registrations/:passTypeId/:serialNumber", wallet.UnregisterDeviceHandler(walletService))
+	passes.GET("/devices/:deviceId/registrations/:passTypeId", wallet.ListUpdatedPassesHandler(walletService))
+	passes.GET("/passes/:passTypeId/:serialNumber", wallet.GetLatestPassHandler(walletService))
+	passes.POST("/log", wallet.LogHandler())
+
+	publicProtected := e.Group("/api", middleware.JWTAuth(tokens))
+
+	idempotent := middleware.Idempotency(r.redis, r.c.IdempotencyKeyTTL)
+
+	adminProtected := e.Group("/api", middleware.JWTAuth(tokens), middleware.AccessCheck(AdminRole))
+
+	staffProtected := e.Group("/api", middleware.JWTAuth(tokens), middleware.AccessCheck(AdminRole, ConductorRole))
 
 	publicProtected.PUT("/users/:userId", user.UpdateUserHandler(userService))
 	publicProtected.GET("/users/:userId", user.GetUserByIdHandler(userService))
 	adminProtected.GET("/users", user.GetUsersList(userService))
 	publicProtected.DELETE("/users/:userId", user.DeleteUserHandler(userService))
 	publicProtected.PUT("/users/:userId/change-password", user.ChangePasswordHandler(userService))
+	publicProtected.POST("/users/:userId/phone", user.ChangePhoneHandler(authService))
+	publicProtected.POST("/users/:userId/phone/verify", user.VerifyPhoneChangeHandler(authService))
+	publicProtected.POST("/users/:userId/email/code", user.SendEmailCodeHandler(authService))
+	publicProtected.POST("/users/:userId/email/verify", user.VerifyEmailHandler(authService))
+	publicProtected.GET("/users/:userId/settings", settings.GetSettingsHandler(settingsService))
+	publicProtected.PUT("/users/:userId/settings", settings.UpdateSettingsHandler(settingsService))
+	publicProtected.GET("/users/:userId/devices", device.ListDevicesHandler(pushService))
+	publicProtected.POST("/users/:userId/devices", device.RegisterDeviceHandler(pushService))
+	publicProtected.DELETE("/users/:userId/devices/:token", device.UnregisterDeviceHandler(pushService))
+	publicProtected.GET("/users/:userId/sessions", session.ListSessionsHandler(sessionService))
+	publicProtected.DELETE("/users/:userId/sessions/:sessionId", session.RevokeSessionHandler(sessionService))
+	publicProtected.GET("/users/:userId/identities", identity.ListIdentitiesHandler(oidcAuth))
+	publicProtected.DELETE("/users/:userId/identities/:provider", identity.UnlinkIdentityHandler(oidcAuth))
 
 	adminProtected.GET("/buses", bus.GetBusesListHandler(busService, r.c))
 	adminProtected.POST("/buses", bus.CreateBusHandler(busService, r.c))
@@ -124,14 +286,55 @@ func (r *Router) Build() *echo.Echo {
 	adminProtected.PUT("/routes/:routeId", route.UpdateRouteHandler(routeService, r.c))
 	adminProtected.DELETE("/routes/:routeId", route.DeleteRouteHandler(routeService, r.c))
 	publicProtected.GET("/routes", route.GetRoutesListHandler(routeService, r.c))
+	publicProtected.GET("/routes/:routeId/seats", ticket.GetRouteSeatsHandler(ticketService))
+	publicProtected.POST("/routes/:routeId/holds", ticket.HoldSeatsHandler(ticketService))
+	publicProtected.DELETE("/routes/:routeId/holds/:holdId", ticket.ReleaseHoldHandler(ticketService))
 
 	adminProtected.GET("/tickets", ticket.GetTicketsSortByHandler(ticketService))
-	publicProtected.POST("/tickets/:routeId", ticket.BuyTicketHandler(ticketService, r.c))
+	publicProtected.POST("/tickets/:routeId", ticket.BuyTicketHandler(ticketService, r.c), idempotent)
+	publicProtected.POST("/tickets/payments/:paymentId/confirm", ticket.ConfirmPaymentHandler(ticketService, r.c))
 	adminProtected.GET("/tickets/users/cancelled", ticket.GetAdminCancelledTicketsHandler(ticketService))
 	publicProtected.GET("/tickets/users/:userId/cancelled", ticket.GetCancelledTicketsHandler(ticketService))
 	publicProtected.GET("/tickets/users/:userId", ticket.GetUserTicketsHandler(ticketService))
 	publicProtected.GET("/tickets/users/:userId/:ticketId", ticket.GetTicketDetailsHandler(ticketService))
-	publicProtected.PUT("/tickets/users/:userId/:ticketId/cancel", ticket.CancelTicketHandler(r.c, ticketService))
+	publicProtected.GET("/tickets/users/:userId/:ticketId/pdf", ticket.GetTicketPDFHandler(ticketService))
+	publicProtected.GET("/tickets/users/:userId/:ticketId/wallet/apple", wallet.GetApplePassHandler(walletService))
+	publicProtected.GET("/tickets/users/:userId/:ticketId/wallet/google", wallet.GetGoogleLinkHandler(walletService))
+	publicProtected.PUT("/tickets/users/:userId/:ticketId/cancel", ticket.CancelTicketHandler(r.c, ticketService), idempotent)
+	publicProtected.GET("/tickets/users/:userId/:ticketId/cancel/quote", ticket.QuoteCancelTicketHandler(ticketService))
+
+	publicProtected.GET("/users/:userId/orders/:orderId", order.GetOrderHandler(ticketService))
+	publicProtected.POST("/users/:userId/orders/:orderId/cancel", order.CancelOrderHandler(r.c, ticketService), idempotent)
+	publicProtected.GET("/users/:userId/orders/:orderId/cancel/quote", order.QuoteCancelOrderHandler(ticketService))
+	publicProtected.GET("/users/:userId/orders/:orderId/receipt", order.GetOrderReceiptHandler(ticketService))
+
+	staffProtected.POST("/validate", boarding.ValidateTicketHandler(boardingService))
+	staffProtected.GET("/manifest-key", boarding.GetManifestKeyHandler(boardingService))
+	staffProtected.GET("/routes/:routeId/manifest", boarding.GetManifestHandler(boardingService))
+	staffProtected.POST("/routes/:routeId/manifest/sync", boarding.SyncManifestHandler(boardingService))
+	adminProtected.GET("/routes/:routeId/boarding-stats", boarding.GetBoardingStatsHandler(boardingService))
+
+	adminProtected.GET("/payments/:paymentId/ledger", ledger.GetPaymentLedgerHandler(ledgerService))
+	adminProtected.GET("/payments/:paymentId/ledger/check", ledger.CheckPaymentLedgerHandler(ledgerService))
+	adminProtected.GET("/orders/:orderNumber/ledger", ledger.GetOrderLedgerHandler(ledgerService))
+
+	adminProtected.GET("/refund-policies", refundpolicy.ListPoliciesHandler(refundPolicyService))
+	adminProtected.POST("/refund-policies", refundpolicy.CreatePolicyHandler(refundPolicyService))
+	adminProtected.GET("/refund-policies/:policyId", refundpolicy.GetPolicyHandler(refundPolicyService))
+	adminProtected.PUT("/refund-policies/:policyId", refundpolicy.UpdatePolicyHandler(refundPolicyService))
+	adminProtected.DELETE("/refund-policies/:policyId", refundpolicy.DeletePolicyHandler(refundPolicyService))
+
+	adminProtected.GET("/outbox", outbox.ListMessagesHandler(outboxService))
+	adminProtected.POST("/outbox/:messageId/resend", outbox.ResendMessageHandler(outboxService))
+
+	adminProtected.GET("/email-templates", emailtemplate.ListTemplatesHandler(emailTemplateService))
+	adminProtected.GET("/email-templates/schema", emailtemplate.GetSchemaHandler(emailTemplateService))
+	adminProtected.GET("/email-templates/:name/:locale", emailtemplate.GetTemplateHandler(emailTemplateService))
+	adminProtected.PUT("/email-templates/:name/:locale", emailtemplate.SaveTemplateHandler(emailTemplateService))
+	adminProtected.DELETE("/email-templates/:name/:locale", emailtemplate.DeleteTemplateHandler(emailTemplateService))
+	adminProtected.GET("/email-templates/:name/:locale/versions", emailtemplate.ListVersionsHandler(emailTemplateService))
+	adminProtected.POST("/email-templates/:name/:locale/versions/:version/restore", emailtemplate.RestoreVersionHandler(emailTemplateService))
+	adminProtected.POST("/email-templates/:name/:locale/preview", emailtemplate.PreviewHandler(emailTemplateService))
 
 	adminProtected.PUT("/pages/:title", page.UpdatePageHandler(pageService))
 	publicProtected.GET("/pages/:title", page.GetPageHandler(pageService))
@@ -140,5 +343,22 @@ func (r *Router) Build() *echo.Echo {
 	publicProtected.DELETE("/users/:userId/favorites/:routeId", favorite.RemoveFavoriteHandler(favService))
 	publicProtected.GET("/users/:userId/favorites", favorite.GetFavoritesHandler(favService))
 
-	return e
+	return e, jobs
+}
+
+// clientIP trusts X-Forwarded-For only when the request came through one of
+// the proxies, anyone else could pick the IP their requests are limited by.
+func clientIP(trustedProxies []string) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, cidr := range trustedProxies {
+		// the config has checked the ranges
+		_, ipRange, _ := net.ParseCIDR(cidr)
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+
+	return echo.ExtractIPFromXFFHeader(options...)
 }
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"aulway/internal/utils/errs"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	idempotencyInProgressTTL  = 2 * time.Minute
+	idempotencyMaxKeyLength   = 255
+	idempotencyRedisKeyPrefix = "idempotency:"
+)
+
+type idempotentResponse struct {
+	RequestHash string `json:"request_hash"`
+	Done        bool   `json:"done"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+type idempotencyCapture struct {
+	http.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (r *idempotencyCapture) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Idempotency replays the stored response when a request is retried with the same
+// Idempotency-Key header. Keys are scoped per user, and reusing a key for a
+// different request is rejected. Requests without the header pass through.
+func Idempotency(redisClient *redis.Client, ttl time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				return next(c)
+			}
+
+			if len(key) > idempotencyMaxKeyLength {
+				return c.JSON(http.StatusBadRequest, errs.Err{Err: "invalid idempotency key", ErrDesc: "key is too long"})
+			}
+
+			userID, _ := c.Get(UserIDKey).(string)
+			redisKey := idempotencyRedisKeyPrefix + userID + ":" + key
+
+			requestHash, err := hashRequest(c)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, errs.Err{Err: "invalid request", ErrDesc: err.Error()})
+			}
+
+			ctx := c.Request().Context()
+
+			marker, _ := json.Marshal(idempotentResponse{RequestHash: requestHash})
+			acquired, err := redisClient.SetNX(ctx, redisKey, marker, idempotencyInProgressTTL).Result()
+			if err != nil {
+				slog.Error("idempotency: failed to store key", "error", err.Error())
+				return c.JSON(http.StatusInternalServerError, errs.Err{Err: "idempotency check failed", ErrDesc: "server error"})
+			}
+
+			if !acquired {
+				return replayResponse(c, redisClient, redisKey, requestHash)
+			}
+
+			res := c.Response()
+			capture := &idempotencyCapture{ResponseWriter: res.Writer, body: new(bytes.Buffer)}
+			res.Writer = capture
+
+			handlerErr := next(c)
+			if handlerErr != nil {
+				// the error is rendered by echo afterwards, so there is nothing to store
+				redisClient.Del(ctx, redisKey)
+				return handlerErr
+			}
+
+			// the request already ran, so use a fresh context in case the client went away
+			storeCtx := context.WithoutCancel(ctx)
+
+			if !replayable(res.Status) {
+				// a retry may well succeed, so it has to run again
+				if err := redisClient.Del(storeCtx, redisKey).Err(); err != nil {
+					slog.Error("idempotency: failed to release key", "error", err.Error())
+				}
+				return nil
+			}
+
+			stored, _ := json.Marshal(idempotentResponse{
+				RequestHash: requestHash,
+				Done:        true,
+				Status:      res.Status,
+				ContentType: res.Header().Get(echo.HeaderContentType),
+				Body:        capture.body.Bytes(),
+			})
+
+			if err := redisClient.Set(storeCtx, redisKey, stored, ttl).Err(); err != nil {
+				slog.Error("idempotency: failed to store response", "error", err.Error())
+			}
+
+			return nil
+		}
+	}
+}
+
+// replayable reports whether a response with the status is the answer to the
+// request itself. Server errors, timeouts, conflicts and rate limits depend on
+// the moment it was made, a retry must not be handed them again.
+func replayable(status int) bool {
+	switch {
+	case status >= 200 && status < 300:
+		return true
+	case status == http.StatusRequestTimeout, status == http.StatusConflict, status == http.StatusTooManyRequests:
+		return false
+	case status >= 400 && status < 500:
+		return true
+	}
+
+	return false
+}
+
+func replayResponse(c echo.Context, redisClient *redis.Client, redisKey, requestHash string) error {
+	payload, err := redisClient.Get(c.Request().Context(), redisKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return c.JSON(http.StatusConflict, errs.Err{Err: "idempotency conflict", ErrDesc: "request with this key is still being processed, retry later"})
+	}
+	if err != nil {
+		slog.Error("idempotency: failed to read key", "error", err.Error())
+		return c.JSON(http.StatusInternalServerError, errs.Err{Err: "idempotency check failed", ErrDesc: "server error"})
+	}
+
+	var stored idempotentResponse
+	if err := json.Unmarshal(payload, &stored); err != nil {
+		return c.JSON(http.StatusInternalServerError, errs.Err{Err: "idempotency check failed", ErrDesc: "server error"})
+	}
+
+	if stored.RequestHash != requestHash {
+		return c.JSON(http.StatusUnprocessableEntity, errs.Err{Err: "idempotency key reused", ErrDesc: "key was already used with a different request"})
+	}
+
+	if !stored.Done {
+		return c.JSON(http.StatusConflict, errs.Err{Err: "idempotency conflict", ErrDesc: "request with this key is still being processed, retry later"})
+	}
+
+	c.Response().Header().Set(IdempotentReplayedHeader, "true")
+	return c.Blob(stored.Status, stored.ContentType, stored.Body)
+}
+
+// hashRequest fingerprints the method, path, query and body. The body is put
+// back so the handler can still bind it.
+func hashRequest(c echo.Context) (string, error) {
+	req := c.Request()
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return "", err
+		}
+		req.Body = io.NopCloser(bytes.NewBuffer(body))
+	}
+
+	h := sha256.New()
+	h.Write([]byte(req.Method + " " + req.URL.Path + "?" + req.URL.RawQuery + "\n"))
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+const testIdempotencyKey = "3f1c9a52-purchase"
+
+// idempotentServer serves POST /tickets through the middleware for the user
+// u-1, the handler answers with whatever handle returns.
+func idempotentServer(t *testing.T, handle echo.HandlerFunc) (*echo.Echo, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	e := echo.New()
+	e.POST("/tickets", handle, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(UserIDKey, "u-1")
+			return next(c)
+		}
+	}, Idempotency(client, time.Hour))
+
+	return e, mr
+}
+
+func postTicket(e *echo.Echo, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/tickets", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(IdempotencyKeyHeader, testIdempotencyKey)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestIdempotencyStoresAndReplays(t *testing.T) {
+	var calls atomic.Int32
+	e, mr := idempotentServer(t, func(c echo.Context) error {
+		calls.Add(1)
+		return c.JSON(http.StatusCreated, map[string]string{"id": "t-1"})
+	})
+
+	first := postTicket(e, `{"seats":[1]}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+	require.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+	require.True(t, mr.Exists(idempotencyRedisKeyPrefix+"u-1:"+testIdempotencyKey))
+	require.Equal(t, time.Hour, mr.TTL(idempotencyRedisKeyPrefix+"u-1:"+testIdempotencyKey))
+
+	replay := postTicket(e, `{"seats":[1]}`)
+	require.Equal(t, http.StatusCreated, replay.Code)
+	require.Equal(t, "true", replay.Header().Get(IdempotentReplayedHeader))
+	require.Equal(t, first.Body.String(), replay.Body.String())
+	require.Equal(t, first.Header().Get(echo.HeaderContentType), replay.Header().Get(echo.HeaderContentType))
+	require.EqualValues(t, 1, calls.Load())
+
+	// the key belongs to the first request, another body is not served its answer
+	other := postTicket(e, `{"seats":[2]}`)
+	require.Equal(t, http.StatusUnprocessableEntity, other.Code)
+	require.EqualValues(t, 1, calls.Load())
+}
+
+func TestIdempotencyRefusesDuplicateInProgress(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	e, _ := idempotentServer(t, func(c echo.Context) error {
+		close(started)
+		<-release
+		return c.JSON(http.StatusCreated, map[string]string{"id": "t-1"})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- postTicket(e, `{"seats":[1]}`) }()
+	<-started
+
+	duplicate := postTicket(e, `{"seats":[1]}`)
+	require.Equal(t, http.StatusConflict, duplicate.Code)
+
+	// a different request with the key in use is still told apart
+	mismatch := postTicket(e, `{"seats":[2]}`)
+	require.Equal(t, http.StatusUnprocessableEntity, mismatch.Code)
+
+	close(release)
+	require.Equal(t, http.StatusCreated, (<-done).Code)
+}
+
+func TestIdempotencyRunsAgainAfterTransientFailure(t *testing.T) {
+	for _, status := range []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusConflict, http.StatusTooManyRequests} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			var calls atomic.Int32
+			e, mr := idempotentServer(t, func(c echo.Context) error {
+				if calls.Add(1) == 1 {
+					return c.JSON(status, map[string]string{"error": "try again"})
+				}
+				return c.JSON(http.StatusCreated, map[string]string{"id": "t-1"})
+			})
+
+			require.Equal(t, status, postTicket(e, `{"seats":[1]}`).Code)
+			require.False(t, mr.Exists(idempotencyRedisKeyPrefix+"u-1:"+testIdempotencyKey))
+
+			retry := postTicket(e, `{"seats":[1]}`)
+			require.Equal(t, http.StatusCreated, retry.Code)
+			require.Empty(t, retry.Header().Get(IdempotentReplayedHeader))
+			require.EqualValues(t, 2, calls.Load())
+		})
+	}
+}
+
+func TestIdempotencyReplaysClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	e, _ := idempotentServer(t, func(c echo.Context) error {
+		calls.Add(1)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "seat 99 does not exist"})
+	})
+
+	require.Equal(t, http.StatusBadRequest, postTicket(e, `{"seats":[99]}`).Code)
+	replay := postTicket(e, `{"seats":[99]}`)
+	require.Equal(t, http.StatusBadRequest, replay.Code)
+	require.Equal(t, "true", replay.Header().Get(IdempotentReplayedHeader))
+	require.EqualValues(t, 1, calls.Load())
+}
+
+func TestIdempotencyPassesRequestsWithoutKey(t *testing.T) {
+	var calls atomic.Int32
+	e, mr := idempotentServer(t, func(c echo.Context) error {
+		calls.Add(1)
+		return c.NoContent(http.StatusCreated)
+	})
+
+	for range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/tickets", strings.NewReader(`{"seats":[1]}`))
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusCreated, rec.Code)
+	}
+	require.EqualValues(t, 2, calls.Load())
+	require.Empty(t, mr.Keys())
+}
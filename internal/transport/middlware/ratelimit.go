@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"aulway/internal/ratelimit"
+	"aulway/internal/utils/errs"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RateLimit allows limit requests per sliding window from one client IP,
+// the rest are answered 429 with Retry-After. Routes sharing the scope share
+// the count.
+func RateLimit(limiter *ratelimit.Limiter, scope string, limit int, window time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return limited(c, next, limiter, scope+":ip:"+c.RealIP(), limit, window)
+		}
+	}
+}
+
+// RateLimitAccount allows limit requests per sliding window for the account
+// named by the field of the JSON or form body, whichever IPs they come from.
+// Requests without the field pass through to be rejected by the handler,
+// bodies that cannot be read for the field are rejected here, the handler
+// could still bind them.
+func RateLimitAccount(limiter *ratelimit.Limiter, scope string, limit int, window time.Duration, field string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			account, err := bodyField(c, field)
+			if errors.Is(err, errUnsupportedBody) {
+				return c.JSON(http.StatusUnsupportedMediaType, errs.Err{Err: "unsupported request body", ErrDesc: "send JSON or a form"})
+			}
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, errs.Err{Err: "invalid request body", ErrDesc: err.Error()})
+			}
+			if account == "" {
+				return next(c)
+			}
+
+			return limited(c, next, limiter, scope+":"+field+":"+account, limit, window)
+		}
+	}
+}
+
+func limited(c echo.Context, next echo.HandlerFunc, limiter *ratelimit.Limiter, key string, limit int, window time.Duration) error {
+	allowed, wait, err := limiter.Allow(c.Request().Context(), key, limit, window)
+	if err != nil {
+		slog.Error("rate limit: failed to count request", "error", err.Error())
+		return c.JSON(http.StatusInternalServerError, errs.Err{Err: "rate limit check failed", ErrDesc: "server error"})
+	}
+	if !allowed {
+		ratelimit.SetRetryAfter(c.Response().Header(), wait)
+		return c.JSON(http.StatusTooManyRequests, errs.Err{Err: "too many requests", ErrDesc: "try again later"})
+	}
+
+	return next(c)
+}
+
+var errUnsupportedBody = errors.New("unsupported request body")
+
+// bodyField reads a string field of the JSON or form body and puts the body
+// back for the handler. The value is lower cased and stripped of spaces,
+// dashes and brackets, and a phone starting with 8 is written with +7, so an
+// account is counted once however it is spelled.
+func bodyField(c echo.Context, field string) (string, error) {
+	req := c.Request()
+	if req.Body == nil {
+		return "", nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	req.Body = io.NopCloser(bytes.NewBuffer(body))
+	if len(body) == 0 {
+		return "", nil
+	}
+
+	// the handler binds names in any case, so the field is looked up the
+	// same way and a body naming it twice is refused rather than guessed at
+	var values []string
+	contentType := req.Header.Get(echo.HeaderContentType)
+	switch {
+	case strings.HasPrefix(contentType, echo.MIMEApplicationJSON):
+		var fields map[string]any
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return "", err
+		}
+		for name, v := range fields {
+			if strings.EqualFold(name, field) {
+				s, _ := v.(string)
+				values = append(values, s)
+			}
+		}
+	case strings.HasPrefix(contentType, echo.MIMEApplicationForm), strings.HasPrefix(contentType, echo.MIMEMultipartForm):
+		// the parsed form is kept on the request, the handler binds from it
+		params, err := c.FormParams()
+		if err != nil {
+			return "", err
+		}
+		req.Body = io.NopCloser(bytes.NewBuffer(body))
+		for name, vs := range params {
+			if strings.EqualFold(name, field) {
+				values = append(values, vs...)
+			}
+		}
+	default:
+		return "", errUnsupportedBody
+	}
+	if len(values) > 1 {
+		return "", fmt.Errorf("%s is given more than once", field)
+	}
+
+	var value string
+	if len(values) == 1 {
+		value = values[0]
+	}
+	value = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(strings.ToLower(value))
+	if strings.HasPrefix(value, "8") && len(value) == 11 {
+		value = "+7" + value[1:]
+	}
+
+	return value, nil
+}
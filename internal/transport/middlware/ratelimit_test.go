@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyField(t *testing.T) {
+	tests := []struct {
+		name        string
+		field       string
+		contentType string
+		body        string
+		want        string
+		wantErr     bool
+	}{
+		{name: "json", field: "email", contentType: echo.MIMEApplicationJSON, body: `{"email":"Aruzhan@Example.com"}`, want: "aruzhan@example.com"},
+		{name: "json in another case", field: "email", contentType: echo.MIMEApplicationJSON, body: `{"EMAIL":"aruzhan@example.com"}`, want: "aruzhan@example.com"},
+		{name: "json named twice", field: "email", contentType: echo.MIMEApplicationJSON, body: `{"email":"a@example.com","Email":"b@example.com"}`, wantErr: true},
+		{name: "json without the field", field: "email", contentType: echo.MIMEApplicationJSON, body: `{"password":"secret"}`, want: ""},
+		{name: "broken json", field: "email", contentType: echo.MIMEApplicationJSON, body: `{"email":`, wantErr: true},
+		{name: "form", field: "phone", contentType: echo.MIMEApplicationForm, body: "phone=8+(701)+123-45-67", want: "+77011234567"},
+		{name: "form in another case", field: "phone", contentType: echo.MIMEApplicationForm, body: "Phone=%2B77011234567", want: "+77011234567"},
+		{name: "form named twice", field: "phone", contentType: echo.MIMEApplicationForm, body: "phone=%2B77011234567&phone=%2B77017654321", wantErr: true},
+		{name: "xml", field: "phone", contentType: echo.MIMEApplicationXML, body: "<phone>+77011234567</phone>", wantErr: true},
+		{name: "no content type", field: "email", body: `{"email":"a@example.com"}`, wantErr: true},
+		{name: "empty body", field: "email", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/auth/signin", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set(echo.HeaderContentType, tt.contentType)
+			}
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			got, err := bodyField(c, tt.field)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBodyFieldLeavesBodyToHandler(t *testing.T) {
+	var got struct {
+		Phone string `json:"phone" form:"phone"`
+	}
+	handler := func(c echo.Context) error {
+		return c.Bind(&got)
+	}
+
+	for _, contentType := range []string{echo.MIMEApplicationJSON, echo.MIMEApplicationForm} {
+		body := `{"phone":"+77011234567"}`
+		if contentType == echo.MIMEApplicationForm {
+			body = "phone=%2B77011234567"
+		}
+		req := httptest.NewRequest(http.MethodPost, "/auth/signin", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, contentType)
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+
+		got.Phone = ""
+		_, err := bodyField(c, "phone")
+		require.NoError(t, err)
+		require.NoError(t, handler(c))
+		require.Equal(t, "+77011234567", got.Phone, contentType)
+	}
+}
+
+func TestRateLimitAccountRejectsOtherBodies(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/auth/signin", strings.NewReader("<email>a@example.com</email>"))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationXML)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+
+	// the limiter is not reached, the body is refused before it
+	err := RateLimitAccount(nil, "auth", 1, 0, "email")(func(c echo.Context) error {
+		t.Fatal("the handler must not run")
+		return nil
+	})(c)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+}
@@ -1,21 +1,31 @@
 package middleware
 
 import (
+	"aulway/internal/domain"
 	"aulway/internal/utils/errs"
+	"context"
+	"errors"
 	"github.com/labstack/echo/v4"
 	"log/slog"
 	"net/http"
 	"strings"
-
-	"github.com/golang-jwt/jwt/v4"
 )
 
 const (
-	UserIDKey   = "user_id"
-	UserRoleKey = "user_role"
+	UserIDKey    = "user_id"
+	UserRoleKey  = "user_role"
+	SessionIDKey = "session_id"
 )
 
-func JWTAuth(jwtSecret string) echo.MiddlewareFunc {
+// TokenVerifier tells who a bearer token was issued to.
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (*domain.Principal, error)
+}
+
+// JWTAuth lets through requests with a bearer token the verifier accepts,
+// either an access token of a session that was not ended or a Firebase ID
+// token.
+func JWTAuth(verifier TokenVerifier) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			authHeader := c.Request().Header.Get("Authorization")
@@ -28,36 +38,26 @@ func JWTAuth(jwtSecret string) echo.MiddlewareFunc {
 				return c.JSON(http.StatusUnauthorized, errs.Err{Err: "authorization failed", ErrDesc: "Bearer token required"})
 			}
 
-			token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, jwt.NewValidationError("unexpected signing method", jwt.ValidationErrorSignatureInvalid)
-				}
-				return []byte(jwtSecret), nil
-			})
-
-			if err != nil || !token.Valid {
+			principal, err := verifier.Verify(c.Request().Context(), tokenString)
+			switch {
+			case errors.Is(err, errs.ErrSessionEnded):
+				return c.JSON(http.StatusUnauthorized, errs.Err{Err: "authorization failed", ErrDesc: "session ended"})
+			case errors.Is(err, errs.ErrAccountExists):
+				return c.JSON(http.StatusConflict, errs.Err{Err: "authorization failed", ErrDesc: err.Error()})
+			case errors.Is(err, errs.ErrInvalidToken), errors.Is(err, errs.ErrUnsupportedToken):
 				return c.JSON(http.StatusUnauthorized, errs.Err{Err: "authorization failed", ErrDesc: "invalid token"})
+			case err != nil:
+				slog.Error("authorization", "error", err.Error())
+				return c.JSON(http.StatusInternalServerError, errs.Err{Err: "authorization failed", ErrDesc: "server error"})
 			}
 
-			if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-				claimedUID, ok := claims["user_id"].(string)
-				if !ok {
-					slog.Error("authorization", "error", "no user property in claims")
-					return c.JSON(http.StatusBadRequest, errs.Err{Err: "authorization failed", ErrDesc: "invalid token"})
-				}
-
-				claimedRole, ok := claims[UserRoleKey].(string)
-				if !ok {
-					slog.Error("authorization", "error", "no role property in claims")
-					return c.JSON(http.StatusBadRequest, errs.Err{Err: "authorization failed", ErrDesc: "invalid token"})
-				}
-
-				c.Set(UserIDKey, claimedUID)
-				c.Set(UserRoleKey, claimedRole)
-
-				return next(c)
+			c.Set(UserIDKey, principal.UserID)
+			c.Set(UserRoleKey, principal.Role)
+			if principal.SessionID != "" {
+				c.Set(SessionIDKey, principal.SessionID)
 			}
-			return c.JSON(http.StatusUnauthorized, errs.Err{Err: "authorization failed", ErrDesc: "invalid token"})
+
+			return next(c)
 		}
 	}
 }
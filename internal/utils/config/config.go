@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/joho/godotenv"
 	"github.com/vrischmann/envconfig"
+	"net"
 )
 
 func NewConfig() (Config, error) {
@@ -16,5 +17,26 @@ func NewConfig() (Config, error) {
 		return Config{}, fmt.Errorf("get configs: %w", err)
 	}
 
+	if err := cfg.validate(); err != nil {
+		return Config{}, fmt.Errorf("get configs: %w", err)
+	}
+
 	return cfg, nil
 }
+
+// validate checks the settings that depend on each other.
+func (c Config) validate() error {
+	// without the secret anyone could post Stripe events that pay, refund or
+	// cancel tickets
+	if c.PaymentProvider == "stripe" && c.StripeWebhookSecret == "" {
+		return fmt.Errorf("STRIPE_WEBHOOK_SECRET is required with the stripe payment provider")
+	}
+
+	for _, cidr := range c.RateLimit.TrustedProxies {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("RATE_LIMIT_TRUSTED_PROXIES: %w", err)
+		}
+	}
+
+	return nil
+}
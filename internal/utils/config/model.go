@@ -16,15 +16,40 @@ type Postgres struct {
 }
 
 type Config struct {
-	Port              string
-	Address           string
-	JWTTokenSecret    string
-	AccessTokenExpire int
-	HeaderTimeout     time.Duration
-	StripeKey         string
+	Port                 string
+	Address              string
+	JWTTokenSecret       string
+	TicketSigningKey     string
+	ManifestSigningKey   string
+	AccessTokenTTL       time.Duration `envconfig:"default=15m"`
+	RefreshTokenTTL      time.Duration `envconfig:"default=720h"`
+	HeaderTimeout        time.Duration
+	StripeKey            string          `envconfig:"optional"`
+	StripeWebhookSecret  string          `envconfig:"optional"`
+	PaymentProvider      string          `envconfig:"default=stripe"`
+	MockGatewayAddress   string          `envconfig:"default=127.0.0.1:12112"`
+	SeatHoldTTL          time.Duration   `envconfig:"default=10m"`
+	PaymentTTL           time.Duration   `envconfig:"default=30m"`
+	PaymentSweepInterval time.Duration   `envconfig:"default=1m"`
+	IdempotencyKeyTTL    time.Duration   `envconfig:"default=24h"`
+	NoShowGrace          time.Duration   `envconfig:"default=30m"`
+	BoardingOpens        time.Duration   `envconfig:"default=2h"`
+	NoShowInterval       time.Duration   `envconfig:"default=5m"`
+	OutboxInterval       time.Duration   `envconfig:"default=10s"`
+	OutboxRetryDelay     time.Duration   `envconfig:"default=30s"`
+	OutboxMaxAttempts    int             `envconfig:"default=8"`
+	ReminderLeads        []time.Duration `envconfig:"optional"`
+	ReminderInterval     time.Duration   `envconfig:"default=1m"`
+	RefundInterval       time.Duration   `envconfig:"default=1m"`
+	RefundRetryDelay     time.Duration   `envconfig:"default=1m"`
 	Postgres
 	Redis
 	SMTP
+	Wallet
+	Firebase
+	SMS
+	RateLimit
+	OIDC
 }
 
 type Redis struct {
@@ -37,6 +62,68 @@ type Redis struct {
 	Duration time.Duration
 }
 
+// Wallet holds the optional Apple Wallet and Google Wallet credentials,
+// either wallet is disabled while its settings are empty.
+type Wallet struct {
+	ApplePassTypeID   string `envconfig:"optional"`
+	AppleTeamID       string `envconfig:"optional"`
+	AppleCertFile     string `envconfig:"optional"`
+	AppleKeyFile      string `envconfig:"optional"`
+	AppleWWDRFile     string `envconfig:"optional"`
+	WebServiceURL     string `envconfig:"optional"`
+	GoogleIssuerID    string `envconfig:"optional"`
+	GoogleClassSuffix string `envconfig:"optional"`
+	GoogleCredentials string `envconfig:"optional"`
+}
+
+// Firebase holds the optional service account of the Firebase project,
+// push notifications are disabled while it is empty. Firebase ID tokens of
+// the project are accepted in place of access tokens when ProjectID is set.
+type Firebase struct {
+	Credentials string `envconfig:"optional"`
+	ProjectID   string `envconfig:"optional"`
+}
+
+// OIDC holds the clients of the providers users can sign in with, a provider
+// is offered once its client id is set. BaseURL is where browsers reach the
+// API, the providers send users back to BaseURL/auth/oidc/<provider>/callback.
+type OIDC struct {
+	BaseURL            string        `envconfig:"optional"`
+	StateTTL           time.Duration `envconfig:"default=10m"`
+	GoogleClientID     string        `envconfig:"optional"`
+	GoogleClientSecret string        `envconfig:"optional"`
+	AppleClientID      string        `envconfig:"optional"`
+	AppleTeamID        string        `envconfig:"optional"`
+	AppleKeyID         string        `envconfig:"optional"`
+	AppleKeyFile       string        `envconfig:"optional"`
+}
+
+// RateLimit bounds the requests to the auth endpoints per client IP and per
+// account, and locks accounts out after repeated failed attempts. The client
+// IP is taken from X-Forwarded-For only behind the TrustedProxies, given as
+// CIDR ranges, otherwise it is the address the request came from.
+type RateLimit struct {
+	TrustedProxies   []string      `envconfig:"optional"`
+	IPLimit          int           `envconfig:"default=30"`
+	IPWindow         time.Duration `envconfig:"default=1m"`
+	AccountLimit     int           `envconfig:"default=10"`
+	AccountWindow    time.Duration `envconfig:"default=15m"`
+	LockoutThreshold int           `envconfig:"default=5"`
+	LockoutBase      time.Duration `envconfig:"default=1m"`
+	LockoutMax       time.Duration `envconfig:"default=1h"`
+}
+
+// SMS holds the Mobizon account the verification codes are texted from,
+// without an API key phone codes cannot be sent.
+type SMS struct {
+	APIKey         string        `envconfig:"optional"`
+	From           string        `envconfig:"optional"`
+	BaseURL        string        `envconfig:"default=https://api.mobizon.kz"`
+	CodeTTL        time.Duration `envconfig:"default=5m"`
+	ResendInterval time.Duration `envconfig:"default=1m"`
+	HourlyLimit    int           `envconfig:"default=5"`
+}
+
 type SMTP struct {
 	Host     string
 	Port     string
@@ -15,8 +15,36 @@ func (e Err) Error() string {
 }
 
 var ErrNoSeatsAvailable = errors.New("no seats available")
+var ErrSeatTaken = errors.New("seat is already taken")
+var ErrUnknownSeat = errors.New("seat does not exist on this bus")
+var ErrHoldNotFound = errors.New("seat hold not found or expired")
+var ErrRouteHasRefundPolicy = errors.New("route already has a refund policy")
+var ErrCancellationNotAllowed = errors.New("cancellation not allowed")
+var ErrInvalidTicketToken = errors.New("ticket code is not valid")
+var ErrTicketNotBoardable = errors.New("ticket cannot be used for boarding")
+var ErrTicketAlreadyBoarded = errors.New("ticket has already been used for boarding")
+var ErrWrongRoute = errors.New("ticket is for another route")
+var ErrOutsideBoardingWindow = errors.New("ticket is not valid for boarding at this time")
+var ErrWalletNotConfigured = errors.New("wallet passes are not configured")
+var ErrSMSNotConfigured = errors.New("text messages are not configured")
+var ErrWalletUnauthorized = errors.New("wallet pass authentication failed")
 var ErrEmptyRequestFields = errors.New("request fields cannot be empty")
 var ErrRequestBinding = errors.New("request binding error")
 var ErrIncorrectPhoneFormat = errors.New("incorrect phone format error")
 var ErrIncorrectEmailFormat = errors.New("incorrect email format error")
 var ErrIncorrectPasswordFormat = errors.New("incorrect password format error")
+var ErrUnknownEmailTemplate = errors.New("no such email template")
+var ErrInvalidEmailTemplate = errors.New("email template is not valid")
+var ErrInvalidCode = errors.New("verification code is invalid or expired")
+var ErrTooManyCodeRequests = errors.New("too many verification codes requested for this phone, try again later")
+var ErrTooManyEmailCodes = errors.New("a verification code was emailed recently, try again later")
+var ErrAccountExists = errors.New("an account with this email exists, sign in to it and verify the email to link")
+var ErrInvalidRefreshToken = errors.New("refresh token is invalid or expired")
+var ErrUnsupportedToken = errors.New("token is not of this kind")
+var ErrInvalidToken = errors.New("invalid token")
+var ErrSessionEnded = errors.New("session ended")
+var ErrUnknownProvider = errors.New("sign-in provider is not available")
+var ErrInvalidSigninState = errors.New("sign-in request is invalid or expired")
+var ErrSigninRefused = errors.New("sign-in with the provider could not be verified")
+var ErrEmailNotVerified = errors.New("provider did not verify the email of the account")
+var ErrLastSigninMethod = errors.New("cannot unlink the only way to sign in")
@@ -0,0 +1,61 @@
+package wallet
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// APNsHost is the production push endpoint, passes have no sandbox.
+const APNsHost = "https://api.push.apple.com"
+
+// ErrDeviceUnregistered means the push token is no longer valid and the
+// registration can be dropped.
+var ErrDeviceUnregistered = errors.New("device is no longer registered")
+
+// APNs tells devices that a pass changed. The push is empty, the device
+// then asks the web service which passes to download again.
+type APNs struct {
+	host   string
+	client *http.Client
+}
+
+func NewAPNs(host string, cert tls.Certificate) *APNs {
+	return &APNs{
+		host: strings.TrimSuffix(host, "/"),
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig:   &tls.Config{Certificates: []tls.Certificate{cert}},
+				ForceAttemptHTTP2: true,
+			},
+		},
+	}
+}
+
+func (p *APNs) Push(ctx context.Context, passTypeID, pushToken string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.host+"/3/device/"+pushToken, strings.NewReader("{}"))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("apns-topic", passTypeID)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("push pass update: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusGone:
+		return ErrDeviceUnregistered
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("push pass update: APNs returned %s", resp.Status)
+	}
+
+	return nil
+}
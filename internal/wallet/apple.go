@@ -0,0 +1,317 @@
+package wallet
+
+import (
+	"archive/zip"
+	"aulway/internal/domain"
+	"bytes"
+	"crypto"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"go.mozilla.org/pkcs7"
+	"os"
+	"sort"
+	"time"
+)
+
+// PKPassContentType is the media type of a pass bundle.
+const PKPassContentType = "application/vnd.apple.pkpass"
+
+// Pass is the pass.json of an Apple Wallet boarding pass.
+type Pass struct {
+	FormatVersion       int       `json:"formatVersion"`
+	PassTypeIdentifier  string    `json:"passTypeIdentifier"`
+	SerialNumber        string    `json:"serialNumber"`
+	TeamIdentifier      string    `json:"teamIdentifier"`
+	OrganizationName    string    `json:"organizationName"`
+	Description         string    `json:"description"`
+	LogoText            string    `json:"logoText,omitempty"`
+	ForegroundColor     string    `json:"foregroundColor,omitempty"`
+	BackgroundColor     string    `json:"backgroundColor,omitempty"`
+	LabelColor          string    `json:"labelColor,omitempty"`
+	WebServiceURL       string    `json:"webServiceURL,omitempty"`
+	AuthenticationToken string    `json:"authenticationToken,omitempty"`
+	RelevantDate        string    `json:"relevantDate,omitempty"`
+	ExpirationDate      string    `json:"expirationDate,omitempty"`
+	Voided              bool      `json:"voided,omitempty"`
+	Barcodes            []Barcode `json:"barcodes"`
+	BoardingPass        PassStyle `json:"boardingPass"`
+}
+
+type Barcode struct {
+	Format          string `json:"format"`
+	Message         string `json:"message"`
+	MessageEncoding string `json:"messageEncoding"`
+	AltText         string `json:"altText,omitempty"`
+}
+
+type PassStyle struct {
+	TransitType     string  `json:"transitType"`
+	HeaderFields    []Field `json:"headerFields,omitempty"`
+	PrimaryFields   []Field `json:"primaryFields,omitempty"`
+	SecondaryFields []Field `json:"secondaryFields,omitempty"`
+	AuxiliaryFields []Field `json:"auxiliaryFields,omitempty"`
+	BackFields      []Field `json:"backFields,omitempty"`
+}
+
+// Field is a pass field. With DateStyle set the value is an ISO 8601 date
+// and the device shows it in its own time zone. ChangeMessage is shown on
+// the lock screen when an update changes the value.
+type Field struct {
+	Key           string `json:"key"`
+	Label         string `json:"label,omitempty"`
+	Value         string `json:"value"`
+	DateStyle     string `json:"dateStyle,omitempty"`
+	TimeStyle     string `json:"timeStyle,omitempty"`
+	ChangeMessage string `json:"changeMessage,omitempty"`
+}
+
+// Apple builds and signs pass bundles with a Pass Type ID certificate.
+type Apple struct {
+	PassTypeID    string
+	TeamID        string
+	WebServiceURL string
+
+	cert *x509.Certificate
+	key  crypto.PrivateKey
+	wwdr *x509.Certificate
+}
+
+// NewApple takes the PEM encoded pass certificate, its private key and the
+// Apple WWDR intermediate certificate.
+func NewApple(passTypeID, teamID, webServiceURL string, certPEM, keyPEM, wwdrPEM []byte) (*Apple, error) {
+	cert, err := parseCertificate(certPEM)
+	if err != nil {
+		return nil, fmt.Errorf("pass certificate: %w", err)
+	}
+	wwdr, err := parseCertificate(wwdrPEM)
+	if err != nil {
+		return nil, fmt.Errorf("WWDR certificate: %w", err)
+	}
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("pass key: %w", err)
+	}
+
+	return &Apple{
+		PassTypeID:    passTypeID,
+		TeamID:        teamID,
+		WebServiceURL: webServiceURL,
+		cert:          cert,
+		key:           key,
+		wwdr:          wwdr,
+	}, nil
+}
+
+// LoadApple reads the PEM files of NewApple from disk.
+func LoadApple(passTypeID, teamID, webServiceURL, certFile, keyFile, wwdrFile string) (*Apple, error) {
+	files := make([][]byte, 0, 3)
+	for _, name := range []string{certFile, keyFile, wwdrFile} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		files = append(files, data)
+	}
+
+	return NewApple(passTypeID, teamID, webServiceURL, files[0], files[1], files[2])
+}
+
+// Certificate is the pass certificate as a TLS client certificate, pass
+// update pushes are authenticated with it.
+func (a *Apple) Certificate() tls.Certificate {
+	return tls.Certificate{
+		Certificate: [][]byte{a.cert.Raw},
+		PrivateKey:  a.key,
+		Leaf:        a.cert,
+	}
+}
+
+// Pass describes a ticket as a boarding pass. The barcode holds the signed
+// ticket token conductors scan, authToken authenticates the device when it
+// asks the web service for updates.
+func (a *Apple) Pass(ticket *domain.Ticket, route *domain.Route, bus *domain.Bus, token, authToken string) *Pass {
+	pass := &Pass{
+		FormatVersion:      1,
+		PassTypeIdentifier: a.PassTypeID,
+		SerialNumber:       ticket.ID,
+		TeamIdentifier:     a.TeamID,
+		OrganizationName:   "AulWay",
+		Description:        "AulWay bus ticket " + route.Departure + " → " + route.Destination,
+		LogoText:           "AulWay",
+		ForegroundColor:    "rgb(255, 255, 255)",
+		BackgroundColor:    "rgb(45, 137, 239)",
+		LabelColor:         "rgb(220, 235, 252)",
+		RelevantDate:       route.StartDate.Format(time.RFC3339),
+		ExpirationDate:     route.EndDate.Format(time.RFC3339),
+		Voided:             !Valid(ticket),
+		Barcodes: []Barcode{{
+			Format:          "PKBarcodeFormatQR",
+			Message:         token,
+			MessageEncoding: "iso-8859-1",
+			AltText:         ticket.OrderNumber,
+		}},
+		BoardingPass: PassStyle{
+			TransitType: "PKTransitTypeBus",
+			HeaderFields: []Field{
+				{Key: "seat", Label: "SEAT", Value: ticket.SeatNumber},
+			},
+			PrimaryFields: []Field{
+				{Key: "origin", Label: "FROM", Value: route.Departure},
+				{Key: "destination", Label: "TO", Value: route.Destination},
+			},
+			SecondaryFields: []Field{
+				{
+					Key:           "departure",
+					Label:         "DEPARTS",
+					Value:         route.StartDate.Format(time.RFC3339),
+					DateStyle:     "PKDateStyleMedium",
+					TimeStyle:     "PKDateStyleShort",
+					ChangeMessage: "Departure changed to %@",
+				},
+				{
+					Key:           "arrival",
+					Label:         "ARRIVES",
+					Value:         route.EndDate.Format(time.RFC3339),
+					DateStyle:     "PKDateStyleMedium",
+					TimeStyle:     "PKDateStyleShort",
+					ChangeMessage: "Arrival changed to %@",
+				},
+			},
+			AuxiliaryFields: []Field{
+				{Key: "order", Label: "ORDER", Value: ticket.OrderNumber},
+				{Key: "status", Label: "STATUS", Value: ticket.Status, ChangeMessage: "Ticket is now %@"},
+			},
+			BackFields: []Field{
+				{Key: "pickup", Label: "Pickup", Value: route.DepartureLocation, ChangeMessage: "Pickup moved to %@"},
+				{Key: "dropoff", Label: "Drop-off", Value: route.DestinationLocation},
+				{Key: "price", Label: "Price", Value: fmt.Sprintf("%d ₸", ticket.Price)},
+			},
+		},
+	}
+	if bus != nil {
+		pass.BoardingPass.HeaderFields = append(pass.BoardingPass.HeaderFields, Field{Key: "bus", Label: "BUS", Value: bus.Number})
+	}
+	if a.WebServiceURL != "" {
+		pass.WebServiceURL = a.WebServiceURL
+		pass.AuthenticationToken = authToken
+	}
+
+	return pass
+}
+
+// Bundle packs the pass with its images into a signed .pkpass archive:
+// manifest.json lists the SHA-1 of every file and signature is a detached
+// PKCS#7 signature of the manifest.
+func (a *Apple) Bundle(pass *Pass) ([]byte, error) {
+	passJSON, err := json.Marshal(pass)
+	if err != nil {
+		return nil, fmt.Errorf("encode pass: %w", err)
+	}
+
+	files := map[string][]byte{
+		"pass.json":   passJSON,
+		"icon.png":    icon29,
+		"icon@2x.png": icon58,
+		"logo.png":    icon29,
+		"logo@2x.png": icon58,
+	}
+
+	manifest := make(map[string]string, len(files))
+	for name, data := range files {
+		sum := sha1.Sum(data)
+		manifest[name] = hex.EncodeToString(sum[:])
+	}
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("encode manifest: %w", err)
+	}
+
+	signature, err := a.sign(manifestJSON)
+	if err != nil {
+		return nil, err
+	}
+	files["manifest.json"] = manifestJSON
+	files["signature"] = signature
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, fmt.Errorf("write %s: %w", name, err)
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			return nil, fmt.Errorf("write %s: %w", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("close pass bundle: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (a *Apple) sign(manifest []byte) ([]byte, error) {
+	sd, err := pkcs7.NewSignedData(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("sign manifest: %w", err)
+	}
+	sd.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+	if err := sd.AddSignerChain(a.cert, a.key, []*x509.Certificate{a.wwdr}, pkcs7.SignerInfoConfig{}); err != nil {
+		return nil, fmt.Errorf("sign manifest: %w", err)
+	}
+	sd.Detach()
+
+	signature, err := sd.Finish()
+	if err != nil {
+		return nil, fmt.Errorf("sign manifest: %w", err)
+	}
+
+	return signature, nil
+}
+
+// Valid reports whether the ticket can still be used, other passes are
+// shown as voided.
+func Valid(ticket *domain.Ticket) bool {
+	return ticket.Status == "approved" || ticket.Status == "boarded"
+}
+
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM certificate found")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func parsePrivateKey(data []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM private key found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New("unsupported private key format")
+}
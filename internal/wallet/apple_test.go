@@ -0,0 +1,253 @@
+package wallet
+
+import (
+	"archive/zip"
+	"aulway/internal/domain"
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"go.mozilla.org/pkcs7"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates the way Apple does: a root, the WWDR
+// intermediate and a Pass Type ID certificate signed by it.
+type testCA struct {
+	root     *x509.Certificate
+	wwdr     *x509.Certificate
+	wwdrPEM  []byte
+	passPEM  []byte
+	passKey  []byte
+	passCert *x509.Certificate
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	rootKey := newKey(t)
+	root := issue(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, rootKey, rootKey)
+
+	wwdrKey := newKey(t)
+	wwdr := issue(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "Test WWDR"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, root, wwdrKey, rootKey)
+
+	passKey := newKey(t)
+	pass := issue(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "Pass Type ID: pass.kz.aulway.ticket", OrganizationalUnit: []string{"TEAM123"}},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, wwdr, passKey, wwdrKey)
+
+	return &testCA{
+		root:     root,
+		wwdr:     wwdr,
+		wwdrPEM:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: wwdr.Raw}),
+		passPEM:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: pass.Raw}),
+		passKey:  pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(passKey)}),
+		passCert: pass,
+	}
+}
+
+func newKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func issue(t *testing.T, template, parent *x509.Certificate, key, parentKey *rsa.PrivateKey) *x509.Certificate {
+	t.Helper()
+
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent = template
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func testTicket() (*domain.Ticket, *domain.Route, *domain.Bus) {
+	start := time.Date(2025, 12, 12, 8, 0, 0, 0, time.FixedZone("Almaty", 5*60*60))
+	return &domain.Ticket{
+		ID:          "0196a3c2-7d1e-7b7a-9d55-6a2f1c8e4b10",
+		RouteID:     "route-1",
+		Price:       5000,
+		SeatNumber:  "3B",
+		Status:      "approved",
+		OrderNumber: "AW-100234",
+	}, &domain.Route{
+		Id:                "route-1",
+		Departure:         "Алматы",
+		Destination:       "Талдыкорган",
+		DepartureLocation: "Сайран автовокзал",
+		StartDate:         start,
+		EndDate:           start.Add(4 * time.Hour),
+		BusId:             "bus-1",
+	}, &domain.Bus{Number: "A 123 BC"}
+}
+
+func unzip(t *testing.T, bundle []byte) map[string][]byte {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	if err != nil {
+		t.Fatalf("bundle is not a zip archive: %v", err)
+	}
+
+	files := make(map[string][]byte, len(zr.File))
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = data
+	}
+	return files
+}
+
+func TestBundleIsSignedByPassCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	apple, err := NewApple("pass.kz.aulway.ticket", "TEAM123", "https://aulway.kz/wallet", ca.passPEM, ca.passKey, ca.wwdrPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ticket, route, bus := testTicket()
+	bundle, err := apple.Bundle(apple.Pass(ticket, route, bus, "AW1.token.sig", "0123456789abcdef0123"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := unzip(t, bundle)
+
+	for _, name := range []string{"pass.json", "manifest.json", "signature", "icon.png", "icon@2x.png"} {
+		if _, ok := files[name]; !ok {
+			t.Fatalf("bundle has no %s", name)
+		}
+	}
+
+	var manifest map[string]string
+	if err := json.Unmarshal(files["manifest.json"], &manifest); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range files {
+		if name == "manifest.json" || name == "signature" {
+			continue
+		}
+		sum := sha1.Sum(data)
+		if manifest[name] != hex.EncodeToString(sum[:]) {
+			t.Fatalf("manifest hash of %s does not match the file", name)
+		}
+	}
+
+	p7, err := pkcs7.Parse(files["signature"])
+	if err != nil {
+		t.Fatalf("signature is not PKCS#7: %v", err)
+	}
+	if len(p7.Content) != 0 {
+		t.Fatal("signature is not detached")
+	}
+	p7.Content = files["manifest.json"]
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.root)
+	if err := p7.VerifyWithChain(roots); err != nil {
+		t.Fatalf("signature does not verify up to the root: %v", err)
+	}
+	if !p7.GetOnlySigner().Equal(ca.passCert) {
+		t.Fatal("manifest is not signed by the pass certificate")
+	}
+
+	p7.Content = []byte(`{"pass.json":"tampered"}`)
+	if err := p7.Verify(); err == nil {
+		t.Fatal("signature verifies a different manifest")
+	}
+}
+
+func TestPassDescribesTicket(t *testing.T) {
+	ca := newTestCA(t)
+	apple, err := NewApple("pass.kz.aulway.ticket", "TEAM123", "https://aulway.kz/wallet", ca.passPEM, ca.passKey, ca.wwdrPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ticket, route, bus := testTicket()
+	bundle, err := apple.Bundle(apple.Pass(ticket, route, bus, "AW1.token.sig", "0123456789abcdef0123"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pass Pass
+	if err := json.Unmarshal(unzip(t, bundle)["pass.json"], &pass); err != nil {
+		t.Fatal(err)
+	}
+
+	if pass.SerialNumber != ticket.ID || pass.PassTypeIdentifier != "pass.kz.aulway.ticket" || pass.TeamIdentifier != "TEAM123" {
+		t.Fatalf("pass identifiers = %s %s %s", pass.SerialNumber, pass.PassTypeIdentifier, pass.TeamIdentifier)
+	}
+	if pass.Barcodes[0].Message != "AW1.token.sig" {
+		t.Fatalf("barcode = %q, want the ticket token", pass.Barcodes[0].Message)
+	}
+	if pass.WebServiceURL != "https://aulway.kz/wallet" || pass.AuthenticationToken == "" {
+		t.Fatal("pass is not set up for updates")
+	}
+	if pass.Voided {
+		t.Fatal("valid ticket is voided")
+	}
+	if departs := pass.BoardingPass.SecondaryFields[0].Value; departs != "2025-12-12T08:00:00+05:00" {
+		t.Fatalf("departure = %s", departs)
+	}
+
+	ticket.Status = "cancelled"
+	if !apple.Pass(ticket, route, bus, "AW1.token.sig", "").Voided {
+		t.Fatal("cancelled ticket is not voided")
+	}
+}
+
+func TestNewAppleRejectsMismatchedFiles(t *testing.T) {
+	ca := newTestCA(t)
+
+	if _, err := NewApple("pass.kz.aulway.ticket", "TEAM123", "", ca.passKey, ca.passKey, ca.wwdrPEM); err == nil {
+		t.Fatal("a key was accepted as the certificate")
+	}
+	if _, err := NewApple("pass.kz.aulway.ticket", "TEAM123", "", ca.passPEM, ca.passPEM, ca.wwdrPEM); err == nil {
+		t.Fatal("a certificate was accepted as the key")
+	}
+}
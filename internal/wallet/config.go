@@ -0,0 +1,38 @@
+package wallet
+
+import (
+	"aulway/internal/utils/config"
+	"context"
+	"fmt"
+	"os"
+)
+
+// FromConfig sets up the wallets that have credentials configured, the
+// other one is returned as nil.
+func FromConfig(ctx context.Context, cfg config.Wallet) (*Apple, *Google, error) {
+	var (
+		apple  *Apple
+		google *Google
+		err    error
+	)
+
+	if cfg.ApplePassTypeID != "" {
+		apple, err = LoadApple(cfg.ApplePassTypeID, cfg.AppleTeamID, cfg.WebServiceURL, cfg.AppleCertFile, cfg.AppleKeyFile, cfg.AppleWWDRFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("apple wallet: %w", err)
+		}
+	}
+
+	if cfg.GoogleIssuerID != "" {
+		credentials, err := os.ReadFile(cfg.GoogleCredentials)
+		if err != nil {
+			return nil, nil, fmt.Errorf("google wallet: read credentials: %w", err)
+		}
+		google, err = NewGoogle(ctx, credentials, cfg.GoogleIssuerID, cfg.GoogleClassSuffix)
+		if err != nil {
+			return nil, nil, fmt.Errorf("google wallet: %w", err)
+		}
+	}
+
+	return apple, google, nil
+}
@@ -0,0 +1,155 @@
+package wallet
+
+import (
+	"aulway/internal/domain"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/walletobjects/v1"
+	"net/http"
+	"time"
+)
+
+// GoogleSaveURL is the prefix of "Add to Google Wallet" links.
+const GoogleSaveURL = "https://pay.google.com/gp/v/save/"
+
+// Google issues Google Wallet transit passes. The transit class is created
+// once in the Google Pay & Wallet console, every ticket is an object of it.
+type Google struct {
+	IssuerID string
+	ClassID  string
+
+	email   string
+	key     *rsa.PrivateKey
+	objects *walletobjects.TransitobjectService
+}
+
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+}
+
+// NewGoogle takes the JSON key of the service account linked to the issuer
+// account and the suffix of the transit class.
+func NewGoogle(ctx context.Context, credentials []byte, issuerID, classSuffix string) (*Google, error) {
+	var account serviceAccount
+	if err := json.Unmarshal(credentials, &account); err != nil {
+		return nil, fmt.Errorf("parse service account: %w", err)
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("parse service account key: %w", err)
+	}
+
+	service, err := walletobjects.NewService(ctx,
+		option.WithCredentialsJSON(credentials),
+		option.WithScopes(walletobjects.WalletObjectIssuerScope),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("google wallet client: %w", err)
+	}
+
+	return &Google{
+		IssuerID: issuerID,
+		ClassID:  issuerID + "." + classSuffix,
+		email:    account.ClientEmail,
+		key:      key,
+		objects:  service.Transitobject,
+	}, nil
+}
+
+// ObjectID is the id of the wallet object of a ticket.
+func (g *Google) ObjectID(ticketID string) string {
+	return g.IssuerID + "." + ticketID
+}
+
+// TransitObject describes a ticket as a Google Wallet transit object.
+func (g *Google) TransitObject(ticket *domain.Ticket, route *domain.Route, token string) *walletobjects.TransitObject {
+	state, status := "ACTIVE", ""
+	switch ticket.Status {
+	case "boarded":
+		status = "USED"
+	case "cancelled", "no_show", "awaiting":
+		state = "INACTIVE"
+	}
+
+	return &walletobjects.TransitObject{
+		Id:            g.ObjectID(ticket.ID),
+		ClassId:       g.ClassID,
+		State:         state,
+		TicketStatus:  status,
+		TripType:      "ONE_WAY",
+		PassengerType: "SINGLE_PASSENGER",
+		TicketNumber:  ticket.OrderNumber,
+		TripId:        route.Id,
+		Barcode: &walletobjects.Barcode{
+			Type:          "QR_CODE",
+			Value:         token,
+			AlternateText: ticket.OrderNumber,
+		},
+		TicketLeg: &walletobjects.TicketLeg{
+			OriginName:        localized(route.Departure),
+			DestinationName:   localized(route.Destination),
+			DepartureDateTime: route.StartDate.Format(time.RFC3339),
+			ArrivalDateTime:   route.EndDate.Format(time.RFC3339),
+			TicketSeat:        &walletobjects.TicketSeat{Seat: ticket.SeatNumber},
+		},
+		TextModulesData: []*walletobjects.TextModuleData{
+			{Id: "pickup", Header: "Pickup", Body: route.DepartureLocation},
+			{Id: "dropoff", Header: "Drop-off", Body: route.DestinationLocation},
+		},
+		ValidTimeInterval: &walletobjects.TimeInterval{
+			End: &walletobjects.DateTime{Date: route.EndDate.Format(time.RFC3339)},
+		},
+	}
+}
+
+// SaveURL signs the object into a save JWT. Opening the link adds the pass
+// to the wallet of the user, creating the object on the first save.
+func (g *Google) SaveURL(object *walletobjects.TransitObject) (string, error) {
+	claims := jwt.MapClaims{
+		"iss":     g.email,
+		"aud":     "google",
+		"typ":     "savetowallet",
+		"iat":     time.Now().Unix(),
+		"origins": []string{},
+		"payload": map[string]any{
+			"transitObjects": []*walletobjects.TransitObject{object},
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(g.key)
+	if err != nil {
+		return "", fmt.Errorf("sign save link: %w", err)
+	}
+
+	return GoogleSaveURL + signed, nil
+}
+
+// Update pushes the current state of a ticket to the saved object. Tickets
+// nobody saved to Google Wallet have no object, they are skipped.
+func (g *Google) Update(ctx context.Context, object *walletobjects.TransitObject) error {
+	_, err := g.objects.Patch(object.Id, object).Context(ctx).Do()
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("update wallet object %s: %w", object.Id, err)
+	}
+
+	return nil
+}
+
+func localized(value string) *walletobjects.LocalizedString {
+	return &walletobjects.LocalizedString{
+		DefaultValue: &walletobjects.TranslatedString{Language: "ru", Value: value},
+	}
+}
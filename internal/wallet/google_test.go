@@ -0,0 +1,95 @@
+package wallet
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v4"
+	"strings"
+	"testing"
+)
+
+func TestSaveURLCarriesSignedTransitObject(t *testing.T) {
+	key := newKey(t)
+	credentials, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "wallet@aulway-test.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		"token_uri":    "https://oauth2.googleapis.com/token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	google, err := NewGoogle(context.Background(), credentials, "3388000000022", "aulway_bus")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ticket, route, _ := testTicket()
+	url, err := google.SaveURL(google.TransitObject(ticket, route, "AW1.token.sig"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(url, GoogleSaveURL) {
+		t.Fatalf("url = %s", url)
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(strings.TrimPrefix(url, GoogleSaveURL), claims, func(*jwt.Token) (any, error) {
+		return &key.PublicKey, nil
+	})
+	if err != nil {
+		t.Fatalf("save JWT does not verify with the service account key: %v", err)
+	}
+	if claims["iss"] != "wallet@aulway-test.iam.gserviceaccount.com" || claims["aud"] != "google" || claims["typ"] != "savetowallet" {
+		t.Fatalf("claims = %v", claims)
+	}
+
+	payload, _ := json.Marshal(claims["payload"])
+	var decoded struct {
+		TransitObjects []struct {
+			ID        string `json:"id"`
+			ClassID   string `json:"classId"`
+			State     string `json:"state"`
+			Barcode   struct{ Value string }
+			TicketLeg struct {
+				DepartureDateTime string
+				TicketSeat        struct{ Seat string }
+			}
+		} `json:"transitObjects"`
+	}
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.TransitObjects) != 1 {
+		t.Fatalf("payload has %d objects", len(decoded.TransitObjects))
+	}
+
+	object := decoded.TransitObjects[0]
+	if object.ID != "3388000000022."+ticket.ID || object.ClassID != "3388000000022.aulway_bus" {
+		t.Fatalf("object %s of class %s", object.ID, object.ClassID)
+	}
+	if object.State != "ACTIVE" || object.Barcode.Value != "AW1.token.sig" || object.TicketLeg.TicketSeat.Seat != "3B" {
+		t.Fatalf("object = %+v", object)
+	}
+	if object.TicketLeg.DepartureDateTime != "2025-12-12T08:00:00+05:00" {
+		t.Fatalf("departure = %s", object.TicketLeg.DepartureDateTime)
+	}
+}
+
+func TestTransitObjectFollowsTicketStatus(t *testing.T) {
+	google := &Google{IssuerID: "1", ClassID: "1.bus"}
+	ticket, route, _ := testTicket()
+
+	ticket.Status = "boarded"
+	if object := google.TransitObject(ticket, route, ""); object.State != "ACTIVE" || object.TicketStatus != "USED" {
+		t.Fatalf("boarded ticket: state %s, status %s", object.State, object.TicketStatus)
+	}
+
+	ticket.Status = "cancelled"
+	if object := google.TransitObject(ticket, route, ""); object.State != "INACTIVE" {
+		t.Fatalf("cancelled ticket: state %s", object.State)
+	}
+}
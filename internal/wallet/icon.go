@@ -0,0 +1,37 @@
+package wallet
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// Wallet requires an icon in every pass. It is drawn here, a white bus
+// window strip on the brand blue, so the bundle has no asset files.
+var (
+	icon29 = drawIcon(29)
+	icon58 = drawIcon(58)
+)
+
+func drawIcon(size int) []byte {
+	brand := color.RGBA{R: 45, G: 137, B: 239, A: 255}
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			img.Set(x, y, brand)
+			inBody := x >= size/6 && x < size-size/6 && y >= size/4 && y < size-size/4
+			if inBody && (y < size/2 || y >= size-size/3) {
+				img.Set(x, y, color.White)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		panic(err)
+	}
+
+	return buf.Bytes()
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"aulway/internal/database/postgres"
 	"aulway/internal/database/redis"
+	"aulway/internal/mockgateway"
+	"aulway/internal/service"
 	xtransport "aulway/internal/transport/http"
 	"aulway/internal/utils/config"
 	"context"
@@ -10,6 +12,7 @@ import (
 	"github.com/labstack/gommon/log"
 	"github.com/oklog/run"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -22,7 +25,7 @@ import (
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
-// @description Use "Bearer {your-firebase-token}"
+// @description Use "Bearer {access-token}", a Firebase ID token works in place of the access token
 // @BasePath /
 func main() {
 	cfg, err := config.NewConfig()
@@ -54,7 +57,7 @@ func main() {
 		panic(err)
 	}
 
-	router := xtransport.NewRouter(cfg, database, redis).Build()
+	router, jobs := xtransport.NewRouter(cfg, database, redis).Build()
 
 	var g run.Group
 	{
@@ -74,6 +77,67 @@ func main() {
 			}()
 		})
 	}
+	{
+		jobCtx, cancelJob := context.WithCancel(ctx)
+		g.Add(func() error {
+			return jobs.NoShows.Run(jobCtx)
+		}, func(err error) {
+			cancelJob()
+		})
+	}
+	{
+		jobCtx, cancelJob := context.WithCancel(ctx)
+		g.Add(func() error {
+			return jobs.QRCodes.Run(jobCtx)
+		}, func(err error) {
+			cancelJob()
+		})
+	}
+	{
+		jobCtx, cancelJob := context.WithCancel(ctx)
+		g.Add(func() error {
+			return jobs.Outbox.Run(jobCtx)
+		}, func(err error) {
+			cancelJob()
+		})
+	}
+	{
+		jobCtx, cancelJob := context.WithCancel(ctx)
+		g.Add(func() error {
+			return jobs.Reminders.Run(jobCtx)
+		}, func(err error) {
+			cancelJob()
+		})
+	}
+	{
+		jobCtx, cancelJob := context.WithCancel(ctx)
+		g.Add(func() error {
+			return jobs.Refunds.Run(jobCtx)
+		}, func(err error) {
+			cancelJob()
+		})
+	}
+	{
+		jobCtx, cancelJob := context.WithCancel(ctx)
+		g.Add(func() error {
+			return jobs.ExpiredPayments.Run(jobCtx)
+		}, func(err error) {
+			cancelJob()
+		})
+	}
+	if cfg.PaymentProvider == service.MockGatewayProvider {
+		gateway := &http.Server{Addr: cfg.MockGatewayAddress, Handler: mockgateway.New().Handler()}
+		g.Add(func() error {
+			slog.Info("mock payment gateway listening", "address", cfg.MockGatewayAddress)
+			return gateway.ListenAndServe()
+		}, func(err error) {
+			ctxShutDown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if shutdownErr := gateway.Shutdown(ctxShutDown); shutdownErr != nil {
+				slog.Error("error shutting down mock payment gateway", "error", shutdownErr.Error())
+			}
+		})
+	}
 	{
 		cancelInterrupt := make(chan struct{})
 		g.Add(func() error {